@@ -0,0 +1,40 @@
+// Команда auditverify проверяет цепочку хешей журнала аудита и завершается с кодом 1,
+// если журнал был изменён в обход сервиса
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/database"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	var cfg = common.GetConfig(".env")
+	var logger = common.NewLogger(cfg)
+	result, err := verify(cfg)
+	if err != nil {
+		logger.Fatal("audit chain verification failed", zap.Error(err))
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(result)
+	if !result.Valid {
+		logger.Error("audit chain is broken", zap.Int64("event_id", result.BrokenId), zap.String("reason", result.Reason))
+		os.Exit(1)
+	}
+}
+
+func verify(cfg common.Config) (audit.VerifyResult, error) {
+	var db = database.ConnectDbWithCfg(cfg)
+	defer func() { _ = db.Close() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	return audit.NewService(audit.NewRepository(db)).Verify(ctx)
+}
@@ -7,8 +7,8 @@ import (
 	"idm/inner/common"
 	"idm/inner/server"
 	"idm/inner/web"
+	"net"
 	"os/signal"
-	"sync"
 	"syscall"
 	"time"
 
@@ -25,12 +25,11 @@ import (
 // @in header
 // @name Authorization
 func main() {
-	var srv, db = server.Build()
+	var srv, db, logger = server.Build()
 	var cfg = common.GetConfig(".env")
 	// Переопределяем версию приложения, которая будет отображаться в swagger UI.
 	// Пакет docs и структура SwaggerInfo в нём появятся поле генерации документации (см. далее).
 	docs.SwaggerInfo.Version = cfg.AppVersion
-	var logger = common.NewLogger(cfg)
 
 	// загружаем сертификаты
 	cer, err := tls.LoadX509KeyPair(cfg.SslSert, cfg.SslKey)
@@ -39,54 +38,78 @@ func main() {
 	}
 	// создаём конфигурацию TLS сервера
 	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cer}}
-	// создаём слушателя https соединения
-	ln, err := tls.Listen("tcp", ":8080", tlsConfig)
+	// публичный API всегда работает по https
+	ln, err := tls.Listen("tcp", cfg.ListenAddr, tlsConfig)
 	if err != nil {
 		logger.Panic("failed TLS listener creating: %s", zap.Error(err))
 	}
-	// Запускаем сервер в отдельной горутине
+	// служебный порт по умолчанию работает по http
+	var managementLn net.Listener
+	if cfg.ManagementTls {
+		managementLn, err = tls.Listen("tcp", cfg.ManagementAddr, tlsConfig)
+	} else {
+		managementLn, err = net.Listen("tcp", cfg.ManagementAddr)
+	}
+	if err != nil {
+		logger.Panic("failed management listener creating: %s", zap.Error(err))
+	}
+	// порт проб всегда работает по http: на нём нет ничего, кроме livez и readyz
+	probeLn, err := net.Listen("tcp", cfg.ProbeAddr)
+	if err != nil {
+		logger.Panic("failed probe listener creating: %s", zap.Error(err))
+	}
+	// Запускаем серверы в отдельных горутинах
 	go func() {
 		var err = srv.App.Listener(ln)
 		if err != nil {
 			logger.Panic("http srv error: %s", zap.Error(err))
 		}
 	}()
+	go func() {
+		var err = srv.Management.Listener(managementLn)
+		if err != nil {
+			logger.Panic("management srv error: %s", zap.Error(err))
+		}
+	}()
+	go func() {
+		var err = srv.Probes.Listener(probeLn)
+		if err != nil {
+			logger.Panic("probe srv error: %s", zap.Error(err))
+		}
+	}()
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	go gracefulShutdown(srv, ctx, &wg, logger)
-	go closeDb(db, ctx, &wg, logger)
-
-	wg.Wait()
+	// БД закрывается только после остановки серверов: в период задержки запросы ещё обслуживаются
+	gracefulShutdown(srv, ctx, logger, cfg.ShutdownDrainDelay)
+	closeDb(db, logger)
 	logger.Sugar().Info("Graceful shutdown complete.")
 }
 
 // Функция "элегантного" завершения работы сервера по сигналу от операционной системы
-func gracefulShutdown(server *web.Server, ctx context.Context, wg *sync.WaitGroup, logger *common.Logger) {
-	// Уведомить основную горутину о завершении работы
-	defer wg.Done()
+func gracefulShutdown(server *web.Server, ctx context.Context, logger *common.Logger, drainDelay time.Duration) {
 	// Слушаем сигнал прерывания от операционной системы
 	<-ctx.Done()
 	logger.Sugar().Info("shutting down gracefully, press Ctrl+C again to force")
+	// readyz начинает отвечать 503, чтобы балансировщик перестал присылать новые запросы
+	server.BeginShutdown()
+	// пока балансировщик не заметил 503 от readyz, он ещё присылает запросы: продолжаем их обслуживать
+	time.Sleep(drainDelay)
 	// Контекст используется для информирования веб-сервера о том,
 	// что у него есть 5 секунд на выполнение запроса, который он обрабатывает в данный момент
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := server.App.ShutdownWithContext(ctx); err != nil {
+	// останавливаем публичный, служебный порты и порт проб одновременно
+	if err := server.Shutdown(ctx); err != nil {
 		logger.Sugar().Error("Server forced to shutdown with error:", zap.Error(err))
 	}
+	// прерываем запросы к БД, которые не успели завершиться за отведённое время
+	server.CancelRequests()
 	logger.Sugar().Info("Server exiting")
 }
 
-func closeDb(db *sqlx.DB, ctx context.Context, wg *sync.WaitGroup, logger *common.Logger) {
-	// Уведомить основную горутину о завершении работы
-	defer wg.Done()
-	// Слушаем сигнал прерывания от операционной системы
-	<-ctx.Done()
+func closeDb(db *sqlx.DB, logger *common.Logger) {
 	err := db.Close()
 	if err != nil {
 		logger.Sugar().Error("Database close error:", zap.Error(err))
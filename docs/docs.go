@@ -15,29 +15,26 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/employees": {
+        "/access-requests": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns all employees",
+                "description": "Returns access requests of the current user's employee, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "employee"
+                    "accessrequest"
                 ],
-                "summary": "List employees",
+                "summary": "List my access requests",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/inner_employee.Response"
-                            }
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_accessrequest_Response"
                         }
                     }
                 }
@@ -48,7 +45,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new employee.",
+                "description": "Requests a role for the current user's employee; the request is routed to the manager, then the role owner, or to IDM administrators if neither exists",
                 "consumes": [
                     "application/json"
                 ],
@@ -56,17 +53,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "employee"
+                    "accessrequest"
                 ],
-                "summary": "create a new employee",
+                "summary": "Request a role",
                 "parameters": [
                     {
-                        "description": "create employee request",
+                        "description": "access request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/inner_employee.CreateRequest"
+                            "$ref": "#/definitions/inner_accessrequest.CreateRequest"
                         }
                     }
                 ],
@@ -74,63 +71,103 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/idm_inner_common.Response-int64"
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_accessrequest_Response"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request, unknown role, or the user has no employee record",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "role is already granted or a pending request exists",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/access-requests/inbox": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes employees with the specified ids",
-                "consumes": [
+                "description": "Returns pending access requests whose current stage the current user can decide, nearest deadline first; administrators see all pending requests",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "accessrequest"
+                ],
+                "summary": "List my pending approvals",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_accessrequest_Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/access-requests/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the access request together with approver decisions. Available to its author, the employee it is filed for, the approver of its current stage and administrators",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "employee"
+                    "accessrequest"
                 ],
-                "summary": "Delete employees by ids",
+                "summary": "Get access request by id",
                 "parameters": [
                     {
-                        "description": "employee ids",
-                        "name": "ids",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "integer"
-                            }
-                        }
+                        "type": "integer",
+                        "description": "access request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_accessrequest_Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
                         }
                     }
                 }
             }
         },
-        "/employees/add": {
+        "/access-requests/{id}/approve": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Add a new employee (no id returned)",
+                "description": "Approves the current stage; approving the last stage grants the role",
                 "consumes": [
                     "application/json"
                 ],
@@ -138,17 +175,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "employee"
+                    "accessrequest"
                 ],
-                "summary": "Add employee",
+                "summary": "Approve access request",
                 "parameters": [
                     {
-                        "description": "create employee request",
+                        "type": "integer",
+                        "description": "access request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "approver comment",
                         "name": "request",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/inner_employee.CreateRequest"
+                            "$ref": "#/definitions/inner_accessrequest.DecisionRequest"
                         }
                     }
                 ],
@@ -156,113 +199,183 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_accessrequest_Response"
+                        }
+                    },
+                    "403": {
+                        "description": "the user is not the approver of the current stage",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "request is closed or overdue",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
                         }
                     }
                 }
             }
         },
-        "/employees/batch": {
+        "/access-requests/{id}/cancel": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns employees by the given ids",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Cancels a pending request; allowed for its author and IDM administrators",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "employee"
+                    "accessrequest"
                 ],
-                "summary": "Get employees by ids",
+                "summary": "Cancel access request",
                 "parameters": [
                     {
-                        "description": "employee ids",
-                        "name": "ids",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "integer"
-                            }
-                        }
+                        "type": "integer",
+                        "description": "access request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/inner_employee.Response"
-                            }
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_accessrequest_Response"
+                        }
+                    },
+                    "403": {
+                        "description": "the user is not the author",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "request is already closed",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
                         }
                     }
                 }
             }
         },
-        "/employees/page": {
-            "get": {
+        "/access-requests/{id}/reject": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns paginated list of employees",
+                "description": "Rejects the request at its current stage; a comment is required",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "employee"
+                    "accessrequest"
                 ],
-                "summary": "Get employees page",
+                "summary": "Reject access request",
                 "parameters": [
                     {
-                        "minimum": 0,
-                        "type": "integer",
-                        "name": "pageNumber",
-                        "in": "query"
-                    },
-                    {
-                        "maximum": 100,
-                        "minimum": 1,
                         "type": "integer",
-                        "name": "pageSize",
-                        "in": "query"
+                        "description": "access request id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "string",
-                        "name": "textFilter",
-                        "in": "query"
+                        "description": "rejection reason",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_accessrequest.DecisionRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/inner_employee.PageResponse"
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_accessrequest_Response"
+                        }
+                    },
+                    "400": {
+                        "description": "comment is missing",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "the user is not the approver of the current stage",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "request is closed or overdue",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
                         }
                     }
                 }
             }
         },
-        "/employees/save": {
+        "/access-reviews": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns all campaigns, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accessreview"
+                ],
+                "summary": "List access review campaigns",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_accessreview_Response"
+                        }
+                    }
+                }
+            },
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create or update employee and return its id",
+                "description": "Starts a campaign reviewing role assignments active in the scope (a role, an org unit subtree or everything); each assignment is reviewed by the employee's manager or the role owner",
                 "consumes": [
                     "application/json"
                 ],
@@ -270,17 +383,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "employee"
+                    "accessreview"
                 ],
-                "summary": "Save employee",
+                "summary": "Start access review campaign",
                 "parameters": [
                     {
-                        "description": "save employee request",
+                        "description": "create access review request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/inner_employee.CreateRequest"
+                            "$ref": "#/definitions/inner_accessreview.CreateRequest"
                         }
                     }
                 ],
@@ -288,35 +401,68 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer",
-                                "format": "int64"
-                            }
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_accessreview_Response"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request or scope not found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "no active role assignments in the scope",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
                         }
                     }
                 }
             }
         },
-        "/employees/{id}": {
+        "/access-reviews/inbox": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns employee by id",
+                "description": "Returns undecided role assignments of open campaigns assigned to the current user; administrators also get assignments without a reviewer",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "employee"
+                    "accessreview"
                 ],
-                "summary": "Get employee by id",
+                "summary": "List my pending review items",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_accessreview_ItemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/access-reviews/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the campaign together with review progress",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accessreview"
+                ],
+                "summary": "Get access review campaign by id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "employee id",
+                        "description": "access review id",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -326,29 +472,37 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/inner_employee.Response"
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_accessreview_Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/access-reviews/{id}/close": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes employee with the specified id",
+                "description": "Closes the campaign before its deadline: revokes assignments decided as revoke (and undecided ones if the campaign is configured so) and stores the final report",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "employee"
+                    "accessreview"
                 ],
-                "summary": "Delete employee by id",
+                "summary": "Close access review campaign",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "employee id",
+                        "description": "access review id",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -358,45 +512,3306 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_accessreview_Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "campaign is already closed",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
                         }
                     }
                 }
             }
-        }
+        },
+        "/access-reviews/{id}/items": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns all role assignments of the campaign with reviewers, decisions and, once closed, outcomes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accessreview"
+                ],
+                "summary": "List access review items",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "access review id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_accessreview_ItemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/access-reviews/{id}/items/{itemId}/decision": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Keeps or revokes the role assignment when the campaign closes, or delegates its review to another employee; the decision can be changed while the campaign is open",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accessreview"
+                ],
+                "summary": "Decide access review item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "access review id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "item id",
+                        "name": "itemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "decision",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_accessreview.DecisionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_accessreview_ItemResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid decision or delegate",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "the user is not the reviewer of the item",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "campaign is closed or overdue",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/access-reviews/{id}/report": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the final report of a closed campaign as a JSON attachment with its sha256 digest (also recorded in the audit log) and its HMAC-SHA256 signature made when the campaign was closed. Both are computed over the report with sorted keys and no whitespace",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accessreview"
+                ],
+                "summary": "Export access review report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "access review id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inner_accessreview.SignedReport"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "campaign is not closed yet",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/audit/events": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns audit events from newest to oldest filtered by actor, action, entity and period",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Get audit events page",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "name": "entityId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "entityType",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "name": "pageNumber",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_audit_PageResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns all employees",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "List employees",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inner_employee.Response"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new employee.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "create a new employee",
+                "parameters": [
+                    {
+                        "description": "create employee request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-int64"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes employees with the specified ids; if any of them is not found, none is deleted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Delete employees by ids",
+                "parameters": [
+                    {
+                        "description": "employee ids",
+                        "name": "ids",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/add": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add a new employee (no id returned)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Add employee",
+                "parameters": [
+                    {
+                        "description": "create employee request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/batch": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns employees by the given ids",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Get employees by ids",
+                "parameters": [
+                    {
+                        "description": "employee ids",
+                        "name": "ids",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inner_employee.Response"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/org-chart": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the reporting tree of the whole organization or of the root employee, as JSON or as Graphviz DOT",
+                "produces": [
+                    "application/json",
+                    "text/vnd.graphviz"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Export org chart",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "root employee id",
+                        "name": "root",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "dot"
+                        ],
+                        "type": "string",
+                        "description": "json (default) or dot",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_employee_ChartNode"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/page": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns paginated list of employees.\nCustom attributes are filtered by exact match with attr.\u003cname\u003e=\u003cvalue\u003e query parameters",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Get employees page",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "IncludeDeleted - показывать и мягко удалённых сотрудников (только для администраторов)",
+                        "name": "includeDeleted",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "name": "pageNumber",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "textFilter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.PageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid page request or unknown attribute",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "includeDeleted requested by non-admin",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/save": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new employee and return its id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Save employee",
+                "parameters": [
+                    {
+                        "description": "save employee request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer",
+                                "format": "int64"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns employee by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Get employee by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "employee version for If-Match"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces employee data. With If-Match header the update is applied only if the employee was not modified since",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Update employee",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag returned by GET /employees/{id}",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "update employee request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes employee with the specified id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Delete employee by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies JSON Merge Patch (RFC 7386) to employee. With If-Match header the patch is applied only if the employee was not modified since",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Patch employee",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag returned by GET /employees/{id}",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "merge patch",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/{id}/grants": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns role grants of the employee with their validity periods, including not yet started ones",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Get employee role grants",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_employee_GrantResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/{id}/managers": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns managers of the employee from the direct manager up to the top of the organization",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Get employee reporting chain",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_employee_Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/{id}/reports": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns direct reports of the employee; with transitive=true all reports down the hierarchy, level by level",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Get employee reports",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "include indirect reports",
+                        "name": "transitive",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_employee_Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Restores soft-deleted employee with the specified id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Restore employee",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_employee_Response"
+                        }
+                    },
+                    "400": {
+                        "description": "name is taken by another employee",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "deleted employee not found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/{id}/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns roles assigned to the employee",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "List roles of employee",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/idm_inner_role.Response"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Assigns the specified roles to the employee, optionally for a limited period (valid_from/valid_to);\nassigning an already assigned role replaces its validity period. Expired grants are revoked automatically",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Assign roles to employee",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "role ids and validity period",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.AssignRolesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request, roles not found, or invalid validity period",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes the specified roles from the employee",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Revoke roles from employee",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "role ids",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.RolesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/{id}/status": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Moves the employee to another lifecycle status; terminating revokes all roles. The effective date must not be in the future",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Change employee lifecycle status",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new status",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_employee.TransitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_employee_Response"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request or effective date in the future",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "transition is not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/employees/{id}/status-history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns lifecycle status transitions of the employee in chronological order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "employee"
+                ],
+                "summary": "Get employee status history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "employee id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_employee_StatusHistoryResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/org-units": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns all org units ordered so that every parent precedes its descendants",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgunit"
+                ],
+                "summary": "List org units",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_orgunit_Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a department under the given parent (or a root one) and returns its id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgunit"
+                ],
+                "summary": "Create org unit",
+                "parameters": [
+                    {
+                        "description": "create org unit request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_orgunit.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-int64"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request, parent or manager not found, or name is taken in the parent",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/org-units/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns org unit by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgunit"
+                ],
+                "summary": "Get org unit by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "org unit id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_orgunit_Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Renames the org unit and sets its manager; use move to change the parent",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgunit"
+                ],
+                "summary": "Update org unit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "org unit id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "update org unit request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_orgunit.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_orgunit_Response"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request, manager not found, or name is taken in the parent",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes an org unit that has no child units and no employees",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgunit"
+                ],
+                "summary": "Delete org unit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "org unit id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "org unit has child units or employees",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/org-units/{id}/ancestors": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the chain of ancestors from the root down to the direct parent",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgunit"
+                ],
+                "summary": "Get org unit ancestors",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "org unit id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_orgunit_Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/org-units/{id}/employees": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns employees of the org unit; with subtree=true also employees of all its descendant units",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgunit"
+                ],
+                "summary": "List employees of org unit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "org unit id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "include descendant units",
+                        "name": "subtree",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inner_employee.Response"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/org-units/{id}/move": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Moves the org unit with its whole subtree under another parent; without parent_id the unit becomes a root",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgunit"
+                ],
+                "summary": "Move org unit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "org unit id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "move org unit request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_orgunit.MoveRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_orgunit_Response"
+                        }
+                    },
+                    "400": {
+                        "description": "parent not found or name is taken in the new parent",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "new parent is inside the unit's own subtree",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/org-units/{id}/subtree": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the org unit and all its descendants",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgunit"
+                ],
+                "summary": "Get org unit subtree",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "org unit id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-array_inner_orgunit_Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns all roles",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "List roles",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inner_role.Response"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new role and return its id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Create role",
+                "parameters": [
+                    {
+                        "description": "create role request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_role.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-int64"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes roles with the specified ids; if any of them is not found, none is deleted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Delete roles by ids",
+                "parameters": [
+                    {
+                        "description": "role ids",
+                        "name": "ids",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles/batch": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns roles by the given ids",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Get roles by ids",
+                "parameters": [
+                    {
+                        "description": "role ids",
+                        "name": "ids",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inner_role.Response"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/roles/page": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns paginated list of roles",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Get roles page",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "IncludeDeleted - показывать и мягко удалённые роли (только для администраторов)",
+                        "name": "includeDeleted",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "name": "pageNumber",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "textFilter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inner_role.PageResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns role by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Get role by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "role id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inner_role.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes role with the specified id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Delete role by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "role id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles/{id}/employees": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns employees the role is assigned to",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "List employees with role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "role id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/inner_employee.Response"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles/{id}/owner": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sets the employee responsible for the role; without owner_id the owner is removed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Set role owner",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "role id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "role owner",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/inner_role.OwnerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_role_Response"
+                        }
+                    },
+                    "400": {
+                        "description": "owner employee not found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Restores soft-deleted role with the specified id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Restore role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "role id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-inner_role_Response"
+                        }
+                    },
+                    "400": {
+                        "description": "name is taken by another role",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "deleted role not found",
+                        "schema": {
+                            "$ref": "#/definitions/idm_inner_common.Response-any"
+                        }
+                    }
+                }
+            }
+        }
     },
     "definitions": {
+        "idm_inner_common.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "param": {
+                    "type": "string"
+                },
+                "tag": {
+                    "type": "string"
+                }
+            }
+        },
+        "idm_inner_common.Response-any": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-array_inner_accessrequest_Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_accessrequest.Response"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-array_inner_accessreview_ItemResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_accessreview.ItemResponse"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-array_inner_accessreview_Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_accessreview.Response"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-array_inner_employee_ChartNode": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_employee.ChartNode"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-array_inner_employee_GrantResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_employee.GrantResponse"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-array_inner_employee_Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_employee.Response"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-array_inner_employee_StatusHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_employee.StatusHistoryResponse"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-array_inner_orgunit_Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_orgunit.Response"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-inner_accessrequest_Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/inner_accessrequest.Response"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-inner_accessreview_ItemResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/inner_accessreview.ItemResponse"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-inner_accessreview_Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/inner_accessreview.Response"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-inner_audit_PageResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/inner_audit.PageResponse"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-inner_employee_Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/inner_employee.Response"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-inner_orgunit_Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/inner_orgunit.Response"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_common.Response-inner_role_Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/inner_role.Response"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
         "idm_inner_common.Response-int64": {
             "type": "object",
             "properties": {
-                "data": {
+                "data": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/idm_inner_common.FieldError"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "idm_inner_role.Response": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "deleted_by": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_accessrequest.CreateRequest": {
+            "type": "object",
+            "required": [
+                "justification",
+                "role_id"
+            ],
+            "properties": {
+                "justification": {
+                    "type": "string",
+                    "maxLength": 1000,
+                    "minLength": 10
+                },
+                "role_id": {
+                    "type": "integer"
+                },
+                "valid_to": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_accessrequest.DecisionRequest": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string",
+                    "maxLength": 1000
+                }
+            }
+        },
+        "inner_accessrequest.DecisionResponse": {
+            "type": "object",
+            "properties": {
+                "approver": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "decided_at": {
+                    "type": "string"
+                },
+                "decision": {
+                    "type": "string"
+                },
+                "stage": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_accessrequest.Response": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current_stage": {
+                    "description": "CurrentStage - этап, ожидающий решения; у закрытых заявок не заполняется",
+                    "type": "string"
+                },
+                "decisions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_accessrequest.DecisionResponse"
+                    }
+                },
+                "due_at": {
+                    "type": "string"
+                },
+                "employee_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "justification": {
+                    "type": "string"
+                },
+                "requested_by": {
+                    "type": "string"
+                },
+                "resolved_at": {
+                    "type": "string"
+                },
+                "resolved_by": {
+                    "type": "string"
+                },
+                "role_id": {
+                    "type": "integer"
+                },
+                "stages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "valid_to": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_accessreview.CreateRequest": {
+            "type": "object",
+            "required": [
+                "due_at",
+                "name",
+                "reviewer_type",
+                "scope_type"
+            ],
+            "properties": {
+                "due_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 2
+                },
+                "reviewer_type": {
+                    "type": "string",
+                    "enum": [
+                        "manager",
+                        "role_owner"
+                    ]
+                },
+                "revoke_undecided": {
+                    "type": "boolean"
+                },
+                "scope_id": {
+                    "type": "integer"
+                },
+                "scope_type": {
+                    "type": "string",
+                    "enum": [
+                        "role",
+                        "org_unit",
+                        "all"
+                    ]
+                }
+            }
+        },
+        "inner_accessreview.DecisionRequest": {
+            "type": "object",
+            "required": [
+                "decision"
+            ],
+            "properties": {
+                "comment": {
+                    "type": "string",
+                    "maxLength": 1000
+                },
+                "decision": {
+                    "type": "string",
+                    "enum": [
+                        "keep",
+                        "revoke",
+                        "delegate"
+                    ]
+                },
+                "delegate_to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "inner_accessreview.ItemResponse": {
+            "type": "object",
+            "properties": {
+                "campaign_id": {
+                    "type": "integer"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "decided_at": {
+                    "type": "string"
+                },
+                "decided_by": {
+                    "type": "string"
+                },
+                "decision": {
+                    "type": "string"
+                },
+                "delegated_by": {
+                    "type": "string"
+                },
+                "employee_id": {
+                    "type": "integer"
+                },
+                "employee_name": {
+                    "type": "string"
+                },
+                "granted_valid_from": {
+                    "type": "string"
+                },
+                "granted_valid_to": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "outcome": {
+                    "type": "string"
+                },
+                "reviewer_id": {
+                    "description": "ReviewerId - проверяющий; если не заполнен, назначение проверяет администратор IDM",
+                    "type": "integer"
+                },
+                "role_id": {
+                    "type": "integer"
+                },
+                "role_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_accessreview.Progress": {
+            "type": "object",
+            "properties": {
+                "decided": {
+                    "type": "integer"
+                },
+                "delegated": {
+                    "type": "integer"
+                },
+                "kept": {
+                    "type": "integer"
+                },
+                "revoked": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "inner_accessreview.Response": {
+            "type": "object",
+            "properties": {
+                "closed_at": {
+                    "type": "string"
+                },
+                "closed_by": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "due_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "progress": {
+                    "description": "Progress - ход проверки; заполняется при чтении одной кампании",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/inner_accessreview.Progress"
+                        }
+                    ]
+                },
+                "report_digest": {
+                    "type": "string"
+                },
+                "reviewer_type": {
+                    "type": "string"
+                },
+                "revoke_undecided": {
+                    "type": "boolean"
+                },
+                "scope_id": {
+                    "type": "integer"
+                },
+                "scope_type": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_accessreview.SignedReport": {
+            "type": "object",
+            "properties": {
+                "digest": {
+                    "type": "string"
+                },
+                "report": {
+                    "type": "object"
+                },
+                "signature": {
+                    "type": "string"
+                },
+                "signature_algorithm": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_audit.PageResponse": {
+            "type": "object",
+            "properties": {
+                "page_number": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "result": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_audit.Response"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "inner_audit.Response": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor_name": {
+                    "type": "string"
+                },
+                "actor_sub": {
+                    "type": "string"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                },
+                "entity_id": {
+                    "type": "integer"
+                },
+                "entity_type": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "prev_hash": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_employee.AssignRolesRequest": {
+            "type": "object",
+            "required": [
+                "role_ids"
+            ],
+            "properties": {
+                "role_ids": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "valid_from": {
+                    "type": "string"
+                },
+                "valid_to": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_employee.ChartNode": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "reports": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/inner_employee.ChartNode"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_employee.CreateRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "attributes": {
+                    "description": "Attributes - дополнительные атрибуты; допустимые имена, типы и правила задаются в конфигурации",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "email": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "employee_number": {
+                    "type": "string",
+                    "maxLength": 32
+                },
+                "first_name": {
+                    "type": "string",
+                    "maxLength": 155
+                },
+                "last_name": {
+                    "type": "string",
+                    "maxLength": 155
+                },
+                "login": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 2
+                },
+                "manager_id": {
                     "type": "integer"
                 },
-                "error": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 155,
+                    "minLength": 2
+                },
+                "org_unit_id": {
+                    "type": "integer"
+                },
+                "phone": {
                     "type": "string"
                 },
-                "success": {
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status - начальный статус: pre_hire для будущих сотрудников, по умолчанию active",
+                    "type": "string",
+                    "enum": [
+                        "pre_hire",
+                        "active"
+                    ]
+                },
+                "title": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "inner_employee.GrantResponse": {
+            "type": "object",
+            "properties": {
+                "active": {
                     "type": "boolean"
+                },
+                "employee_id": {
+                    "type": "integer"
+                },
+                "role_id": {
+                    "type": "integer"
+                },
+                "role_name": {
+                    "type": "string"
+                },
+                "valid_from": {
+                    "type": "string"
+                },
+                "valid_to": {
+                    "type": "string"
                 }
             }
         },
-        "inner_employee.CreateRequest": {
+        "inner_employee.PageResponse": {
+            "type": "object",
+            "properties": {
+                "page_number": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "result": {},
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "inner_employee.Response": {
+            "type": "object",
+            "properties": {
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "deleted_by": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "employee_number": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "manager_id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "org_unit_id": {
+                    "type": "integer"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_employee.RolesRequest": {
+            "type": "object",
+            "required": [
+                "role_ids"
+            ],
+            "properties": {
+                "role_ids": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "inner_employee.StatusHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "changed_at": {
+                    "type": "string"
+                },
+                "changed_by": {
+                    "type": "string"
+                },
+                "effective_date": {
+                    "type": "string"
+                },
+                "from_status": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to_status": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_employee.TransitionRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "effective_date": {
+                    "description": "EffectiveDate - дата, с которой действует статус: не позже текущей, по умолчанию текущая",
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pre_hire",
+                        "active",
+                        "suspended",
+                        "on_leave",
+                        "terminated"
+                    ]
+                }
+            }
+        },
+        "inner_employee.UpdateRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "attributes": {
+                    "description": "Attributes - дополнительные атрибуты; допустимые имена, типы и правила задаются в конфигурации",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "email": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "employee_number": {
+                    "type": "string",
+                    "maxLength": 32
+                },
+                "first_name": {
+                    "type": "string",
+                    "maxLength": 155
+                },
+                "last_name": {
+                    "type": "string",
+                    "maxLength": 155
+                },
+                "login": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 2
+                },
+                "manager_id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 155,
+                    "minLength": 2
+                },
+                "org_unit_id": {
+                    "type": "integer"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "inner_orgunit.CreateRequest": {
             "type": "object",
             "required": [
                 "name"
             ],
             "properties": {
+                "manager_id": {
+                    "type": "integer"
+                },
                 "name": {
                     "type": "string",
                     "maxLength": 155,
                     "minLength": 2
+                },
+                "parent_id": {
+                    "description": "ParentId - родительское подразделение; без него подразделение создаётся корневым",
+                    "type": "integer"
                 }
             }
         },
-        "inner_employee.PageResponse": {
+        "inner_orgunit.MoveRequest": {
+            "type": "object",
+            "properties": {
+                "parent_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "inner_orgunit.Response": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "depth": {
+                    "description": "Depth - уровень вложенности, у корневых подразделений 0",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "manager_id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "type": "integer"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "inner_orgunit.UpdateRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "manager_id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 155,
+                    "minLength": 2
+                }
+            }
+        },
+        "inner_role.CreateRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 155,
+                    "minLength": 2
+                },
+                "owner_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "inner_role.OwnerRequest": {
+            "type": "object",
+            "properties": {
+                "owner_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "inner_role.PageResponse": {
             "type": "object",
             "properties": {
                 "page_number": {
@@ -411,18 +3826,27 @@ const docTemplate = `{
                 }
             }
         },
-        "inner_employee.Response": {
+        "inner_role.Response": {
             "type": "object",
             "properties": {
                 "created_at": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "deleted_by": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
                 "name": {
                     "type": "string"
                 },
+                "owner_id": {
+                    "type": "integer"
+                },
                 "updated_at": {
                     "type": "string"
                 }
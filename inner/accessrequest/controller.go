@@ -0,0 +1,240 @@
+package accessrequest
+
+import (
+	"context"
+	"strconv"
+
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	server               *web.Server
+	accessRequestService Svc
+	logger               *common.Logger
+}
+
+// Svc описывает набор методов бизнес-логики по работе с заявками на доступ.
+// admin - текущий пользователь администратор IDM: он может согласовать любой этап и отозвать любую заявку
+type Svc interface {
+	FindById(ctx context.Context, id int64, admin bool) (Response, error)
+	FindMine(ctx context.Context) ([]Response, error)
+	FindInbox(ctx context.Context, admin bool) ([]Response, error)
+	Create(ctx context.Context, req CreateRequest) (Response, error)
+	Approve(ctx context.Context, id int64, req DecisionRequest, admin bool) (Response, error)
+	Reject(ctx context.Context, id int64, req DecisionRequest, admin bool) (Response, error)
+	Cancel(ctx context.Context, id int64, admin bool) (Response, error)
+}
+
+func NewController(server *web.Server, accessRequestService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:               server,
+		accessRequestService: accessRequestService,
+		logger:               logger,
+	}
+}
+
+// RegisterRoutes регистрирует маршруты; права доступа к ним задаются политикой (policies.yaml)
+func (c *Controller) RegisterRoutes() {
+
+	grp := c.server.GroupApiV1.Group("/access-requests")
+
+	grp.Post("/", c.CreateAccessRequest)
+	grp.Post("/:id/approve", c.ApproveAccessRequest)
+	grp.Post("/:id/reject", c.RejectAccessRequest)
+	grp.Post("/:id/cancel", c.CancelAccessRequest)
+
+	grp.Get("/", c.GetMyAccessRequests)
+	// inbox регистрируется раньше /:id, иначе его перехватит маршрут с параметром
+	grp.Get("/inbox", c.GetInbox)
+	grp.Get("/:id", c.GetAccessRequest)
+}
+
+// CreateAccessRequest godoc
+// @Summary      Request a role
+// @Description  Requests a role for the current user's employee; the request is routed to the manager, then the role owner, or to IDM administrators if neither exists
+// @Tags         accessrequest
+// @Accept       json
+// @Produce      json
+// @Param        request  body      accessrequest.CreateRequest  true  "access request"
+// @Success      200      {object}  common.Response[accessrequest.Response]
+// @Failure      400      {object}  common.Response[any]  "invalid request, unknown role, or the user has no employee record"
+// @Failure      409      {object}  common.Response[any]  "role is already granted or a pending request exists"
+// @Router       /access-requests [post]
+// @Security BearerAuth
+func (c *Controller) CreateAccessRequest(ctx *fiber.Ctx) error {
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("create access request", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	c.logger.Ctx(ctx.UserContext()).Debug("create access request: received request", zap.Any("request", request))
+
+	resp, err := c.accessRequestService.Create(ctx.UserContext(), request)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("create access request", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// GetMyAccessRequests godoc
+// @Summary      List my access requests
+// @Description  Returns access requests of the current user's employee, newest first
+// @Tags         accessrequest
+// @Produce      json
+// @Success      200  {object}  common.Response[[]accessrequest.Response]
+// @Router       /access-requests [get]
+// @Security BearerAuth
+func (c *Controller) GetMyAccessRequests(ctx *fiber.Ctx) error {
+	resps, err := c.accessRequestService.FindMine(ctx.UserContext())
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get my access requests", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// GetInbox godoc
+// @Summary      List my pending approvals
+// @Description  Returns pending access requests whose current stage the current user can decide, nearest deadline first; administrators see all pending requests
+// @Tags         accessrequest
+// @Produce      json
+// @Success      200  {object}  common.Response[[]accessrequest.Response]
+// @Router       /access-requests/inbox [get]
+// @Security BearerAuth
+func (c *Controller) GetInbox(ctx *fiber.Ctx) error {
+	resps, err := c.accessRequestService.FindInbox(ctx.UserContext(), isAdmin(ctx))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get access request inbox", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// GetAccessRequest godoc
+// @Summary      Get access request by id
+// @Description  Returns the access request together with approver decisions. Available to its author, the employee it is filed for, the approver of its current stage and administrators
+// @Tags         accessrequest
+// @Produce      json
+// @Param        id   path      int  true  "access request id"
+// @Success      200  {object}  common.Response[accessrequest.Response]
+// @Failure      403  {object}  common.Response[any]
+// @Failure      404  {object}  common.Response[any]
+// @Router       /access-requests/{id} [get]
+// @Security BearerAuth
+func (c *Controller) GetAccessRequest(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resp, err := c.accessRequestService.FindById(ctx.UserContext(), id, isAdmin(ctx))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get access request", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// ApproveAccessRequest godoc
+// @Summary      Approve access request
+// @Description  Approves the current stage; approving the last stage grants the role
+// @Tags         accessrequest
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                            true   "access request id"
+// @Param        request  body      accessrequest.DecisionRequest  false  "approver comment"
+// @Success      200      {object}  common.Response[accessrequest.Response]
+// @Failure      403      {object}  common.Response[any]  "the user is not the approver of the current stage"
+// @Failure      404      {object}  common.Response[any]
+// @Failure      409      {object}  common.Response[any]  "request is closed or overdue"
+// @Router       /access-requests/{id}/approve [post]
+// @Security BearerAuth
+func (c *Controller) ApproveAccessRequest(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	var request DecisionRequest
+	// тело с комментарием необязательно
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			c.logger.Ctx(ctx.UserContext()).Error("approve access request", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+		}
+	}
+	resp, err := c.accessRequestService.Approve(ctx.UserContext(), id, request, isAdmin(ctx))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("approve access request", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// RejectAccessRequest godoc
+// @Summary      Reject access request
+// @Description  Rejects the request at its current stage; a comment is required
+// @Tags         accessrequest
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                            true  "access request id"
+// @Param        request  body      accessrequest.DecisionRequest  true  "rejection reason"
+// @Success      200      {object}  common.Response[accessrequest.Response]
+// @Failure      400      {object}  common.Response[any]  "comment is missing"
+// @Failure      403      {object}  common.Response[any]  "the user is not the approver of the current stage"
+// @Failure      404      {object}  common.Response[any]
+// @Failure      409      {object}  common.Response[any]  "request is closed or overdue"
+// @Router       /access-requests/{id}/reject [post]
+// @Security BearerAuth
+func (c *Controller) RejectAccessRequest(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	var request DecisionRequest
+	// без тела запрос отклоняется сервисом: комментарий к отказу обязателен
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			c.logger.Ctx(ctx.UserContext()).Error("reject access request", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+		}
+	}
+	resp, err := c.accessRequestService.Reject(ctx.UserContext(), id, request, isAdmin(ctx))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("reject access request", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// CancelAccessRequest godoc
+// @Summary      Cancel access request
+// @Description  Cancels a pending request; allowed for its author and IDM administrators
+// @Tags         accessrequest
+// @Produce      json
+// @Param        id   path      int  true  "access request id"
+// @Success      200  {object}  common.Response[accessrequest.Response]
+// @Failure      403  {object}  common.Response[any]  "the user is not the author"
+// @Failure      404  {object}  common.Response[any]
+// @Failure      409  {object}  common.Response[any]  "request is already closed"
+// @Router       /access-requests/{id}/cancel [post]
+// @Security BearerAuth
+func (c *Controller) CancelAccessRequest(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resp, err := c.accessRequestService.Cancel(ctx.UserContext(), id, isAdmin(ctx))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("cancel access request", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+func isAdmin(ctx *fiber.Ctx) bool {
+	return web.Satisfies(ctx, web.HasAnyRole(web.IdmAdmin))
+}
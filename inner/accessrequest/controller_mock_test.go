@@ -0,0 +1,188 @@
+package accessrequest
+
+import (
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/policy"
+	"idm/inner/web"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Объявляем структуру мока сервиса accessrequest.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64, admin bool) (Response, error) {
+	args := svc.Called(ctx, id, admin)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindMine(ctx context.Context) ([]Response, error) {
+	args := svc.Called(ctx)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindInbox(ctx context.Context, admin bool) ([]Response, error) {
+	args := svc.Called(ctx, admin)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) Create(ctx context.Context, req CreateRequest) (Response, error) {
+	args := svc.Called(ctx, req)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Approve(ctx context.Context, id int64, req DecisionRequest, admin bool) (Response, error) {
+	args := svc.Called(ctx, id, req, admin)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Reject(ctx context.Context, id int64, req DecisionRequest, admin bool) (Response, error) {
+	args := svc.Called(ctx, id, req, admin)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Cancel(ctx context.Context, id int64, admin bool) (Response, error) {
+	args := svc.Called(ctx, id, admin)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+// newTestServer регистрирует маршруты заявок за политикой доступа по умолчанию
+func newTestServer(t *testing.T, roles ...string) (*web.Server, *MockService) {
+	routePolicy, err := policy.Load("../../policies.yaml")
+	assert.NoError(t, err)
+	enforcer, err := policy.NewEnforcer(routePolicy, "")
+	assert.NoError(t, err)
+
+	server := web.NewServer()
+	server.GroupApiV1.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: roles}}})
+		return c.Next()
+	}, enforcer.Middleware())
+
+	svc := new(MockService)
+	NewController(server, svc, common.NewLogger(common.GetConfig(".env"))).RegisterRoutes()
+	return server, svc
+}
+
+func TestCreateAccessRequest(t *testing.T) {
+	a := assert.New(t)
+	server, svc := newTestServer(t, web.IdmUser)
+	svc.On("Create", mock.Anything, CreateRequest{RoleId: 5, Justification: "need access to billing reports"}).
+		Return(Response{Id: 10, RoleId: 5, Status: StatusPending, CurrentStage: StageManager}, nil)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests",
+		strings.NewReader(`{"role_id":5,"justification":"need access to billing reports"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.App.Test(req, -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	a.NoError(err)
+	var got common.Response[Response]
+	a.NoError(json.Unmarshal(body, &got))
+	a.Equal(int64(10), got.Data.Id)
+	a.Equal(StageManager, got.Data.CurrentStage)
+}
+
+func TestGetInbox(t *testing.T) {
+	a := assert.New(t)
+	for role, admin := range map[string]bool{web.IdmAdmin: true, web.IdmUser: false} {
+		server, svc := newTestServer(t, role)
+		svc.On("FindInbox", mock.Anything, admin).Return([]Response{{Id: 10}}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/access-requests/inbox", nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		// inbox не перехватывается маршрутом /:id
+		svc.AssertNotCalled(t, "FindById", mock.Anything, mock.Anything, mock.Anything)
+		svc.AssertExpectations(t)
+	}
+}
+
+func TestGetAccessRequest(t *testing.T) {
+	a := assert.New(t)
+	t.Run("admin flag is passed to the service", func(t *testing.T) {
+		for role, admin := range map[string]bool{web.IdmAdmin: true, web.IdmUser: false} {
+			server, svc := newTestServer(t, role)
+			svc.On("FindById", mock.Anything, int64(10), admin).Return(Response{Id: 10}, nil)
+
+			resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/access-requests/10", nil), -1)
+			a.NoError(err)
+			a.Equal(http.StatusOK, resp.StatusCode)
+			svc.AssertExpectations(t)
+		}
+	})
+
+	t.Run("someone else's request", func(t *testing.T) {
+		server, svc := newTestServer(t, web.IdmUser)
+		svc.On("FindById", mock.Anything, int64(10), false).Return(Response{}, common.ForbiddenError{
+			Message: "access request 10 is available only to its author, subject, current approver or an administrator"})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/access-requests/10", nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestApproveAccessRequest(t *testing.T) {
+	a := assert.New(t)
+	tests := []struct {
+		name       string
+		body       string
+		result     error
+		wantStatus int
+	}{
+		{name: "approved without comment", wantStatus: http.StatusOK},
+		{name: "not the approver", wantStatus: http.StatusForbidden,
+			result: common.ForbiddenError{Message: "only the manager approver or an administrator can decide access request 10"}},
+		{name: "closed request", wantStatus: http.StatusConflict,
+			result: common.ConflictError{Message: "access request 10 is already approved"}},
+		{name: "unknown request", wantStatus: http.StatusNotFound, result: notFound(10)},
+		{name: "malformed body", body: `{"comment":1}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, svc := newTestServer(t, web.IdmUser)
+			svc.On("Approve", mock.Anything, int64(10), DecisionRequest{}, false).
+				Return(Response{Id: 10, Status: StatusApproved}, tt.result)
+
+			req := httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests/10/approve", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := server.App.Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestRejectAndCancelAccessRequest(t *testing.T) {
+	a := assert.New(t)
+	server, svc := newTestServer(t, web.IdmAdmin)
+	svc.On("Reject", mock.Anything, int64(10), DecisionRequest{Comment: "not needed"}, true).
+		Return(Response{Id: 10, Status: StatusRejected}, nil)
+	svc.On("Cancel", mock.Anything, int64(11), true).
+		Return(Response{}, common.ConflictError{Message: "access request 11 is already rejected"})
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests/10/reject", strings.NewReader(`{"comment":"not needed"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.App.Test(req, -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests/11/cancel", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusConflict, resp.StatusCode)
+}
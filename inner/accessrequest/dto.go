@@ -0,0 +1,148 @@
+package accessrequest
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Entity - заявка сотрудника на роль
+type Entity struct {
+	Id            int64      `db:"id"`
+	EmployeeId    int64      `db:"employee_id"`
+	RoleId        int64      `db:"role_id"`
+	RequestedBy   string     `db:"requested_by"`
+	Justification string     `db:"justification"`
+	ValidTo       *time.Time `db:"valid_to"`
+	Status        string     `db:"status"`
+	// Stages - этапы согласования по порядку, Stage - индекс текущего этапа
+	Stages pq.StringArray `db:"stages"`
+	Stage  int            `db:"stage"`
+	// DueAt - срок согласования текущего этапа
+	DueAt      time.Time  `db:"due_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at"`
+	ResolvedAt *time.Time `db:"resolved_at"`
+	ResolvedBy *string    `db:"resolved_by"`
+}
+
+// currentStage возвращает этап, ожидающий решения; у закрытых заявок - пустую строку
+func (e *Entity) currentStage() string {
+	if e.Status != StatusPending || e.Stage >= len(e.Stages) {
+		return ""
+	}
+	return e.Stages[e.Stage]
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:            e.Id,
+		EmployeeId:    e.EmployeeId,
+		RoleId:        e.RoleId,
+		RequestedBy:   e.RequestedBy,
+		Justification: e.Justification,
+		ValidTo:       e.ValidTo,
+		Status:        e.Status,
+		Stages:        e.Stages,
+		CurrentStage:  e.currentStage(),
+		DueAt:         e.DueAt,
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+		ResolvedAt:    e.ResolvedAt,
+		ResolvedBy:    e.ResolvedBy,
+	}
+}
+
+type Response struct {
+	Id            int64      `json:"id"`
+	EmployeeId    int64      `json:"employee_id"`
+	RoleId        int64      `json:"role_id"`
+	RequestedBy   string     `json:"requested_by"`
+	Justification string     `json:"justification"`
+	ValidTo       *time.Time `json:"valid_to,omitempty"`
+	Status        string     `json:"status"`
+	Stages        []string   `json:"stages"`
+	// CurrentStage - этап, ожидающий решения; у закрытых заявок не заполняется
+	CurrentStage string             `json:"current_stage,omitempty"`
+	DueAt        time.Time          `json:"due_at"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	ResolvedAt   *time.Time         `json:"resolved_at,omitempty"`
+	ResolvedBy   *string            `json:"resolved_by,omitempty"`
+	Decisions    []DecisionResponse `json:"decisions,omitempty"`
+}
+
+// DecisionEntity - решение согласующего по одному этапу заявки
+type DecisionEntity struct {
+	Id        int64     `db:"id"`
+	RequestId int64     `db:"request_id"`
+	Stage     string    `db:"stage"`
+	Approver  string    `db:"approver"`
+	Decision  string    `db:"decision"`
+	Comment   *string   `db:"comment"`
+	DecidedAt time.Time `db:"decided_at"`
+}
+
+func (e *DecisionEntity) toResponse() DecisionResponse {
+	return DecisionResponse{Stage: e.Stage, Approver: e.Approver, Decision: e.Decision, Comment: e.Comment, DecidedAt: e.DecidedAt}
+}
+
+type DecisionResponse struct {
+	Stage     string    `json:"stage"`
+	Approver  string    `json:"approver"`
+	Decision  string    `json:"decision"`
+	Comment   *string   `json:"comment,omitempty"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// CreateRequest - заявка текущего пользователя на роль; без valid_to роль запрашивается бессрочно
+type CreateRequest struct {
+	RoleId        int64      `json:"role_id" validate:"required,gt=0"`
+	Justification string     `json:"justification" validate:"required,min=10,max=1000"`
+	ValidTo       *time.Time `json:"valid_to,omitempty"`
+}
+
+// DecisionRequest - комментарий согласующего; при отказе обязателен
+type DecisionRequest struct {
+	Comment string `json:"comment" validate:"max=1000"`
+}
+
+// subject - сотрудник, которому запрашивается роль
+type subject struct {
+	Id        int64  `db:"id"`
+	Status    string `db:"status"`
+	ManagerId *int64 `db:"manager_id"`
+}
+
+// auditEntityType - тип сущности заявки в журнале аудита
+const auditEntityType = "access_request"
+
+// auditState - состояние заявки, которое сохраняется в журнале аудита
+type auditState struct {
+	Id            int64      `json:"id"`
+	EmployeeId    int64      `json:"employee_id"`
+	RoleId        int64      `json:"role_id"`
+	Justification string     `json:"justification"`
+	ValidTo       *time.Time `json:"valid_to,omitempty"`
+	Status        string     `json:"status"`
+	Stage         string     `json:"stage,omitempty"`
+}
+
+func (e *Entity) toAuditState() auditState {
+	return auditState{
+		Id:            e.Id,
+		EmployeeId:    e.EmployeeId,
+		RoleId:        e.RoleId,
+		Justification: e.Justification,
+		ValidTo:       e.ValidTo,
+		Status:        e.Status,
+		Stage:         e.currentStage(),
+	}
+}
+
+// auditGrant - назначение роли по согласованной заявке в журнале аудита сотрудника
+type auditGrant struct {
+	RoleIds         []int64    `json:"role_ids"`
+	ValidTo         *time.Time `json:"valid_to,omitempty"`
+	AccessRequestId int64      `json:"access_request_id"`
+}
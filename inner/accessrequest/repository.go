@@ -0,0 +1,230 @@
+package accessrequest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/employee"
+	"idm/inner/tracing"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.FindById")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = r.db.GetContext(ctx, &entity, "SELECT * FROM access_request WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(id)
+	}
+	return &entity, err
+}
+
+// FindDecisions возвращает решения по заявке в порядке их принятия
+func (r *Repository) FindDecisions(ctx context.Context, requestId int64) (decisions []DecisionEntity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.FindDecisions")
+	defer func() { span.Finish(int64(len(decisions)), err) }()
+	err = r.db.SelectContext(ctx, &decisions,
+		"SELECT * FROM access_request_decision WHERE request_id = $1 ORDER BY id", requestId)
+	return decisions, err
+}
+
+// FindByEmployee возвращает заявки сотрудника, начиная с последней
+func (r *Repository) FindByEmployee(ctx context.Context, employeeId int64) (requests []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.FindByEmployee")
+	defer func() { span.Finish(int64(len(requests)), err) }()
+	err = r.db.SelectContext(ctx, &requests,
+		"SELECT * FROM access_request WHERE employee_id = $1 ORDER BY id DESC", employeeId)
+	return requests, err
+}
+
+// FindInbox возвращает незакрытые заявки, текущий этап которых может согласовать сотрудник approverId:
+// как руководитель сотрудника или как владелец роли. Администратору (admin) доступны все незакрытые заявки.
+// Собственные заявки согласующего в выборку не попадают. Первыми идут заявки с ближайшим сроком
+func (r *Repository) FindInbox(ctx context.Context, approverId int64, admin bool) (requests []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.FindInbox")
+	defer func() { span.Finish(int64(len(requests)), err) }()
+	err = r.db.SelectContext(ctx, &requests,
+		`SELECT ar.* FROM access_request ar
+		JOIN employee e ON e.id = ar.employee_id
+		JOIN role r ON r.id = ar.role_id
+		WHERE ar.status = 'pending' AND ar.employee_id <> $1 AND (
+			$2
+			OR ar.stages[ar.stage + 1] = 'manager' AND e.manager_id = $1
+			OR ar.stages[ar.stage + 1] = 'role_owner' AND r.owner_id = $1
+		)
+		ORDER BY ar.due_at, ar.id`, approverId, admin)
+	return requests, err
+}
+
+// IsCurrentApprover сообщает, ожидает ли заявка решения сотрудника approverId на текущем этапе -
+// по тем же правилам, что и FindInbox для согласующего
+func (r *Repository) IsCurrentApprover(ctx context.Context, id, approverId int64) (isApprover bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.IsCurrentApprover")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = r.db.GetContext(ctx, &isApprover,
+		`SELECT exists(SELECT 1 FROM access_request ar
+		JOIN employee e ON e.id = ar.employee_id
+		JOIN role r ON r.id = ar.role_id
+		WHERE ar.id = $1 AND ar.status = 'pending' AND ar.employee_id <> $2 AND (
+			ar.stages[ar.stage + 1] = 'manager' AND e.manager_id = $2
+			OR ar.stages[ar.stage + 1] = 'role_owner' AND r.owner_id = $2
+		))`, id, approverId)
+	return isApprover, err
+}
+
+// FindEmployeeIdByLogin возвращает id неудалённого сотрудника с логином login (без учёта регистра) или 0, если такого нет
+func (r *Repository) FindEmployeeIdByLogin(ctx context.Context, login string) (int64, error) {
+	return employee.FindIdByLogin(ctx, r.db, login)
+}
+
+func (r *Repository) BeginTransaction(ctx context.Context) (tx *sqlx.Tx, err error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// FindByIdForUpdateTx читает заявку и блокирует строку до конца транзакции
+func (r *Repository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.FindByIdForUpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity, "SELECT * FROM access_request WHERE id = $1 FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(id)
+	}
+	return &entity, err
+}
+
+// FindEmployeeIdByLoginTx - то же, что FindEmployeeIdByLogin, внутри транзакции
+func (r *Repository) FindEmployeeIdByLoginTx(ctx context.Context, tx *sqlx.Tx, login string) (int64, error) {
+	return employee.FindIdByLogin(ctx, tx, login)
+}
+
+// FindSubjectTx читает статус и руководителя сотрудника и блокирует его строку до конца транзакции,
+// чтобы роль не была выдана одновременно с увольнением
+func (r *Repository) FindSubjectTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *subject, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.FindSubjectTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var s subject
+	err = tx.GetContext(ctx, &s,
+		"SELECT id, status, manager_id FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+	}
+	return &s, err
+}
+
+// FindRoleOwnerTx возвращает владельца неудалённой роли; nil - если владелец не назначен
+func (r *Repository) FindRoleOwnerTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (ownerId *int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.FindRoleOwnerTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &ownerId, "SELECT owner_id FROM role WHERE id = $1 AND deleted_at IS NULL", roleId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", roleId)}
+	}
+	return ownerId, err
+}
+
+// HasActiveGrantTx проверяет, действует ли у сотрудника назначение роли в данный момент
+func (r *Repository) HasActiveGrantTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.HasActiveGrantTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &isExists,
+		`select exists(select 1 from employee_role
+		where employee_id = $1 and role_id = $2 and valid_from <= now() and (valid_to is null or valid_to > now()))`,
+		employeeId, roleId)
+	return isExists, err
+}
+
+// CreateTx сохраняет новую заявку; вторая незакрытая заявка на ту же роль отклоняется уникальным индексом
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, request *Entity) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.CreateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		`INSERT INTO access_request (employee_id, role_id, requested_by, justification, valid_to, status, stages, stage, due_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING *`,
+		request.EmployeeId, request.RoleId, request.RequestedBy, request.Justification, request.ValidTo,
+		request.Status, request.Stages, request.Stage, request.DueAt)
+	if err != nil {
+		return nil, uniqueViolation(err, request)
+	}
+	return &entity, nil
+}
+
+// UpdateTx сохраняет статус, этап и срок согласования заявки
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, request *Entity) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.UpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		`UPDATE access_request SET status = $1, stage = $2, due_at = $3, resolved_at = $4, resolved_by = $5, updated_at = now()
+		WHERE id = $6 RETURNING *`,
+		request.Status, request.Stage, request.DueAt, request.ResolvedAt, request.ResolvedBy, request.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(request.Id)
+	}
+	return &entity, err
+}
+
+// AddDecisionTx сохраняет решение согласующего
+func (r *Repository) AddDecisionTx(ctx context.Context, tx *sqlx.Tx, decision *DecisionEntity) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.AddDecisionTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO access_request_decision (request_id, stage, approver, decision, comment, decided_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		decision.RequestId, decision.Stage, decision.Approver, decision.Decision, decision.Comment, decision.DecidedAt)
+	return err
+}
+
+// GrantRoleTx назначает сотруднику роль по согласованной заявке: назначение действует с текущего момента до validTo.
+// Уже существующее назначение той же роли перезаписывается, как при ручном назначении
+func (r *Repository) GrantRoleTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64, validTo *time.Time) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.GrantRoleTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	_, err = tx.ExecContext(ctx,
+		`insert into employee_role (employee_id, role_id, valid_from, valid_to) values ($1, $2, now(), $3)
+		on conflict (employee_id, role_id) do update set valid_from = excluded.valid_from, valid_to = excluded.valid_to`,
+		employeeId, roleId, validTo)
+	return err
+}
+
+// ExpireOverdueTx закрывает незакрытые заявки, срок согласования которых истёк к моменту now, и возвращает их.
+// Заявки блокируются обновлением, поэтому при нескольких экземплярах сервиса каждая закрывается один раз
+func (r *Repository) ExpireOverdueTx(ctx context.Context, tx *sqlx.Tx, now time.Time) (requests []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessrequest.ExpireOverdueTx")
+	defer func() { span.Finish(int64(len(requests)), err) }()
+	err = tx.SelectContext(ctx, &requests,
+		`UPDATE access_request SET status = 'expired', resolved_at = $1, updated_at = now()
+		WHERE status = 'pending' AND due_at <= $1 RETURNING *`, now)
+	return requests, err
+}
+
+func notFound(id int64) error {
+	return common.NotFoundError{Message: fmt.Sprintf("access request with id %d not found", id)}
+}
+
+// uniqueViolation превращает нарушение уникальности незакрытой заявки в ConflictError.
+// Сервис не проверяет дубликаты заранее: индекс надёжнее и при одновременных заявках
+func uniqueViolation(err error, request *Entity) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return common.ConflictError{Message: fmt.Sprintf(
+			"employee %d already has a pending request for role %d", request.EmployeeId, request.RoleId)}
+	}
+	return err
+}
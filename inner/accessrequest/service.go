@@ -0,0 +1,497 @@
+package accessrequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/employee"
+	"idm/inner/events"
+	"idm/inner/metrics"
+	"idm/inner/tracing"
+	"idm/inner/validator"
+)
+
+// типы событий о заявках на доступ
+const (
+	EventCreated = "access_request.created"
+	// EventAdvanced - этап согласован, заявка перешла к следующему согласующему
+	EventAdvanced  = "access_request.advanced"
+	EventApproved  = "access_request.approved"
+	EventRejected  = "access_request.rejected"
+	EventCancelled = "access_request.cancelled"
+	EventExpired   = "access_request.expired"
+)
+
+type Service struct {
+	repo      Repo
+	auditor   Auditor
+	publisher events.Publisher
+	logger    *common.Logger
+	validator *validator.Validator
+	// sla - срок согласования одного этапа заявки
+	sla time.Duration
+}
+
+// Auditor записывает изменения заявок и выданные по ним роли в журнал аудита в той же транзакции, что и само изменение
+type Auditor interface {
+	RecordTx(ctx context.Context, tx *sqlx.Tx, record audit.Record) error
+}
+
+type Repo interface {
+	FindById(ctx context.Context, id int64) (*Entity, error)
+	FindDecisions(ctx context.Context, requestId int64) ([]DecisionEntity, error)
+	FindByEmployee(ctx context.Context, employeeId int64) ([]Entity, error)
+	FindInbox(ctx context.Context, approverId int64, admin bool) ([]Entity, error)
+	IsCurrentApprover(ctx context.Context, id, approverId int64) (bool, error)
+	FindEmployeeIdByLogin(ctx context.Context, login string) (int64, error)
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+	FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error)
+	FindEmployeeIdByLoginTx(ctx context.Context, tx *sqlx.Tx, login string) (int64, error)
+	FindSubjectTx(ctx context.Context, tx *sqlx.Tx, id int64) (*subject, error)
+	FindRoleOwnerTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (*int64, error)
+	HasActiveGrantTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64) (bool, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, request *Entity) (*Entity, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, request *Entity) (*Entity, error)
+	AddDecisionTx(ctx context.Context, tx *sqlx.Tx, decision *DecisionEntity) error
+	GrantRoleTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64, validTo *time.Time) error
+	ExpireOverdueTx(ctx context.Context, tx *sqlx.Tx, now time.Time) ([]Entity, error)
+}
+
+func NewService(repo Repo, auditor Auditor, publisher events.Publisher, logger *common.Logger, sla time.Duration) *Service {
+	return &Service{
+		repo:      repo,
+		auditor:   auditor,
+		publisher: publisher,
+		logger:    logger,
+		validator: validator.New(),
+		sla:       sla,
+	}
+}
+
+// FindById возвращает заявку вместе с решениями согласующих. Заявку видят её автор, сотрудник, на которого она подана,
+// согласующий текущего этапа и администратор IDM (admin)
+func (svc *Service) FindById(ctx context.Context, id int64, admin bool) (Response, error) {
+	ctx, span := tracing.Start(ctx, "accessrequest.Service.FindById")
+	defer span.End()
+	e, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to find access request with id %d: %w", id, err)
+	}
+	if !admin {
+		if err := svc.authorizeRead(ctx, e); err != nil {
+			return Response{}, err
+		}
+	}
+	decisions, err := svc.repo.FindDecisions(ctx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to find decisions of access request with id %d: %w", id, err)
+	}
+	response := e.toResponse()
+	for i := range decisions {
+		response.Decisions = append(response.Decisions, decisions[i].toResponse())
+	}
+	return response, nil
+}
+
+// FindMine возвращает заявки текущего пользователя; у пользователя без карточки сотрудника заявок нет
+func (svc *Service) FindMine(ctx context.Context) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "accessrequest.Service.FindMine")
+	defer span.End()
+	employeeId, err := svc.currentEmployee(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if employeeId == 0 {
+		return []Response{}, nil
+	}
+	entities, err := svc.repo.FindByEmployee(ctx, employeeId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find access requests of employee with id %d: %w", employeeId, err)
+	}
+	return toResponses(entities), nil
+}
+
+// FindInbox возвращает заявки, ожидающие решения текущего пользователя; admin - пользователь администратор IDM
+func (svc *Service) FindInbox(ctx context.Context, admin bool) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "accessrequest.Service.FindInbox")
+	defer span.End()
+	approverId, err := svc.currentEmployee(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if approverId == 0 && !admin {
+		return []Response{}, nil
+	}
+	entities, err := svc.repo.FindInbox(ctx, approverId, admin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending approvals: %w", err)
+	}
+	return toResponses(entities), nil
+}
+
+// Create создаёт заявку текущего пользователя на роль. Этапы согласования определяются при создании:
+// руководитель сотрудника, затем владелец роли, а если согласовать некому - администратор IDM
+func (svc *Service) Create(ctx context.Context, req CreateRequest) (Response, error) {
+	ctx, span := tracing.Start(ctx, "accessrequest.Service.Create")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return Response{}, err
+	}
+	now := time.Now()
+	if req.ValidTo != nil && !req.ValidTo.After(now) {
+		return Response{}, common.RequestValidationError{Message: "valid_to must be in the future"}
+	}
+	login := common.ActorFromContext(ctx)
+	var created *Entity
+	err := database.InTransaction(ctx, svc.repo, "creating access request", func(tx *sqlx.Tx) error {
+		employeeId, err := svc.repo.FindEmployeeIdByLoginTx(ctx, tx, login)
+		if err != nil {
+			return fmt.Errorf("error finding employee with login %s: %w", login, err)
+		}
+		if employeeId == 0 {
+			return common.RequestValidationError{Message: fmt.Sprintf("user %s has no employee record", login)}
+		}
+		requester, err := svc.repo.FindSubjectTx(ctx, tx, employeeId)
+		if err != nil {
+			return fmt.Errorf("error finding employee with id %d: %w", employeeId, err)
+		}
+		if requester.Status == employee.StatusTerminated {
+			return common.ConflictError{Message: fmt.Sprintf("employee %d is terminated", employeeId)}
+		}
+		ownerId, err := svc.repo.FindRoleOwnerTx(ctx, tx, req.RoleId)
+		if errors.As(err, &common.NotFoundError{}) {
+			return common.RequestValidationError{Message: fmt.Sprintf("role %d not found", req.RoleId)}
+		}
+		if err != nil {
+			return fmt.Errorf("error finding role with id %d: %w", req.RoleId, err)
+		}
+		granted, err := svc.repo.HasActiveGrantTx(ctx, tx, employeeId, req.RoleId)
+		if err != nil {
+			return fmt.Errorf("error checking role %d of employee %d: %w", req.RoleId, employeeId, err)
+		}
+		if granted {
+			return common.ConflictError{Message: fmt.Sprintf("employee %d already has role %d", employeeId, req.RoleId)}
+		}
+		created, err = svc.repo.CreateTx(ctx, tx, &Entity{
+			EmployeeId:    employeeId,
+			RoleId:        req.RoleId,
+			RequestedBy:   login,
+			Justification: strings.TrimSpace(req.Justification),
+			ValidTo:       req.ValidTo,
+			Status:        StatusPending,
+			Stages:        planStages(employeeId, requester.ManagerId, ownerId),
+			DueAt:         now.Add(svc.sla),
+		})
+		if err != nil {
+			return fmt.Errorf("error creating access request for role %d: %w", req.RoleId, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionCreate, created.Id, nil, created.toAuditState())
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	svc.publish(ctx, EventCreated, created)
+	metrics.AccessRequests.WithLabelValues(StatusPending).Inc()
+	return created.toResponse(), nil
+}
+
+// Approve согласует текущий этап заявки; на последнем этапе заявка одобряется и роль назначается сотруднику
+func (svc *Service) Approve(ctx context.Context, id int64, req DecisionRequest, admin bool) (Response, error) {
+	ctx, span := tracing.Start(ctx, "accessrequest.Service.Approve")
+	defer span.End()
+	return svc.decide(ctx, id, DecisionApprove, req, admin)
+}
+
+// Reject отклоняет заявку на текущем этапе; комментарий с причиной обязателен
+func (svc *Service) Reject(ctx context.Context, id int64, req DecisionRequest, admin bool) (Response, error) {
+	ctx, span := tracing.Start(ctx, "accessrequest.Service.Reject")
+	defer span.End()
+	if strings.TrimSpace(req.Comment) == "" {
+		return Response{}, common.RequestValidationError{Message: "comment is required to reject an access request"}
+	}
+	return svc.decide(ctx, id, DecisionReject, req, admin)
+}
+
+// Cancel отзывает незакрытую заявку; отозвать её может автор или администратор IDM
+func (svc *Service) Cancel(ctx context.Context, id int64, admin bool) (Response, error) {
+	ctx, span := tracing.Start(ctx, "accessrequest.Service.Cancel")
+	defer span.End()
+	login := common.ActorFromContext(ctx)
+	var before, updated *Entity
+	err := database.InTransaction(ctx, svc.repo, "cancelling access request", func(tx *sqlx.Tx) (err error) {
+		before, err = svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding access request with id %d: %w", id, err)
+		}
+		if !admin && before.RequestedBy != login {
+			return common.ForbiddenError{Message: fmt.Sprintf("access request %d can be cancelled only by its author", id)}
+		}
+		if !canTransition(before.Status, StatusCancelled) {
+			return closed(before)
+		}
+		updated, err = svc.repo.UpdateTx(ctx, tx, resolve(before, StatusCancelled, login, time.Now()))
+		if err != nil {
+			return fmt.Errorf("error cancelling access request with id %d: %w", id, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionCancel, id, before.toAuditState(), updated.toAuditState())
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	svc.publish(ctx, EventCancelled, updated)
+	metrics.AccessRequests.WithLabelValues(StatusCancelled).Inc()
+	return updated.toResponse(), nil
+}
+
+// ExpireOverdue закрывает заявки, срок согласования текущего этапа которых истёк к моменту now,
+// и возвращает их число
+func (svc *Service) ExpireOverdue(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "accessrequest.Service.ExpireOverdue")
+	defer span.End()
+	var expired []Entity
+	err := database.InTransaction(ctx, svc.repo, "expiring access requests", func(tx *sqlx.Tx) (err error) {
+		expired, err = svc.repo.ExpireOverdueTx(ctx, tx, now)
+		if err != nil {
+			return fmt.Errorf("error expiring access requests: %w", err)
+		}
+		for i := range expired {
+			before := expired[i]
+			before.Status = StatusPending
+			if err := svc.recordTx(ctx, tx, audit.ActionExpire, expired[i].Id, before.toAuditState(), expired[i].toAuditState()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for i := range expired {
+		svc.publish(ctx, EventExpired, &expired[i])
+	}
+	metrics.AccessRequests.WithLabelValues(StatusExpired).Add(float64(len(expired)))
+	return len(expired), nil
+}
+
+// decide принимает решение по текущему этапу заявки от имени текущего пользователя
+func (svc *Service) decide(ctx context.Context, id int64, decision string, req DecisionRequest, admin bool) (Response, error) {
+	if err := svc.validator.Validate(req); err != nil {
+		return Response{}, err
+	}
+	now := time.Now()
+	login := common.ActorFromContext(ctx)
+	var before, updated *Entity
+	err := database.InTransaction(ctx, svc.repo, "deciding access request", func(tx *sqlx.Tx) (err error) {
+		before, err = svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding access request with id %d: %w", id, err)
+		}
+		if before.Status != StatusPending {
+			return closed(before)
+		}
+		if !before.DueAt.After(now) {
+			return common.ConflictError{Message: fmt.Sprintf("access request %d is overdue", id)}
+		}
+		beneficiary, err := svc.repo.FindSubjectTx(ctx, tx, before.EmployeeId)
+		if err != nil {
+			return fmt.Errorf("error finding employee with id %d: %w", before.EmployeeId, err)
+		}
+		if err := svc.authorizeTx(ctx, tx, before, beneficiary, login, admin); err != nil {
+			return err
+		}
+		var comment *string
+		if req.Comment != "" {
+			comment = &req.Comment
+		}
+		err = svc.repo.AddDecisionTx(ctx, tx, &DecisionEntity{
+			RequestId: id, Stage: before.currentStage(), Approver: login, Decision: decision, Comment: comment, DecidedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("error saving decision on access request with id %d: %w", id, err)
+		}
+		updated, err = svc.repo.UpdateTx(ctx, tx, decide(before, decision, login, now, svc.sla))
+		if err != nil {
+			return fmt.Errorf("error updating access request with id %d: %w", id, err)
+		}
+		if updated.Status == StatusApproved {
+			if err := svc.grantTx(ctx, tx, updated, beneficiary); err != nil {
+				return err
+			}
+		}
+		// события аудита пишутся после всех изменений: запись блокирует цепочку журнала до конца транзакции
+		action := audit.ActionApprove
+		if decision == DecisionReject {
+			action = audit.ActionReject
+		}
+		if err := svc.recordTx(ctx, tx, action, id, before.toAuditState(), updated.toAuditState()); err != nil {
+			return err
+		}
+		if updated.Status == StatusApproved {
+			return svc.recordGrantTx(ctx, tx, updated)
+		}
+		return nil
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	switch updated.Status {
+	case StatusPending:
+		svc.publish(ctx, EventAdvanced, updated)
+	case StatusApproved:
+		svc.publish(ctx, EventApproved, updated)
+		metrics.AccessRequests.WithLabelValues(StatusApproved).Inc()
+		metrics.RoleAssignments.WithLabelValues("assign").Inc()
+	case StatusRejected:
+		svc.publish(ctx, EventRejected, updated)
+		metrics.AccessRequests.WithLabelValues(StatusRejected).Inc()
+	}
+	return updated.toResponse(), nil
+}
+
+// authorizeTx проверяет, что пользователь login может принять решение по текущему этапу заявки:
+// это руководитель сотрудника на этапе manager, владелец роли на этапе role_owner или администратор IDM на любом этапе.
+// Решение по собственной заявке не может принять никто, включая администратора
+func (svc *Service) authorizeTx(ctx context.Context, tx *sqlx.Tx, request *Entity, beneficiary *subject, login string, admin bool) error {
+	approverId, err := svc.repo.FindEmployeeIdByLoginTx(ctx, tx, login)
+	if err != nil {
+		return fmt.Errorf("error finding employee with login %s: %w", login, err)
+	}
+	if approverId != 0 && approverId == request.EmployeeId {
+		return common.ForbiddenError{Message: fmt.Sprintf("access request %d cannot be decided by its subject", request.Id)}
+	}
+	if admin {
+		return nil
+	}
+	allowed := false
+	if approverId != 0 {
+		switch request.currentStage() {
+		case StageManager:
+			allowed = beneficiary.ManagerId != nil && *beneficiary.ManagerId == approverId
+		case StageRoleOwner:
+			ownerId, err := svc.repo.FindRoleOwnerTx(ctx, tx, request.RoleId)
+			if err != nil {
+				return fmt.Errorf("error finding owner of role with id %d: %w", request.RoleId, err)
+			}
+			allowed = ownerId != nil && *ownerId == approverId
+		}
+	}
+	if !allowed {
+		return common.ForbiddenError{Message: fmt.Sprintf(
+			"only the %s approver or an administrator can decide access request %d", request.currentStage(), request.Id)}
+	}
+	return nil
+}
+
+// authorizeRead проверяет, что текущий пользователь - автор заявки, сотрудник, на которого она подана,
+// или согласующий её текущего этапа
+func (svc *Service) authorizeRead(ctx context.Context, request *Entity) error {
+	if request.RequestedBy == common.ActorFromContext(ctx) {
+		return nil
+	}
+	employeeId, err := svc.currentEmployee(ctx)
+	if err != nil {
+		return err
+	}
+	if employeeId != 0 {
+		if employeeId == request.EmployeeId {
+			return nil
+		}
+		approver, err := svc.repo.IsCurrentApprover(ctx, request.Id, employeeId)
+		if err != nil {
+			return fmt.Errorf("error checking approvers of access request with id %d: %w", request.Id, err)
+		}
+		if approver {
+			return nil
+		}
+	}
+	return common.ForbiddenError{Message: fmt.Sprintf(
+		"access request %d is available only to its author, subject, current approver or an administrator", request.Id)}
+}
+
+// grantTx назначает сотруднику роль по одобренной заявке
+func (svc *Service) grantTx(ctx context.Context, tx *sqlx.Tx, request *Entity, beneficiary *subject) error {
+	if beneficiary.Status == employee.StatusTerminated {
+		return common.ConflictError{Message: fmt.Sprintf("employee %d is terminated", beneficiary.Id)}
+	}
+	if err := svc.repo.GrantRoleTx(ctx, tx, request.EmployeeId, request.RoleId, request.ValidTo); err != nil {
+		return fmt.Errorf("error assigning role %d to employee %d: %w", request.RoleId, request.EmployeeId, err)
+	}
+	return nil
+}
+
+// recordGrantTx записывает назначение роли по одобренной заявке в журнал аудита сотрудника
+func (svc *Service) recordGrantTx(ctx context.Context, tx *sqlx.Tx, request *Entity) error {
+	err := svc.auditor.RecordTx(ctx, tx, audit.Record{
+		Action:     audit.ActionAssignRoles,
+		EntityType: "employee",
+		EntityId:   request.EmployeeId,
+		After:      auditGrant{RoleIds: []int64{request.RoleId}, ValidTo: request.ValidTo, AccessRequestId: request.Id},
+	})
+	if err != nil {
+		return fmt.Errorf("error recording audit event for employee with id %d: %w", request.EmployeeId, err)
+	}
+	return nil
+}
+
+// currentEmployee возвращает id карточки сотрудника текущего пользователя или 0, если карточки нет
+func (svc *Service) currentEmployee(ctx context.Context) (int64, error) {
+	login := common.ActorFromContext(ctx)
+	if login == "" {
+		return 0, nil
+	}
+	id, err := svc.repo.FindEmployeeIdByLogin(ctx, login)
+	if err != nil {
+		return 0, fmt.Errorf("error finding employee with login %s: %w", login, err)
+	}
+	return id, nil
+}
+
+// publish сообщает о переходе заявки после фиксации транзакции; ошибка публикации только журналируется
+func (svc *Service) publish(ctx context.Context, eventType string, request *Entity) {
+	event := events.Event{
+		Type:       eventType,
+		OccurredAt: request.UpdatedAt,
+		EntityType: auditEntityType,
+		EntityId:   request.Id,
+		Data:       request.toResponse(),
+	}
+	if err := svc.publisher.Publish(ctx, event); err != nil {
+		svc.logger.Ctx(ctx).Error("publish access request event", zap.String("event_type", eventType),
+			zap.Int64("access_request_id", request.Id), zap.Error(err))
+	}
+}
+
+func (svc *Service) recordTx(ctx context.Context, tx *sqlx.Tx, action string, id int64, before any, after any) error {
+	err := svc.auditor.RecordTx(ctx, tx, audit.Record{
+		Action:     action,
+		EntityType: auditEntityType,
+		EntityId:   id,
+		Before:     before,
+		After:      after,
+	})
+	if err != nil {
+		return fmt.Errorf("error recording audit event for access request with id %d: %w", id, err)
+	}
+	return nil
+}
+
+// closed - ошибка действия над уже закрытой заявкой
+func closed(request *Entity) error {
+	return common.ConflictError{Message: fmt.Sprintf("access request %d is already %s", request.Id, request.Status)}
+}
+
+func toResponses(entities []Entity) []Response {
+	responses := make([]Response, 0, len(entities))
+	for i := range entities {
+		responses = append(responses, entities[i].toResponse())
+	}
+	return responses
+}
@@ -0,0 +1,466 @@
+package accessrequest
+
+import (
+	"context"
+	"database/sql/driver"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/events"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// chainLockQuery подменяет блокировку цепочки журнала, чтобы sqlmock проверял, где в транзакции пишется аудит
+const chainLockQuery = "SELECT audit_chain_lock"
+
+// stubAuditor запоминает события аудита вместо записи в БД.
+// С lockChain каждое событие выполняет в транзакции chainLockQuery
+type stubAuditor struct {
+	records   []audit.Record
+	lockChain bool
+}
+
+func (a *stubAuditor) RecordTx(ctx context.Context, tx *sqlx.Tx, record audit.Record) error {
+	if a.lockChain {
+		if _, err := tx.ExecContext(ctx, chainLockQuery); err != nil {
+			return err
+		}
+	}
+	a.records = append(a.records, record)
+	return nil
+}
+
+// stubPublisher запоминает опубликованные события
+type stubPublisher struct {
+	events []events.Event
+}
+
+func (p *stubPublisher) Publish(ctx context.Context, event events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+// newSqlmockService создаёт сервис с настоящим репозиторием поверх sqlmock
+func newSqlmockService(t *testing.T) (*Service, sqlmock.Sqlmock, *stubAuditor, *stubPublisher) {
+	dbMock, m, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = dbMock.Close() })
+	auditor, publisher := &stubAuditor{}, &stubPublisher{}
+	logger := &common.Logger{Logger: zap.NewNop()}
+	svc := NewService(NewRepository(sqlx.NewDb(dbMock, "postgres")), auditor, publisher, logger, 72*time.Hour)
+	return svc, m, auditor, publisher
+}
+
+var (
+	columns = []string{"id", "employee_id", "role_id", "requested_by", "justification", "valid_to", "status",
+		"stages", "stage", "due_at", "created_at", "updated_at", "resolved_at", "resolved_by"}
+	loginQuery    = regexp.QuoteMeta("SELECT coalesce((SELECT id FROM employee WHERE lower(login) = lower($1)")
+	subjectQuery  = regexp.QuoteMeta("SELECT id, status, manager_id FROM employee WHERE id = $1")
+	ownerQuery    = regexp.QuoteMeta("SELECT owner_id FROM role WHERE id = $1")
+	grantedQuery  = regexp.QuoteMeta("select exists(select 1 from employee_role")
+	insertQuery   = regexp.QuoteMeta("INSERT INTO access_request (")
+	lockQuery     = regexp.QuoteMeta("SELECT * FROM access_request WHERE id = $1 FOR UPDATE")
+	decisionQuery = regexp.QuoteMeta("INSERT INTO access_request_decision")
+	updateQuery   = regexp.QuoteMeta("UPDATE access_request SET status = $1")
+	grantQuery    = regexp.QuoteMeta("insert into employee_role")
+)
+
+func ptr(id int64) *int64 {
+	return &id
+}
+
+// asUser возвращает контекст запроса пользователя с логином login
+func asUser(login string) context.Context {
+	return common.WithUser(context.Background(), common.User{Subject: "sub-" + login, Username: login})
+}
+
+// requestRows - строки результата с заявками в порядке columns
+func requestRows(requests ...Entity) *sqlmock.Rows {
+	rows := sqlmock.NewRows(columns)
+	for _, e := range requests {
+		stages, _ := e.Stages.Value()
+		var validTo, resolvedAt, resolvedBy driver.Value
+		if e.ValidTo != nil {
+			validTo = *e.ValidTo
+		}
+		if e.ResolvedAt != nil {
+			resolvedAt = *e.ResolvedAt
+		}
+		if e.ResolvedBy != nil {
+			resolvedBy = *e.ResolvedBy
+		}
+		rows.AddRow(e.Id, e.EmployeeId, e.RoleId, e.RequestedBy, e.Justification, validTo, e.Status,
+			stages, e.Stage, e.DueAt, e.CreatedAt, e.UpdatedAt, resolvedAt, resolvedBy)
+	}
+	return rows
+}
+
+func subjectRow(id int64, status string, managerId *int64) *sqlmock.Rows {
+	var manager driver.Value
+	if managerId != nil {
+		manager = *managerId
+	}
+	return sqlmock.NewRows([]string{"id", "status", "manager_id"}).AddRow(id, status, manager)
+}
+
+func idRow(id int64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id"}).AddRow(id)
+}
+
+func ownerRow(ownerId *int64) *sqlmock.Rows {
+	var owner driver.Value
+	if ownerId != nil {
+		owner = *ownerId
+	}
+	return sqlmock.NewRows([]string{"owner_id"}).AddRow(owner)
+}
+
+func TestService_Create(t *testing.T) {
+	a := assert.New(t)
+	req := CreateRequest{RoleId: 5, Justification: "need access to billing reports"}
+
+	t.Run("routes to manager and role owner", func(t *testing.T) {
+		svc, m, auditor, publisher := newSqlmockService(t)
+		created := Entity{Id: 10, EmployeeId: 1, RoleId: 5, RequestedBy: "jdoe", Justification: req.Justification,
+			Status: StatusPending, Stages: []string{StageManager, StageRoleOwner}, DueAt: time.Now().Add(72 * time.Hour)}
+		m.ExpectBegin()
+		m.ExpectQuery(loginQuery).WithArgs("jdoe").WillReturnRows(idRow(1))
+		m.ExpectQuery(subjectQuery).WithArgs(int64(1)).WillReturnRows(subjectRow(1, "active", ptr(2)))
+		m.ExpectQuery(ownerQuery).WithArgs(int64(5)).WillReturnRows(ownerRow(ptr(3)))
+		m.ExpectQuery(grantedQuery).WithArgs(int64(1), int64(5)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		m.ExpectQuery(insertQuery).
+			WithArgs(int64(1), int64(5), "jdoe", req.Justification, nil, StatusPending, "{\"manager\",\"role_owner\"}", 0, sqlmock.AnyArg()).
+			WillReturnRows(requestRows(created))
+		m.ExpectCommit()
+
+		got, err := svc.Create(asUser("jdoe"), req)
+		a.NoError(err)
+		a.Equal(int64(10), got.Id)
+		a.Equal(StageManager, got.CurrentStage)
+		a.Len(auditor.records, 1)
+		a.Equal(audit.ActionCreate, auditor.records[0].Action)
+		a.Len(publisher.events, 1)
+		a.Equal(EventCreated, publisher.events[0].Type)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("user without employee record", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(loginQuery).WithArgs("svc-account").WillReturnRows(idRow(0))
+		m.ExpectRollback()
+
+		_, err := svc.Create(asUser("svc-account"), req)
+		a.ErrorAs(err, &common.RequestValidationError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(loginQuery).WithArgs("jdoe").WillReturnRows(idRow(1))
+		m.ExpectQuery(subjectQuery).WithArgs(int64(1)).WillReturnRows(subjectRow(1, "active", nil))
+		m.ExpectQuery(ownerQuery).WithArgs(int64(5)).WillReturnRows(sqlmock.NewRows([]string{"owner_id"}))
+		m.ExpectRollback()
+
+		_, err := svc.Create(asUser("jdoe"), req)
+		a.ErrorAs(err, &common.RequestValidationError{})
+		a.NotErrorAs(err, &common.NotFoundError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("role already granted", func(t *testing.T) {
+		svc, m, _, publisher := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(loginQuery).WithArgs("jdoe").WillReturnRows(idRow(1))
+		m.ExpectQuery(subjectQuery).WithArgs(int64(1)).WillReturnRows(subjectRow(1, "active", nil))
+		m.ExpectQuery(ownerQuery).WithArgs(int64(5)).WillReturnRows(ownerRow(nil))
+		m.ExpectQuery(grantedQuery).WithArgs(int64(1), int64(5)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		m.ExpectRollback()
+
+		_, err := svc.Create(asUser("jdoe"), req)
+		a.ErrorAs(err, &common.ConflictError{})
+		a.Empty(publisher.events)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		past := time.Now().Add(-time.Hour)
+		_, err := svc.Create(asUser("jdoe"), CreateRequest{RoleId: 5, Justification: "short"})
+		a.ErrorAs(err, &common.RequestValidationError{})
+		_, err = svc.Create(asUser("jdoe"), CreateRequest{RoleId: 5, Justification: req.Justification, ValidTo: &past})
+		a.ErrorAs(err, &common.RequestValidationError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+}
+
+func TestService_Approve(t *testing.T) {
+	a := assert.New(t)
+	pending := Entity{Id: 10, EmployeeId: 1, RoleId: 5, RequestedBy: "jdoe", Justification: "need access",
+		Status: StatusPending, Stages: []string{StageManager, StageRoleOwner}, DueAt: time.Now().Add(time.Hour)}
+
+	t.Run("manager approves first stage", func(t *testing.T) {
+		svc, m, auditor, publisher := newSqlmockService(t)
+		advanced := pending
+		advanced.Stage = 1
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(10)).WillReturnRows(requestRows(pending))
+		m.ExpectQuery(subjectQuery).WithArgs(int64(1)).WillReturnRows(subjectRow(1, "active", ptr(2)))
+		m.ExpectQuery(loginQuery).WithArgs("boss").WillReturnRows(idRow(2))
+		m.ExpectExec(decisionQuery).WithArgs(int64(10), StageManager, "boss", DecisionApprove, nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		m.ExpectQuery(updateQuery).WithArgs(StatusPending, 1, sqlmock.AnyArg(), nil, nil, int64(10)).
+			WillReturnRows(requestRows(advanced))
+		m.ExpectCommit()
+
+		got, err := svc.Approve(asUser("boss"), 10, DecisionRequest{}, false)
+		a.NoError(err)
+		a.Equal(StageRoleOwner, got.CurrentStage)
+		a.Len(auditor.records, 1)
+		a.Equal(audit.ActionApprove, auditor.records[0].Action)
+		a.Equal(EventAdvanced, publisher.events[0].Type)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("role owner approves last stage and role is granted", func(t *testing.T) {
+		svc, m, auditor, publisher := newSqlmockService(t)
+		auditor.lockChain = true
+		validTo := time.Now().Add(30 * 24 * time.Hour)
+		current := pending
+		current.Stage = 1
+		current.ValidTo = &validTo
+		approved := current
+		approved.Status = StatusApproved
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(10)).WillReturnRows(requestRows(current))
+		m.ExpectQuery(subjectQuery).WithArgs(int64(1)).WillReturnRows(subjectRow(1, "active", ptr(2)))
+		m.ExpectQuery(loginQuery).WithArgs("owner").WillReturnRows(idRow(3))
+		m.ExpectQuery(ownerQuery).WithArgs(int64(5)).WillReturnRows(ownerRow(ptr(3)))
+		m.ExpectExec(decisionQuery).WithArgs(int64(10), StageRoleOwner, "owner", DecisionApprove, "ok", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		m.ExpectQuery(updateQuery).WithArgs(StatusApproved, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), "owner", int64(10)).
+			WillReturnRows(requestRows(approved))
+		m.ExpectExec(grantQuery).WithArgs(int64(1), int64(5), validTo).WillReturnResult(sqlmock.NewResult(1, 1))
+		// блокировка цепочки аудита берётся только после выдачи роли - как в ExpireGrants
+		m.ExpectExec(chainLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		m.ExpectExec(chainLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		m.ExpectCommit()
+
+		got, err := svc.Approve(asUser("owner"), 10, DecisionRequest{Comment: "ok"}, false)
+		a.NoError(err)
+		a.Equal(StatusApproved, got.Status)
+		a.Len(auditor.records, 2)
+		a.Equal(audit.ActionAssignRoles, auditor.records[1].Action)
+		a.Equal("employee", auditor.records[1].EntityType)
+		a.Equal(EventApproved, publisher.events[0].Type)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("other employee is forbidden", func(t *testing.T) {
+		svc, m, auditor, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(10)).WillReturnRows(requestRows(pending))
+		m.ExpectQuery(subjectQuery).WithArgs(int64(1)).WillReturnRows(subjectRow(1, "active", ptr(2)))
+		m.ExpectQuery(loginQuery).WithArgs("peer").WillReturnRows(idRow(7))
+		m.ExpectRollback()
+
+		_, err := svc.Approve(asUser("peer"), 10, DecisionRequest{}, false)
+		a.ErrorAs(err, &common.ForbiddenError{})
+		a.Empty(auditor.records)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("admin cannot approve own request", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(10)).WillReturnRows(requestRows(pending))
+		m.ExpectQuery(subjectQuery).WithArgs(int64(1)).WillReturnRows(subjectRow(1, "active", ptr(2)))
+		m.ExpectQuery(loginQuery).WithArgs("jdoe").WillReturnRows(idRow(1))
+		m.ExpectRollback()
+
+		_, err := svc.Approve(asUser("jdoe"), 10, DecisionRequest{}, true)
+		a.ErrorAs(err, &common.ForbiddenError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("overdue request", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		overdue := pending
+		overdue.DueAt = time.Now().Add(-time.Minute)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(10)).WillReturnRows(requestRows(overdue))
+		m.ExpectRollback()
+
+		_, err := svc.Approve(asUser("boss"), 10, DecisionRequest{}, false)
+		a.ErrorAs(err, &common.ConflictError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+}
+
+func TestService_Reject(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("comment is required", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		_, err := svc.Reject(asUser("boss"), 10, DecisionRequest{Comment: "  "}, false)
+		a.ErrorAs(err, &common.RequestValidationError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("closed request", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(10)).WillReturnRows(requestRows(Entity{
+			Id: 10, EmployeeId: 1, RoleId: 5, Status: StatusApproved, Stages: []string{StageAdmin}}))
+		m.ExpectRollback()
+
+		_, err := svc.Reject(asUser("boss"), 10, DecisionRequest{Comment: "no"}, true)
+		a.ErrorAs(err, &common.ConflictError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+}
+
+func TestService_Cancel(t *testing.T) {
+	a := assert.New(t)
+	pending := Entity{Id: 10, EmployeeId: 1, RoleId: 5, RequestedBy: "jdoe", Status: StatusPending,
+		Stages: []string{StageAdmin}, DueAt: time.Now().Add(time.Hour)}
+
+	t.Run("author cancels", func(t *testing.T) {
+		svc, m, auditor, publisher := newSqlmockService(t)
+		cancelled := pending
+		cancelled.Status = StatusCancelled
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(10)).WillReturnRows(requestRows(pending))
+		m.ExpectQuery(updateQuery).WithArgs(StatusCancelled, 0, sqlmock.AnyArg(), sqlmock.AnyArg(), "jdoe", int64(10)).
+			WillReturnRows(requestRows(cancelled))
+		m.ExpectCommit()
+
+		got, err := svc.Cancel(asUser("jdoe"), 10, false)
+		a.NoError(err)
+		a.Equal(StatusCancelled, got.Status)
+		a.Equal(audit.ActionCancel, auditor.records[0].Action)
+		a.Equal(EventCancelled, publisher.events[0].Type)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("someone else is forbidden", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(10)).WillReturnRows(requestRows(pending))
+		m.ExpectRollback()
+
+		_, err := svc.Cancel(asUser("peer"), 10, false)
+		a.ErrorAs(err, &common.ForbiddenError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+}
+
+func TestService_FindById(t *testing.T) {
+	a := assert.New(t)
+	request := Entity{Id: 10, EmployeeId: 1, RoleId: 5, RequestedBy: "jdoe", Status: StatusPending,
+		Stages: []string{StageManager}, DueAt: time.Now().Add(time.Hour)}
+	findQuery := regexp.QuoteMeta("SELECT * FROM access_request WHERE id = $1")
+	decisionsQuery := regexp.QuoteMeta("SELECT * FROM access_request_decision WHERE request_id = $1")
+	approverQuery := regexp.QuoteMeta("SELECT exists(SELECT 1 FROM access_request ar")
+	noDecisions := func() *sqlmock.Rows { return sqlmock.NewRows([]string{"id"}) }
+
+	t.Run("author", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectQuery(findQuery).WithArgs(int64(10)).WillReturnRows(requestRows(request))
+		m.ExpectQuery(decisionsQuery).WithArgs(int64(10)).WillReturnRows(noDecisions())
+
+		got, err := svc.FindById(asUser("jdoe"), 10, false)
+		a.NoError(err)
+		a.Equal(int64(10), got.Id)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("current approver", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectQuery(findQuery).WithArgs(int64(10)).WillReturnRows(requestRows(request))
+		m.ExpectQuery(loginQuery).WithArgs("boss").WillReturnRows(idRow(2))
+		m.ExpectQuery(approverQuery).WithArgs(int64(10), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		m.ExpectQuery(decisionsQuery).WithArgs(int64(10)).WillReturnRows(noDecisions())
+
+		_, err := svc.FindById(asUser("boss"), 10, false)
+		a.NoError(err)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("other employee is forbidden", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectQuery(findQuery).WithArgs(int64(10)).WillReturnRows(requestRows(request))
+		m.ExpectQuery(loginQuery).WithArgs("peer").WillReturnRows(idRow(7))
+		m.ExpectQuery(approverQuery).WithArgs(int64(10), int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		_, err := svc.FindById(asUser("peer"), 10, false)
+		a.ErrorAs(err, &common.ForbiddenError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectQuery(findQuery).WithArgs(int64(10)).WillReturnRows(requestRows(request))
+		m.ExpectQuery(decisionsQuery).WithArgs(int64(10)).WillReturnRows(noDecisions())
+
+		_, err := svc.FindById(asUser("admin"), 10, true)
+		a.NoError(err)
+		a.NoError(m.ExpectationsWereMet())
+	})
+}
+
+func TestService_FindInbox(t *testing.T) {
+	a := assert.New(t)
+	inboxQuery := regexp.QuoteMeta("SELECT ar.* FROM access_request ar")
+
+	t.Run("approver", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectQuery(loginQuery).WithArgs("boss").WillReturnRows(idRow(2))
+		m.ExpectQuery(inboxQuery).WithArgs(int64(2), false).WillReturnRows(requestRows(Entity{
+			Id: 10, EmployeeId: 1, RoleId: 5, Status: StatusPending, Stages: []string{StageManager}}))
+
+		got, err := svc.FindInbox(asUser("boss"), false)
+		a.NoError(err)
+		a.Len(got, 1)
+		a.Equal(StageManager, got[0].CurrentStage)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("user without employee record", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectQuery(loginQuery).WithArgs("svc-account").WillReturnRows(idRow(0))
+
+		got, err := svc.FindInbox(asUser("svc-account"), false)
+		a.NoError(err)
+		a.Empty(got)
+		a.NoError(m.ExpectationsWereMet())
+	})
+}
+
+func TestService_ExpireOverdue(t *testing.T) {
+	a := assert.New(t)
+	svc, m, auditor, publisher := newSqlmockService(t)
+	now := time.Now()
+	expired := Entity{Id: 10, EmployeeId: 1, RoleId: 5, Status: StatusExpired, Stages: []string{StageManager}, ResolvedAt: &now}
+	m.ExpectBegin()
+	m.ExpectQuery(regexp.QuoteMeta("UPDATE access_request SET status = 'expired'")).WithArgs(now).
+		WillReturnRows(requestRows(expired))
+	m.ExpectCommit()
+
+	count, err := svc.ExpireOverdue(context.Background(), now)
+	a.NoError(err)
+	a.Equal(1, count)
+	a.Equal(audit.ActionExpire, auditor.records[0].Action)
+	a.Equal(StatusPending, auditor.records[0].Before.(auditState).Status)
+	a.Equal(EventExpired, publisher.events[0].Type)
+	a.NoError(m.ExpectationsWereMet())
+}
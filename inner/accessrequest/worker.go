@@ -0,0 +1,47 @@
+package accessrequest
+
+import (
+	"context"
+	"time"
+
+	"idm/inner/common"
+
+	"go.uber.org/zap"
+)
+
+// Target - сервис, закрывающий заявки с истёкшим к моменту now сроком согласования
+type Target interface {
+	ExpireOverdue(ctx context.Context, now time.Time) (int, error)
+}
+
+// Worker периодически закрывает заявки, которые не согласовали в срок (SLA)
+type Worker struct {
+	logger *common.Logger
+	target Target
+	now    func() time.Time
+
+	*common.Periodic
+}
+
+func NewWorker(logger *common.Logger, interval time.Duration, target Target) *Worker {
+	w := &Worker{
+		logger: logger,
+		target: target,
+		now:    time.Now,
+	}
+	w.Periodic = common.NewPeriodic(interval, w.RunOnce)
+	return w
+}
+
+// RunOnce закрывает заявки, срок согласования которых истёк к текущему моменту
+func (w *Worker) RunOnce(ctx context.Context) {
+	now := w.now()
+	expired, err := w.target.ExpireOverdue(ctx, now)
+	if err != nil {
+		w.logger.Error("expire overdue access requests", zap.Error(err))
+		return
+	}
+	if expired > 0 {
+		w.logger.Info("expired overdue access requests", zap.Int("count", expired), zap.Time("expired_by", now))
+	}
+}
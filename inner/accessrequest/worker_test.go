@@ -0,0 +1,58 @@
+package accessrequest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"idm/inner/logtest"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// stubTarget запоминает моменты, к которым его просили закрыть просроченные заявки
+type stubTarget struct {
+	mu      sync.Mutex
+	nows    []time.Time
+	expired int
+	err     error
+}
+
+func (s *stubTarget) ExpireOverdue(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nows = append(s.nows, now)
+	return s.expired, s.err
+}
+
+func newTestWorker(target Target) (*Worker, *observer.ObservedLogs) {
+	logger, logs := logtest.New()
+	return NewWorker(logger, time.Minute, target), logs
+}
+
+func TestWorker_RunOnce(t *testing.T) {
+	now := time.Date(2025, 12, 25, 12, 0, 0, 0, time.UTC)
+
+	t.Run("expired requests are logged", func(t *testing.T) {
+		target := &stubTarget{expired: 2}
+		worker, logs := newTestWorker(target)
+		worker.now = func() time.Time { return now }
+
+		worker.RunOnce(context.Background())
+
+		assert.Equal(t, []time.Time{now}, target.nows)
+		assert.Equal(t, 1, logs.FilterMessage("expired overdue access requests").FilterField(zap.Int("count", 2)).Len())
+	})
+
+	t.Run("target error is logged", func(t *testing.T) {
+		worker, logs := newTestWorker(&stubTarget{err: errors.New("db down")})
+
+		worker.RunOnce(context.Background())
+
+		assert.Equal(t, 1, logs.FilterMessage("expire overdue access requests").Len())
+	})
+}
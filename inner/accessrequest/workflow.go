@@ -0,0 +1,83 @@
+package accessrequest
+
+import "time"
+
+// статусы заявки; все, кроме pending, конечные
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// этапы согласования
+const (
+	StageManager   = "manager"
+	StageRoleOwner = "role_owner"
+	// StageAdmin назначается, если у заявки нет ни руководителя, ни владельца роли
+	StageAdmin = "admin"
+)
+
+// решения согласующего
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+// transitions - допустимые переходы между статусами заявки
+var transitions = map[string][]string{
+	StatusPending: {StatusApproved, StatusRejected, StatusCancelled, StatusExpired},
+}
+
+// canTransition сообщает, разрешён ли переход заявки из статуса from в статус to
+func canTransition(from, to string) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// planStages возвращает этапы согласования заявки сотрудника employeeId: сначала руководитель, затем владелец роли.
+// Владелец роли не согласует повторно, если он же руководитель, и не согласует собственную заявку.
+// Если согласовать некому, заявку согласует администратор
+func planStages(employeeId int64, managerId, ownerId *int64) []string {
+	var stages []string
+	if managerId != nil {
+		stages = append(stages, StageManager)
+	}
+	if ownerId != nil && *ownerId != employeeId && (managerId == nil || *ownerId != *managerId) {
+		stages = append(stages, StageRoleOwner)
+	}
+	if len(stages) == 0 {
+		stages = append(stages, StageAdmin)
+	}
+	return stages
+}
+
+// decide возвращает копию заявки после решения approver: отказ закрывает заявку,
+// одобрение переводит её на следующий этап с новым сроком согласования, а на последнем этапе - одобряет
+func decide(current *Entity, decision, approver string, now time.Time, sla time.Duration) *Entity {
+	if decision == DecisionApprove && current.Stage+1 < len(current.Stages) {
+		next := *current
+		next.Stage++
+		next.DueAt = now.Add(sla)
+		return &next
+	}
+	status := StatusApproved
+	if decision == DecisionReject {
+		status = StatusRejected
+	}
+	return resolve(current, status, approver, now)
+}
+
+// resolve возвращает копию заявки, закрытую в статусе status
+func resolve(current *Entity, status, actor string, now time.Time) *Entity {
+	next := *current
+	next.Status = status
+	next.ResolvedAt = &now
+	next.ResolvedBy = &actor
+	return &next
+}
@@ -0,0 +1,61 @@
+package accessrequest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanStages(t *testing.T) {
+	tests := []struct {
+		name      string
+		managerId *int64
+		ownerId   *int64
+		want      []string
+	}{
+		{name: "manager and owner", managerId: ptr(2), ownerId: ptr(3), want: []string{StageManager, StageRoleOwner}},
+		{name: "manager only", managerId: ptr(2), want: []string{StageManager}},
+		{name: "owner only", ownerId: ptr(3), want: []string{StageRoleOwner}},
+		{name: "owner is the manager", managerId: ptr(2), ownerId: ptr(2), want: []string{StageManager}},
+		{name: "owner is the employee", ownerId: ptr(1), want: []string{StageAdmin}},
+		{name: "nobody", want: []string{StageAdmin}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, planStages(1, tt.managerId, tt.ownerId))
+		})
+	}
+}
+
+func TestDecide(t *testing.T) {
+	a := assert.New(t)
+	now := time.Date(2025, 12, 25, 12, 0, 0, 0, time.UTC)
+	current := &Entity{Id: 1, Status: StatusPending, Stages: []string{StageManager, StageRoleOwner}, DueAt: now.Add(time.Hour)}
+
+	advanced := decide(current, DecisionApprove, "boss", now, 24*time.Hour)
+	a.Equal(StatusPending, advanced.Status)
+	a.Equal(StageRoleOwner, advanced.currentStage())
+	a.Equal(now.Add(24*time.Hour), advanced.DueAt)
+	a.Nil(advanced.ResolvedAt)
+	// исходная заявка не меняется
+	a.Equal(0, current.Stage)
+
+	approved := decide(advanced, DecisionApprove, "owner", now, 24*time.Hour)
+	a.Equal(StatusApproved, approved.Status)
+	a.Equal("owner", *approved.ResolvedBy)
+	a.Empty(approved.currentStage())
+
+	rejected := decide(current, DecisionReject, "boss", now, 24*time.Hour)
+	a.Equal(StatusRejected, rejected.Status)
+	a.Equal(now, *rejected.ResolvedAt)
+}
+
+func TestCanTransition(t *testing.T) {
+	a := assert.New(t)
+	for _, to := range []string{StatusApproved, StatusRejected, StatusCancelled, StatusExpired} {
+		a.True(canTransition(StatusPending, to), to)
+		a.False(canTransition(to, StatusPending), to)
+	}
+	a.False(canTransition(StatusApproved, StatusCancelled))
+}
@@ -0,0 +1,256 @@
+package accessreview
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	server              *web.Server
+	accessReviewService Svc
+	logger              *common.Logger
+}
+
+// Svc описывает набор методов бизнес-логики по работе с кампаниями пересмотра доступа.
+// admin - текущий пользователь администратор IDM: он проверяет назначения без проверяющего и может решить любое
+type Svc interface {
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	FindItems(ctx context.Context, id int64) ([]ItemResponse, error)
+	FindMyItems(ctx context.Context, admin bool) ([]ItemResponse, error)
+	Create(ctx context.Context, req CreateRequest) (Response, error)
+	Decide(ctx context.Context, id, itemId int64, req DecisionRequest, admin bool) (ItemResponse, error)
+	Close(ctx context.Context, id int64) (Response, error)
+	Report(ctx context.Context, id int64) (SignedReport, error)
+}
+
+func NewController(server *web.Server, accessReviewService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:              server,
+		accessReviewService: accessReviewService,
+		logger:              logger,
+	}
+}
+
+// RegisterRoutes регистрирует маршруты; права доступа к ним задаются политикой (policies.yaml)
+func (c *Controller) RegisterRoutes() {
+
+	grp := c.server.GroupApiV1.Group("/access-reviews")
+
+	grp.Post("/", c.CreateAccessReview)
+	grp.Post("/:id/items/:itemId/decision", c.DecideReviewItem)
+	grp.Post("/:id/close", c.CloseAccessReview)
+
+	grp.Get("/", c.GetAllAccessReviews)
+	// inbox регистрируется раньше /:id, иначе его перехватит маршрут с параметром
+	grp.Get("/inbox", c.GetReviewInbox)
+	grp.Get("/:id", c.GetAccessReview)
+	grp.Get("/:id/items", c.GetAccessReviewItems)
+	grp.Get("/:id/report", c.ExportAccessReviewReport)
+}
+
+// CreateAccessReview godoc
+// @Summary      Start access review campaign
+// @Description  Starts a campaign reviewing role assignments active in the scope (a role, an org unit subtree or everything); each assignment is reviewed by the employee's manager or the role owner
+// @Tags         accessreview
+// @Accept       json
+// @Produce      json
+// @Param        request  body      accessreview.CreateRequest  true  "create access review request"
+// @Success      200      {object}  common.Response[accessreview.Response]
+// @Failure      400      {object}  common.Response[any]  "invalid request or scope not found"
+// @Failure      409      {object}  common.Response[any]  "no active role assignments in the scope"
+// @Router       /access-reviews [post]
+// @Security BearerAuth
+func (c *Controller) CreateAccessReview(ctx *fiber.Ctx) error {
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("create access review", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	c.logger.Ctx(ctx.UserContext()).Debug("create access review: received request", zap.Any("request", request))
+
+	resp, err := c.accessReviewService.Create(ctx.UserContext(), request)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("create access review", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// GetAllAccessReviews godoc
+// @Summary      List access review campaigns
+// @Description  Returns all campaigns, newest first
+// @Tags         accessreview
+// @Produce      json
+// @Success      200  {object}  common.Response[[]accessreview.Response]
+// @Router       /access-reviews [get]
+// @Security BearerAuth
+func (c *Controller) GetAllAccessReviews(ctx *fiber.Ctx) error {
+	resps, err := c.accessReviewService.FindAll(ctx.UserContext())
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get all access reviews", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// GetReviewInbox godoc
+// @Summary      List my pending review items
+// @Description  Returns undecided role assignments of open campaigns assigned to the current user; administrators also get assignments without a reviewer
+// @Tags         accessreview
+// @Produce      json
+// @Success      200  {object}  common.Response[[]accessreview.ItemResponse]
+// @Router       /access-reviews/inbox [get]
+// @Security BearerAuth
+func (c *Controller) GetReviewInbox(ctx *fiber.Ctx) error {
+	resps, err := c.accessReviewService.FindMyItems(ctx.UserContext(), isAdmin(ctx))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get access review inbox", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// GetAccessReview godoc
+// @Summary      Get access review campaign by id
+// @Description  Returns the campaign together with review progress
+// @Tags         accessreview
+// @Produce      json
+// @Param        id   path      int  true  "access review id"
+// @Success      200  {object}  common.Response[accessreview.Response]
+// @Failure      404  {object}  common.Response[any]
+// @Router       /access-reviews/{id} [get]
+// @Security BearerAuth
+func (c *Controller) GetAccessReview(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resp, err := c.accessReviewService.FindById(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get access review", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// GetAccessReviewItems godoc
+// @Summary      List access review items
+// @Description  Returns all role assignments of the campaign with reviewers, decisions and, once closed, outcomes
+// @Tags         accessreview
+// @Produce      json
+// @Param        id   path      int  true  "access review id"
+// @Success      200  {object}  common.Response[[]accessreview.ItemResponse]
+// @Failure      404  {object}  common.Response[any]
+// @Router       /access-reviews/{id}/items [get]
+// @Security BearerAuth
+func (c *Controller) GetAccessReviewItems(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resps, err := c.accessReviewService.FindItems(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get access review items", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// DecideReviewItem godoc
+// @Summary      Decide access review item
+// @Description  Keeps or revokes the role assignment when the campaign closes, or delegates its review to another employee; the decision can be changed while the campaign is open
+// @Tags         accessreview
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                           true  "access review id"
+// @Param        itemId   path      int                           true  "item id"
+// @Param        request  body      accessreview.DecisionRequest  true  "decision"
+// @Success      200      {object}  common.Response[accessreview.ItemResponse]
+// @Failure      400      {object}  common.Response[any]  "invalid decision or delegate"
+// @Failure      403      {object}  common.Response[any]  "the user is not the reviewer of the item"
+// @Failure      404      {object}  common.Response[any]
+// @Failure      409      {object}  common.Response[any]  "campaign is closed or overdue"
+// @Router       /access-reviews/{id}/items/{itemId}/decision [post]
+// @Security BearerAuth
+func (c *Controller) DecideReviewItem(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	itemId, err := strconv.ParseInt(ctx.Params("itemId"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid item id")
+	}
+	var request DecisionRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("decide access review item", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	resp, err := c.accessReviewService.Decide(ctx.UserContext(), id, itemId, request, isAdmin(ctx))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("decide access review item", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// CloseAccessReview godoc
+// @Summary      Close access review campaign
+// @Description  Closes the campaign before its deadline: revokes assignments decided as revoke (and undecided ones if the campaign is configured so) and stores the final report
+// @Tags         accessreview
+// @Produce      json
+// @Param        id   path      int  true  "access review id"
+// @Success      200  {object}  common.Response[accessreview.Response]
+// @Failure      404  {object}  common.Response[any]
+// @Failure      409  {object}  common.Response[any]  "campaign is already closed"
+// @Router       /access-reviews/{id}/close [post]
+// @Security BearerAuth
+func (c *Controller) CloseAccessReview(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resp, err := c.accessReviewService.Close(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("close access review", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// ExportAccessReviewReport godoc
+// @Summary      Export access review report
+// @Description  Returns the final report of a closed campaign as a JSON attachment with its sha256 digest (also recorded in the audit log) and its HMAC-SHA256 signature made when the campaign was closed. Both are computed over the report with sorted keys and no whitespace
+// @Tags         accessreview
+// @Produce      json
+// @Param        id   path      int  true  "access review id"
+// @Success      200  {object}  accessreview.SignedReport
+// @Failure      404  {object}  common.Response[any]
+// @Failure      409  {object}  common.Response[any]  "campaign is not closed yet"
+// @Router       /access-reviews/{id}/report [get]
+// @Security BearerAuth
+func (c *Controller) ExportAccessReviewReport(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	report, err := c.accessReviewService.Report(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("export access review report", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	ctx.Attachment(fmt.Sprintf("access-review-%d-report.json", id))
+	return ctx.JSON(report)
+}
+
+func isAdmin(ctx *fiber.Ctx) bool {
+	return web.Satisfies(ctx, web.HasAnyRole(web.IdmAdmin))
+}
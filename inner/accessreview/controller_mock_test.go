@@ -0,0 +1,184 @@
+package accessreview
+
+import (
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/policy"
+	"idm/inner/web"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Объявляем структуру мока сервиса accessreview.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindAll(ctx context.Context) ([]Response, error) {
+	args := svc.Called(ctx)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindItems(ctx context.Context, id int64) ([]ItemResponse, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).([]ItemResponse), args.Error(1)
+}
+
+func (svc *MockService) FindMyItems(ctx context.Context, admin bool) ([]ItemResponse, error) {
+	args := svc.Called(ctx, admin)
+	return args.Get(0).([]ItemResponse), args.Error(1)
+}
+
+func (svc *MockService) Create(ctx context.Context, req CreateRequest) (Response, error) {
+	args := svc.Called(ctx, req)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Decide(ctx context.Context, id, itemId int64, req DecisionRequest, admin bool) (ItemResponse, error) {
+	args := svc.Called(ctx, id, itemId, req, admin)
+	return args.Get(0).(ItemResponse), args.Error(1)
+}
+
+func (svc *MockService) Close(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Report(ctx context.Context, id int64) (SignedReport, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(SignedReport), args.Error(1)
+}
+
+// newTestServer регистрирует маршруты кампаний за политикой доступа по умолчанию
+func newTestServer(t *testing.T, roles ...string) (*web.Server, *MockService) {
+	routePolicy, err := policy.Load("../../policies.yaml")
+	assert.NoError(t, err)
+	enforcer, err := policy.NewEnforcer(routePolicy, "")
+	assert.NoError(t, err)
+
+	server := web.NewServer()
+	server.GroupApiV1.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: roles}}})
+		return c.Next()
+	}, enforcer.Middleware())
+
+	svc := new(MockService)
+	NewController(server, svc, common.NewLogger(common.GetConfig(".env"))).RegisterRoutes()
+	return server, svc
+}
+
+func TestCreateAccessReview(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("admin starts campaign", func(t *testing.T) {
+		server, svc := newTestServer(t, web.IdmAdmin)
+		svc.On("Create", mock.Anything, mock.MatchedBy(func(req CreateRequest) bool {
+			return req.Name == "Q4 review" && req.ScopeType == ScopeAll && req.RevokeUndecided
+		})).Return(Response{Id: 7, Status: StatusOpen, Progress: &Progress{Total: 3}}, nil)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/access-reviews", strings.NewReader(
+			`{"name":"Q4 review","scope_type":"all","reviewer_type":"manager","due_at":"2030-01-01T00:00:00Z","revoke_undecided":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req, -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		a.NoError(err)
+		var got common.Response[Response]
+		a.NoError(json.Unmarshal(body, &got))
+		a.Equal(int64(7), got.Data.Id)
+		a.Equal(int64(3), got.Data.Progress.Total)
+	})
+
+	t.Run("user is forbidden", func(t *testing.T) {
+		server, svc := newTestServer(t, web.IdmUser)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/access-reviews", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req, -1)
+		a.NoError(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetReviewInbox(t *testing.T) {
+	a := assert.New(t)
+	for role, admin := range map[string]bool{web.IdmAdmin: true, web.IdmUser: false} {
+		server, svc := newTestServer(t, role)
+		svc.On("FindMyItems", mock.Anything, admin).Return([]ItemResponse{{Id: 11}}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/access-reviews/inbox", nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		// inbox не перехватывается маршрутом /:id
+		svc.AssertNotCalled(t, "FindById", mock.Anything, mock.Anything)
+		svc.AssertExpectations(t)
+	}
+}
+
+func TestDecideReviewItem(t *testing.T) {
+	a := assert.New(t)
+	tests := []struct {
+		name       string
+		body       string
+		result     error
+		wantStatus int
+	}{
+		{name: "kept", body: `{"decision":"keep"}`, wantStatus: http.StatusOK},
+		{name: "not the reviewer", body: `{"decision":"keep"}`, wantStatus: http.StatusForbidden,
+			result: common.ForbiddenError{Message: "only the assigned reviewer or an administrator can review item 11"}},
+		{name: "closed campaign", body: `{"decision":"keep"}`, wantStatus: http.StatusConflict, result: closed(7)},
+		{name: "malformed body", body: `{"decision":1}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, svc := newTestServer(t, web.IdmUser)
+			svc.On("Decide", mock.Anything, int64(7), int64(11), DecisionRequest{Decision: DecisionKeep}, false).
+				Return(ItemResponse{Id: 11, Decision: str(DecisionKeep)}, tt.result)
+
+			req := httptest.NewRequest(fiber.MethodPost, "/api/v1/access-reviews/7/items/11/decision", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := server.App.Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestExportAccessReviewReport(t *testing.T) {
+	a := assert.New(t)
+	server, svc := newTestServer(t, web.IdmAdmin)
+	svc.On("Report", mock.Anything, int64(7)).Return(SignedReport{
+		Report: json.RawMessage(`{"campaign_id":7}`), Digest: "sha256:abc", Signature: "def", SignatureAlgorithm: SignatureAlgorithm,
+	}, nil)
+	svc.On("Report", mock.Anything, int64(8)).
+		Return(SignedReport{}, common.ConflictError{Message: "access review 8 is not closed yet"})
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/access-reviews/7/report", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	a.Contains(resp.Header.Get(fiber.HeaderContentDisposition), "access-review-7-report.json")
+	body, err := io.ReadAll(resp.Body)
+	a.NoError(err)
+	a.JSONEq(`{"report":{"campaign_id":7},"digest":"sha256:abc","signature":"def","signature_algorithm":"HMAC-SHA256"}`, string(body))
+
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/access-reviews/8/report", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusConflict, resp.StatusCode)
+}
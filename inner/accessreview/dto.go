@@ -0,0 +1,229 @@
+package accessreview
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entity - кампания пересмотра доступа: проверка назначений ролей в заданном охвате к сроку
+type Entity struct {
+	Id              int64      `db:"id"`
+	Name            string     `db:"name"`
+	ScopeType       string     `db:"scope_type"`
+	ScopeId         *int64     `db:"scope_id"`
+	ReviewerType    string     `db:"reviewer_type"`
+	RevokeUndecided bool       `db:"revoke_undecided"`
+	Status          string     `db:"status"`
+	DueAt           time.Time  `db:"due_at"`
+	CreatedBy       string     `db:"created_by"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	ClosedAt        *time.Time `db:"closed_at"`
+	ClosedBy        *string    `db:"closed_by"`
+	// Report - итоговый отчёт в каноническом JSON, ReportDigest - его sha256,
+	// ReportSignature - его HMAC-SHA256, вычисленный при закрытии
+	Report          *string `db:"report"`
+	ReportDigest    *string `db:"report_digest"`
+	ReportSignature *string `db:"report_signature"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:              e.Id,
+		Name:            e.Name,
+		ScopeType:       e.ScopeType,
+		ScopeId:         e.ScopeId,
+		ReviewerType:    e.ReviewerType,
+		RevokeUndecided: e.RevokeUndecided,
+		Status:          e.Status,
+		DueAt:           e.DueAt,
+		CreatedBy:       e.CreatedBy,
+		CreatedAt:       e.CreatedAt,
+		UpdatedAt:       e.UpdatedAt,
+		ClosedAt:        e.ClosedAt,
+		ClosedBy:        e.ClosedBy,
+		ReportDigest:    e.ReportDigest,
+	}
+}
+
+type Response struct {
+	Id              int64      `json:"id"`
+	Name            string     `json:"name"`
+	ScopeType       string     `json:"scope_type"`
+	ScopeId         *int64     `json:"scope_id,omitempty"`
+	ReviewerType    string     `json:"reviewer_type"`
+	RevokeUndecided bool       `json:"revoke_undecided"`
+	Status          string     `json:"status"`
+	DueAt           time.Time  `json:"due_at"`
+	CreatedBy       string     `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	ClosedAt        *time.Time `json:"closed_at,omitempty"`
+	ClosedBy        *string    `json:"closed_by,omitempty"`
+	ReportDigest    *string    `json:"report_digest,omitempty"`
+	// Progress - ход проверки; заполняется при чтении одной кампании
+	Progress *Progress `json:"progress,omitempty"`
+}
+
+// Progress - число назначений в кампании по состоянию проверки
+type Progress struct {
+	Total     int64 `json:"total" db:"total"`
+	Decided   int64 `json:"decided" db:"decided"`
+	Kept      int64 `json:"kept" db:"kept"`
+	Revoked   int64 `json:"revoked" db:"revoked"`
+	Delegated int64 `json:"delegated" db:"delegated"`
+}
+
+// ItemEntity - назначение роли сотруднику, которое проверяется в кампании
+type ItemEntity struct {
+	Id           int64  `db:"id"`
+	CampaignId   int64  `db:"campaign_id"`
+	EmployeeId   int64  `db:"employee_id"`
+	EmployeeName string `db:"employee_name"`
+	RoleId       int64  `db:"role_id"`
+	RoleName     string `db:"role_name"`
+	// GrantedValidFrom и GrantedValidTo - срок действия назначения на момент запуска кампании
+	GrantedValidFrom *time.Time `db:"granted_valid_from"`
+	GrantedValidTo   *time.Time `db:"granted_valid_to"`
+	ReviewerId       *int64     `db:"reviewer_id"`
+	Decision         *string    `db:"decision"`
+	Comment          *string    `db:"comment"`
+	DecidedBy        *string    `db:"decided_by"`
+	DecidedAt        *time.Time `db:"decided_at"`
+	DelegatedBy      *string    `db:"delegated_by"`
+	RemindedAt       *time.Time `db:"reminded_at"`
+	Outcome          *string    `db:"outcome"`
+}
+
+func (e *ItemEntity) toResponse() ItemResponse {
+	return ItemResponse{
+		Id:               e.Id,
+		CampaignId:       e.CampaignId,
+		EmployeeId:       e.EmployeeId,
+		EmployeeName:     e.EmployeeName,
+		RoleId:           e.RoleId,
+		RoleName:         e.RoleName,
+		GrantedValidFrom: e.GrantedValidFrom,
+		GrantedValidTo:   e.GrantedValidTo,
+		ReviewerId:       e.ReviewerId,
+		Decision:         e.Decision,
+		Comment:          e.Comment,
+		DecidedBy:        e.DecidedBy,
+		DecidedAt:        e.DecidedAt,
+		DelegatedBy:      e.DelegatedBy,
+		Outcome:          e.Outcome,
+	}
+}
+
+type ItemResponse struct {
+	Id               int64      `json:"id"`
+	CampaignId       int64      `json:"campaign_id"`
+	EmployeeId       int64      `json:"employee_id"`
+	EmployeeName     string     `json:"employee_name"`
+	RoleId           int64      `json:"role_id"`
+	RoleName         string     `json:"role_name"`
+	GrantedValidFrom *time.Time `json:"granted_valid_from,omitempty"`
+	GrantedValidTo   *time.Time `json:"granted_valid_to,omitempty"`
+	// ReviewerId - проверяющий; если не заполнен, назначение проверяет администратор IDM
+	ReviewerId  *int64     `json:"reviewer_id,omitempty"`
+	Decision    *string    `json:"decision,omitempty"`
+	Comment     *string    `json:"comment,omitempty"`
+	DecidedBy   *string    `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	DelegatedBy *string    `json:"delegated_by,omitempty"`
+	Outcome     *string    `json:"outcome,omitempty"`
+}
+
+// CreateRequest - запуск кампании; scope_id обязателен для охвата role и org_unit и не указывается для all
+type CreateRequest struct {
+	Name            string    `json:"name" validate:"required,min=2,max=255"`
+	ScopeType       string    `json:"scope_type" validate:"required,oneof=role org_unit all"`
+	ScopeId         *int64    `json:"scope_id,omitempty" validate:"omitempty,gt=0"`
+	ReviewerType    string    `json:"reviewer_type" validate:"required,oneof=manager role_owner"`
+	DueAt           time.Time `json:"due_at" validate:"required"`
+	RevokeUndecided bool      `json:"revoke_undecided"`
+}
+
+// DecisionRequest - решение проверяющего по назначению; при delegate проверка передаётся сотруднику delegate_to
+type DecisionRequest struct {
+	Decision   string `json:"decision" validate:"required,oneof=keep revoke delegate"`
+	Comment    string `json:"comment" validate:"max=1000"`
+	DelegateTo *int64 `json:"delegate_to,omitempty" validate:"omitempty,gt=0"`
+}
+
+// Reminder - напоминание проверяющему о назначениях кампании, по которым он ещё не принял решение
+type Reminder struct {
+	CampaignId int64 `json:"campaign_id" db:"campaign_id"`
+	// ReviewerId - проверяющий; nil - очередь администраторов IDM
+	ReviewerId *int64    `json:"reviewer_id,omitempty" db:"reviewer_id"`
+	Pending    int       `json:"pending" db:"pending"`
+	DueAt      time.Time `json:"due_at" db:"due_at"`
+}
+
+// SignedReport - итоговый отчёт закрытой кампании для выгрузки.
+// Digest - sha256 отчёта в каноническом виде (ключи отсортированы, без пробелов), он же записан в журнал аудита при закрытии.
+// Signature - HMAC-SHA256 того же представления на ключе ACCESS_REVIEW_SIGNING_KEY, вычисленный при закрытии
+type SignedReport struct {
+	Report             json.RawMessage `json:"report" swaggertype:"object"`
+	Digest             string          `json:"digest"`
+	Signature          string          `json:"signature"`
+	SignatureAlgorithm string          `json:"signature_algorithm"`
+}
+
+// auditEntityType - тип сущности кампании в журнале аудита
+const auditEntityType = "access_review"
+
+// auditState - состояние кампании, которое сохраняется в журнале аудита
+type auditState struct {
+	Id              int64     `json:"id"`
+	Name            string    `json:"name"`
+	ScopeType       string    `json:"scope_type"`
+	ScopeId         *int64    `json:"scope_id,omitempty"`
+	ReviewerType    string    `json:"reviewer_type"`
+	RevokeUndecided bool      `json:"revoke_undecided"`
+	Status          string    `json:"status"`
+	DueAt           time.Time `json:"due_at"`
+	Items           int64     `json:"items,omitempty"`
+	ReportDigest    *string   `json:"report_digest,omitempty"`
+}
+
+func (e *Entity) toAuditState() auditState {
+	return auditState{
+		Id:              e.Id,
+		Name:            e.Name,
+		ScopeType:       e.ScopeType,
+		ScopeId:         e.ScopeId,
+		ReviewerType:    e.ReviewerType,
+		RevokeUndecided: e.RevokeUndecided,
+		Status:          e.Status,
+		DueAt:           e.DueAt,
+		ReportDigest:    e.ReportDigest,
+	}
+}
+
+// auditItem - решение по назначению в журнале аудита кампании
+type auditItem struct {
+	ItemId     int64   `json:"item_id"`
+	EmployeeId int64   `json:"employee_id"`
+	RoleId     int64   `json:"role_id"`
+	ReviewerId *int64  `json:"reviewer_id,omitempty"`
+	Decision   *string `json:"decision,omitempty"`
+	Comment    *string `json:"comment,omitempty"`
+}
+
+func (e *ItemEntity) toAuditItem() auditItem {
+	return auditItem{
+		ItemId:     e.Id,
+		EmployeeId: e.EmployeeId,
+		RoleId:     e.RoleId,
+		ReviewerId: e.ReviewerId,
+		Decision:   e.Decision,
+		Comment:    e.Comment,
+	}
+}
+
+// auditRevoke - роли, отозванные у сотрудника по итогам кампании, в журнале аудита сотрудника
+type auditRevoke struct {
+	RoleIds        []int64 `json:"role_ids"`
+	AccessReviewId int64   `json:"access_review_id"`
+}
@@ -0,0 +1,299 @@
+package accessreview
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/employee"
+	"idm/inner/tracing"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+// selectItems - выборка назначений кампании вместе с именами сотрудника и роли для отчёта и очереди проверяющего
+const selectItems = `SELECT i.*, e.name AS employee_name, r.name AS role_name FROM access_review_item i
+	JOIN employee e ON e.id = i.employee_id
+	JOIN role r ON r.id = i.role_id`
+
+func (r *Repository) FindById(ctx context.Context, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.FindById")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = r.db.GetContext(ctx, &entity, "SELECT * FROM access_review_campaign WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(id)
+	}
+	return &entity, err
+}
+
+// FindAll возвращает кампании, начиная с последней
+func (r *Repository) FindAll(ctx context.Context) (campaigns []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.FindAll")
+	defer func() { span.Finish(int64(len(campaigns)), err) }()
+	err = r.db.SelectContext(ctx, &campaigns, "SELECT * FROM access_review_campaign ORDER BY id DESC")
+	return campaigns, err
+}
+
+// CountItems считает назначения кампании по состоянию проверки
+func (r *Repository) CountItems(ctx context.Context, campaignId int64) (progress Progress, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.CountItems")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = r.db.GetContext(ctx, &progress,
+		`SELECT count(*) AS total, count(decision) AS decided,
+			count(*) FILTER (WHERE decision = 'keep') AS kept,
+			count(*) FILTER (WHERE decision = 'revoke') AS revoked,
+			count(delegated_by) AS delegated
+		FROM access_review_item WHERE campaign_id = $1`, campaignId)
+	return progress, err
+}
+
+// FindItems возвращает все назначения кампании
+func (r *Repository) FindItems(ctx context.Context, campaignId int64) (items []ItemEntity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.FindItems")
+	defer func() { span.Finish(int64(len(items)), err) }()
+	err = r.db.SelectContext(ctx, &items, selectItems+" WHERE i.campaign_id = $1 ORDER BY i.id", campaignId)
+	return items, err
+}
+
+// FindPendingItems возвращает назначения открытых кампаний без решения, которые проверяет сотрудник reviewerId.
+// Администратору (admin) дополнительно достаются назначения без проверяющего. Назначения самого проверяющего
+// в выборку не попадают. Первыми идут кампании с ближайшим сроком
+func (r *Repository) FindPendingItems(ctx context.Context, reviewerId int64, admin bool) (items []ItemEntity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.FindPendingItems")
+	defer func() { span.Finish(int64(len(items)), err) }()
+	err = r.db.SelectContext(ctx, &items, selectItems+`
+		JOIN access_review_campaign c ON c.id = i.campaign_id
+		WHERE c.status = 'open' AND i.decision IS NULL AND i.employee_id <> $1
+			AND (i.reviewer_id = $1 OR $2 AND i.reviewer_id IS NULL)
+		ORDER BY c.due_at, i.id`, reviewerId, admin)
+	return items, err
+}
+
+// FindEmployeeIdByLogin возвращает id неудалённого сотрудника с логином login (без учёта регистра) или 0, если такого нет
+func (r *Repository) FindEmployeeIdByLogin(ctx context.Context, login string) (int64, error) {
+	return employee.FindIdByLogin(ctx, r.db, login)
+}
+
+// FindOverdueIds возвращает открытые кампании, срок которых истёк к моменту now
+func (r *Repository) FindOverdueIds(ctx context.Context, now time.Time) (ids []int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.FindOverdueIds")
+	defer func() { span.Finish(int64(len(ids)), err) }()
+	err = r.db.SelectContext(ctx, &ids,
+		"SELECT id FROM access_review_campaign WHERE status = 'open' AND due_at <= $1 ORDER BY id", now)
+	return ids, err
+}
+
+func (r *Repository) BeginTransaction(ctx context.Context) (tx *sqlx.Tx, err error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// FindByIdForUpdateTx читает кампанию и блокирует строку до конца транзакции
+func (r *Repository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.FindByIdForUpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity, "SELECT * FROM access_review_campaign WHERE id = $1 FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(id)
+	}
+	return &entity, err
+}
+
+// FindByIdForShareTx читает кампанию и не даёт закрыть её до конца транзакции;
+// решения по разным назначениям одной кампании при этом принимаются параллельно
+func (r *Repository) FindByIdForShareTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.FindByIdForShareTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity, "SELECT * FROM access_review_campaign WHERE id = $1 FOR SHARE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(id)
+	}
+	return &entity, err
+}
+
+// RoleExistsTx проверяет, что роль из охвата кампании существует и не удалена
+func (r *Repository) RoleExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.RoleExistsTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &isExists, "select exists(select 1 from role where id = $1 and deleted_at is null)", id)
+	return isExists, err
+}
+
+// OrgUnitExistsTx проверяет, что подразделение из охвата кампании существует
+func (r *Repository) OrgUnitExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.OrgUnitExistsTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &isExists, "select exists(select 1 from org_unit where id = $1)", id)
+	return isExists, err
+}
+
+// EmployeeExistsTx проверяет, что сотрудник, которому передаётся проверка, существует и не удалён
+func (r *Repository) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.EmployeeExistsTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &isExists, "select exists(select 1 from employee where id = $1 and deleted_at is null)", id)
+	return isExists, err
+}
+
+// FindEmployeeIdByLoginTx - то же, что FindEmployeeIdByLogin, внутри транзакции
+func (r *Repository) FindEmployeeIdByLoginTx(ctx context.Context, tx *sqlx.Tx, login string) (int64, error) {
+	return employee.FindIdByLogin(ctx, tx, login)
+}
+
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, campaign *Entity) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.CreateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		`INSERT INTO access_review_campaign (name, scope_type, scope_id, reviewer_type, revoke_undecided, due_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *`,
+		campaign.Name, campaign.ScopeType, campaign.ScopeId, campaign.ReviewerType, campaign.RevokeUndecided,
+		campaign.DueAt, campaign.CreatedBy)
+	return &entity, err
+}
+
+// AddItemsTx включает в кампанию назначения ролей, действующие в её охвате, и возвращает их число.
+// Проверяющий - руководитель сотрудника или владелец роли; если его нет или это сам сотрудник,
+// назначение остаётся без проверяющего и его проверяет администратор IDM
+func (r *Repository) AddItemsTx(ctx context.Context, tx *sqlx.Tx, campaign *Entity) (count int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.AddItemsTx")
+	defer func() { span.Finish(count, err) }()
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO access_review_item (campaign_id, employee_id, role_id, granted_valid_from, granted_valid_to, reviewer_id)
+		SELECT $1, er.employee_id, er.role_id, er.valid_from, er.valid_to,
+			NULLIF(CASE WHEN $2 = 'manager' THEN e.manager_id ELSE r.owner_id END, er.employee_id)
+		FROM employee_role er
+		JOIN employee e ON e.id = er.employee_id AND e.deleted_at IS NULL
+		JOIN role r ON r.id = er.role_id AND r.deleted_at IS NULL
+		WHERE er.valid_from <= now() AND (er.valid_to IS NULL OR er.valid_to > now())
+			AND ($3 = 'all'
+				OR $3 = 'role' AND er.role_id = $4
+				OR $3 = 'org_unit' AND e.org_unit_id IN (
+					SELECT id FROM org_unit WHERE path LIKE (SELECT path FROM org_unit WHERE id = $4) || '%'))`,
+		campaign.Id, campaign.ReviewerType, campaign.ScopeType, campaign.ScopeId)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// FindItemForUpdateTx читает назначение кампании и блокирует его строку до конца транзакции
+func (r *Repository) FindItemForUpdateTx(ctx context.Context, tx *sqlx.Tx, campaignId, id int64) (_ *ItemEntity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.FindItemForUpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var item ItemEntity
+	err = tx.GetContext(ctx, &item, selectItems+" WHERE i.id = $1 AND i.campaign_id = $2 FOR UPDATE OF i", id, campaignId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("item %d of access review %d not found", id, campaignId)}
+	}
+	return &item, err
+}
+
+// UpdateItemTx сохраняет проверяющего и решение по назначению
+func (r *Repository) UpdateItemTx(ctx context.Context, tx *sqlx.Tx, item *ItemEntity) (_ *ItemEntity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.UpdateItemTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var updated ItemEntity
+	err = tx.GetContext(ctx, &updated,
+		`WITH i AS (
+			UPDATE access_review_item SET reviewer_id = $1, decision = $2, comment = $3, decided_by = $4, decided_at = $5,
+				delegated_by = $6, reminded_at = $7
+			WHERE id = $8 RETURNING *
+		)
+		SELECT i.*, e.name AS employee_name, r.name AS role_name FROM i
+		JOIN employee e ON e.id = i.employee_id
+		JOIN role r ON r.id = i.role_id`,
+		item.ReviewerId, item.Decision, item.Comment, item.DecidedBy, item.DecidedAt, item.DelegatedBy, item.RemindedAt, item.Id)
+	return &updated, err
+}
+
+// FindItemsTx возвращает все назначения кампании внутри транзакции закрытия
+func (r *Repository) FindItemsTx(ctx context.Context, tx *sqlx.Tx, campaignId int64) (items []ItemEntity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.FindItemsTx")
+	defer func() { span.Finish(int64(len(items)), err) }()
+	err = tx.SelectContext(ctx, &items, selectItems+" WHERE i.campaign_id = $1 ORDER BY i.id", campaignId)
+	return items, err
+}
+
+// RevokeGrantTx снимает проверенное в кампании назначение роли. false - если назначения уже нет
+// или его выдали заново с другим сроком действия: такое назначение кампания не проверяла
+func (r *Repository) RevokeGrantTx(ctx context.Context, tx *sqlx.Tx, item *ItemEntity) (revoked bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.RevokeGrantTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM employee_role WHERE employee_id = $1 AND role_id = $2
+			AND valid_from = $3 AND valid_to IS NOT DISTINCT FROM $4`,
+		item.EmployeeId, item.RoleId, item.GrantedValidFrom, item.GrantedValidTo)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// SetOutcomesTx сохраняет результаты закрытия: outcomes[i] - для назначения ids[i]
+func (r *Repository) SetOutcomesTx(ctx context.Context, tx *sqlx.Tx, ids []int64, outcomes []string) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.SetOutcomesTx")
+	defer func() { span.Finish(int64(len(ids)), err) }()
+	_, err = tx.ExecContext(ctx,
+		`UPDATE access_review_item AS i SET outcome = o.outcome
+		FROM unnest($1::bigint[], $2::text[]) AS o(id, outcome) WHERE i.id = o.id`,
+		pq.Array(ids), pq.Array(outcomes))
+	return err
+}
+
+// CloseTx закрывает кампанию и сохраняет итоговый отчёт с его sha256 и подписью
+func (r *Repository) CloseTx(
+	ctx context.Context, tx *sqlx.Tx, id int64, closedAt time.Time, closedBy *string, report, reportDigest, reportSignature string,
+) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.CloseTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		`UPDATE access_review_campaign SET status = 'closed', closed_at = $1, closed_by = $2, report = $3, report_digest = $4,
+			report_signature = $5, updated_at = now()
+		WHERE id = $6 RETURNING *`,
+		closedAt, closedBy, report, reportDigest, reportSignature, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(id)
+	}
+	return &entity, err
+}
+
+// RemindTx отмечает напоминание по назначениям без решения в открытых кампаниях, о которых не напоминали
+// с момента remindedBefore, и возвращает их число по кампаниям и проверяющим.
+// Строки блокируются обновлением, поэтому при нескольких экземплярах сервиса напоминание отправляется один раз
+func (r *Repository) RemindTx(ctx context.Context, tx *sqlx.Tx, now, remindedBefore time.Time) (reminders []Reminder, err error) {
+	ctx, span := tracing.StartQuery(ctx, "accessreview.RemindTx")
+	defer func() { span.Finish(int64(len(reminders)), err) }()
+	err = tx.SelectContext(ctx, &reminders,
+		`WITH reminded AS (
+			UPDATE access_review_item i SET reminded_at = $1
+			FROM access_review_campaign c
+			WHERE c.id = i.campaign_id AND c.status = 'open' AND c.due_at > $1
+				AND i.decision IS NULL AND (i.reminded_at IS NULL OR i.reminded_at <= $2)
+			RETURNING i.campaign_id, i.reviewer_id, c.due_at
+		)
+		SELECT campaign_id, reviewer_id, count(*) AS pending, due_at FROM reminded
+		GROUP BY campaign_id, reviewer_id, due_at
+		ORDER BY campaign_id, reviewer_id`, now, remindedBefore)
+	return reminders, err
+}
+
+func notFound(id int64) error {
+	return common.NotFoundError{Message: fmt.Sprintf("access review with id %d not found", id)}
+}
@@ -0,0 +1,191 @@
+package accessreview
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// охват кампании
+const (
+	ScopeRole    = "role"
+	ScopeOrgUnit = "org_unit"
+	ScopeAll     = "all"
+)
+
+// кто проверяет назначения
+const (
+	ReviewerManager   = "manager"
+	ReviewerRoleOwner = "role_owner"
+)
+
+// статусы кампании
+const (
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+// решения по назначению; delegate не закрывает назначение, а передаёт его другому проверяющему
+const (
+	DecisionKeep     = "keep"
+	DecisionRevoke   = "revoke"
+	DecisionDelegate = "delegate"
+)
+
+// результаты закрытия кампании для назначения
+const (
+	OutcomeKept    = "kept"
+	OutcomeRevoked = "revoked"
+	// OutcomeMissing - проверенного назначения к закрытию кампании уже нет (снято или выдано заново), отзывать нечего
+	OutcomeMissing = "missing"
+)
+
+// SignatureAlgorithm - алгоритм подписи выгружаемого отчёта
+const SignatureAlgorithm = "HMAC-SHA256"
+
+// shouldRevoke сообщает, отзывается ли назначение при закрытии кампании:
+// по решению revoke, а без решения - если кампания настроена отзывать непроверенные назначения
+func shouldRevoke(item *ItemEntity, revokeUndecided bool) bool {
+	if item.Decision == nil {
+		return revokeUndecided
+	}
+	return *item.Decision == DecisionRevoke
+}
+
+// Report - итоговый отчёт кампании: параметры, сводка и решение по каждому назначению
+type Report struct {
+	CampaignId      int64        `json:"campaign_id"`
+	Name            string       `json:"name"`
+	ScopeType       string       `json:"scope_type"`
+	ScopeId         *int64       `json:"scope_id,omitempty"`
+	ReviewerType    string       `json:"reviewer_type"`
+	RevokeUndecided bool         `json:"revoke_undecided"`
+	CreatedBy       string       `json:"created_by"`
+	CreatedAt       time.Time    `json:"created_at"`
+	DueAt           time.Time    `json:"due_at"`
+	ClosedAt        time.Time    `json:"closed_at"`
+	ClosedBy        string       `json:"closed_by,omitempty"`
+	Summary         Summary      `json:"summary"`
+	Items           []ReportItem `json:"items"`
+}
+
+// Summary - сводка отчёта: Undecided - назначения, по которым к закрытию не было решения
+type Summary struct {
+	Total     int `json:"total"`
+	Kept      int `json:"kept"`
+	Revoked   int `json:"revoked"`
+	Missing   int `json:"missing"`
+	Undecided int `json:"undecided"`
+}
+
+type ReportItem struct {
+	EmployeeId   int64      `json:"employee_id"`
+	EmployeeName string     `json:"employee_name"`
+	RoleId       int64      `json:"role_id"`
+	RoleName     string     `json:"role_name"`
+	ReviewerId   *int64     `json:"reviewer_id,omitempty"`
+	Decision     *string    `json:"decision,omitempty"`
+	Comment      *string    `json:"comment,omitempty"`
+	DecidedBy    *string    `json:"decided_by,omitempty"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty"`
+	DelegatedBy  *string    `json:"delegated_by,omitempty"`
+	Outcome      string     `json:"outcome"`
+}
+
+// buildReport собирает отчёт по назначениям кампании, для которых уже заполнен результат закрытия
+func buildReport(campaign *Entity, items []ItemEntity, closedAt time.Time, closedBy string) Report {
+	report := Report{
+		CampaignId:      campaign.Id,
+		Name:            campaign.Name,
+		ScopeType:       campaign.ScopeType,
+		ScopeId:         campaign.ScopeId,
+		ReviewerType:    campaign.ReviewerType,
+		RevokeUndecided: campaign.RevokeUndecided,
+		CreatedBy:       campaign.CreatedBy,
+		CreatedAt:       campaign.CreatedAt.UTC(),
+		DueAt:           campaign.DueAt.UTC(),
+		ClosedAt:        closedAt.UTC(),
+		ClosedBy:        closedBy,
+		Items:           make([]ReportItem, 0, len(items)),
+	}
+	for i := range items {
+		item := &items[i]
+		outcome := valueOf(item.Outcome)
+		report.Summary.Total++
+		switch outcome {
+		case OutcomeKept:
+			report.Summary.Kept++
+		case OutcomeRevoked:
+			report.Summary.Revoked++
+		case OutcomeMissing:
+			report.Summary.Missing++
+		}
+		if item.Decision == nil {
+			report.Summary.Undecided++
+		}
+		var decidedAt *time.Time
+		if item.DecidedAt != nil {
+			utc := item.DecidedAt.UTC()
+			decidedAt = &utc
+		}
+		report.Items = append(report.Items, ReportItem{
+			EmployeeId:   item.EmployeeId,
+			EmployeeName: item.EmployeeName,
+			RoleId:       item.RoleId,
+			RoleName:     item.RoleName,
+			ReviewerId:   item.ReviewerId,
+			Decision:     item.Decision,
+			Comment:      item.Comment,
+			DecidedBy:    item.DecidedBy,
+			DecidedAt:    decidedAt,
+			DelegatedBy:  item.DelegatedBy,
+			Outcome:      outcome,
+		})
+	}
+	return report
+}
+
+// canonicalJson сериализует значение в канонический JSON: ключи объектов отсортированы, пробелов нет,
+// HTML-символы не экранируются. Такое представление воспроизводимо вне сервиса (например, jq -cS)
+func canonicalJson(value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(decoded); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// digest возвращает sha256 отчёта в виде "sha256:<hex>"
+func digest(report []byte) string {
+	sum := sha256.Sum256(report)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// sign возвращает HMAC-SHA256 отчёта в hex
+func sign(key, report []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(report)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func valueOf(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,79 @@
+package accessreview
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldRevoke(t *testing.T) {
+	tests := []struct {
+		name            string
+		decision        *string
+		revokeUndecided bool
+		want            bool
+	}{
+		{name: "keep", decision: str(DecisionKeep), revokeUndecided: true, want: false},
+		{name: "revoke", decision: str(DecisionRevoke), want: true},
+		{name: "undecided is kept by default", want: false},
+		{name: "undecided is revoked if configured", revokeUndecided: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, shouldRevoke(&ItemEntity{Decision: tt.decision}, tt.revokeUndecided))
+		})
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	a := assert.New(t)
+	moscow := time.FixedZone("MSK", 3*60*60)
+	closedAt := time.Date(2025, 12, 31, 15, 0, 0, 0, moscow)
+	campaign := &Entity{Id: 7, Name: "Q4 review", ScopeType: ScopeRole, ScopeId: ptr(5), ReviewerType: ReviewerManager,
+		CreatedBy: "admin", DueAt: closedAt}
+	items := []ItemEntity{
+		{Id: 1, EmployeeId: 1, RoleId: 5, Decision: str(DecisionKeep), Outcome: str(OutcomeKept)},
+		{Id: 2, EmployeeId: 2, RoleId: 5, Decision: str(DecisionRevoke), Outcome: str(OutcomeRevoked)},
+		{Id: 3, EmployeeId: 3, RoleId: 5, Outcome: str(OutcomeKept)},
+		{Id: 4, EmployeeId: 4, RoleId: 5, Decision: str(DecisionRevoke), Outcome: str(OutcomeMissing)},
+	}
+
+	report := buildReport(campaign, items, closedAt, "")
+
+	a.Equal(Summary{Total: 4, Kept: 2, Revoked: 1, Missing: 1, Undecided: 1}, report.Summary)
+	a.Len(report.Items, 4)
+	a.Equal(OutcomeRevoked, report.Items[1].Outcome)
+	// время в отчёте всегда в UTC, чтобы дайджест не зависел от часового пояса сервера
+	a.Equal(time.UTC, report.ClosedAt.Location())
+	a.True(closedAt.Equal(report.ClosedAt))
+	a.Empty(report.ClosedBy)
+}
+
+func TestCanonicalJson(t *testing.T) {
+	a := assert.New(t)
+	value := struct {
+		Zeta  string         `json:"zeta"`
+		Alpha map[string]int `json:"alpha"`
+		Html  string         `json:"html"`
+	}{Zeta: "z", Alpha: map[string]int{"b": 2, "a": 1}, Html: "<a&b>"}
+
+	data, err := canonicalJson(value)
+
+	a.NoError(err)
+	a.Equal(`{"alpha":{"a":1,"b":2},"html":"<a&b>","zeta":"z"}`, string(data))
+}
+
+func TestDigestAndSign(t *testing.T) {
+	a := assert.New(t)
+	report := []byte(`{"campaign_id":7}`)
+	sum := sha256.Sum256(report)
+	a.Equal("sha256:"+hex.EncodeToString(sum[:]), digest(report))
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(report)
+	a.Equal(hex.EncodeToString(mac.Sum(nil)), sign([]byte("secret"), report))
+}
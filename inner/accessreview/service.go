@@ -0,0 +1,525 @@
+package accessreview
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/events"
+	"idm/inner/metrics"
+	"idm/inner/tracing"
+	"idm/inner/validator"
+)
+
+// типы событий о кампаниях пересмотра доступа
+const (
+	EventStarted = "access_review.started"
+	EventClosed  = "access_review.closed"
+	// EventReminder - напоминание проверяющему о назначениях без решения
+	EventReminder = "access_review.reminder"
+)
+
+type Service struct {
+	repo      Repo
+	auditor   Auditor
+	publisher events.Publisher
+	logger    *common.Logger
+	validator *validator.Validator
+	// signingKey - ключ HMAC, которым отчёт подписывается при закрытии кампании и проверяется при выгрузке
+	signingKey []byte
+	// reminderInterval - как часто напоминать о назначениях без решения
+	reminderInterval time.Duration
+}
+
+// Auditor записывает изменения кампаний и отозванные по ним роли в журнал аудита в той же транзакции, что и само изменение
+type Auditor interface {
+	RecordTx(ctx context.Context, tx *sqlx.Tx, record audit.Record) error
+}
+
+type Repo interface {
+	FindById(ctx context.Context, id int64) (*Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	CountItems(ctx context.Context, campaignId int64) (Progress, error)
+	FindItems(ctx context.Context, campaignId int64) ([]ItemEntity, error)
+	FindPendingItems(ctx context.Context, reviewerId int64, admin bool) ([]ItemEntity, error)
+	FindEmployeeIdByLogin(ctx context.Context, login string) (int64, error)
+	FindOverdueIds(ctx context.Context, now time.Time) ([]int64, error)
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+	FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error)
+	FindByIdForShareTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error)
+	RoleExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	OrgUnitExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	FindEmployeeIdByLoginTx(ctx context.Context, tx *sqlx.Tx, login string) (int64, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, campaign *Entity) (*Entity, error)
+	AddItemsTx(ctx context.Context, tx *sqlx.Tx, campaign *Entity) (int64, error)
+	FindItemForUpdateTx(ctx context.Context, tx *sqlx.Tx, campaignId, id int64) (*ItemEntity, error)
+	UpdateItemTx(ctx context.Context, tx *sqlx.Tx, item *ItemEntity) (*ItemEntity, error)
+	FindItemsTx(ctx context.Context, tx *sqlx.Tx, campaignId int64) ([]ItemEntity, error)
+	RevokeGrantTx(ctx context.Context, tx *sqlx.Tx, item *ItemEntity) (bool, error)
+	SetOutcomesTx(ctx context.Context, tx *sqlx.Tx, ids []int64, outcomes []string) error
+	CloseTx(ctx context.Context, tx *sqlx.Tx, id int64, closedAt time.Time, closedBy *string, report, reportDigest, reportSignature string) (*Entity, error)
+	RemindTx(ctx context.Context, tx *sqlx.Tx, now, remindedBefore time.Time) ([]Reminder, error)
+}
+
+func NewService(
+	repo Repo, auditor Auditor, publisher events.Publisher, logger *common.Logger, signingKey string, reminderInterval time.Duration,
+) *Service {
+	return &Service{
+		repo:             repo,
+		auditor:          auditor,
+		publisher:        publisher,
+		logger:           logger,
+		validator:        validator.New(),
+		signingKey:       []byte(signingKey),
+		reminderInterval: reminderInterval,
+	}
+}
+
+// FindById возвращает кампанию вместе с ходом проверки
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	ctx, span := tracing.Start(ctx, "accessreview.Service.FindById")
+	defer span.End()
+	e, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to find access review with id %d: %w", id, err)
+	}
+	progress, err := svc.repo.CountItems(ctx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to count items of access review with id %d: %w", id, err)
+	}
+	response := e.toResponse()
+	response.Progress = &progress
+	return response, nil
+}
+
+// FindAll возвращает все кампании, начиная с последней
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "accessreview.Service.FindAll")
+	defer span.End()
+	entities, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]Response, 0, len(entities))
+	for i := range entities {
+		responses = append(responses, entities[i].toResponse())
+	}
+	return responses, nil
+}
+
+// FindItems возвращает все назначения кампании
+func (svc *Service) FindItems(ctx context.Context, id int64) ([]ItemResponse, error) {
+	ctx, span := tracing.Start(ctx, "accessreview.Service.FindItems")
+	defer span.End()
+	if _, err := svc.repo.FindById(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to find access review with id %d: %w", id, err)
+	}
+	items, err := svc.repo.FindItems(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find items of access review with id %d: %w", id, err)
+	}
+	return toItemResponses(items), nil
+}
+
+// FindMyItems возвращает назначения открытых кампаний, ожидающие решения текущего пользователя;
+// администратору IDM (admin) достаются также назначения без проверяющего
+func (svc *Service) FindMyItems(ctx context.Context, admin bool) ([]ItemResponse, error) {
+	ctx, span := tracing.Start(ctx, "accessreview.Service.FindMyItems")
+	defer span.End()
+	var reviewerId int64
+	if login := common.ActorFromContext(ctx); login != "" {
+		var err error
+		if reviewerId, err = svc.repo.FindEmployeeIdByLogin(ctx, login); err != nil {
+			return nil, fmt.Errorf("error finding employee with login %s: %w", login, err)
+		}
+	}
+	if reviewerId == 0 && !admin {
+		return []ItemResponse{}, nil
+	}
+	items, err := svc.repo.FindPendingItems(ctx, reviewerId, admin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending review items: %w", err)
+	}
+	return toItemResponses(items), nil
+}
+
+// Create запускает кампанию: в неё попадают назначения ролей, действующие в охвате на момент запуска.
+// Кампания без назначений не создаётся
+func (svc *Service) Create(ctx context.Context, req CreateRequest) (Response, error) {
+	ctx, span := tracing.Start(ctx, "accessreview.Service.Create")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return Response{}, err
+	}
+	if err := checkScope(req); err != nil {
+		return Response{}, err
+	}
+	if !req.DueAt.After(time.Now()) {
+		return Response{}, common.RequestValidationError{Message: "due_at must be in the future"}
+	}
+	var created *Entity
+	var count int64
+	err := database.InTransaction(ctx, svc.repo, "creating access review", func(tx *sqlx.Tx) error {
+		if err := svc.checkScopeExistsTx(ctx, tx, req); err != nil {
+			return err
+		}
+		var err error
+		created, err = svc.repo.CreateTx(ctx, tx, &Entity{
+			Name:            req.Name,
+			ScopeType:       req.ScopeType,
+			ScopeId:         req.ScopeId,
+			ReviewerType:    req.ReviewerType,
+			RevokeUndecided: req.RevokeUndecided,
+			DueAt:           req.DueAt,
+			CreatedBy:       common.ActorFromContext(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("error creating access review with name: %s %w", req.Name, err)
+		}
+		if count, err = svc.repo.AddItemsTx(ctx, tx, created); err != nil {
+			return fmt.Errorf("error adding items to access review with id %d: %w", created.Id, err)
+		}
+		if count == 0 {
+			return common.ConflictError{Message: "there are no active role assignments in the access review scope"}
+		}
+		state := created.toAuditState()
+		state.Items = count
+		return svc.recordTx(ctx, tx, audit.ActionCreate, created.Id, nil, state)
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	svc.publish(ctx, EventStarted, created.Id, created.toResponse())
+	response := created.toResponse()
+	response.Progress = &Progress{Total: count}
+	return response, nil
+}
+
+// Decide сохраняет решение по назначению кампании: keep или revoke применяются при закрытии кампании,
+// delegate передаёт проверку другому сотруднику. Решение можно изменить, пока кампания открыта.
+// Решение принимает назначенный проверяющий или администратор IDM (admin), но не сам сотрудник, чьё назначение проверяется
+func (svc *Service) Decide(ctx context.Context, id, itemId int64, req DecisionRequest, admin bool) (ItemResponse, error) {
+	ctx, span := tracing.Start(ctx, "accessreview.Service.Decide")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return ItemResponse{}, err
+	}
+	if (req.Decision == DecisionDelegate) != (req.DelegateTo != nil) {
+		return ItemResponse{}, common.RequestValidationError{Message: "delegate_to is required to delegate and allowed only with it"}
+	}
+	now := time.Now()
+	login := common.ActorFromContext(ctx)
+	var updated *ItemEntity
+	err := database.InTransaction(ctx, svc.repo, "deciding access review item", func(tx *sqlx.Tx) error {
+		campaign, err := svc.repo.FindByIdForShareTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding access review with id %d: %w", id, err)
+		}
+		if campaign.Status != StatusOpen {
+			return closed(id)
+		}
+		if !campaign.DueAt.After(now) {
+			return common.ConflictError{Message: fmt.Sprintf("access review %d is overdue", id)}
+		}
+		item, err := svc.repo.FindItemForUpdateTx(ctx, tx, id, itemId)
+		if err != nil {
+			return fmt.Errorf("error finding item %d of access review with id %d: %w", itemId, id, err)
+		}
+		reviewerId, err := svc.repo.FindEmployeeIdByLoginTx(ctx, tx, login)
+		if err != nil {
+			return fmt.Errorf("error finding employee with login %s: %w", login, err)
+		}
+		if reviewerId != 0 && reviewerId == item.EmployeeId {
+			return common.ForbiddenError{Message: fmt.Sprintf("item %d is a role assignment of the reviewer", itemId)}
+		}
+		if !admin && (reviewerId == 0 || item.ReviewerId == nil || *item.ReviewerId != reviewerId) {
+			return common.ForbiddenError{Message: fmt.Sprintf("only the assigned reviewer or an administrator can review item %d", itemId)}
+		}
+		before := *item
+		if req.Decision == DecisionDelegate {
+			if err := svc.checkDelegateTx(ctx, tx, item, *req.DelegateTo); err != nil {
+				return err
+			}
+			item.ReviewerId = req.DelegateTo
+			item.DelegatedBy = &login
+			item.Decision, item.DecidedBy, item.DecidedAt, item.RemindedAt = nil, nil, nil, nil
+		} else {
+			item.Decision = &req.Decision
+			item.DecidedBy = &login
+			item.DecidedAt = &now
+		}
+		item.Comment = nil
+		if req.Comment != "" {
+			item.Comment = &req.Comment
+		}
+		if updated, err = svc.repo.UpdateItemTx(ctx, tx, item); err != nil {
+			return fmt.Errorf("error updating item %d of access review with id %d: %w", itemId, id, err)
+		}
+		action := audit.ActionReview
+		if req.Decision == DecisionDelegate {
+			action = audit.ActionDelegate
+		}
+		return svc.recordTx(ctx, tx, action, id, before.toAuditItem(), updated.toAuditItem())
+	})
+	if err != nil {
+		return ItemResponse{}, err
+	}
+	metrics.AccessReviewDecisions.WithLabelValues(req.Decision).Inc()
+	return updated.toResponse(), nil
+}
+
+// Close закрывает кампанию досрочно от имени текущего пользователя
+func (svc *Service) Close(ctx context.Context, id int64) (Response, error) {
+	ctx, span := tracing.Start(ctx, "accessreview.Service.Close")
+	defer span.End()
+	return svc.close(ctx, id, time.Now(), common.ActorFromContext(ctx))
+}
+
+// CloseOverdue закрывает открытые кампании, срок которых истёк к моменту now, и возвращает их число.
+// Кампанию, которую уже закрыл другой экземпляр сервиса, пропускает
+func (svc *Service) CloseOverdue(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "accessreview.Service.CloseOverdue")
+	defer span.End()
+	ids, err := svc.repo.FindOverdueIds(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("error finding overdue access reviews: %w", err)
+	}
+	count := 0
+	for _, id := range ids {
+		_, err := svc.close(ctx, id, now, "")
+		if errors.As(err, &common.ConflictError{}) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Remind отмечает напоминания по назначениям без решения, о которых не напоминали дольше reminderInterval,
+// и публикует одно событие на кампанию и проверяющего. Возвращает число напоминаний
+func (svc *Service) Remind(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "accessreview.Service.Remind")
+	defer span.End()
+	var reminders []Reminder
+	err := database.InTransaction(ctx, svc.repo, "reminding access reviewers", func(tx *sqlx.Tx) (err error) {
+		reminders, err = svc.repo.RemindTx(ctx, tx, now, now.Add(-svc.reminderInterval))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	for i := range reminders {
+		svc.publish(ctx, EventReminder, reminders[i].CampaignId, reminders[i])
+	}
+	return len(reminders), nil
+}
+
+// Report возвращает итоговый отчёт закрытой кампании с его sha256 и подписью, сохранёнными при закрытии.
+// Отчёт, изменённый в БД после закрытия, не выгружается: без ключа подпись к нему не подобрать
+func (svc *Service) Report(ctx context.Context, id int64) (SignedReport, error) {
+	ctx, span := tracing.Start(ctx, "accessreview.Service.Report")
+	defer span.End()
+	e, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return SignedReport{}, fmt.Errorf("failed to find access review with id %d: %w", id, err)
+	}
+	if e.Status != StatusClosed || e.Report == nil {
+		return SignedReport{}, common.ConflictError{Message: fmt.Sprintf("access review %d is not closed yet", id)}
+	}
+	report := []byte(*e.Report)
+	sum := digest(report)
+	if sum != valueOf(e.ReportDigest) {
+		return SignedReport{}, fmt.Errorf("report of access review %d does not match its digest", id)
+	}
+	signature := valueOf(e.ReportSignature)
+	if !hmac.Equal([]byte(signature), []byte(sign(svc.signingKey, report))) {
+		return SignedReport{}, fmt.Errorf("report of access review %d does not match its signature", id)
+	}
+	return SignedReport{Report: report, Digest: sum, Signature: signature, SignatureAlgorithm: SignatureAlgorithm}, nil
+}
+
+// close применяет решения кампании и закрывает её: отзывает назначения с решением revoke
+// (и без решения, если так настроена кампания), сохраняет итоговый отчёт и записывает его sha256 в журнал аудита.
+// actor - кто закрывает кампанию; пустой - закрытие по сроку
+func (svc *Service) close(ctx context.Context, id int64, now time.Time, actor string) (Response, error) {
+	var closedCampaign *Entity
+	revoked := 0
+	err := database.InTransaction(ctx, svc.repo, "closing access review", func(tx *sqlx.Tx) error {
+		campaign, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding access review with id %d: %w", id, err)
+		}
+		if campaign.Status != StatusOpen {
+			return closed(id)
+		}
+		items, err := svc.repo.FindItemsTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding items of access review with id %d: %w", id, err)
+		}
+		ids := make([]int64, 0, len(items))
+		outcomes := make([]string, 0, len(items))
+		roleIds := make(map[int64][]int64)
+		for i := range items {
+			outcome := OutcomeKept
+			if shouldRevoke(&items[i], campaign.RevokeUndecided) {
+				ok, err := svc.repo.RevokeGrantTx(ctx, tx, &items[i])
+				if err != nil {
+					return fmt.Errorf("error revoking role %d from employee %d: %w", items[i].RoleId, items[i].EmployeeId, err)
+				}
+				outcome = OutcomeMissing
+				if ok {
+					outcome = OutcomeRevoked
+					roleIds[items[i].EmployeeId] = append(roleIds[items[i].EmployeeId], items[i].RoleId)
+				}
+			}
+			items[i].Outcome = &outcome
+			ids = append(ids, items[i].Id)
+			outcomes = append(outcomes, outcome)
+		}
+		if err := svc.repo.SetOutcomesTx(ctx, tx, ids, outcomes); err != nil {
+			return fmt.Errorf("error saving outcomes of access review with id %d: %w", id, err)
+		}
+		report, err := canonicalJson(buildReport(campaign, items, now, actor))
+		if err != nil {
+			return fmt.Errorf("error building report of access review with id %d: %w", id, err)
+		}
+		var closedBy *string
+		if actor != "" {
+			closedBy = &actor
+		}
+		closedCampaign, err = svc.repo.CloseTx(ctx, tx, id, now, closedBy, string(report), digest(report), sign(svc.signingKey, report))
+		if err != nil {
+			return fmt.Errorf("error closing access review with id %d: %w", id, err)
+		}
+		// события аудита пишутся после всех изменений: запись блокирует цепочку журнала до конца транзакции
+		employeeIds := make([]int64, 0, len(roleIds))
+		for employeeId := range roleIds {
+			employeeIds = append(employeeIds, employeeId)
+		}
+		slices.Sort(employeeIds)
+		for _, employeeId := range employeeIds {
+			slices.Sort(roleIds[employeeId])
+			revoked += len(roleIds[employeeId])
+			err := svc.auditor.RecordTx(ctx, tx, audit.Record{
+				Action:     audit.ActionRevokeRoles,
+				EntityType: "employee",
+				EntityId:   employeeId,
+				Before:     auditRevoke{RoleIds: roleIds[employeeId], AccessReviewId: id},
+			})
+			if err != nil {
+				return fmt.Errorf("error recording audit event for employee with id %d: %w", employeeId, err)
+			}
+		}
+		return svc.recordTx(ctx, tx, audit.ActionClose, id, campaign.toAuditState(), closedCampaign.toAuditState())
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	svc.publish(ctx, EventClosed, id, closedCampaign.toResponse())
+	metrics.RoleAssignments.WithLabelValues("revoke").Add(float64(revoked))
+	return closedCampaign.toResponse(), nil
+}
+
+// checkScope проверяет, что scope_id указан ровно для тех охватов, которым он нужен
+func checkScope(req CreateRequest) error {
+	if req.ScopeType == ScopeAll && req.ScopeId != nil {
+		return common.RequestValidationError{Message: "scope_id must be empty for scope all"}
+	}
+	if req.ScopeType != ScopeAll && req.ScopeId == nil {
+		return common.RequestValidationError{Message: fmt.Sprintf("scope_id is required for scope %s", req.ScopeType)}
+	}
+	return nil
+}
+
+// checkScopeExistsTx проверяет, что роль или подразделение из охвата кампании существует
+func (svc *Service) checkScopeExistsTx(ctx context.Context, tx *sqlx.Tx, req CreateRequest) error {
+	var exists bool
+	var err error
+	switch req.ScopeType {
+	case ScopeRole:
+		exists, err = svc.repo.RoleExistsTx(ctx, tx, *req.ScopeId)
+	case ScopeOrgUnit:
+		exists, err = svc.repo.OrgUnitExistsTx(ctx, tx, *req.ScopeId)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error checking %s %d: %w", req.ScopeType, *req.ScopeId, err)
+	}
+	if !exists {
+		return common.RequestValidationError{Message: fmt.Sprintf("%s %d not found", req.ScopeType, *req.ScopeId)}
+	}
+	return nil
+}
+
+// checkDelegateTx проверяет, что проверку назначения можно передать сотруднику delegateTo
+func (svc *Service) checkDelegateTx(ctx context.Context, tx *sqlx.Tx, item *ItemEntity, delegateTo int64) error {
+	if delegateTo == item.EmployeeId {
+		return common.RequestValidationError{Message: fmt.Sprintf("item %d cannot be delegated to the employee under review", item.Id)}
+	}
+	if item.ReviewerId != nil && *item.ReviewerId == delegateTo {
+		return common.RequestValidationError{Message: fmt.Sprintf("employee %d already reviews item %d", delegateTo, item.Id)}
+	}
+	exists, err := svc.repo.EmployeeExistsTx(ctx, tx, delegateTo)
+	if err != nil {
+		return fmt.Errorf("error checking employee with id %d: %w", delegateTo, err)
+	}
+	if !exists {
+		return common.RequestValidationError{Message: fmt.Sprintf("employee %d not found", delegateTo)}
+	}
+	return nil
+}
+
+// publish сообщает о событии кампании после фиксации транзакции; ошибка публикации только журналируется
+func (svc *Service) publish(ctx context.Context, eventType string, id int64, data any) {
+	event := events.Event{
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		EntityType: auditEntityType,
+		EntityId:   id,
+		Data:       data,
+	}
+	if err := svc.publisher.Publish(ctx, event); err != nil {
+		svc.logger.Ctx(ctx).Error("publish access review event", zap.String("event_type", eventType),
+			zap.Int64("access_review_id", id), zap.Error(err))
+	}
+}
+
+func (svc *Service) recordTx(ctx context.Context, tx *sqlx.Tx, action string, id int64, before any, after any) error {
+	err := svc.auditor.RecordTx(ctx, tx, audit.Record{
+		Action:     action,
+		EntityType: auditEntityType,
+		EntityId:   id,
+		Before:     before,
+		After:      after,
+	})
+	if err != nil {
+		return fmt.Errorf("error recording audit event for access review with id %d: %w", id, err)
+	}
+	return nil
+}
+
+// closed - ошибка действия над уже закрытой кампанией
+func closed(id int64) error {
+	return common.ConflictError{Message: fmt.Sprintf("access review %d is closed", id)}
+}
+
+func toItemResponses(items []ItemEntity) []ItemResponse {
+	responses := make([]ItemResponse, 0, len(items))
+	for i := range items {
+		responses = append(responses, items[i].toResponse())
+	}
+	return responses
+}
@@ -0,0 +1,470 @@
+package accessreview
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/events"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// chainLockQuery подменяет блокировку цепочки журнала, чтобы sqlmock проверял, где в транзакции пишется аудит
+const chainLockQuery = "SELECT audit_chain_lock"
+
+// stubAuditor запоминает события аудита вместо записи в БД.
+// С lockChain каждое событие выполняет в транзакции chainLockQuery
+type stubAuditor struct {
+	records   []audit.Record
+	lockChain bool
+}
+
+func (a *stubAuditor) RecordTx(ctx context.Context, tx *sqlx.Tx, record audit.Record) error {
+	if a.lockChain {
+		if _, err := tx.ExecContext(ctx, chainLockQuery); err != nil {
+			return err
+		}
+	}
+	a.records = append(a.records, record)
+	return nil
+}
+
+// stubPublisher запоминает опубликованные события
+type stubPublisher struct {
+	events []events.Event
+}
+
+func (p *stubPublisher) Publish(ctx context.Context, event events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+// capture - аргумент sqlmock, который подходит под любое значение и запоминает его
+type capture struct {
+	value driver.Value
+}
+
+func (c *capture) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+const signingKey = "secret"
+
+// newSqlmockService создаёт сервис с настоящим репозиторием поверх sqlmock
+func newSqlmockService(t *testing.T) (*Service, sqlmock.Sqlmock, *stubAuditor, *stubPublisher) {
+	dbMock, m, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = dbMock.Close() })
+	auditor, publisher := &stubAuditor{}, &stubPublisher{}
+	logger := &common.Logger{Logger: zap.NewNop()}
+	svc := NewService(NewRepository(sqlx.NewDb(dbMock, "postgres")), auditor, publisher, logger, signingKey, 24*time.Hour)
+	return svc, m, auditor, publisher
+}
+
+var (
+	campaignColumns = []string{"id", "name", "scope_type", "scope_id", "reviewer_type", "revoke_undecided", "status",
+		"due_at", "created_by", "created_at", "updated_at", "closed_at", "closed_by", "report", "report_digest",
+		"report_signature"}
+	itemColumns = []string{"id", "campaign_id", "employee_id", "role_id", "granted_valid_from", "granted_valid_to", "reviewer_id", "decision",
+		"comment", "decided_by", "decided_at", "delegated_by", "reminded_at", "outcome", "employee_name", "role_name"}
+	findQuery        = regexp.QuoteMeta("SELECT * FROM access_review_campaign WHERE id = $1")
+	updateLockQuery  = regexp.QuoteMeta("SELECT * FROM access_review_campaign WHERE id = $1 FOR UPDATE")
+	shareLockQuery   = regexp.QuoteMeta("SELECT * FROM access_review_campaign WHERE id = $1 FOR SHARE")
+	roleExistsQuery  = regexp.QuoteMeta("select exists(select 1 from role where id = $1")
+	employeeExists   = regexp.QuoteMeta("select exists(select 1 from employee where id = $1")
+	loginQuery       = regexp.QuoteMeta("SELECT coalesce((SELECT id FROM employee WHERE lower(login) = lower($1)")
+	insertQuery      = regexp.QuoteMeta("INSERT INTO access_review_campaign (")
+	addItemsQuery    = regexp.QuoteMeta("INSERT INTO access_review_item (")
+	selectItemsQuery = regexp.QuoteMeta(selectItems)
+	updateItemQuery  = regexp.QuoteMeta("WITH i AS (")
+	revokeQuery      = regexp.QuoteMeta("DELETE FROM employee_role WHERE employee_id = $1 AND role_id = $2")
+	outcomesQuery    = regexp.QuoteMeta("UPDATE access_review_item AS i SET outcome = o.outcome")
+	closeQuery       = regexp.QuoteMeta("UPDATE access_review_campaign SET status = 'closed'")
+	overdueQuery     = regexp.QuoteMeta("SELECT id FROM access_review_campaign WHERE status = 'open' AND due_at <= $1")
+	remindQuery      = regexp.QuoteMeta("WITH reminded AS (")
+)
+
+func ptr(id int64) *int64 {
+	return &id
+}
+
+func str(s string) *string {
+	return &s
+}
+
+// nullable - значение для строки sqlmock: nil вместо пустого указателя
+func nullable[T any](p *T) driver.Value {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// asUser возвращает контекст запроса пользователя с логином login
+func asUser(login string) context.Context {
+	return common.WithUser(context.Background(), common.User{Subject: "sub-" + login, Username: login})
+}
+
+// campaignRows - строки результата с кампаниями в порядке campaignColumns
+func campaignRows(campaigns ...Entity) *sqlmock.Rows {
+	rows := sqlmock.NewRows(campaignColumns)
+	for _, e := range campaigns {
+		rows.AddRow(e.Id, e.Name, e.ScopeType, nullable(e.ScopeId), e.ReviewerType, e.RevokeUndecided, e.Status,
+			e.DueAt, e.CreatedBy, e.CreatedAt, e.UpdatedAt, nullable(e.ClosedAt), nullable(e.ClosedBy),
+			nullable(e.Report), nullable(e.ReportDigest), nullable(e.ReportSignature))
+	}
+	return rows
+}
+
+// itemRows - строки результата с назначениями в порядке itemColumns
+func itemRows(items ...ItemEntity) *sqlmock.Rows {
+	rows := sqlmock.NewRows(itemColumns)
+	for _, e := range items {
+		rows.AddRow(e.Id, e.CampaignId, e.EmployeeId, e.RoleId, nullable(e.GrantedValidFrom), nullable(e.GrantedValidTo),
+			nullable(e.ReviewerId),
+			nullable(e.Decision), nullable(e.Comment), nullable(e.DecidedBy), nullable(e.DecidedAt),
+			nullable(e.DelegatedBy), nullable(e.RemindedAt), nullable(e.Outcome), e.EmployeeName, e.RoleName)
+	}
+	return rows
+}
+
+func idRow(id int64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id"}).AddRow(id)
+}
+
+func existsRow(exists bool) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"exists"}).AddRow(exists)
+}
+
+func openCampaign() Entity {
+	return Entity{Id: 7, Name: "Q4 review", ScopeType: ScopeRole, ScopeId: ptr(5), ReviewerType: ReviewerManager,
+		Status: StatusOpen, DueAt: time.Now().Add(24 * time.Hour), CreatedBy: "admin"}
+}
+
+func TestService_Create(t *testing.T) {
+	a := assert.New(t)
+	req := CreateRequest{Name: "Q4 review", ScopeType: ScopeRole, ScopeId: ptr(5), ReviewerType: ReviewerManager,
+		DueAt: time.Now().Add(24 * time.Hour)}
+
+	t.Run("starts campaign with active grants", func(t *testing.T) {
+		svc, m, auditor, publisher := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(roleExistsQuery).WithArgs(int64(5)).WillReturnRows(existsRow(true))
+		m.ExpectQuery(insertQuery).
+			WithArgs(req.Name, ScopeRole, int64(5), ReviewerManager, false, req.DueAt, "admin").
+			WillReturnRows(campaignRows(openCampaign()))
+		m.ExpectExec(addItemsQuery).WithArgs(int64(7), ReviewerManager, ScopeRole, int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		m.ExpectCommit()
+
+		got, err := svc.Create(asUser("admin"), req)
+		a.NoError(err)
+		a.Equal(int64(7), got.Id)
+		a.Equal(int64(3), got.Progress.Total)
+		a.Len(auditor.records, 1)
+		a.Equal(audit.ActionCreate, auditor.records[0].Action)
+		a.Equal(int64(3), auditor.records[0].After.(auditState).Items)
+		a.Len(publisher.events, 1)
+		a.Equal(EventStarted, publisher.events[0].Type)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("scope_id is required for role scope", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		invalid := req
+		invalid.ScopeId = nil
+
+		_, err := svc.Create(asUser("admin"), invalid)
+		a.ErrorAs(err, &common.RequestValidationError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(roleExistsQuery).WithArgs(int64(5)).WillReturnRows(existsRow(false))
+		m.ExpectRollback()
+
+		_, err := svc.Create(asUser("admin"), req)
+		a.ErrorAs(err, &common.RequestValidationError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("scope without grants", func(t *testing.T) {
+		svc, m, auditor, publisher := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(roleExistsQuery).WithArgs(int64(5)).WillReturnRows(existsRow(true))
+		m.ExpectQuery(insertQuery).WillReturnRows(campaignRows(openCampaign()))
+		m.ExpectExec(addItemsQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		m.ExpectRollback()
+
+		_, err := svc.Create(asUser("admin"), req)
+		a.ErrorAs(err, &common.ConflictError{})
+		a.Empty(auditor.records)
+		a.Empty(publisher.events)
+		a.NoError(m.ExpectationsWereMet())
+	})
+}
+
+func TestService_Decide(t *testing.T) {
+	a := assert.New(t)
+	item := ItemEntity{Id: 11, CampaignId: 7, EmployeeId: 1, RoleId: 5, ReviewerId: ptr(2),
+		EmployeeName: "John Doe", RoleName: "billing"}
+
+	t.Run("reviewer keeps grant", func(t *testing.T) {
+		svc, m, auditor, _ := newSqlmockService(t)
+		decided := item
+		decided.Decision, decided.DecidedBy, decided.DecidedAt = str(DecisionKeep), str("boss"), &time.Time{}
+		m.ExpectBegin()
+		m.ExpectQuery(shareLockQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(openCampaign()))
+		m.ExpectQuery(selectItemsQuery).WithArgs(int64(11), int64(7)).WillReturnRows(itemRows(item))
+		m.ExpectQuery(loginQuery).WithArgs("boss").WillReturnRows(idRow(2))
+		m.ExpectQuery(updateItemQuery).
+			WithArgs(int64(2), DecisionKeep, nil, "boss", sqlmock.AnyArg(), nil, nil, int64(11)).
+			WillReturnRows(itemRows(decided))
+		m.ExpectCommit()
+
+		got, err := svc.Decide(asUser("boss"), 7, 11, DecisionRequest{Decision: DecisionKeep}, false)
+		a.NoError(err)
+		a.Equal(DecisionKeep, *got.Decision)
+		a.Len(auditor.records, 1)
+		a.Equal(audit.ActionReview, auditor.records[0].Action)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("reviewer delegates review", func(t *testing.T) {
+		svc, m, auditor, _ := newSqlmockService(t)
+		delegated := item
+		delegated.ReviewerId, delegated.DelegatedBy = ptr(4), str("boss")
+		m.ExpectBegin()
+		m.ExpectQuery(shareLockQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(openCampaign()))
+		m.ExpectQuery(selectItemsQuery).WithArgs(int64(11), int64(7)).WillReturnRows(itemRows(item))
+		m.ExpectQuery(loginQuery).WithArgs("boss").WillReturnRows(idRow(2))
+		m.ExpectQuery(employeeExists).WithArgs(int64(4)).WillReturnRows(existsRow(true))
+		m.ExpectQuery(updateItemQuery).
+			WithArgs(int64(4), nil, "on vacation", nil, nil, "boss", nil, int64(11)).
+			WillReturnRows(itemRows(delegated))
+		m.ExpectCommit()
+
+		req := DecisionRequest{Decision: DecisionDelegate, Comment: "on vacation", DelegateTo: ptr(4)}
+		got, err := svc.Decide(asUser("boss"), 7, 11, req, false)
+		a.NoError(err)
+		a.Equal(int64(4), *got.ReviewerId)
+		a.Nil(got.Decision)
+		a.Equal(audit.ActionDelegate, auditor.records[0].Action)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("delegate_to without delegate", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+
+		_, err := svc.Decide(asUser("boss"), 7, 11, DecisionRequest{Decision: DecisionKeep, DelegateTo: ptr(4)}, false)
+		a.ErrorAs(err, &common.RequestValidationError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("not the reviewer", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(shareLockQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(openCampaign()))
+		m.ExpectQuery(selectItemsQuery).WithArgs(int64(11), int64(7)).WillReturnRows(itemRows(item))
+		m.ExpectQuery(loginQuery).WithArgs("peer").WillReturnRows(idRow(3))
+		m.ExpectRollback()
+
+		_, err := svc.Decide(asUser("peer"), 7, 11, DecisionRequest{Decision: DecisionRevoke}, false)
+		a.ErrorAs(err, &common.ForbiddenError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("admin cannot review own grant", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(shareLockQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(openCampaign()))
+		m.ExpectQuery(selectItemsQuery).WithArgs(int64(11), int64(7)).WillReturnRows(itemRows(item))
+		m.ExpectQuery(loginQuery).WithArgs("jdoe").WillReturnRows(idRow(1))
+		m.ExpectRollback()
+
+		_, err := svc.Decide(asUser("jdoe"), 7, 11, DecisionRequest{Decision: DecisionKeep}, true)
+		a.ErrorAs(err, &common.ForbiddenError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("closed campaign", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		campaign := openCampaign()
+		campaign.Status = StatusClosed
+		m.ExpectBegin()
+		m.ExpectQuery(shareLockQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(campaign))
+		m.ExpectRollback()
+
+		_, err := svc.Decide(asUser("boss"), 7, 11, DecisionRequest{Decision: DecisionKeep}, false)
+		a.ErrorAs(err, &common.ConflictError{})
+		a.NoError(m.ExpectationsWereMet())
+	})
+}
+
+func TestService_Close(t *testing.T) {
+	a := assert.New(t)
+	svc, m, auditor, publisher := newSqlmockService(t)
+	auditor.lockChain = true
+	campaign := openCampaign()
+	campaign.RevokeUndecided = true
+	grantedFrom := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	grantedTo := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	items := []ItemEntity{
+		{Id: 11, CampaignId: 7, EmployeeId: 1, RoleId: 5, GrantedValidFrom: &grantedFrom, Decision: str(DecisionKeep)},
+		{Id: 12, CampaignId: 7, EmployeeId: 2, RoleId: 5, GrantedValidFrom: &grantedFrom, GrantedValidTo: &grantedTo,
+			Decision: str(DecisionRevoke)},
+		// назначение без решения, которое сняли или выдали заново с другим сроком до закрытия кампании
+		{Id: 13, CampaignId: 7, EmployeeId: 3, RoleId: 5, GrantedValidFrom: &grantedFrom},
+	}
+	report, reportDigest, reportSignature := &capture{}, &capture{}, &capture{}
+	closedCampaign := campaign
+	closedCampaign.Status = StatusClosed
+	m.ExpectBegin()
+	m.ExpectQuery(updateLockQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(campaign))
+	m.ExpectQuery(selectItemsQuery).WithArgs(int64(7)).WillReturnRows(itemRows(items...))
+	// снимается только то назначение, которое проверялось: с тем же сроком действия
+	m.ExpectExec(revokeQuery).WithArgs(int64(2), int64(5), grantedFrom, grantedTo).WillReturnResult(sqlmock.NewResult(0, 1))
+	m.ExpectExec(revokeQuery).WithArgs(int64(3), int64(5), grantedFrom, nil).WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectExec(outcomesQuery).
+		WithArgs("{11,12,13}", `{"kept","revoked","missing"}`).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	m.ExpectQuery(closeQuery).WithArgs(sqlmock.AnyArg(), "admin", report, reportDigest, reportSignature, int64(7)).
+		WillReturnRows(campaignRows(closedCampaign))
+	// блокировка цепочки аудита берётся только после снятия назначений - как в ExpireGrants
+	m.ExpectExec(chainLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectExec(chainLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectCommit()
+
+	got, err := svc.Close(asUser("admin"), 7)
+	a.NoError(err)
+	a.Equal(StatusClosed, got.Status)
+	a.NoError(m.ExpectationsWereMet())
+
+	// сохранённый отчёт соответствует своему дайджесту и содержит итоги закрытия
+	stored := []byte(report.value.(string))
+	a.Equal(digest(stored), reportDigest.value)
+	// подпись вычисляется при закрытии и хранится вместе с отчётом
+	a.Equal(sign([]byte(signingKey), stored), reportSignature.value)
+	var parsed Report
+	a.NoError(json.Unmarshal(stored, &parsed))
+	a.Equal(Summary{Total: 3, Kept: 1, Revoked: 1, Missing: 1, Undecided: 1}, parsed.Summary)
+	a.Equal("admin", parsed.ClosedBy)
+
+	a.Len(auditor.records, 2)
+	a.Equal(audit.ActionRevokeRoles, auditor.records[0].Action)
+	a.Equal(int64(2), auditor.records[0].EntityId)
+	a.Equal(auditRevoke{RoleIds: []int64{5}, AccessReviewId: 7}, auditor.records[0].Before)
+	a.Equal(audit.ActionClose, auditor.records[1].Action)
+	a.Len(publisher.events, 1)
+	a.Equal(EventClosed, publisher.events[0].Type)
+}
+
+func TestService_CloseOverdue(t *testing.T) {
+	a := assert.New(t)
+	svc, m, _, _ := newSqlmockService(t)
+	now := time.Now()
+	closedCampaign := openCampaign()
+	closedCampaign.Id, closedCampaign.Status = 8, StatusClosed
+	m.ExpectQuery(overdueQuery).WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)).AddRow(int64(8)))
+	m.ExpectBegin()
+	m.ExpectQuery(updateLockQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(openCampaign()))
+	m.ExpectQuery(selectItemsQuery).WithArgs(int64(7)).WillReturnRows(itemRows())
+	m.ExpectExec(outcomesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	// закрытие по сроку выполняется без пользователя
+	m.ExpectQuery(closeQuery).WithArgs(now, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), int64(7)).
+		WillReturnRows(campaignRows(closedCampaign))
+	m.ExpectCommit()
+	// кампанию 8 уже закрыл другой экземпляр сервиса
+	m.ExpectBegin()
+	m.ExpectQuery(updateLockQuery).WithArgs(int64(8)).WillReturnRows(campaignRows(closedCampaign))
+	m.ExpectRollback()
+
+	count, err := svc.CloseOverdue(context.Background(), now)
+	a.NoError(err)
+	a.Equal(1, count)
+	a.NoError(m.ExpectationsWereMet())
+}
+
+func TestService_Remind(t *testing.T) {
+	a := assert.New(t)
+	svc, m, _, publisher := newSqlmockService(t)
+	now := time.Now()
+	dueAt := now.Add(48 * time.Hour)
+	m.ExpectBegin()
+	m.ExpectQuery(remindQuery).WithArgs(now, now.Add(-24*time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"campaign_id", "reviewer_id", "pending", "due_at"}).
+			AddRow(int64(7), int64(2), 3, dueAt).
+			AddRow(int64(7), nil, 1, dueAt))
+	m.ExpectCommit()
+
+	count, err := svc.Remind(context.Background(), now)
+	a.NoError(err)
+	a.Equal(2, count)
+	a.Len(publisher.events, 2)
+	a.Equal(EventReminder, publisher.events[0].Type)
+	a.Equal(Reminder{CampaignId: 7, ReviewerId: ptr(2), Pending: 3, DueAt: dueAt}, publisher.events[0].Data)
+	a.NoError(m.ExpectationsWereMet())
+}
+
+func TestService_Report(t *testing.T) {
+	a := assert.New(t)
+	stored := `{"campaign_id":7,"summary":{"total":0}}`
+	closedCampaign := openCampaign()
+	closedCampaign.Status, closedCampaign.Report, closedCampaign.ReportDigest, closedCampaign.ReportSignature =
+		StatusClosed, str(stored), str(digest([]byte(stored))), str(sign([]byte(signingKey), []byte(stored)))
+
+	t.Run("signed report", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectQuery(findQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(closedCampaign))
+
+		got, err := svc.Report(context.Background(), 7)
+		a.NoError(err)
+		a.JSONEq(stored, string(got.Report))
+		a.Equal(*closedCampaign.ReportDigest, got.Digest)
+		a.Equal(*closedCampaign.ReportSignature, got.Signature)
+		a.Equal(SignatureAlgorithm, got.SignatureAlgorithm)
+	})
+
+	t.Run("campaign is open", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		m.ExpectQuery(findQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(openCampaign()))
+
+		_, err := svc.Report(context.Background(), 7)
+		a.ErrorAs(err, &common.ConflictError{})
+	})
+
+	t.Run("tampered report", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		tampered := closedCampaign
+		tampered.Report = str(`{"campaign_id":7,"summary":{"total":1}}`)
+		m.ExpectQuery(findQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(tampered))
+
+		_, err := svc.Report(context.Background(), 7)
+		a.Error(err)
+		a.NotErrorAs(err, &common.ConflictError{})
+	})
+
+	t.Run("report and digest rewritten together", func(t *testing.T) {
+		svc, m, _, _ := newSqlmockService(t)
+		forged := closedCampaign
+		forged.Report = str(`{"campaign_id":7,"summary":{"total":1}}`)
+		forged.ReportDigest = str(digest([]byte(*forged.Report)))
+		m.ExpectQuery(findQuery).WithArgs(int64(7)).WillReturnRows(campaignRows(forged))
+
+		// без ключа подпись под изменённый отчёт не подобрать
+		_, err := svc.Report(context.Background(), 7)
+		a.ErrorContains(err, "does not match its signature")
+	})
+}
@@ -0,0 +1,55 @@
+package accessreview
+
+import (
+	"context"
+	"time"
+
+	"idm/inner/common"
+
+	"go.uber.org/zap"
+)
+
+// Target - сервис, закрывающий кампании с истёкшим к моменту now сроком и напоминающий проверяющим о нерешённых назначениях
+type Target interface {
+	CloseOverdue(ctx context.Context, now time.Time) (int, error)
+	Remind(ctx context.Context, now time.Time) (int, error)
+}
+
+// Worker периодически закрывает просроченные кампании пересмотра доступа и рассылает напоминания
+type Worker struct {
+	logger *common.Logger
+	target Target
+	now    func() time.Time
+
+	*common.Periodic
+}
+
+func NewWorker(logger *common.Logger, interval time.Duration, target Target) *Worker {
+	w := &Worker{
+		logger: logger,
+		target: target,
+		now:    time.Now,
+	}
+	w.Periodic = common.NewPeriodic(interval, w.RunOnce)
+	return w
+}
+
+// RunOnce сначала закрывает просроченные кампании, затем напоминает о нерешённых назначениях открытых.
+// Ошибка закрытия не мешает напоминаниям
+func (w *Worker) RunOnce(ctx context.Context) {
+	now := w.now()
+	closed, err := w.target.CloseOverdue(ctx, now)
+	if err != nil {
+		w.logger.Error("close overdue access reviews", zap.Error(err))
+	} else if closed > 0 {
+		w.logger.Info("closed overdue access reviews", zap.Int("count", closed), zap.Time("closed_by", now))
+	}
+	reminded, err := w.target.Remind(ctx, now)
+	if err != nil {
+		w.logger.Error("remind access reviewers", zap.Error(err))
+		return
+	}
+	if reminded > 0 {
+		w.logger.Info("reminded access reviewers", zap.Int("count", reminded))
+	}
+}
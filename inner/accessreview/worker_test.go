@@ -0,0 +1,77 @@
+package accessreview
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"idm/inner/logtest"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// stubTarget запоминает моменты, к которым его просили закрыть кампании и напомнить проверяющим
+type stubTarget struct {
+	mu         sync.Mutex
+	closeNows  []time.Time
+	remindNows []time.Time
+	closed     int
+	reminded   int
+	closeErr   error
+}
+
+func (s *stubTarget) CloseOverdue(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeNows = append(s.closeNows, now)
+	return s.closed, s.closeErr
+}
+
+func (s *stubTarget) Remind(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remindNows = append(s.remindNows, now)
+	return s.reminded, nil
+}
+
+func (s *stubTarget) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.remindNows)
+}
+
+func newTestWorker(target Target) (*Worker, *observer.ObservedLogs) {
+	logger, logs := logtest.New()
+	return NewWorker(logger, time.Minute, target), logs
+}
+
+func TestWorker_RunOnce(t *testing.T) {
+	now := time.Date(2025, 12, 30, 12, 0, 0, 0, time.UTC)
+
+	t.Run("closed campaigns and reminders are logged", func(t *testing.T) {
+		target := &stubTarget{closed: 1, reminded: 2}
+		worker, logs := newTestWorker(target)
+		worker.now = func() time.Time { return now }
+
+		worker.RunOnce(context.Background())
+
+		assert.Equal(t, []time.Time{now}, target.closeNows)
+		assert.Equal(t, []time.Time{now}, target.remindNows)
+		assert.Equal(t, 1, logs.FilterMessage("closed overdue access reviews").FilterField(zap.Int("count", 1)).Len())
+		assert.Equal(t, 1, logs.FilterMessage("reminded access reviewers").FilterField(zap.Int("count", 2)).Len())
+	})
+
+	t.Run("close error does not block reminders", func(t *testing.T) {
+		target := &stubTarget{closeErr: errors.New("db down"), reminded: 1}
+		worker, logs := newTestWorker(target)
+
+		worker.RunOnce(context.Background())
+
+		assert.Equal(t, 1, logs.FilterMessage("close overdue access reviews").Len())
+		assert.Equal(t, 1, target.calls())
+	})
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"context"
+
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	server       *web.Server
+	auditService Svc
+}
+
+// Svc описывает методы бизнес-логики журнала аудита, доступные через API
+type Svc interface {
+	GetEventsPage(ctx context.Context, req PageRequest) (PageResponse, error)
+}
+
+func NewController(server *web.Server, auditService Svc) *Controller {
+	return &Controller{
+		server:       server,
+		auditService: auditService,
+	}
+}
+
+// RegisterRoutes регистрирует маршруты; права доступа к ним задаются политикой (policies.yaml)
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/audit/events", c.GetEventsPage)
+}
+
+// GetEventsPage godoc
+// @Summary      Get audit events page
+// @Description  Returns audit events from newest to oldest filtered by actor, action, entity and period
+// @Tags         audit
+// @Produce      json
+// @Param        request  query     audit.PageRequest  true  "page request and filters"
+// @Success      200      {object}  common.Response[audit.PageResponse]
+// @Router       /audit/events [get]
+// @Security BearerAuth
+func (c *Controller) GetEventsPage(ctx *fiber.Ctx) error {
+	var req PageRequest
+	if err := ctx.QueryParser(&req); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "bad query params")
+	}
+	page, err := c.auditService.GetEventsPage(ctx.UserContext(), req)
+	if err != nil {
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, page)
+}
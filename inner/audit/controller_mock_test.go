@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"idm/inner/policy"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) GetEventsPage(ctx context.Context, req PageRequest) (PageResponse, error) {
+	args := svc.Called(ctx, req)
+	return args.Get(0).(PageResponse), args.Error(1)
+}
+
+func newTestServer(t *testing.T, svc Svc, roles ...string) *web.Server {
+	routePolicy, err := policy.Load("../../policies.yaml")
+	require.NoError(t, err)
+	enforcer, err := policy.NewEnforcer(routePolicy, "idm")
+	require.NoError(t, err)
+
+	var claims = &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: roles}}
+	var auth = func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+	server := web.NewServer()
+	server.GroupApiV1.Use(auth, enforcer.Middleware())
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestGetEventsPage(t *testing.T) {
+	t.Run("admin reads events", func(t *testing.T) {
+		a := assert.New(t)
+		svc := new(MockService)
+		svc.On("GetEventsPage", mock.Anything, PageRequest{PageSize: 10, EntityType: "employee", EntityId: 7}).
+			Return(PageResponse{PageSize: 10}, nil)
+		server := newTestServer(t, svc, web.IdmAdmin)
+
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/audit/events?pageSize=10&entityType=employee&entityId=7", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(fiber.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("user is forbidden", func(t *testing.T) {
+		svc := new(MockService)
+		server := newTestServer(t, svc, web.IdmUser)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/audit/events?pageSize=10", nil))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "GetEventsPage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("bad query params", func(t *testing.T) {
+		server := newTestServer(t, new(MockService), web.IdmAdmin)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/audit/events?entityId=abc", nil))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
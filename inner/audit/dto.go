@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// действия, которые попадают в журнал аудита
+const (
+	ActionCreate      = "create"
+	ActionUpdate      = "update"
+	ActionDelete      = "delete"
+	ActionAssignRoles = "assign_roles"
+	ActionRevokeRoles = "revoke_roles"
+	ActionRestore     = "restore"
+	// ActionPurge - окончательное удаление мягко удалённой записи фоновой очисткой
+	ActionPurge = "purge"
+	// ActionTransition - смена статуса жизненного цикла сотрудника
+	ActionTransition = "transition"
+	// ActionMove - перенос подразделения вместе с поддеревом к другому родителю
+	ActionMove = "move"
+	// ActionExpireRoles - отзыв назначений ролей, срок действия которых истёк
+	ActionExpireRoles = "expire_roles"
+	// ActionApprove и ActionReject - решение согласующего по заявке на доступ
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionCancel  = "cancel"
+	// ActionExpire - закрытие заявки на доступ по истечении срока согласования
+	ActionExpire = "expire"
+	// ActionReview и ActionDelegate - решение по назначению в кампании пересмотра доступа и передача его проверки
+	ActionReview   = "review"
+	ActionDelegate = "delegate"
+	// ActionClose - закрытие кампании пересмотра доступа с применением решений
+	ActionClose = "close"
+)
+
+// Record - изменение, которое сервис записывает в журнал аудита.
+// Before и After - состояние сущности до и после изменения (nil, если его нет), сериализуются в JSON
+type Record struct {
+	Action     string
+	EntityType string
+	EntityId   int64
+	Before     any
+	After      any
+}
+
+// Entity - событие журнала аудита
+type Entity struct {
+	Id         int64          `db:"id"`
+	OccurredAt time.Time      `db:"occurred_at"`
+	ActorSub   string         `db:"actor_sub"`
+	ActorName  string         `db:"actor_name"`
+	Action     string         `db:"action"`
+	EntityType string         `db:"entity_type"`
+	EntityId   int64          `db:"entity_id"`
+	Before     sql.NullString `db:"before"`
+	After      sql.NullString `db:"after"`
+	RequestId  string         `db:"request_id"`
+	Ip         string         `db:"ip"`
+	PrevHash   string         `db:"prev_hash"`
+	Hash       string         `db:"hash"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:         e.Id,
+		OccurredAt: e.OccurredAt,
+		ActorSub:   e.ActorSub,
+		ActorName:  e.ActorName,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityId:   e.EntityId,
+		Before:     rawJson(e.Before),
+		After:      rawJson(e.After),
+		RequestId:  e.RequestId,
+		Ip:         e.Ip,
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}
+}
+
+func rawJson(value sql.NullString) json.RawMessage {
+	if !value.Valid {
+		return nil
+	}
+	return json.RawMessage(value.String)
+}
+
+type Response struct {
+	Id         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	ActorSub   string          `json:"actor_sub"`
+	ActorName  string          `json:"actor_name"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityId   int64           `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	RequestId  string          `json:"request_id"`
+	Ip         string          `json:"ip"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// PageRequest - фильтры и параметры страницы журнала аудита.
+// Actor сравнивается и с sub, и с логином пользователя; From и To - границы occurred_at в RFC 3339
+type PageRequest struct {
+	PageSize   int `validate:"min=1,max=100"`
+	PageNumber int `validate:"min=0"`
+	Actor      string
+	Action     string
+	EntityType string
+	EntityId   int64  `validate:"min=0"`
+	From       string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To         string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+// Filter - разобранные фильтры журнала аудита для репозитория
+type Filter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityId   int64
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+type PageResponse struct {
+	Result     []Response `json:"result"`
+	PageSize   int        `json:"page_size"`
+	PageNumber int        `json:"page_number"`
+	Total      int64      `json:"total"`
+}
+
+// VerifyResult - результат проверки цепочки хешей журнала.
+// BrokenId - первое событие, на котором цепочка нарушена
+type VerifyResult struct {
+	Checked  int64  `json:"checked"`
+	Valid    bool   `json:"valid"`
+	BrokenId int64  `json:"broken_id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
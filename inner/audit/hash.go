@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// chainPayload - поля события, которые входят в хеш, в фиксированном порядке
+type chainPayload struct {
+	PrevHash   string          `json:"prev_hash"`
+	OccurredAt string          `json:"occurred_at"`
+	ActorSub   string          `json:"actor_sub"`
+	ActorName  string          `json:"actor_name"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityId   int64           `json:"entity_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	RequestId  string          `json:"request_id"`
+	Ip         string          `json:"ip"`
+}
+
+// hashEvent вычисляет хеш события: sha256 от канонического JSON его полей вместе с хешем предыдущего события.
+// Postgres хранит jsonb и время в своём формате, поэтому before/after приводятся к каноническому JSON,
+// а время - к UTC с точностью до микросекунд, чтобы хеш совпадал после чтения события из БД
+func hashEvent(e *Entity) (string, error) {
+	before, err := canonicalJson(e.Before)
+	if err != nil {
+		return "", err
+	}
+	after, err := canonicalJson(e.After)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(chainPayload{
+		PrevHash:   e.PrevHash,
+		OccurredAt: e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		ActorSub:   e.ActorSub,
+		ActorName:  e.ActorName,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityId:   e.EntityId,
+		Before:     before,
+		After:      after,
+		RequestId:  e.RequestId,
+		Ip:         e.Ip,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJson пересобирает JSON: ключи объектов сортируются, пробелы убираются, числа сохраняются как есть
+func canonicalJson(value sql.NullString) (json.RawMessage, error) {
+	if !value.Valid {
+		return json.RawMessage("null"), nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value.String)))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(decoded)
+}
+
+// toJson сериализует состояние сущности для before/after; nil сохраняется как NULL
+func toJson(value any) (sql.NullString, error) {
+	if value == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"idm/inner/database"
+	"idm/inner/tracing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// chainLockKey - ключ advisory-блокировки, которой сериализуется запись в цепочку событий
+const chainLockKey = 0x61756469
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+// LockChainTx блокирует цепочку событий до конца транзакции, чтобы параллельные записи
+// не ссылались на один и тот же предыдущий хеш
+func (r *Repository) LockChainTx(ctx context.Context, tx *sqlx.Tx) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "audit.LockChainTx")
+	defer func() { span.Finish(0, err) }()
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", chainLockKey)
+	return err
+}
+
+// LastHashTx возвращает хеш последнего события или пустую строку, если журнал пуст
+func (r *Repository) LastHashTx(ctx context.Context, tx *sqlx.Tx) (hash string, err error) {
+	ctx, span := tracing.StartQuery(ctx, "audit.LastHashTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &hash, "SELECT hash FROM audit_event ORDER BY id DESC LIMIT 1")
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return hash, err
+}
+
+func (r *Repository) AddTx(ctx context.Context, tx *sqlx.Tx, event *Entity) (id int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "audit.AddTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &id,
+		`INSERT INTO audit_event (occurred_at, actor_sub, actor_name, action, entity_type, entity_id,
+			before, after, request_id, ip, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, $8::jsonb, $9, $10, $11, $12)
+		RETURNING id`,
+		event.OccurredAt, event.ActorSub, event.ActorName, event.Action, event.EntityType, event.EntityId,
+		event.Before, event.After, event.RequestId, event.Ip, event.PrevHash, event.Hash)
+	return id, err
+}
+
+// FindPage возвращает страницу событий, подходящих под фильтр, от новых к старым, и их общее число
+func (r *Repository) FindPage(ctx context.Context, filter Filter) (events []Entity, total int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "audit.FindPage")
+	defer func() { span.Finish(int64(len(events)), err) }()
+	const where = ` WHERE ($1 = '' OR actor_sub = $1 OR actor_name = $1)
+		AND ($2 = '' OR action = $2)
+		AND ($3 = '' OR entity_type = $3)
+		AND ($4 = 0 OR entity_id = $4)
+		AND ($5::timestamptz IS NULL OR occurred_at >= $5)
+		AND ($6::timestamptz IS NULL OR occurred_at < $6)`
+	args := []any{filter.Actor, filter.Action, filter.EntityType, filter.EntityId, filter.From, filter.To}
+	err = r.db.SelectContext(ctx, &events,
+		"SELECT * FROM audit_event"+where+" ORDER BY id DESC LIMIT $7 OFFSET $8",
+		append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM audit_event"+where, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// FindAfter возвращает не больше limit событий с id больше afterId в порядке цепочки
+func (r *Repository) FindAfter(ctx context.Context, afterId int64, limit int) (events []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "audit.FindAfter")
+	defer func() { span.Finish(int64(len(events)), err) }()
+	err = r.db.SelectContext(ctx, &events,
+		"SELECT * FROM audit_event WHERE id > $1 ORDER BY id LIMIT $2", afterId, limit)
+	return events, err
+}
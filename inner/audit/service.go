@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"idm/inner/common"
+	"idm/inner/tracing"
+	"idm/inner/validator"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// verifyBatchSize - сколько событий читается за один запрос при проверке цепочки
+const verifyBatchSize = 1000
+
+type Service struct {
+	repo      Repo
+	validator *validator.Validator
+}
+
+type Repo interface {
+	LockChainTx(ctx context.Context, tx *sqlx.Tx) error
+	LastHashTx(ctx context.Context, tx *sqlx.Tx) (string, error)
+	AddTx(ctx context.Context, tx *sqlx.Tx, event *Entity) (int64, error)
+	FindPage(ctx context.Context, filter Filter) ([]Entity, int64, error)
+	FindAfter(ctx context.Context, afterId int64, limit int) ([]Entity, error)
+}
+
+func NewService(repo Repo) *Service {
+	return &Service{repo: repo, validator: validator.New()}
+}
+
+// RecordTx записывает событие в журнал в транзакции самого изменения: если изменение откатится,
+// события тоже не будет. Пользователь, id запроса и IP берутся из контекста.
+// Запись блокирует цепочку журнала до конца транзакции, поэтому события пишутся последними -
+// после всех изменений, блокирующих строки; иначе транзакции могут взаимно заблокироваться
+func (svc *Service) RecordTx(ctx context.Context, tx *sqlx.Tx, record Record) error {
+	ctx, span := tracing.Start(ctx, "audit.Service.RecordTx")
+	defer span.End()
+	user, _ := common.UserFromContext(ctx)
+	event := Entity{
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		ActorSub:   user.Subject,
+		ActorName:  user.Username,
+		Action:     record.Action,
+		EntityType: record.EntityType,
+		EntityId:   record.EntityId,
+		RequestId:  common.RequestIdFromContext(ctx),
+		Ip:         common.ClientIpFromContext(ctx),
+	}
+	var err error
+	if event.Before, err = toJson(record.Before); err != nil {
+		return fmt.Errorf("error marshaling audit state: %w", err)
+	}
+	if event.After, err = toJson(record.After); err != nil {
+		return fmt.Errorf("error marshaling audit state: %w", err)
+	}
+	if err = svc.repo.LockChainTx(ctx, tx); err != nil {
+		return fmt.Errorf("error locking audit chain: %w", err)
+	}
+	if event.PrevHash, err = svc.repo.LastHashTx(ctx, tx); err != nil {
+		return fmt.Errorf("error reading last audit event: %w", err)
+	}
+	if event.Hash, err = hashEvent(&event); err != nil {
+		return fmt.Errorf("error hashing audit event: %w", err)
+	}
+	if _, err = svc.repo.AddTx(ctx, tx, &event); err != nil {
+		return fmt.Errorf("error adding audit event: %w", err)
+	}
+	return nil
+}
+
+func (svc *Service) GetEventsPage(ctx context.Context, req PageRequest) (PageResponse, error) {
+	ctx, span := tracing.Start(ctx, "audit.Service.GetEventsPage")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return PageResponse{}, err
+	}
+	filter := Filter{
+		Actor:      req.Actor,
+		Action:     req.Action,
+		EntityType: req.EntityType,
+		EntityId:   req.EntityId,
+		From:       parseTime(req.From),
+		To:         parseTime(req.To),
+		Limit:      req.PageSize,
+		Offset:     req.PageNumber * req.PageSize,
+	}
+	events, total, err := svc.repo.FindPage(ctx, filter)
+	if err != nil {
+		return PageResponse{}, err
+	}
+	result := make([]Response, 0, len(events))
+	for _, e := range events {
+		result = append(result, e.toResponse())
+	}
+	return PageResponse{
+		Result:     result,
+		PageSize:   req.PageSize,
+		PageNumber: req.PageNumber,
+		Total:      total,
+	}, nil
+}
+
+// Verify проходит журнал от первого события к последнему и проверяет, что каждое событие ссылается
+// на хеш предыдущего, а его собственный хеш совпадает с вычисленным по полям.
+// Удаление событий с конца журнала цепочка не выявляет: для этого нужно сверять последний хеш с сохранённым ранее
+func (svc *Service) Verify(ctx context.Context) (VerifyResult, error) {
+	ctx, span := tracing.Start(ctx, "audit.Service.Verify")
+	defer span.End()
+	var result VerifyResult
+	var prevHash string
+	var lastId int64
+	for {
+		events, err := svc.repo.FindAfter(ctx, lastId, verifyBatchSize)
+		if err != nil {
+			return VerifyResult{}, fmt.Errorf("error reading audit events after id %d: %w", lastId, err)
+		}
+		for i := range events {
+			event := &events[i]
+			result.Checked++
+			if reason := verifyEvent(event, prevHash); reason != "" {
+				result.BrokenId = event.Id
+				result.Reason = reason
+				return result, nil
+			}
+			prevHash = event.Hash
+			lastId = event.Id
+		}
+		if len(events) < verifyBatchSize {
+			break
+		}
+	}
+	result.Valid = true
+	return result, nil
+}
+
+// verifyEvent возвращает причину, по которой событие нарушает цепочку, или пустую строку
+func verifyEvent(event *Entity, prevHash string) string {
+	if event.PrevHash != prevHash {
+		return "prev_hash does not match hash of previous event"
+	}
+	hash, err := hashEvent(event)
+	if err != nil {
+		return fmt.Sprintf("event can not be hashed: %v", err)
+	}
+	if hash != event.Hash {
+		return "hash does not match event fields"
+	}
+	return ""
+}
+
+// parseTime разбирает границу периода; формат уже проверен валидатором
+func parseTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
@@ -0,0 +1,233 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"idm/inner/common"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) LockChainTx(ctx context.Context, tx *sqlx.Tx) error {
+	return m.Called(ctx, tx).Error(0)
+}
+
+func (m *MockRepo) LastHashTx(ctx context.Context, tx *sqlx.Tx) (string, error) {
+	args := m.Called(ctx, tx)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRepo) AddTx(ctx context.Context, tx *sqlx.Tx, event *Entity) (int64, error) {
+	args := m.Called(ctx, tx, event)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) FindPage(ctx context.Context, filter Filter) ([]Entity, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]Entity), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRepo) FindAfter(ctx context.Context, afterId int64, limit int) ([]Entity, error) {
+	args := m.Called(ctx, afterId, limit)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+// chain строит корректную цепочку из событий, заполняя prev_hash и hash
+func chain(t *testing.T, events ...Entity) []Entity {
+	prevHash := ""
+	for i := range events {
+		events[i].Id = int64(i + 1)
+		events[i].PrevHash = prevHash
+		hash, err := hashEvent(&events[i])
+		require.NoError(t, err)
+		events[i].Hash = hash
+		prevHash = hash
+	}
+	return events
+}
+
+func testEvents(t *testing.T) []Entity {
+	at := time.Date(2025, 11, 1, 12, 0, 0, 123456000, time.UTC)
+	return chain(t,
+		Entity{OccurredAt: at, ActorSub: "u-1", ActorName: "admin", Action: ActionCreate, EntityType: "employee",
+			EntityId: 1, After: sql.NullString{String: `{"id":1,"name":"Alice"}`, Valid: true}},
+		Entity{OccurredAt: at.Add(time.Second), ActorSub: "u-1", ActorName: "admin", Action: ActionUpdate, EntityType: "employee",
+			EntityId: 1, Before: sql.NullString{String: `{"id":1,"name":"Alice"}`, Valid: true},
+			After: sql.NullString{String: `{"id":1,"name":"Bob"}`, Valid: true}},
+		Entity{OccurredAt: at.Add(2 * time.Second), ActorSub: "u-2", ActorName: "root", Action: ActionDelete, EntityType: "employee",
+			EntityId: 1, Before: sql.NullString{String: `{"id":1,"name":"Bob"}`, Valid: true}},
+	)
+}
+
+func TestService_RecordTx(t *testing.T) {
+	a := assert.New(t)
+	dbMock, m, err := sqlmock.New()
+	require.NoError(t, err)
+	defer dbMock.Close()
+	db := sqlx.NewDb(dbMock, "postgres")
+	svc := NewService(NewRepository(db))
+
+	ctx := common.WithUser(context.Background(), common.User{Subject: "u-1", Username: "admin"})
+	ctx = common.WithRequestId(ctx, "req-1")
+	ctx = common.WithClientIp(ctx, "10.0.0.1")
+
+	var inserted []any
+	m.ExpectBegin()
+	m.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery(regexp.QuoteMeta("SELECT hash FROM audit_event ORDER BY id DESC LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("prev"))
+	m.ExpectQuery(regexp.QuoteMeta("INSERT INTO audit_event")).
+		WithArgs(sqlmock.AnyArg(), "u-1", "admin", ActionDelete, "employee", int64(5),
+			`{"id":5,"name":"Alice"}`, nil, "req-1", "10.0.0.1", "prev", argCollector{&inserted}).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	m.ExpectCommit()
+
+	tx, err := db.Beginx()
+	require.NoError(t, err)
+	err = svc.RecordTx(ctx, tx, Record{
+		Action:     ActionDelete,
+		EntityType: "employee",
+		EntityId:   5,
+		Before:     map[string]any{"id": 5, "name": "Alice"},
+	})
+	a.NoError(err)
+	a.NoError(tx.Commit())
+	a.NoError(m.ExpectationsWereMet())
+	a.Len(inserted, 1)
+	a.Regexp("^[0-9a-f]{64}$", inserted[0])
+}
+
+// argCollector принимает любой аргумент запроса и запоминает его
+type argCollector struct {
+	values *[]any
+}
+
+func (c argCollector) Match(value driver.Value) bool {
+	*c.values = append(*c.values, value)
+	return true
+}
+
+func TestService_Verify(t *testing.T) {
+	tests := []struct {
+		name       string
+		tamper     func(events []Entity) []Entity
+		wantValid  bool
+		wantBroken int64
+		wantReason string
+	}{
+		{
+			name:      "intact chain",
+			tamper:    func(events []Entity) []Entity { return events },
+			wantValid: true,
+		},
+		{
+			name: "modified field",
+			tamper: func(events []Entity) []Entity {
+				events[1].After.String = `{"id":1,"name":"Eve"}`
+				return events
+			},
+			wantBroken: 2,
+			wantReason: "hash does not match event fields",
+		},
+		{
+			name: "deleted event",
+			tamper: func(events []Entity) []Entity {
+				return append(events[:1], events[2:]...)
+			},
+			wantBroken: 3,
+			wantReason: "prev_hash does not match hash of previous event",
+		},
+		{
+			name: "rehashed event without relinking chain",
+			tamper: func(events []Entity) []Entity {
+				events[0].ActorName = "someone else"
+				events[0].Hash, _ = hashEvent(&events[0])
+				return events
+			},
+			wantBroken: 2,
+			wantReason: "prev_hash does not match hash of previous event",
+		},
+		{
+			name: "jsonb reformatting does not break chain",
+			tamper: func(events []Entity) []Entity {
+				events[1].Before.String = `{"name": "Alice", "id": 1}`
+				return events
+			},
+			wantValid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+			events := tt.tamper(testEvents(t))
+			repo := new(MockRepo)
+			repo.On("FindAfter", mock.Anything, int64(0), verifyBatchSize).Return(events, nil)
+
+			result, err := NewService(repo).Verify(context.Background())
+			a.NoError(err)
+			a.Equal(tt.wantValid, result.Valid)
+			a.Equal(tt.wantBroken, result.BrokenId)
+			a.Equal(tt.wantReason, result.Reason)
+		})
+	}
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(MockRepo)
+		repo.On("FindAfter", mock.Anything, int64(0), verifyBatchSize).Return([]Entity(nil), errors.New("db down"))
+		_, err := NewService(repo).Verify(context.Background())
+		assert.ErrorContains(t, err, "db down")
+	})
+}
+
+func TestService_GetEventsPage(t *testing.T) {
+	t.Run("filters are passed to repository", func(t *testing.T) {
+		a := assert.New(t)
+		repo := new(MockRepo)
+		from := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+		events := testEvents(t)
+		repo.On("FindPage", mock.Anything, Filter{
+			Actor:      "admin",
+			EntityType: "employee",
+			EntityId:   1,
+			From:       &from,
+			Limit:      2,
+			Offset:     2,
+		}).Return(events[:1], int64(3), nil)
+
+		page, err := NewService(repo).GetEventsPage(context.Background(), PageRequest{
+			PageSize:   2,
+			PageNumber: 1,
+			Actor:      "admin",
+			EntityType: "employee",
+			EntityId:   1,
+			From:       "2025-11-01T00:00:00Z",
+		})
+		a.NoError(err)
+		a.Equal(int64(3), page.Total)
+		a.Len(page.Result, 1)
+		a.JSONEq(`{"id":1,"name":"Alice"}`, string(page.Result[0].After))
+		a.Nil(page.Result[0].Before)
+	})
+
+	t.Run("invalid period", func(t *testing.T) {
+		repo := new(MockRepo)
+		_, err := NewService(repo).GetEventsPage(context.Background(), PageRequest{PageSize: 10, From: "yesterday"})
+		assert.ErrorAs(t, err, &common.RequestValidationError{})
+		repo.AssertNotCalled(t, "FindPage", mock.Anything, mock.Anything)
+	})
+}
@@ -2,6 +2,9 @@ package common
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,9 +18,100 @@ type Config struct {
 	LogDevelopMode bool
 	SslSert        string `validate:"required"`
 	SslKey         string `validate:"required"`
+	// KeycloakJwkUrl - адрес JWKS, через запятую можно указать несколько адресов
 	KeycloakJwkUrl string `validate:"required"`
+	// KeycloakClientId - клиент Keycloak, чьи роли из resource_access используются для авторизации
+	KeycloakClientId string
+	// JwtIssuers - доверенные издатели токенов (iss); если не заданы, издатель не проверяется
+	JwtIssuers []string
+	// JwtAudiences - допустимые аудитории (aud), токен должен содержать хотя бы одну из них
+	JwtAudiences []string
+	// JwtAuthorizedParties - клиенты (azp), которым разрешено обращаться к API
+	JwtAuthorizedParties []string
+	// JwtLeeway - допустимое расхождение часов при проверке exp, nbf и iat
+	JwtLeeway time.Duration
+	// JwksRefreshInterval - период фонового обновления ключей JWKS
+	JwksRefreshInterval time.Duration
+	// DbQueryTimeout - ограничение времени на запросы к БД в рамках одного HTTP-запроса
+	DbQueryTimeout time.Duration
+	// ListenAddr - адрес публичного API (TLS)
+	ListenAddr string
+	// ManagementAddr - адрес служебного порта: пробы, info, метрики, pprof и swagger
+	ManagementAddr string
+	// ManagementTls - включает TLS на служебном порту; по умолчанию он работает по HTTP
+	ManagementTls bool
+	// ProbeAddr - адрес порта проб livez и readyz (HTTP); кроме проб на нём ничего нет
+	ProbeAddr string
+	// ShutdownDrainDelay - сколько после сигнала остановки readyz отвечает 503, а сервер ещё принимает запросы,
+	// чтобы балансировщик успел исключить экземпляр; 0 отключает задержку
+	ShutdownDrainDelay time.Duration
+	// AccessLogSampleRate - доля успешных запросов (от 0 до 1), попадающих в access log;
+	// запросы, завершившиеся ошибкой, логируются всегда
+	AccessLogSampleRate float64
+	// TracingExporter - куда отправлять трейсы: otlp, stdout или none
+	TracingExporter string
+	// PolicyFile - файл политики доступа к маршрутам (YAML или JSON)
+	PolicyFile string
+	// SoftDeleteRetention - срок хранения мягко удалённых записей, после которого они удаляются окончательно;
+	// 0 отключает очистку
+	SoftDeleteRetention time.Duration
+	// PurgeInterval - период запуска фоновой очистки
+	PurgeInterval time.Duration
+	// GrantExpiryInterval - период фонового отзыва назначений ролей, срок действия которых истёк
+	GrantExpiryInterval time.Duration
+	// AccessRequestSla - срок согласования одного этапа заявки на доступ; по его истечении заявка закрывается
+	AccessRequestSla time.Duration
+	// AccessRequestCheckInterval - период фонового закрытия заявок с истёкшим сроком согласования
+	AccessRequestCheckInterval time.Duration
+	// AccessReviewCheckInterval - период фонового закрытия просроченных кампаний пересмотра доступа и рассылки напоминаний
+	AccessReviewCheckInterval time.Duration
+	// AccessReviewReminderInterval - как часто напоминать проверяющему о нерешённых назначениях кампании
+	AccessReviewReminderInterval time.Duration
+	// AccessReviewSigningKey - ключ HMAC-подписи отчётов кампаний пересмотра доступа; без него сервис не запускается
+	AccessReviewSigningKey string
+	// EmployeeAttributesFile - файл со схемой дополнительных атрибутов сотрудников (YAML или JSON);
+	// если не задан, дополнительные атрибуты не принимаются
+	EmployeeAttributesFile string
 }
 
+// DefaultDbQueryTimeout используется, если DB_QUERY_TIMEOUT не задан или задан некорректно
+const DefaultDbQueryTimeout = 5 * time.Second
+
+// адреса по умолчанию: служебный порт доступен только локально, порт проб слушает все интерфейсы,
+// чтобы до livez и readyz достучался kubelet
+const (
+	DefaultListenAddr     = ":8080"
+	DefaultManagementAddr = "localhost:8081"
+	DefaultProbeAddr      = ":8082"
+)
+
+// DefaultShutdownDrainDelay используется, если SHUTDOWN_DRAIN_DELAY не задан или задан некорректно
+const DefaultShutdownDrainDelay = 5 * time.Second
+
+// DefaultPolicyFile используется, если POLICY_FILE не задан
+const DefaultPolicyFile = "policies.yaml"
+
+// DefaultPurgeInterval используется, если PURGE_INTERVAL не задан или задан некорректно
+const DefaultPurgeInterval = time.Hour
+
+// DefaultGrantExpiryInterval используется, если GRANT_EXPIRY_INTERVAL не задан или задан некорректно
+const DefaultGrantExpiryInterval = time.Minute
+
+// значения по умолчанию для ACCESS_REQUEST_SLA и ACCESS_REQUEST_CHECK_INTERVAL
+const (
+	DefaultAccessRequestSla           = 72 * time.Hour
+	DefaultAccessRequestCheckInterval = time.Minute
+)
+
+// значения по умолчанию для ACCESS_REVIEW_CHECK_INTERVAL и ACCESS_REVIEW_REMINDER_INTERVAL
+const (
+	DefaultAccessReviewCheckInterval    = 10 * time.Minute
+	DefaultAccessReviewReminderInterval = 24 * time.Hour
+)
+
+// DefaultJwksRefreshInterval используется, если JWKS_REFRESH_INTERVAL не задан или задан некорректно
+const DefaultJwksRefreshInterval = time.Hour
+
 func GetConfig(envFile string) Config {
 	_ = godotenv.Load(envFile)
 	var cfg = Config{
@@ -30,6 +124,81 @@ func GetConfig(envFile string) Config {
 		SslSert:        os.Getenv("SSL_CERT"),
 		SslKey:         os.Getenv("SSL_KEY"),
 		KeycloakJwkUrl: os.Getenv("KEYCLOAK_JWK_URL"),
+		DbQueryTimeout: parseDuration(os.Getenv("DB_QUERY_TIMEOUT"), DefaultDbQueryTimeout),
+
+		KeycloakClientId:     os.Getenv("KEYCLOAK_CLIENT_ID"),
+		JwtIssuers:           SplitList(os.Getenv("JWT_ISSUERS")),
+		JwtAudiences:         SplitList(os.Getenv("JWT_AUDIENCES")),
+		JwtAuthorizedParties: SplitList(os.Getenv("JWT_AUTHORIZED_PARTIES")),
+		JwtLeeway:            parseDuration(os.Getenv("JWT_LEEWAY"), 0),
+		JwksRefreshInterval:  parseDuration(os.Getenv("JWKS_REFRESH_INTERVAL"), DefaultJwksRefreshInterval),
+		PolicyFile:           getEnv("POLICY_FILE", DefaultPolicyFile),
+		ListenAddr:           getEnv("LISTEN_ADDR", DefaultListenAddr),
+		ManagementAddr:       getEnv("MANAGEMENT_ADDR", DefaultManagementAddr),
+		ManagementTls:        os.Getenv("MANAGEMENT_TLS") == "true",
+		ProbeAddr:            getEnv("PROBE_ADDR", DefaultProbeAddr),
+		ShutdownDrainDelay:   parseNonNegativeDuration(os.Getenv("SHUTDOWN_DRAIN_DELAY"), DefaultShutdownDrainDelay),
+		TracingExporter:      os.Getenv("TRACING_EXPORTER"),
+		AccessLogSampleRate:  parseRate(os.Getenv("ACCESS_LOG_SAMPLE_RATE"), 1),
+		SoftDeleteRetention:  parseDuration(os.Getenv("SOFT_DELETE_RETENTION"), 0),
+		PurgeInterval:        parseDuration(os.Getenv("PURGE_INTERVAL"), DefaultPurgeInterval),
+		GrantExpiryInterval:  parseDuration(os.Getenv("GRANT_EXPIRY_INTERVAL"), DefaultGrantExpiryInterval),
+
+		AccessRequestSla:           parseDuration(os.Getenv("ACCESS_REQUEST_SLA"), DefaultAccessRequestSla),
+		AccessRequestCheckInterval: parseDuration(os.Getenv("ACCESS_REQUEST_CHECK_INTERVAL"), DefaultAccessRequestCheckInterval),
+
+		AccessReviewCheckInterval:    parseDuration(os.Getenv("ACCESS_REVIEW_CHECK_INTERVAL"), DefaultAccessReviewCheckInterval),
+		AccessReviewReminderInterval: parseDuration(os.Getenv("ACCESS_REVIEW_REMINDER_INTERVAL"), DefaultAccessReviewReminderInterval),
+		AccessReviewSigningKey:       os.Getenv("ACCESS_REVIEW_SIGNING_KEY"),
+
+		EmployeeAttributesFile: os.Getenv("EMPLOYEE_ATTRIBUTES_FILE"),
 	}
 	return cfg
 }
+
+// getEnv возвращает значение переменной окружения или fallback, если она не задана
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// parseDuration разбирает длительность в формате time.ParseDuration (например, "3s", "500ms")
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// parseNonNegativeDuration разбирает длительность, как parseDuration, но принимает и 0;
+// fallback возвращается для пустого, некорректного или отрицательного значения
+func parseNonNegativeDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fallback
+	}
+	return d
+}
+
+// parseRate разбирает долю от 0 до 1; при пустом или некорректном значении возвращает fallback
+func parseRate(value string, fallback float64) float64 {
+	rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return fallback
+	}
+	return rate
+}
+
+// SplitList разбирает список значений, разделённых запятыми; пустые элементы отбрасываются
+func SplitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -1,11 +1,13 @@
-package common
+package common_test
 
 import (
 	"os"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"idm/inner/common"
 	"idm/inner/validator"
 
 	"github.com/stretchr/testify/assert"
@@ -132,7 +134,7 @@ func Test_Config_Loading_TableDriven(t *testing.T) {
 				for k, v := range tc.envOverlay {
 					_ = os.Setenv(k, v)
 				}
-				cfg := GetConfig(envPath)
+				cfg := common.GetConfig(envPath)
 				assert.Equal(t, tc.expectDriver, cfg.DbDriverName)
 				assert.Equal(t, tc.expectDSN, cfg.Dsn)
 			})
@@ -200,7 +202,7 @@ func Test_Config_TLSValidation_TableDriven(t *testing.T) {
 			writeDotEnvFile(envPath, buildDotEnv(tc.dotEnvVars))
 
 			withCleanEnv(func() {
-				cfg := GetConfig(envPath)
+				cfg := common.GetConfig(envPath)
 				v := validator.New()
 				err := v.Validate(cfg)
 				if tc.wantError {
@@ -212,3 +214,198 @@ func Test_Config_TLSValidation_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func Test_Config_DbQueryTimeout(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"not set", "", common.DefaultDbQueryTimeout},
+		{"valid", "750ms", 750 * time.Millisecond},
+		{"invalid", "abc", common.DefaultDbQueryTimeout},
+		{"negative", "-1s", common.DefaultDbQueryTimeout},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			envPath := t.TempDir() + "/.env"
+			writeDotEnvFile(envPath, buildDotEnv(map[string]string{"DB_QUERY_TIMEOUT": tc.value}))
+
+			withCleanEnv(func() {
+				_ = os.Unsetenv("DB_QUERY_TIMEOUT")
+				cfg := common.GetConfig(envPath)
+				assert.Equal(t, tc.want, cfg.DbQueryTimeout)
+			})
+		})
+	}
+}
+
+func Test_Config_ShutdownDrainDelay(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"not set", "", common.DefaultShutdownDrainDelay},
+		{"valid", "15s", 15 * time.Second},
+		{"zero disables drain", "0", 0},
+		{"invalid", "abc", common.DefaultShutdownDrainDelay},
+		{"negative", "-1s", common.DefaultShutdownDrainDelay},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			envPath := t.TempDir() + "/.env"
+			writeDotEnvFile(envPath, buildDotEnv(map[string]string{"SHUTDOWN_DRAIN_DELAY": tc.value}))
+
+			withCleanEnv(func() {
+				_ = os.Unsetenv("SHUTDOWN_DRAIN_DELAY")
+				cfg := common.GetConfig(envPath)
+				assert.Equal(t, tc.want, cfg.ShutdownDrainDelay)
+			})
+		})
+	}
+}
+
+func Test_Config_JwtLists(t *testing.T) {
+	envPath := t.TempDir() + "/.env"
+	writeDotEnvFile(envPath, buildDotEnv(map[string]string{
+		"JWT_ISSUERS":   "http://kc/realms/idm, http://kc/realms/partners",
+		"JWT_AUDIENCES": "idm,,",
+		"JWT_LEEWAY":    "30s",
+	}))
+
+	withCleanEnv(func() {
+		for _, key := range []string{"JWT_ISSUERS", "JWT_AUDIENCES", "JWT_AUTHORIZED_PARTIES", "JWT_LEEWAY", "JWKS_REFRESH_INTERVAL"} {
+			_ = os.Unsetenv(key)
+		}
+		cfg := common.GetConfig(envPath)
+		assert.Equal(t, []string{"http://kc/realms/idm", "http://kc/realms/partners"}, cfg.JwtIssuers)
+		assert.Equal(t, []string{"idm"}, cfg.JwtAudiences)
+		assert.Empty(t, cfg.JwtAuthorizedParties)
+		assert.Equal(t, 30*time.Second, cfg.JwtLeeway)
+		assert.Equal(t, common.DefaultJwksRefreshInterval, cfg.JwksRefreshInterval)
+	})
+}
+
+func Test_Config_ListenAddrs(t *testing.T) {
+	keys := []string{"LISTEN_ADDR", "MANAGEMENT_ADDR", "MANAGEMENT_TLS", "PROBE_ADDR"}
+
+	t.Run("defaults", func(t *testing.T) {
+		envPath := t.TempDir() + "/.env"
+		writeDotEnvFile(envPath, "")
+		withCleanEnv(func() {
+			for _, key := range keys {
+				_ = os.Unsetenv(key)
+			}
+			cfg := common.GetConfig(envPath)
+			assert.Equal(t, common.DefaultListenAddr, cfg.ListenAddr)
+			assert.Equal(t, common.DefaultManagementAddr, cfg.ManagementAddr)
+			assert.False(t, cfg.ManagementTls)
+			assert.Equal(t, common.DefaultProbeAddr, cfg.ProbeAddr)
+		})
+	})
+
+	t.Run("from env", func(t *testing.T) {
+		envPath := t.TempDir() + "/.env"
+		writeDotEnvFile(envPath, buildDotEnv(map[string]string{
+			"LISTEN_ADDR":     ":8443",
+			"MANAGEMENT_ADDR": ":9090",
+			"MANAGEMENT_TLS":  "true",
+			"PROBE_ADDR":      ":9091",
+		}))
+		withCleanEnv(func() {
+			for _, key := range keys {
+				_ = os.Unsetenv(key)
+			}
+			cfg := common.GetConfig(envPath)
+			assert.Equal(t, ":8443", cfg.ListenAddr)
+			assert.Equal(t, ":9090", cfg.ManagementAddr)
+			assert.True(t, cfg.ManagementTls)
+			assert.Equal(t, ":9091", cfg.ProbeAddr)
+		})
+	})
+}
+
+func Test_Config_AccessLogSampleRate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  float64
+	}{
+		{"not set", "", 1},
+		{"valid", "0.25", 0.25},
+		{"disabled", "0", 0},
+		{"out of range", "1.5", 1},
+		{"invalid", "abc", 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			envPath := t.TempDir() + "/.env"
+			writeDotEnvFile(envPath, buildDotEnv(map[string]string{"ACCESS_LOG_SAMPLE_RATE": tc.value}))
+
+			withCleanEnv(func() {
+				_ = os.Unsetenv("ACCESS_LOG_SAMPLE_RATE")
+				cfg := common.GetConfig(envPath)
+				assert.Equal(t, tc.want, cfg.AccessLogSampleRate)
+			})
+		})
+	}
+}
+
+func Test_Config_SoftDeletePurge(t *testing.T) {
+	keys := []string{"SOFT_DELETE_RETENTION", "PURGE_INTERVAL", "GRANT_EXPIRY_INTERVAL",
+		"ACCESS_REQUEST_SLA", "ACCESS_REQUEST_CHECK_INTERVAL",
+		"ACCESS_REVIEW_CHECK_INTERVAL", "ACCESS_REVIEW_REMINDER_INTERVAL", "ACCESS_REVIEW_SIGNING_KEY"}
+
+	t.Run("defaults", func(t *testing.T) {
+		envPath := t.TempDir() + "/.env"
+		writeDotEnvFile(envPath, "")
+		withCleanEnv(func() {
+			for _, key := range keys {
+				_ = os.Unsetenv(key)
+			}
+			cfg := common.GetConfig(envPath)
+			// без срока хранения очистка выключена
+			assert.Zero(t, cfg.SoftDeleteRetention)
+			assert.Equal(t, common.DefaultPurgeInterval, cfg.PurgeInterval)
+			assert.Equal(t, common.DefaultGrantExpiryInterval, cfg.GrantExpiryInterval)
+			assert.Equal(t, common.DefaultAccessRequestSla, cfg.AccessRequestSla)
+			assert.Equal(t, common.DefaultAccessRequestCheckInterval, cfg.AccessRequestCheckInterval)
+			assert.Equal(t, common.DefaultAccessReviewCheckInterval, cfg.AccessReviewCheckInterval)
+			assert.Equal(t, common.DefaultAccessReviewReminderInterval, cfg.AccessReviewReminderInterval)
+			// без ключа отчёты не подписываются
+			assert.Empty(t, cfg.AccessReviewSigningKey)
+		})
+	})
+
+	t.Run("from env", func(t *testing.T) {
+		envPath := t.TempDir() + "/.env"
+		writeDotEnvFile(envPath, buildDotEnv(map[string]string{
+			"SOFT_DELETE_RETENTION":           "720h",
+			"PURGE_INTERVAL":                  "bad",
+			"GRANT_EXPIRY_INTERVAL":           "30s",
+			"ACCESS_REQUEST_SLA":              "24h",
+			"ACCESS_REQUEST_CHECK_INTERVAL":   "-1m",
+			"ACCESS_REVIEW_CHECK_INTERVAL":    "5m",
+			"ACCESS_REVIEW_REMINDER_INTERVAL": "0",
+			"ACCESS_REVIEW_SIGNING_KEY":       "secret",
+		}))
+		withCleanEnv(func() {
+			for _, key := range keys {
+				_ = os.Unsetenv(key)
+			}
+			cfg := common.GetConfig(envPath)
+			assert.Equal(t, 720*time.Hour, cfg.SoftDeleteRetention)
+			assert.Equal(t, common.DefaultPurgeInterval, cfg.PurgeInterval)
+			assert.Equal(t, 30*time.Second, cfg.GrantExpiryInterval)
+			assert.Equal(t, 24*time.Hour, cfg.AccessRequestSla)
+			assert.Equal(t, common.DefaultAccessRequestCheckInterval, cfg.AccessRequestCheckInterval)
+			assert.Equal(t, 5*time.Minute, cfg.AccessReviewCheckInterval)
+			assert.Equal(t, common.DefaultAccessReviewReminderInterval, cfg.AccessReviewReminderInterval)
+			assert.Equal(t, "secret", cfg.AccessReviewSigningKey)
+		})
+	})
+}
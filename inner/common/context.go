@@ -0,0 +1,60 @@
+package common
+
+import "context"
+
+type requestIdKey struct{}
+
+// WithRequestId кладёт id запроса в контекст, чтобы он попадал в логи сервисов и репозиториев
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+// RequestIdFromContext возвращает id запроса из контекста или пустую строку
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
+
+// User - пользователь, от имени которого выполняется запрос
+type User struct {
+	// Subject - идентификатор пользователя в Keycloak (claim sub)
+	Subject string
+	// Username - логин пользователя (claim preferred_username)
+	Username string
+}
+
+type userKey struct{}
+
+// WithUser кладёт в контекст пользователя, прошедшего аутентификацию
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext возвращает пользователя из контекста; false - если запрос анонимный
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey{}).(User)
+	return user, ok
+}
+
+type clientIpKey struct{}
+
+// WithClientIp кладёт в контекст IP-адрес клиента, выполнившего запрос
+func WithClientIp(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIpKey{}, ip)
+}
+
+// ClientIpFromContext возвращает IP-адрес клиента из контекста или пустую строку
+func ClientIpFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIpKey{}).(string)
+	return ip
+}
+
+// ActorFromContext возвращает имя пользователя для служебных полей вроде deleted_by:
+// логин, если он есть в токене, иначе sub; пустая строка - для анонимных и фоновых операций
+func ActorFromContext(ctx context.Context) string {
+	user, _ := UserFromContext(ctx)
+	if user.Username != "" {
+		return user.Username
+	}
+	return user.Subject
+}
@@ -1,13 +1,31 @@
 package common
 
+// RequestValidationError - ошибка валидации запроса.
+// Fields содержит ошибки по отдельным полям, Err - исходную ошибку
 type RequestValidationError struct {
 	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+// FieldError - ошибка валидации отдельного поля запроса.
+// Messages хранит переводы сообщения по языкам, Message - сообщение на языке ответа
+type FieldError struct {
+	Field    string            `json:"field"`
+	Tag      string            `json:"tag"`
+	Param    string            `json:"param,omitempty"`
+	Message  string            `json:"message"`
+	Messages map[string]string `json:"-"`
 }
 
 func (err RequestValidationError) Error() string {
 	return err.Message
 }
 
+func (err RequestValidationError) Unwrap() error {
+	return err.Err
+}
+
 type AlreadyExistsError struct {
 	Message string
 }
@@ -15,3 +33,37 @@ type AlreadyExistsError struct {
 func (err AlreadyExistsError) Error() string {
 	return err.Message
 }
+
+type PreconditionFailedError struct {
+	Message string
+}
+
+func (err PreconditionFailedError) Error() string {
+	return err.Message
+}
+
+type NotFoundError struct {
+	Message string
+}
+
+func (err NotFoundError) Error() string {
+	return err.Message
+}
+
+// ConflictError - операция противоречит текущему состоянию сущности, например недопустимый переход статуса
+type ConflictError struct {
+	Message string
+}
+
+func (err ConflictError) Error() string {
+	return err.Message
+}
+
+// ForbiddenError - у пользователя нет прав на действие с конкретной сущностью, хотя сам маршрут ему доступен
+type ForbiddenError struct {
+	Message string
+}
+
+func (err ForbiddenError) Error() string {
+	return err.Message
+}
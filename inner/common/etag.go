@@ -0,0 +1,30 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETag формирует сильный ETag из времени последнего изменения записи (с точностью до микросекунд, как в Postgres)
+func ETag(updatedAt time.Time) string {
+	return fmt.Sprintf(`"%d"`, updatedAt.UnixMicro())
+}
+
+// ParseIfMatch разбирает значение заголовка If-Match.
+// Возвращает nil, если заголовок пустой или равен "*" (подходит любая версия),
+// и ошибку, если значение не является ETag, выданным функцией ETag.
+func ParseIfMatch(header string) (*time.Time, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	raw := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	micros, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, PreconditionFailedError{Message: "invalid If-Match header"}
+	}
+	version := time.UnixMicro(micros)
+	return &version, nil
+}
@@ -0,0 +1,16 @@
+package common
+
+// MissingIds возвращает id из requested, которых нет в found
+func MissingIds(requested []int64, found []int64) []int64 {
+	foundSet := make(map[int64]struct{}, len(found))
+	for _, id := range found {
+		foundSet[id] = struct{}{}
+	}
+	var missing []int64
+	for _, id := range requested {
+		if _, ok := foundSet[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,13 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MissingIds(t *testing.T) {
+	assert.Equal(t, []int64{9, 11}, MissingIds([]int64{7, 9, 8, 11}, []int64{8, 7}))
+	assert.Empty(t, MissingIds([]int64{7, 7}, []int64{7}))
+	assert.Empty(t, MissingIds(nil, []int64{7}))
+}
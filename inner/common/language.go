@@ -0,0 +1,46 @@
+package common
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	LanguageEn = "en"
+	LanguageRu = "ru"
+)
+
+// DefaultLanguage - язык сообщений, если клиент не указал поддерживаемый язык
+const DefaultLanguage = LanguageEn
+
+// SupportedLanguages - языки, на которые переводятся сообщения об ошибках
+var SupportedLanguages = []string{LanguageEn, LanguageRu}
+
+// PreferredLanguage выбирает язык из заголовка Accept-Language.
+// Учитывается только основной подтег (ru-RU -> ru), языки с q=0 пропускаются.
+// Берётся язык с наибольшим q, при равенстве - первый по порядку
+func PreferredLanguage(acceptLanguage string) string {
+	best, bestQuality := DefaultLanguage, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, quality := parseLanguageRange(part)
+		if quality <= bestQuality || !slices.Contains(SupportedLanguages, tag) {
+			continue
+		}
+		best, bestQuality = tag, quality
+	}
+	return best
+}
+
+func parseLanguageRange(part string) (string, float64) {
+	tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+	quality := 1.0
+	if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+		var err error
+		if quality, err = strconv.ParseFloat(q, 64); err != nil {
+			quality = 0
+		}
+	}
+	tag, _, _ = strings.Cut(tag, "-")
+	return strings.ToLower(strings.TrimSpace(tag)), quality
+}
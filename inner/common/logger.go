@@ -1,8 +1,11 @@
 package common
 
 import (
+	"context"
+
 	"github.com/gofiber/contrib/fiberzap/v2"
 	"github.com/gofiber/fiber/v2/log"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -10,6 +13,8 @@ import (
 // Logger структура логгера
 type Logger struct {
 	*zap.Logger
+	// уровень логирования, общий для логгера и всех производных от него через Ctx
+	level *LogLevel
 }
 
 // NewLogger функция-конструктор логгера
@@ -29,8 +34,9 @@ func NewLogger(cfg Config) *Logger {
 		EncodeCaller:     zapcore.ShortCallerEncoder,
 		ConsoleSeparator: "  ",
 	}
+	var level = newLogLevel(parseLogLevel(cfg.LogLevel))
 	var zapCfg = zap.Config{
-		Level:       zap.NewAtomicLevelAt(parseLogLevel(cfg.LogLevel)),
+		Level:       level.atomic,
 		Development: cfg.LogDevelopMode,
 		Sampling: &zap.SamplingConfig{
 			Initial:    100,
@@ -45,11 +51,38 @@ func NewLogger(cfg Config) *Logger {
 	}
 	var logger = zap.Must(zapCfg.Build())
 	logger.Info("logger construction succeeded")
-	var created = &Logger{logger}
+	var created = &Logger{Logger: logger, level: level}
 	created.setNewFiberZapLogger()
 	return created
 }
 
+// Ctx возвращает логгер, добавляющий к записям id запроса, пользователя и идентификаторы трассировки из ctx,
+// чтобы логи можно было сопоставить с запросами и трейсами
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	var fields []zap.Field
+	if requestId := RequestIdFromContext(ctx); requestId != "" {
+		fields = append(fields, zap.String("request_id", requestId))
+	}
+	if user, ok := UserFromContext(ctx); ok {
+		fields = append(fields, zap.String("sub", user.Subject), zap.String("preferred_username", user.Username))
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", spanCtx.TraceID().String()),
+			zap.String("span_id", spanCtx.SpanID().String()),
+		)
+	}
+	if len(fields) == 0 {
+		return l
+	}
+	return &Logger{Logger: l.With(fields...), level: l.level}
+}
+
+// Level возвращает управление уровнем логирования; nil, если логгер создан не через NewLogger
+func (l *Logger) Level() *LogLevel {
+	return l.level
+}
+
 // setNewFiberZapLogger устанавливает логгер для fiber
 func (l *Logger) setNewFiberZapLogger() {
 	var fiberzapLogger = fiberzap.NewLogger(fiberzap.LoggerConfig{
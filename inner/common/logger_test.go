@@ -0,0 +1,44 @@
+package common_test
+
+import (
+	"context"
+	"testing"
+
+	"idm/inner/common"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger_Ctx(t *testing.T) {
+	a := assert.New(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := &common.Logger{Logger: zap.New(core)}
+
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = common.WithRequestId(ctx, "req-1")
+	ctx = common.WithUser(ctx, common.User{Subject: "user-1", Username: "ivanov"})
+
+	logger.Ctx(ctx).Info("with context")
+	logger.Ctx(context.Background()).Info("without context")
+
+	entries := logs.AllUntimed()
+	a.Len(entries, 2)
+	a.Equal(map[string]any{
+		"request_id":         "req-1",
+		"sub":                "user-1",
+		"preferred_username": "ivanov",
+		"trace_id":           "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":            "00f067aa0ba902b7",
+	}, entries[0].ContextMap())
+	a.Empty(entries[1].ContextMap())
+}
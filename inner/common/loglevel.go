@@ -0,0 +1,76 @@
+package common
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogLevel - уровень логирования, который можно менять во время работы сервиса,
+// в том числе временно: по истечении ttl уровень возвращается к прежнему
+type LogLevel struct {
+	atomic zap.AtomicLevel
+
+	mu sync.Mutex
+	// отложенный возврат к уровню revertTo в момент revertAt
+	revertTimer *time.Timer
+	revertAt    time.Time
+	revertTo    zapcore.Level
+}
+
+// LogLevelState - текущий уровень логирования и запланированный возврат, если он есть
+type LogLevelState struct {
+	Level    string     `json:"level"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+	RevertTo string     `json:"revert_to,omitempty"`
+}
+
+func newLogLevel(level zapcore.Level) *LogLevel {
+	return &LogLevel{atomic: zap.NewAtomicLevelAt(level)}
+}
+
+// State возвращает текущий уровень логирования
+func (l *LogLevel) State() LogLevelState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := LogLevelState{Level: l.atomic.Level().String()}
+	if l.revertTimer != nil {
+		revertAt := l.revertAt
+		state.RevertAt = &revertAt
+		state.RevertTo = l.revertTo.String()
+	}
+	return state
+}
+
+// Set меняет уровень логирования. При ttl > 0 через ttl уровень вернётся к тому,
+// что был до первого временного изменения; при ttl = 0 изменение постоянное
+func (l *LogLevel) Set(level zapcore.Level, ttl time.Duration) LogLevelState {
+	l.mu.Lock()
+	// повторное временное изменение продлевает его, но возвращаемся всё равно к исходному уровню
+	revertTo := l.atomic.Level()
+	if l.revertTimer != nil {
+		l.revertTimer.Stop()
+		l.revertTimer = nil
+		revertTo = l.revertTo
+	}
+	l.atomic.SetLevel(level)
+	if ttl > 0 {
+		l.revertTo = revertTo
+		l.revertAt = time.Now().Add(ttl)
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			// таймер мог быть заменён новым изменением уровня
+			if l.revertTimer == timer {
+				l.atomic.SetLevel(l.revertTo)
+				l.revertTimer = nil
+			}
+		})
+		l.revertTimer = timer
+	}
+	l.mu.Unlock()
+	return l.State()
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogLevel_Set(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("permanent change", func(t *testing.T) {
+		level := newLogLevel(zapcore.InfoLevel)
+		state := level.Set(zapcore.DebugLevel, 0)
+		a.Equal(LogLevelState{Level: "debug"}, state)
+		a.True(level.atomic.Enabled(zapcore.DebugLevel))
+	})
+
+	t.Run("temporary change reverts after ttl", func(t *testing.T) {
+		level := newLogLevel(zapcore.InfoLevel)
+		state := level.Set(zapcore.DebugLevel, 20*time.Millisecond)
+		a.Equal("debug", state.Level)
+		a.Equal("info", state.RevertTo)
+		a.NotNil(state.RevertAt)
+
+		a.Eventually(func() bool {
+			return level.State() == LogLevelState{Level: "info"}
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("repeated temporary change keeps original level", func(t *testing.T) {
+		level := newLogLevel(zapcore.WarnLevel)
+		level.Set(zapcore.DebugLevel, time.Hour)
+		state := level.Set(zapcore.ErrorLevel, 20*time.Millisecond)
+		a.Equal("error", state.Level)
+		a.Equal("warn", state.RevertTo)
+
+		a.Eventually(func() bool {
+			return level.State().Level == "warn"
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("permanent change cancels revert", func(t *testing.T) {
+		level := newLogLevel(zapcore.InfoLevel)
+		level.Set(zapcore.DebugLevel, 20*time.Millisecond)
+		level.Set(zapcore.ErrorLevel, 0)
+
+		time.Sleep(50 * time.Millisecond)
+		a.Equal(LogLevelState{Level: "error"}, level.State())
+	})
+}
@@ -0,0 +1,36 @@
+package common
+
+import "encoding/json"
+
+// MergePatch применяет JSON Merge Patch (RFC 7386) к исходному JSON-документу
+func MergePatch(original []byte, patch []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(original, &target); err != nil {
+		return nil, err
+	}
+	var p any
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, RequestValidationError{Message: "invalid merge patch", Err: err}
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target any, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		// не-объект заменяет исходное значение целиком
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergeValue(targetObj[k], v)
+	}
+	return targetObj
+}
@@ -0,0 +1,57 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MergePatch_TableDriven(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+		want     string
+		wantErr  bool
+	}{
+		{name: "replace field", original: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add field", original: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "remove field", original: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "nested merge", original: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"d":null,"f":"g"}}`, want: `{"a":{"b":"c","f":"g"}}`},
+		{name: "array replaced", original: `{"a":[1,2]}`, patch: `{"a":[3]}`, want: `{"a":[3]}`},
+		{name: "empty patch", original: `{"a":"b"}`, patch: `{}`, want: `{"a":"b"}`},
+		{name: "invalid patch", original: `{"a":"b"}`, patch: `{`, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tc.original), []byte(tc.patch))
+			if tc.wantErr {
+				assert.ErrorAs(t, err, &RequestValidationError{})
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+}
+
+func Test_ETag_RoundTrip(t *testing.T) {
+	updatedAt := time.Date(2025, 6, 24, 12, 0, 0, 123456000, time.UTC)
+
+	version, err := ParseIfMatch(ETag(updatedAt))
+	assert.NoError(t, err)
+	assert.True(t, version.Equal(updatedAt))
+
+	version, err = ParseIfMatch("W/" + ETag(updatedAt))
+	assert.NoError(t, err)
+	assert.True(t, version.Equal(updatedAt))
+
+	version, err = ParseIfMatch("*")
+	assert.NoError(t, err)
+	assert.Nil(t, version)
+
+	_, err = ParseIfMatch(`"not-a-version"`)
+	assert.ErrorAs(t, err, &PreconditionFailedError{})
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Periodic выполняет run в фоне: первый проход сразу после Start, следующие - раз в interval.
+// Фоновые обработчики встраивают его и реализуют только сам проход
+type Periodic struct {
+	interval time.Duration
+	run      func(ctx context.Context)
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func NewPeriodic(interval time.Duration, run func(ctx context.Context)) *Periodic {
+	return &Periodic{
+		interval: interval,
+		run:      run,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start запускает проходы в фоне; ctx прохода отменяется при Stop
+func (p *Periodic) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			p.run(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop останавливает проходы и ждёт завершения текущего не дольше, чем позволяет ctx.
+// Подходит для web.Server.OnShutdown
+func (p *Periodic) Stop(ctx context.Context) error {
+	if p.cancel == nil {
+		return nil
+	}
+	p.stopOnce.Do(p.cancel)
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
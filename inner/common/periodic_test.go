@@ -0,0 +1,61 @@
+package common
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeriodic_StartStop(t *testing.T) {
+	a := assert.New(t)
+	var calls atomic.Int32
+	periodic := NewPeriodic(time.Minute, func(ctx context.Context) { calls.Add(1) })
+
+	// до запуска останавливать нечего
+	a.NoError(periodic.Stop(context.Background()))
+
+	periodic.Start()
+	a.Eventually(func() bool { return calls.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	a.NoError(periodic.Stop(ctx))
+	// повторная остановка безопасна
+	a.NoError(periodic.Stop(ctx))
+	a.Equal(int32(1), calls.Load())
+}
+
+func TestPeriodic_StopCancelsRun(t *testing.T) {
+	a := assert.New(t)
+	started := make(chan struct{})
+	periodic := NewPeriodic(time.Minute, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+	periodic.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	a.NoError(periodic.Stop(ctx))
+}
+
+func TestPeriodic_StopTimeout(t *testing.T) {
+	a := assert.New(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	periodic := NewPeriodic(time.Minute, func(ctx context.Context) {
+		close(started)
+		<-release
+	})
+	periodic.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	// проход не учитывает отмену - Stop возвращает ошибку контекста, не дожидаясь его
+	a.ErrorIs(periodic.Stop(ctx), context.DeadlineExceeded)
+	close(release)
+}
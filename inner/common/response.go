@@ -1,12 +1,41 @@
 package common
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// ProblemContentType - тип содержимого ошибок в формате RFC 7807.
+// Клиент включает этот формат заголовком Accept: application/problem+json
+const ProblemContentType = "application/problem+json"
+
+// internalErrorMessage возвращается клиенту вместо текста внутренних ошибок
+const internalErrorMessage = "internal server error"
+
+// InvalidBodyMessage возвращается клиенту, если тело запроса не удалось разобрать;
+// подробности разбора только логируются
+const InvalidBodyMessage = "invalid request body"
 
 // Response[T] generic response
 type Response[T any] struct {
-	Success bool   `json:"success"`
-	Message string `json:"error"`
-	Data    T      `json:"data"`
+	Success bool         `json:"success"`
+	Message string       `json:"error"`
+	Errors  []FieldError `json:"errors,omitempty"`
+	Data    T            `json:"data"`
+}
+
+// Problem - описание ошибки в формате RFC 7807
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestId string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
 }
 
 func ErrResponse(
@@ -14,9 +43,36 @@ func ErrResponse(
 	code int,
 	message string,
 ) error {
+	if acceptsProblem(c) {
+		return problemResponse(c, code, message, nil)
+	}
+	return c.Status(code).JSON(&Response[any]{
+		Success: false,
+		Message: message,
+		Data:    nil,
+	})
+}
+
+// ServiceErrResponse формирует ответ по ошибке бизнес-логики.
+// Статус определяется через ErrorStatus, текст внутренних (5xx) ошибок клиенту не передаётся -
+// его нужно логировать до вызова
+func ServiceErrResponse(
+	c *fiber.Ctx,
+	err error,
+) error {
+	code := ErrorStatus(err)
+	message := err.Error()
+	if code >= fiber.StatusInternalServerError {
+		message = internalErrorMessage
+	}
+	fields := localizedFieldErrors(err, PreferredLanguage(c.Get(fiber.HeaderAcceptLanguage)))
+	if acceptsProblem(c) {
+		return problemResponse(c, code, message, fields)
+	}
 	return c.Status(code).JSON(&Response[any]{
 		Success: false,
 		Message: message,
+		Errors:  fields,
 		Data:    nil,
 	})
 }
@@ -30,3 +86,49 @@ func OkResponse[T any](
 		Data:    data,
 	})
 }
+
+// acceptsProblem проверяет, что клиент явно запросил формат application/problem+json
+func acceptsProblem(c *fiber.Ctx) bool {
+	return strings.Contains(c.Get(fiber.HeaderAccept), ProblemContentType)
+}
+
+func problemResponse(
+	c *fiber.Ctx,
+	code int,
+	detail string,
+	fields []FieldError,
+) error {
+	return c.Status(code).JSON(&Problem{
+		Type:      "about:blank",
+		Title:     utils.StatusMessage(code),
+		Status:    code,
+		Detail:    detail,
+		Instance:  c.OriginalURL(),
+		RequestId: RequestId(c),
+		Errors:    fields,
+	}, ProblemContentType)
+}
+
+// RequestId достаёт id запроса, выставленный мидлваром requestid
+func RequestId(c *fiber.Ctx) string {
+	if rid, ok := c.Locals("requestid").(string); ok && rid != "" {
+		return rid
+	}
+	return c.GetRespHeader(fiber.HeaderXRequestID)
+}
+
+// localizedFieldErrors возвращает ошибки по полям из RequestValidationError с сообщениями на языке lang
+func localizedFieldErrors(err error, lang string) []FieldError {
+	var validationErr RequestValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) == 0 {
+		return nil
+	}
+	fields := make([]FieldError, 0, len(validationErr.Fields))
+	for _, field := range validationErr.Fields {
+		if msg, ok := field.Messages[lang]; ok {
+			field.Message = msg
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
@@ -0,0 +1,148 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newProblemTestApp(err error) *fiber.App {
+	app := fiber.New()
+	app.Use(requestid.New(requestid.Config{Generator: func() string { return "rid-1" }}))
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return ServiceErrResponse(c, err)
+	})
+	return app
+}
+
+func TestServiceErrResponse(t *testing.T) {
+	a := assert.New(t)
+
+	validationErr := RequestValidationError{
+		Message: "Key: 'CreateRequest.name' Error:Field validation for 'name' failed on the 'required' tag",
+		Fields: []FieldError{{
+			Field:    "name",
+			Tag:      "required",
+			Message:  "name is a required field",
+			Messages: map[string]string{LanguageEn: "name is a required field", LanguageRu: "name обязательное поле"},
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		accept     string
+		language   string
+		wantStatus int
+		wantType   string
+		wantTitle  string
+		wantDetail string
+		wantFields []FieldError
+	}{
+		{
+			name:       "legacy format hides internal error",
+			err:        errors.New("pq: relation \"employee\" does not exist"),
+			wantStatus: fiber.StatusInternalServerError,
+			wantType:   fiber.MIMEApplicationJSON,
+			wantDetail: internalErrorMessage,
+		},
+		{
+			name:       "problem format hides internal error",
+			err:        errors.New("pq: relation \"employee\" does not exist"),
+			accept:     ProblemContentType,
+			wantStatus: fiber.StatusInternalServerError,
+			wantType:   ProblemContentType,
+			wantTitle:  "Internal Server Error",
+			wantDetail: internalErrorMessage,
+		},
+		{
+			name:       "problem format keeps business error text",
+			err:        NotFoundError{Message: "employee with id 1 not found"},
+			accept:     ProblemContentType,
+			wantStatus: fiber.StatusNotFound,
+			wantType:   ProblemContentType,
+			wantTitle:  "Not Found",
+			wantDetail: "employee with id 1 not found",
+		},
+		{
+			name:       "problem format lists field errors",
+			err:        validationErr,
+			accept:     "application/problem+json, application/json",
+			wantStatus: fiber.StatusBadRequest,
+			wantType:   ProblemContentType,
+			wantTitle:  "Bad Request",
+			wantDetail: validationErr.Error(),
+			wantFields: []FieldError{{Field: "name", Tag: "required", Message: "name is a required field"}},
+		},
+		{
+			name:       "legacy format lists translated field errors",
+			err:        validationErr,
+			language:   "ru-RU,ru;q=0.9,en;q=0.8",
+			wantStatus: fiber.StatusBadRequest,
+			wantType:   fiber.MIMEApplicationJSON,
+			wantDetail: validationErr.Error(),
+			wantFields: []FieldError{{Field: "name", Tag: "required", Message: "name обязательное поле"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/fail?x=1", nil)
+			if tt.accept != "" {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+			if tt.language != "" {
+				req.Header.Set(fiber.HeaderAcceptLanguage, tt.language)
+			}
+			resp, err := newProblemTestApp(tt.err).Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+			a.Contains(resp.Header.Get(fiber.HeaderContentType), tt.wantType)
+
+			data, _ := io.ReadAll(resp.Body)
+			if tt.wantType != ProblemContentType {
+				var body Response[any]
+				a.NoError(json.Unmarshal(data, &body))
+				a.False(body.Success)
+				a.Equal(tt.wantDetail, body.Message)
+				a.Equal(tt.wantFields, body.Errors)
+				return
+			}
+			var problem Problem
+			a.NoError(json.Unmarshal(data, &problem))
+			a.Equal("about:blank", problem.Type)
+			a.Equal(tt.wantStatus, problem.Status)
+			a.Equal(tt.wantTitle, problem.Title)
+			a.Equal(tt.wantDetail, problem.Detail)
+			a.Equal("/fail?x=1", problem.Instance)
+			a.Equal("rid-1", problem.RequestId)
+			a.Equal(tt.wantFields, problem.Errors)
+		})
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", LanguageEn},
+		{"ru", LanguageRu},
+		{"ru-RU,ru;q=0.9,en-US;q=0.8", LanguageRu},
+		{"de-DE,de;q=0.9", LanguageEn},
+		{"en;q=0.5,ru;q=0.8", LanguageRu},
+		{"ru;q=0", LanguageEn},
+		{"fr, RU-ru;q=0.3", LanguageRu},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.want, PreferredLanguage(tt.header))
+		})
+	}
+}
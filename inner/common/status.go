@@ -0,0 +1,26 @@
+package common
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrorStatus сопоставляет ошибку бизнес-логики HTTP-статусу ответа.
+// Ошибки сравниваются через errors.As, поэтому обёрнутые через %w ошибки тоже распознаются.
+func ErrorStatus(err error) int {
+	switch {
+	case errors.As(err, &NotFoundError{}):
+		return fiber.StatusNotFound
+	case errors.As(err, &ForbiddenError{}):
+		return fiber.StatusForbidden
+	case errors.As(err, &PreconditionFailedError{}):
+		return fiber.StatusPreconditionFailed
+	case errors.As(err, &ConflictError{}):
+		return fiber.StatusConflict
+	case errors.As(err, &RequestValidationError{}) || errors.As(err, &AlreadyExistsError{}):
+		return fiber.StatusBadRequest
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
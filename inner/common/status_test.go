@@ -0,0 +1,33 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ErrorStatus_TableDriven(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", NotFoundError{Message: "x"}, fiber.StatusNotFound},
+		{"wrapped not found", fmt.Errorf("finding: %w", NotFoundError{Message: "x"}), fiber.StatusNotFound},
+		{"validation", RequestValidationError{Message: "x"}, fiber.StatusBadRequest},
+		{"already exists", AlreadyExistsError{Message: "x"}, fiber.StatusBadRequest},
+		{"precondition failed", PreconditionFailedError{Message: "x"}, fiber.StatusPreconditionFailed},
+		{"forbidden", ForbiddenError{Message: "x"}, fiber.StatusForbidden},
+		{"wrapped conflict", fmt.Errorf("changing status: %w", ConflictError{Message: "x"}), fiber.StatusConflict},
+		{"unknown", errors.New("x"), fiber.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ErrorStatus(tc.err))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TxBeginner открывает транзакцию; реализуется репозиториями
+type TxBeginner interface {
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+}
+
+// InTransaction выполняет fn в транзакции: коммитит при успехе и откатывает при ошибке или панике.
+// operation попадает в текст ошибок транзакции
+func InTransaction(ctx context.Context, db TxBeginner, operation string, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := db.BeginTransaction(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: error creating transaction: %w", operation, err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s panic: %v", operation, r)
+			if errTx := tx.Rollback(); errTx != nil {
+				err = fmt.Errorf("%s: rolling back transaction errors: %w, %w", operation, err, errTx)
+			}
+		} else if err != nil {
+			if errTx := tx.Rollback(); errTx != nil {
+				err = fmt.Errorf("%s: rolling back transaction errors: %w, %w", operation, err, errTx)
+			}
+		} else {
+			if errTx := tx.Commit(); errTx != nil {
+				err = fmt.Errorf("%s: commiting transaction error: %w", operation, errTx)
+			}
+		}
+	}()
+	return fn(tx)
+}
+
+// FoundRows - число строк для спана запроса, читающего или пишущего одну строку
+func FoundRows(err error) int64 {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
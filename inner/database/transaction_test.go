@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+type sqlxBeginner struct {
+	db *sqlx.DB
+}
+
+func (b sqlxBeginner) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	return b.db.BeginTxx(ctx, nil)
+}
+
+func newBeginner(t *testing.T) (sqlxBeginner, sqlmock.Sqlmock) {
+	dbMock, m, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = dbMock.Close() })
+	return sqlxBeginner{db: sqlx.NewDb(dbMock, "postgres")}, m
+}
+
+func TestInTransaction(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("commits on success", func(t *testing.T) {
+		db, m := newBeginner(t)
+		m.ExpectBegin()
+		m.ExpectCommit()
+
+		a.NoError(InTransaction(context.Background(), db, "op", func(tx *sqlx.Tx) error { return nil }))
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back on error", func(t *testing.T) {
+		db, m := newBeginner(t)
+		m.ExpectBegin()
+		m.ExpectRollback()
+		fail := errors.New("fail")
+
+		err := InTransaction(context.Background(), db, "op", func(tx *sqlx.Tx) error { return fail })
+		a.ErrorIs(err, fail)
+		a.NoError(m.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back on panic", func(t *testing.T) {
+		db, m := newBeginner(t)
+		m.ExpectBegin()
+		m.ExpectRollback()
+
+		err := InTransaction(context.Background(), db, "op", func(tx *sqlx.Tx) error { panic("boom") })
+		a.ErrorContains(err, "op panic: boom")
+		a.NoError(m.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,359 @@
+package employee
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// типы дополнительных атрибутов сотрудника
+const (
+	AttributeString  = "string"
+	AttributeInteger = "integer"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+	AttributeDate    = "date"
+)
+
+// attributeFieldPrefix - префикс имени поля в ошибках валидации атрибутов
+const attributeFieldPrefix = "attributes."
+
+// attributeNamePattern - имена атрибутов используются в параметрах запроса attr.<name>, поэтому ограничены
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// Attributes - значения дополнительных атрибутов сотрудника, хранятся в JSONB
+type Attributes map[string]any
+
+// Value сериализует атрибуты для записи в БД; nil записывается как пустой объект
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]any(a))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan читает атрибуты из JSONB
+func (a *Attributes) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(value, a)
+	case string:
+		return json.Unmarshal([]byte(value), a)
+	}
+	return fmt.Errorf("unsupported attributes type %T", src)
+}
+
+// AttributeSchema - описание дополнительных атрибутов сотрудника из конфигурации.
+// Атрибуты, не описанные в схеме, не принимаются; пустая схема запрещает любые атрибуты
+type AttributeSchema struct {
+	Attributes []AttributeDefinition `json:"attributes" yaml:"attributes"`
+
+	byName map[string]*AttributeDefinition
+}
+
+// AttributeDefinition - тип и правила проверки одного атрибута
+type AttributeDefinition struct {
+	Name     string `json:"name" yaml:"name"`
+	Type     string `json:"type" yaml:"type"`
+	Required bool   `json:"required,omitempty" yaml:"required"`
+	// Min и Max - границы значения для чисел и длины для строк
+	Min *float64 `json:"min,omitempty" yaml:"min"`
+	Max *float64 `json:"max,omitempty" yaml:"max"`
+	// Pattern - регулярное выражение, которому должна соответствовать строка
+	Pattern string `json:"pattern,omitempty" yaml:"pattern"`
+	// Enum - допустимые значения строки
+	Enum []string `json:"enum,omitempty" yaml:"enum"`
+
+	pattern *regexp.Regexp
+}
+
+// LoadAttributeSchema читает схему атрибутов из файла; формат определяется по расширению (.json, иначе YAML)
+func LoadAttributeSchema(path string) (AttributeSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return AttributeSchema{}, fmt.Errorf("reading attribute schema file: %w", err)
+	}
+	return ParseAttributeSchema(data, strings.EqualFold(filepath.Ext(path), ".json"))
+}
+
+// ParseAttributeSchema разбирает схему атрибутов в формате JSON или YAML и проверяет описания.
+// Неизвестные поля считаются ошибкой, чтобы опечатка в правиле не отключила проверку
+func ParseAttributeSchema(data []byte, isJson bool) (AttributeSchema, error) {
+	var schema AttributeSchema
+	if isJson {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&schema); err != nil {
+			return AttributeSchema{}, fmt.Errorf("parsing attribute schema: %w", err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&schema); err != nil {
+			return AttributeSchema{}, fmt.Errorf("parsing attribute schema: %w", err)
+		}
+	}
+	schema.byName = make(map[string]*AttributeDefinition, len(schema.Attributes))
+	for i := range schema.Attributes {
+		definition := &schema.Attributes[i]
+		if err := definition.compile(); err != nil {
+			return AttributeSchema{}, fmt.Errorf("attribute #%d: %w", i+1, err)
+		}
+		if _, ok := schema.byName[definition.Name]; ok {
+			return AttributeSchema{}, fmt.Errorf("attribute %q is declared twice", definition.Name)
+		}
+		schema.byName[definition.Name] = definition
+	}
+	return schema, nil
+}
+
+// compile проверяет описание атрибута и компилирует шаблон
+func (d *AttributeDefinition) compile() error {
+	if !attributeNamePattern.MatchString(d.Name) {
+		return fmt.Errorf("name %q must match %s", d.Name, attributeNamePattern)
+	}
+	switch d.Type {
+	case AttributeString:
+	case AttributeInteger, AttributeNumber, AttributeBoolean, AttributeDate:
+		if d.Pattern != "" || len(d.Enum) > 0 {
+			return fmt.Errorf("attribute %q: pattern and enum are allowed only for strings", d.Name)
+		}
+	default:
+		return fmt.Errorf("attribute %q: unknown type %q", d.Name, d.Type)
+	}
+	if (d.Type == AttributeBoolean || d.Type == AttributeDate) && (d.Min != nil || d.Max != nil) {
+		return fmt.Errorf("attribute %q: min and max are not allowed for %s", d.Name, d.Type)
+	}
+	if d.Min != nil && d.Max != nil && *d.Min > *d.Max {
+		return fmt.Errorf("attribute %q: min is greater than max", d.Name)
+	}
+	if d.Pattern != "" {
+		pattern, err := regexp.Compile(d.Pattern)
+		if err != nil {
+			return fmt.Errorf("attribute %q: %w", d.Name, err)
+		}
+		d.pattern = pattern
+	}
+	return nil
+}
+
+// validate проверяет атрибуты по схеме и возвращает RequestValidationError с ошибками по каждому атрибуту.
+// Атрибуты со значением null считаются незаданными и удаляются
+func (s *AttributeSchema) validate(attributes Attributes) error {
+	var fields []common.FieldError
+	for _, name := range sortedKeys(attributes) {
+		value := attributes[name]
+		if value == nil {
+			delete(attributes, name)
+			continue
+		}
+		definition, ok := s.byName[name]
+		if !ok {
+			fields = append(fields, attributeError(name, "unknown", "",
+				"%s is not a known attribute", "%s не является известным атрибутом"))
+			continue
+		}
+		if field, ok := definition.check(value); !ok {
+			fields = append(fields, field)
+		}
+	}
+	for i := range s.Attributes {
+		definition := &s.Attributes[i]
+		if _, ok := attributes[definition.Name]; definition.Required && !ok {
+			fields = append(fields, attributeError(definition.Name, "required", "",
+				"%s is a required field", "%s обязательное поле"))
+		}
+	}
+	return validationError(fields)
+}
+
+// parseFilter приводит значения фильтра по атрибутам к типам из схемы.
+// Значения из строки запроса приходят строками, поэтому "42" для integer становится числом
+func (s *AttributeSchema) parseFilter(filter Attributes) (Attributes, error) {
+	if len(filter) == 0 {
+		return nil, nil
+	}
+	parsed := make(Attributes, len(filter))
+	var fields []common.FieldError
+	for _, name := range sortedKeys(filter) {
+		definition, ok := s.byName[name]
+		if !ok {
+			fields = append(fields, attributeError(name, "unknown", "",
+				"%s is not a known attribute", "%s не является известным атрибутом"))
+			continue
+		}
+		value := filter[name]
+		if text, isText := value.(string); isText {
+			value = definition.parse(text)
+		}
+		if _, ok := definition.typeOf(value); !ok {
+			fields = append(fields, definition.typeError())
+			continue
+		}
+		parsed[name] = value
+	}
+	if err := validationError(fields); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// parse разбирает строковое значение по типу атрибута; если строка не разбирается, она возвращается как есть
+func (d *AttributeDefinition) parse(text string) any {
+	switch d.Type {
+	case AttributeInteger:
+		if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return float64(value)
+		}
+	case AttributeNumber:
+		if value, err := strconv.ParseFloat(text, 64); err == nil {
+			return value
+		}
+	case AttributeBoolean:
+		if value, err := strconv.ParseBool(text); err == nil {
+			return value
+		}
+	}
+	return text
+}
+
+// check проверяет значение атрибута; при ошибке возвращает описание ошибки поля
+func (d *AttributeDefinition) check(value any) (common.FieldError, bool) {
+	number, ok := d.typeOf(value)
+	if !ok {
+		return d.typeError(), false
+	}
+	if d.Type == AttributeString {
+		text := value.(string)
+		length := float64(len([]rune(text)))
+		if d.Min != nil && length < *d.Min {
+			return attributeError(d.Name, "min", formatLimit(*d.Min),
+				"%s must be at least %s characters in length", "%s должен содержать минимум %s символов"), false
+		}
+		if d.Max != nil && length > *d.Max {
+			return attributeError(d.Name, "max", formatLimit(*d.Max),
+				"%s must be a maximum of %s characters in length", "%s должен содержать максимум %s символов"), false
+		}
+		if d.pattern != nil && !d.pattern.MatchString(text) {
+			return attributeError(d.Name, "pattern", d.Pattern,
+				"%s must match pattern %s", "%s должен соответствовать шаблону %s"), false
+		}
+		if len(d.Enum) > 0 && !slices.Contains(d.Enum, text) {
+			return attributeError(d.Name, "oneof", strings.Join(d.Enum, " "),
+				"%s must be one of [%s]", "%s должен быть одним из [%s]"), false
+		}
+		return common.FieldError{}, true
+	}
+	if d.Min != nil && number < *d.Min {
+		return attributeError(d.Name, "min", formatLimit(*d.Min),
+			"%s must be %s or greater", "%s должен быть больше или равен %s"), false
+	}
+	if d.Max != nil && number > *d.Max {
+		return attributeError(d.Name, "max", formatLimit(*d.Max),
+			"%s must be %s or less", "%s должен быть меньше или равен %s"), false
+	}
+	return common.FieldError{}, true
+}
+
+// typeOf проверяет, что значение соответствует типу атрибута; для чисел возвращает само число
+func (d *AttributeDefinition) typeOf(value any) (float64, bool) {
+	switch d.Type {
+	case AttributeString:
+		_, ok := value.(string)
+		return 0, ok
+	case AttributeDate:
+		text, ok := value.(string)
+		if !ok {
+			return 0, false
+		}
+		_, err := time.Parse(dateLayout, text)
+		return 0, err == nil
+	case AttributeBoolean:
+		_, ok := value.(bool)
+		return 0, ok
+	}
+	// числа из JSON приходят как float64
+	number, ok := value.(float64)
+	if !ok || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, false
+	}
+	if d.Type == AttributeInteger && number != math.Trunc(number) {
+		return 0, false
+	}
+	return number, true
+}
+
+func (d *AttributeDefinition) typeError() common.FieldError {
+	return attributeError(d.Name, "type", d.Type, "%s must be of type %s", "%s должен иметь тип %s")
+}
+
+// attributeError формирует ошибку атрибута с сообщениями на всех поддерживаемых языках.
+// Шаблоны сообщений получают имя поля и, если задан, параметр правила
+func attributeError(name, tag, param, en, ru string) common.FieldError {
+	field := attributeFieldPrefix + name
+	args := []any{field}
+	if param != "" {
+		args = append(args, param)
+	}
+	messages := map[string]string{
+		common.LanguageEn: fmt.Sprintf(en, args...),
+		common.LanguageRu: fmt.Sprintf(ru, args...),
+	}
+	return common.FieldError{
+		Field:    field,
+		Tag:      tag,
+		Param:    param,
+		Message:  messages[common.DefaultLanguage],
+		Messages: messages,
+	}
+}
+
+// validationError собирает ошибки атрибутов в RequestValidationError; без ошибок возвращает nil
+func validationError(fields []common.FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field.Messages[common.LanguageEn])
+	}
+	return common.RequestValidationError{
+		Message: strings.Join(messages, "; "),
+		Fields:  fields,
+		Err:     errors.New("invalid attributes"),
+	}
+}
+
+func formatLimit(limit float64) string {
+	return strconv.FormatFloat(limit, 'f', -1, 64)
+}
+
+func sortedKeys(attributes Attributes) []string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,157 @@
+package employee
+
+import (
+	"errors"
+	"testing"
+
+	"idm/inner/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testAttributeSchema(t *testing.T) AttributeSchema {
+	schema, err := LoadAttributeSchema("../../employee_attributes.example.yaml")
+	assert.NoError(t, err)
+	return schema
+}
+
+func TestParseAttributeSchema(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("example", func(t *testing.T) {
+		schema := testAttributeSchema(t)
+		a.Len(schema.Attributes, 6)
+		a.Equal(AttributeInteger, schema.byName["clearance_level"].Type)
+		a.Equal(5.0, *schema.byName["clearance_level"].Max)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		schema, err := ParseAttributeSchema([]byte(`{"attributes":[{"name":"site","type":"string","required":true}]}`), true)
+		a.NoError(err)
+		a.True(schema.byName["site"].Required)
+	})
+
+	errorCases := []struct {
+		name   string
+		data   string
+		isJson bool
+	}{
+		{"unknown field", "attributes:\n  - { name: site, type: string, regex: '^a' }", false},
+		{"unknown json field", `{"attributes":[{"name":"site","kind":"string"}]}`, true},
+		{"unknown type", "attributes:\n  - { name: site, type: text }", false},
+		{"invalid name", "attributes:\n  - { name: Site.Code, type: string }", false},
+		{"duplicate", "attributes:\n  - { name: site, type: string }\n  - { name: site, type: integer }", false},
+		{"invalid pattern", "attributes:\n  - { name: site, type: string, pattern: '[' }", false},
+		{"enum for number", "attributes:\n  - { name: level, type: integer, enum: ['1'] }", false},
+		{"min for boolean", "attributes:\n  - { name: remote, type: boolean, min: 1 }", false},
+		{"min greater than max", "attributes:\n  - { name: level, type: integer, min: 5, max: 1 }", false},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAttributeSchema([]byte(tt.data), tt.isJson)
+			a.Error(err)
+		})
+	}
+}
+
+func TestAttributeSchema_Validate(t *testing.T) {
+	schema := testAttributeSchema(t)
+	required, err := ParseAttributeSchema([]byte("attributes:\n  - { name: site, type: string, required: true }"), false)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		schema     AttributeSchema
+		attributes Attributes
+		wantField  string
+		wantTag    string
+	}{
+		{name: "valid", schema: schema, attributes: Attributes{
+			"cost_center": "CC-0042", "contract_type": "permanent", "clearance_level": 3.0,
+			"fte": 0.5, "remote": true, "badge_expires": "2026-01-31",
+		}},
+		{name: "no attributes", schema: schema},
+		{name: "null is dropped", schema: schema, attributes: Attributes{"remote": nil}},
+		{name: "unknown", schema: schema, attributes: Attributes{"shoe_size": 42.0},
+			wantField: "attributes.shoe_size", wantTag: "unknown"},
+		{name: "any attribute without schema", schema: AttributeSchema{}, attributes: Attributes{"remote": true},
+			wantField: "attributes.remote", wantTag: "unknown"},
+		{name: "wrong type", schema: schema, attributes: Attributes{"remote": "yes"},
+			wantField: "attributes.remote", wantTag: "type"},
+		{name: "fraction for integer", schema: schema, attributes: Attributes{"clearance_level": 1.5},
+			wantField: "attributes.clearance_level", wantTag: "type"},
+		{name: "malformed date", schema: schema, attributes: Attributes{"badge_expires": "31.01.2026"},
+			wantField: "attributes.badge_expires", wantTag: "type"},
+		{name: "above max", schema: schema, attributes: Attributes{"clearance_level": 6.0},
+			wantField: "attributes.clearance_level", wantTag: "max"},
+		{name: "below min", schema: schema, attributes: Attributes{"fte": 0.0},
+			wantField: "attributes.fte", wantTag: "min"},
+		{name: "pattern", schema: schema, attributes: Attributes{"cost_center": "42"},
+			wantField: "attributes.cost_center", wantTag: "pattern"},
+		{name: "enum", schema: schema, attributes: Attributes{"contract_type": "intern"},
+			wantField: "attributes.contract_type", wantTag: "oneof"},
+		{name: "required", schema: required, attributes: Attributes{},
+			wantField: "attributes.site", wantTag: "required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schema.validate(tt.attributes)
+			if tt.wantTag == "" {
+				assert.NoError(t, err)
+				// null означает отсутствие атрибута и в БД не попадает
+				for _, value := range tt.attributes {
+					assert.NotNil(t, value)
+				}
+				return
+			}
+			var validationErr common.RequestValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			assert.Len(t, validationErr.Fields, 1)
+			assert.Equal(t, tt.wantField, validationErr.Fields[0].Field)
+			assert.Equal(t, tt.wantTag, validationErr.Fields[0].Tag)
+			assert.NotEmpty(t, validationErr.Fields[0].Messages[common.LanguageRu])
+		})
+	}
+}
+
+func TestAttributeSchema_ParseFilter(t *testing.T) {
+	a := assert.New(t)
+	schema := testAttributeSchema(t)
+
+	filter, err := schema.parseFilter(Attributes{
+		"clearance_level": "3", "fte": "0.5", "remote": "true", "cost_center": "CC-0042",
+	})
+	a.NoError(err)
+	// значения из строки запроса приводятся к типам атрибутов, чтобы совпасть с JSONB
+	a.Equal(Attributes{"clearance_level": 3.0, "fte": 0.5, "remote": true, "cost_center": "CC-0042"}, filter)
+
+	filter, err = schema.parseFilter(nil)
+	a.NoError(err)
+	a.Nil(filter)
+
+	_, err = schema.parseFilter(Attributes{"clearance_level": "high", "shoe_size": "42"})
+	var validationErr common.RequestValidationError
+	a.True(errors.As(err, &validationErr))
+	a.Len(validationErr.Fields, 2)
+	a.Equal("type", validationErr.Fields[0].Tag)
+	a.Equal("unknown", validationErr.Fields[1].Tag)
+}
+
+func TestAttributes_ValueScan(t *testing.T) {
+	a := assert.New(t)
+
+	value, err := Attributes(nil).Value()
+	a.NoError(err)
+	a.Equal("{}", value)
+
+	value, err = Attributes{"remote": true}.Value()
+	a.NoError(err)
+	a.Equal(`{"remote":true}`, value)
+
+	var scanned Attributes
+	a.NoError(scanned.Scan([]byte(`{"clearance_level": 3, "remote": false}`)))
+	a.Equal(Attributes{"clearance_level": 3.0, "remote": false}, scanned)
+	a.NoError(scanned.Scan(nil))
+	a.Nil(scanned)
+	a.Error(scanned.Scan(42))
+}
@@ -1,140 +1,97 @@
 package employee
 
 import (
-	"errors"
-
+	"context"
 	"idm/inner/common"
+	"idm/inner/role"
 	"idm/inner/web"
 	"strconv"
-
-	"github.com/gofiber/fiber/v2"
-
 	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
 type Controller struct {
 	server          *web.Server
 	employeeService Svc
+	roleService     RoleSvc
 	logger          *common.Logger
 }
 
 // Svc описывает набор методов бизнес-логики по работе с сотрудниками
 type Svc interface {
-	FindById(id int64) (Response, error)
-	Add(req CreateRequest) error
-	Save(req CreateRequest) (int64, error)
-	FindAll() ([]Response, error)
-	FindByIds(ids []int64) ([]Response, error)
-	DeleteById(id int64) error
-	DeleteByIds(ids []int64) error
-	SaveWithTransaction(e CreateRequest) (int64, error)
-	GetEmployeesPage(req PageRequest) (PageResponse, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	Add(ctx context.Context, req CreateRequest) error
+	Save(ctx context.Context, req CreateRequest) (int64, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	FindByIds(ctx context.Context, ids []int64) ([]Response, error)
+	DeleteById(ctx context.Context, id int64) error
+	DeleteByIds(ctx context.Context, ids []int64) error
+	SaveWithTransaction(ctx context.Context, e CreateRequest) (int64, error)
+	GetEmployeesPage(ctx context.Context, req PageRequest) (PageResponse, error)
+	AssignRoles(ctx context.Context, employeeId int64, req AssignRolesRequest) error
+	RevokeRoles(ctx context.Context, employeeId int64, req RolesRequest) error
+	FindByRoleId(ctx context.Context, roleId int64) ([]Response, error)
+	FindByOrgUnit(ctx context.Context, orgUnitId int64, subtree bool) ([]Response, error)
+	Update(ctx context.Context, id int64, req UpdateRequest, expectedVersion *time.Time) (Response, error)
+	Patch(ctx context.Context, id int64, patch []byte, expectedVersion *time.Time) (Response, error)
+	Restore(ctx context.Context, id int64) (Response, error)
+	Transition(ctx context.Context, id int64, req TransitionRequest) (Response, error)
+	GetStatusHistory(ctx context.Context, id int64) ([]StatusHistoryResponse, error)
+	FindManagers(ctx context.Context, id int64) ([]Response, error)
+	FindReports(ctx context.Context, id int64, transitive bool) ([]Response, error)
+	OrgChart(ctx context.Context, req OrgChartRequest) ([]ChartNode, error)
+	FindGrants(ctx context.Context, employeeId int64) ([]GrantResponse, error)
+}
+
+// RoleSvc описывает методы сервиса ролей, нужные маршрутам назначения ролей сотрудникам
+type RoleSvc interface {
+	FindByEmployeeId(ctx context.Context, employeeId int64) ([]role.Response, error)
 }
 
-func NewController(server *web.Server, employeeService Svc, logger *common.Logger) *Controller {
+func NewController(server *web.Server, employeeService Svc, roleService RoleSvc, logger *common.Logger) *Controller {
 	return &Controller{
 		server:          server,
 		employeeService: employeeService,
+		roleService:     roleService,
 		logger:          logger,
 	}
 }
 
-func requireRoles(roles ...string) fiber.Handler {
-	// нормализуем и фиксируем список обязательных ролей
-	req := make(map[string]struct{}, len(roles))
-	for _, r := range roles {
-		k := strings.ToUpper(strings.TrimSpace(r))
-		if k != "" {
-			req[k] = struct{}{}
-		}
-	}
-	return func(ctx *fiber.Ctx) error {
-		token, ok := ctx.Locals(web.JwtKey).(*jwt.Token)
-		if !ok || token == nil {
-			return common.ErrResponse(ctx, fiber.StatusUnauthorized, "unauthorized")
-		}
-		claims, ok := token.Claims.(*web.IdmClaims)
-		if !ok || claims == nil {
-			return common.ErrResponse(ctx, fiber.StatusUnauthorized, "unauthorized")
-		}
-		// сет ролей пользователя
-		user := make(map[string]struct{}, len(claims.RealmAccess.Roles))
-		for _, ur := range claims.RealmAccess.Roles {
-			k := strings.ToUpper(strings.TrimSpace(ur))
-			if k != "" {
-				user[k] = struct{}{}
-			}
-		}
-		// проверяем, что присутствуют ВСЕ обязательные роли
-		for k := range req {
-			if _, ok := user[k]; !ok {
-				return common.ErrResponse(ctx, fiber.StatusForbidden, "forbidden")
-			}
-		}
-		return ctx.Next()
-	}
-}
-func requireAnyRole(roles ...string) fiber.Handler {
-	// зафиксируем срез требуемых ролей
-	req := make([]string, len(roles))
-	copy(req, roles)
-
-	return func(ctx *fiber.Ctx) error {
-		token, ok := ctx.Locals(web.JwtKey).(*jwt.Token)
-		if !ok || token == nil {
-			return common.ErrResponse(ctx, fiber.StatusUnauthorized, "unauthorized")
-		}
-		claims, ok := token.Claims.(*web.IdmClaims)
-		if !ok || claims == nil {
-			return common.ErrResponse(ctx, fiber.StatusUnauthorized, "unauthorized")
-		}
-		if !hasAnyRole(claims.RealmAccess.Roles, req...) {
-			return common.ErrResponse(ctx, fiber.StatusForbidden, "forbidden")
-		}
-		return ctx.Next()
-	}
-}
-func hasAnyRole(userRoles []string, required ...string) bool {
-	if len(userRoles) == 0 || len(required) == 0 {
-		return false
-	}
-	// Нормализуем требуемые роли в set (UPPER + trim)
-	req := make(map[string]struct{}, len(required))
-	for _, r := range required {
-		k := strings.ToUpper(strings.TrimSpace(r))
-		if k != "" {
-			req[k] = struct{}{}
-		}
-	}
-	// Проверяем, есть ли среди ролей пользователя любая требуемая
-	for _, ur := range userRoles {
-		k := strings.ToUpper(strings.TrimSpace(ur))
-		if _, ok := req[k]; ok {
-			return true
-		}
-	}
-	return false
-}
-
+// RegisterRoutes регистрирует маршруты; права доступа к ним задаются политикой (policies.yaml)
 func (c *Controller) RegisterRoutes() {
 
 	grp := c.server.GroupApiV1.Group("/employees")
 
-	// admin only
-	grp.Post("/", requireRoles(web.IdmAdmin), c.CreateEmployee)
-	grp.Post("/add", requireRoles(web.IdmAdmin), c.AddEmployee)
-	grp.Post("/save", requireRoles(web.IdmAdmin), c.SaveEmployee)
-	grp.Delete("/", requireRoles(web.IdmAdmin), c.DeleteEmployeesByIds)
-	grp.Delete("/:id", requireRoles(web.IdmAdmin), c.DeleteEmployeeById)
-
-	// read (admin OR user)
-	grp.Get("/", requireAnyRole(web.IdmAdmin, web.IdmUser), c.GetAllEmployees)
-	grp.Get("/page", requireAnyRole(web.IdmAdmin, web.IdmUser), c.GetEmployeesPage)
-	grp.Post("/batch", requireAnyRole(web.IdmAdmin, web.IdmUser), c.GetEmployeesByIds)
-	grp.Get("/:id", requireAnyRole(web.IdmAdmin, web.IdmUser), c.GetEmployee)
+	grp.Post("/", c.CreateEmployee)
+	grp.Post("/add", c.AddEmployee)
+	grp.Post("/save", c.SaveEmployee)
+	grp.Delete("/", c.DeleteEmployeesByIds)
+	grp.Delete("/:id", c.DeleteEmployeeById)
+	grp.Put("/:id", c.UpdateEmployee)
+	grp.Patch("/:id", c.PatchEmployee)
+	grp.Post("/:id/roles", c.AssignRoles)
+	grp.Delete("/:id/roles", c.RevokeRoles)
+	grp.Post("/:id/restore", c.RestoreEmployee)
+	grp.Post("/:id/status", c.TransitionEmployee)
+
+	grp.Get("/", c.GetAllEmployees)
+	grp.Get("/page", c.GetEmployeesPage)
+	grp.Get("/org-chart", c.GetOrgChart)
+	grp.Post("/batch", c.GetEmployeesByIds)
+	grp.Get("/:id", c.GetEmployee)
+	grp.Get("/:id/status-history", c.GetStatusHistory)
+	grp.Get("/:id/grants", c.GetGrants)
+	grp.Get("/:id/roles", c.GetRolesByEmployeeId)
+	grp.Get("/:id/managers", c.GetManagers)
+	grp.Get("/:id/reports", c.GetReports)
+
+	// сотрудники, которым назначена роль
+	c.server.GroupApiV1.Get("/roles/:id/employees", c.GetEmployeesByRoleId)
+	// сотрудники подразделения
+	c.server.GroupApiV1.Get("/org-units/:id/employees", c.GetEmployeesByOrgUnit)
 }
 
 // CreateEmployee Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees"
@@ -152,30 +109,21 @@ func (c *Controller) CreateEmployee(ctx *fiber.Ctx) error {
 	// анмаршалим JSON body запроса в структуру CreateRequest
 	var request CreateRequest
 	if err := ctx.BodyParser(&request); err != nil {
-		c.logger.Error("create employee", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		c.logger.Ctx(ctx.UserContext()).Error("create employee", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
 	}
-	c.logger.Debug("create employee: received request", zap.Any("request", request))
+	c.logger.Ctx(ctx.UserContext()).Debug("create employee: received request", zap.Any("request", request))
 
 	// вызываем метод CreateEmployee сервиса employee.Service
-	var newEmployeeId, err = c.employeeService.SaveWithTransaction(request)
-	if err != nil {
-		switch {
-		// если сервис возвращает ошибку RequestValidationError или AlreadyExistsError,
-		// то мы возвращаем ответ с кодом 400 (BadRequest)
-		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
-			c.logger.Error("create employee", zap.Error(err))
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-		// если сервис возвращает другую ошибку, то мы возвращаем ответ с кодом 500 (InternalServerError)
-		default:
-			c.logger.Error("create employee", zap.Error(err))
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		}
+	var newEmployeeId, err = c.employeeService.SaveWithTransaction(ctx.UserContext(), request)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("create employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
 	}
 
 	// функция OkResponse() формирует и направляет ответ в случае успеха
 	if err = common.OkResponse(ctx, newEmployeeId); err != nil {
-		c.logger.Error("create employee", zap.Error(err))
+		c.logger.Ctx(ctx.UserContext()).Error("create employee", zap.Error(err))
 		// функция ErrorResponse() формирует и направляет ответ в случае ошибки
 		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "error returning created employee id")
 	}
@@ -197,27 +145,21 @@ func (c *Controller) CreateEmployee(ctx *fiber.Ctx) error {
 func (c *Controller) AddEmployee(ctx *fiber.Ctx) error {
 	var req CreateRequest
 	if err := ctx.BodyParser(&req); err != nil {
-		c.logger.Error("Add employee", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-	}
-	c.logger.Debug("Add employee: received request", zap.Any("request", req))
-
-	if err := c.employeeService.Add(req); err != nil {
-		switch {
-		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
-			c.logger.Error("Add employee", zap.Error(err))
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-		default:
-			c.logger.Error("Add employee", zap.Error(err))
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		}
+		c.logger.Ctx(ctx.UserContext()).Error("Add employee", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	c.logger.Ctx(ctx.UserContext()).Debug("Add employee: received request", zap.Any("request", req))
+
+	if err := c.employeeService.Add(ctx.UserContext(), req); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Add employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, fiber.Map{"message": "added"})
 }
 
 // SaveEmployee godoc
 // @Summary      Save employee
-// @Description  Create or update employee and return its id
+// @Description  Create a new employee and return its id
 // @Tags         employee
 // @Accept       json
 // @Produce      json
@@ -229,21 +171,15 @@ func (c *Controller) AddEmployee(ctx *fiber.Ctx) error {
 func (c *Controller) SaveEmployee(ctx *fiber.Ctx) error {
 	var req CreateRequest
 	if err := ctx.BodyParser(&req); err != nil {
-		c.logger.Error("Save employee", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		c.logger.Ctx(ctx.UserContext()).Error("Save employee", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
 	}
-	c.logger.Debug("Save employee: received request", zap.Any("request", req))
+	c.logger.Ctx(ctx.UserContext()).Debug("Save employee: received request", zap.Any("request", req))
 
-	id, err := c.employeeService.Save(req)
+	id, err := c.employeeService.Save(ctx.UserContext(), req)
 	if err != nil {
-		switch {
-		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
-			c.logger.Error("Save employee", zap.Error(err))
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-		default:
-			c.logger.Error("Save employee", zap.Error(err))
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		}
+		c.logger.Ctx(ctx.UserContext()).Error("Save employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, fiber.Map{"id": id})
 }
@@ -255,23 +191,26 @@ func (c *Controller) SaveEmployee(ctx *fiber.Ctx) error {
 // @Produce      json
 // @Param        id   path      int  true  "employee id"
 // @Success      200  {object}  employee.Response
+// @Header       200  {string}  ETag  "employee version for If-Match"
+// @Failure      404  {object}  common.Response[any]
 // @Router       /employees/{id} [get]
 // GetEmployee handles GET /api/v1/employees/:id
 // @Security BearerAuth
 func (c *Controller) GetEmployee(ctx *fiber.Ctx) error {
 	idParam := ctx.Params("id")
-	c.logger.Debug("Get employee", zap.String("id", idParam))
+	c.logger.Ctx(ctx.UserContext()).Debug("Get employee", zap.String("id", idParam))
 
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		c.logger.Error("Get employee", zap.Error(err))
+		c.logger.Ctx(ctx.UserContext()).Error("Get employee", zap.Error(err))
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
 	}
-	resp, err := c.employeeService.FindById(id)
+	resp, err := c.employeeService.FindById(ctx.UserContext(), id)
 	if err != nil {
-		c.logger.Error("Get employee", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		c.logger.Ctx(ctx.UserContext()).Error("Get employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
 	}
+	ctx.Set(fiber.HeaderETag, common.ETag(resp.UpdatedAt))
 	return common.OkResponse(ctx, resp)
 }
 
@@ -285,21 +224,24 @@ func (c *Controller) GetEmployee(ctx *fiber.Ctx) error {
 // GetAllEmployees handles GET /api/v1/employees
 // @Security BearerAuth
 func (c *Controller) GetAllEmployees(ctx *fiber.Ctx) error {
-	resps, err := c.employeeService.FindAll()
+	resps, err := c.employeeService.FindAll(ctx.UserContext())
 	if err != nil {
-		c.logger.Error("Get all employee", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		c.logger.Ctx(ctx.UserContext()).Error("Get all employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, resps)
 }
 
 // GetEmployeesPage godoc
 // @Summary      Get employees page
-// @Description  Returns paginated list of employees
+// @Description  Returns paginated list of employees.
+// @Description  Custom attributes are filtered by exact match with attr.<name>=<value> query parameters
 // @Tags         employee
 // @Produce      json
 // @Param        request  query     employee.PageRequest  true  "page request"
 // @Success      200      {object}  employee.PageResponse
+// @Failure      400      {object}  common.Response[any]  "invalid page request or unknown attribute"
+// @Failure      403      {object}  common.Response[any]  "includeDeleted requested by non-admin"
 // @Router       /employees/page [get]
 // @Security BearerAuth
 func (c *Controller) GetEmployeesPage(ctx *fiber.Ctx) error {
@@ -307,12 +249,14 @@ func (c *Controller) GetEmployeesPage(ctx *fiber.Ctx) error {
 	if err := ctx.QueryParser(&req); err != nil {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "bad query params")
 	}
-	pageResp, err := c.employeeService.GetEmployeesPage(req)
+	req.Attributes = attributeFilter(ctx)
+	// удалённых сотрудников видят только администраторы
+	if req.IncludeDeleted && !web.Satisfies(ctx, web.HasAnyRole(web.IdmAdmin)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "forbidden")
+	}
+	pageResp, err := c.employeeService.GetEmployeesPage(ctx.UserContext(), req)
 	if err != nil {
-		if errors.As(err, &common.RequestValidationError{}) {
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-		}
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return common.ServiceErrResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, pageResp)
 }
@@ -331,13 +275,13 @@ func (c *Controller) GetEmployeesPage(ctx *fiber.Ctx) error {
 func (c *Controller) GetEmployeesByIds(ctx *fiber.Ctx) error {
 	var ids []int64
 	if err := ctx.BodyParser(&ids); err != nil {
-		c.logger.Error("Get employees by ids", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		c.logger.Ctx(ctx.UserContext()).Error("Get employees by ids", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
 	}
-	resps, err := c.employeeService.FindByIds(ids)
+	resps, err := c.employeeService.FindByIds(ctx.UserContext(), ids)
 	if err != nil {
-		c.logger.Error("Get all employee", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		c.logger.Ctx(ctx.UserContext()).Error("Get all employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, resps)
 }
@@ -349,44 +293,465 @@ func (c *Controller) GetEmployeesByIds(ctx *fiber.Ctx) error {
 // @Produce      json
 // @Param        id   path      int  true  "employee id"
 // @Success      200  {object}  map[string]string
+// @Failure      404  {object}  common.Response[any]
 // @Router       /employees/{id} [delete]
 // DeleteEmployeeById handles DELETE /api/v1/employees/:id
 // @Security BearerAuth
 func (c *Controller) DeleteEmployeeById(ctx *fiber.Ctx) error {
 	idParam := ctx.Params("id")
-	c.logger.Debug("Delete employee", zap.String("id", idParam))
+	c.logger.Ctx(ctx.UserContext()).Debug("Delete employee", zap.String("id", idParam))
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		c.logger.Error("Delete employee", zap.Error(err))
+		c.logger.Ctx(ctx.UserContext()).Error("Delete employee", zap.Error(err))
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
 	}
-	if err := c.employeeService.DeleteById(id); err != nil {
-		c.logger.Error("Delete employee", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	if err := c.employeeService.DeleteById(ctx.UserContext(), id); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Delete employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, fiber.Map{"message": "deleted"})
 }
 
+// RestoreEmployee godoc
+// @Summary      Restore employee
+// @Description  Restores soft-deleted employee with the specified id
+// @Tags         employee
+// @Produce      json
+// @Param        id   path      int  true  "employee id"
+// @Success      200  {object}  common.Response[employee.Response]
+// @Failure      404  {object}  common.Response[any]  "deleted employee not found"
+// @Failure      400  {object}  common.Response[any]  "name is taken by another employee"
+// @Router       /employees/{id}/restore [post]
+// @Security BearerAuth
+func (c *Controller) RestoreEmployee(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resp, err := c.employeeService.Restore(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Restore employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
 // DeleteEmployeesByIds godoc
 // @Summary      Delete employees by ids
-// @Description  Deletes employees with the specified ids
+// @Description  Deletes employees with the specified ids; if any of them is not found, none is deleted
 // @Tags         employee
 // @Accept       json
 // @Produce      json
 // @Param        ids  body      []int64  true  "employee ids"
 // @Success      200  {object}  map[string]string
+// @Failure      404  {object}  common.Response[any]
 // @Router       /employees [delete]
 // DeleteEmployeesByIds handles DELETE /api/v1/employees
 // @Security BearerAuth
 func (c *Controller) DeleteEmployeesByIds(ctx *fiber.Ctx) error {
 	var ids []int64
 	if err := ctx.BodyParser(&ids); err != nil {
-		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
 	}
-	c.logger.Debug("Delete employees by ids", zap.Int64s("ids", ids))
-	if err := c.employeeService.DeleteByIds(ids); err != nil {
-		c.logger.Error("Delete employee", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	c.logger.Ctx(ctx.UserContext()).Debug("Delete employees by ids", zap.Int64s("ids", ids))
+	if err := c.employeeService.DeleteByIds(ctx.UserContext(), ids); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Delete employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
 	}
 	return common.OkResponse(ctx, fiber.Map{"message": "deleted"})
 }
+
+// AssignRoles godoc
+// @Summary      Assign roles to employee
+// @Description  Assigns the specified roles to the employee, optionally for a limited period (valid_from/valid_to);
+// @Description  assigning an already assigned role replaces its validity period. Expired grants are revoked automatically
+// @Tags         employee
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                          true  "employee id"
+// @Param        request  body      employee.AssignRolesRequest  true  "role ids and validity period"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  common.Response[any]  "invalid request, roles not found, or invalid validity period"
+// @Failure      404      {object}  common.Response[any]
+// @Router       /employees/{id}/roles [post]
+// AssignRoles handles POST /api/v1/employees/:id/roles
+// @Security BearerAuth
+func (c *Controller) AssignRoles(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Assign roles", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	var req AssignRolesRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Assign roles", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	c.logger.Ctx(ctx.UserContext()).Debug("Assign roles: received request", zap.Int64("id", id), zap.Any("request", req))
+
+	if err := c.employeeService.AssignRoles(ctx.UserContext(), id, req); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Assign roles", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, fiber.Map{"message": "assigned"})
+}
+
+// TransitionEmployee godoc
+// @Summary      Change employee lifecycle status
+// @Description  Moves the employee to another lifecycle status; terminating revokes all roles. The effective date must not be in the future
+// @Tags         employee
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                         true  "employee id"
+// @Param        request  body      employee.TransitionRequest  true  "new status"
+// @Success      200      {object}  common.Response[employee.Response]
+// @Failure      400      {object}  common.Response[any]  "invalid request or effective date in the future"
+// @Failure      404      {object}  common.Response[any]
+// @Failure      409      {object}  common.Response[any]  "transition is not allowed"
+// @Router       /employees/{id}/status [post]
+// @Security BearerAuth
+func (c *Controller) TransitionEmployee(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	var req TransitionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Transition employee", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	resp, err := c.employeeService.Transition(ctx.UserContext(), id, req)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Transition employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// GetStatusHistory godoc
+// @Summary      Get employee status history
+// @Description  Returns lifecycle status transitions of the employee in chronological order
+// @Tags         employee
+// @Produce      json
+// @Param        id   path      int  true  "employee id"
+// @Success      200  {object}  common.Response[[]employee.StatusHistoryResponse]
+// @Failure      404  {object}  common.Response[any]
+// @Router       /employees/{id}/status-history [get]
+// @Security BearerAuth
+func (c *Controller) GetStatusHistory(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	history, err := c.employeeService.GetStatusHistory(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get status history", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, history)
+}
+
+// GetGrants godoc
+// @Summary      Get employee role grants
+// @Description  Returns role grants of the employee with their validity periods, including not yet started ones
+// @Tags         employee
+// @Produce      json
+// @Param        id   path      int  true  "employee id"
+// @Success      200  {object}  common.Response[[]employee.GrantResponse]
+// @Failure      404  {object}  common.Response[any]
+// @Router       /employees/{id}/grants [get]
+// @Security BearerAuth
+func (c *Controller) GetGrants(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	grants, err := c.employeeService.FindGrants(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get role grants", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, grants)
+}
+
+// GetManagers godoc
+// @Summary      Get employee reporting chain
+// @Description  Returns managers of the employee from the direct manager up to the top of the organization
+// @Tags         employee
+// @Produce      json
+// @Param        id   path      int  true  "employee id"
+// @Success      200  {object}  common.Response[[]employee.Response]
+// @Failure      404  {object}  common.Response[any]
+// @Router       /employees/{id}/managers [get]
+// @Security BearerAuth
+func (c *Controller) GetManagers(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	managers, err := c.employeeService.FindManagers(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get managers", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, managers)
+}
+
+// GetReports godoc
+// @Summary      Get employee reports
+// @Description  Returns direct reports of the employee; with transitive=true all reports down the hierarchy, level by level
+// @Tags         employee
+// @Produce      json
+// @Param        id          path      int   true   "employee id"
+// @Param        transitive  query     bool  false  "include indirect reports"
+// @Success      200         {object}  common.Response[[]employee.Response]
+// @Failure      404         {object}  common.Response[any]
+// @Router       /employees/{id}/reports [get]
+// @Security BearerAuth
+func (c *Controller) GetReports(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	reports, err := c.employeeService.FindReports(ctx.UserContext(), id, ctx.QueryBool("transitive"))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get reports", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, reports)
+}
+
+// GetOrgChart godoc
+// @Summary      Export org chart
+// @Description  Returns the reporting tree of the whole organization or of the root employee, as JSON or as Graphviz DOT
+// @Tags         employee
+// @Produce      json
+// @Produce      text/vnd.graphviz
+// @Param        root    query     int     false  "root employee id"
+// @Param        format  query     string  false  "json (default) or dot"  Enums(json, dot)
+// @Success      200     {object}  common.Response[[]employee.ChartNode]
+// @Failure      400     {object}  common.Response[any]
+// @Failure      404     {object}  common.Response[any]
+// @Router       /employees/org-chart [get]
+// @Security BearerAuth
+func (c *Controller) GetOrgChart(ctx *fiber.Ctx) error {
+	var req OrgChartRequest
+	if err := ctx.QueryParser(&req); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "bad query params")
+	}
+	chart, err := c.employeeService.OrgChart(ctx.UserContext(), req)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get org chart", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	if req.Format == OrgChartDot {
+		ctx.Set(fiber.HeaderContentType, "text/vnd.graphviz; charset=utf-8")
+		return ctx.SendString(renderDot(chart))
+	}
+	return common.OkResponse(ctx, chart)
+}
+
+// RevokeRoles godoc
+// @Summary      Revoke roles from employee
+// @Description  Revokes the specified roles from the employee
+// @Tags         employee
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                   true  "employee id"
+// @Param        request  body      employee.RolesRequest  true  "role ids"
+// @Success      200      {object}  map[string]string
+// @Failure      404      {object}  common.Response[any]
+// @Router       /employees/{id}/roles [delete]
+// RevokeRoles handles DELETE /api/v1/employees/:id/roles
+// @Security BearerAuth
+func (c *Controller) RevokeRoles(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Revoke roles", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	var req RolesRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Revoke roles", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	c.logger.Ctx(ctx.UserContext()).Debug("Revoke roles: received request", zap.Int64("id", id), zap.Any("request", req))
+
+	if err := c.employeeService.RevokeRoles(ctx.UserContext(), id, req); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Revoke roles", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, fiber.Map{"message": "revoked"})
+}
+
+// GetEmployeesByRoleId godoc
+// @Summary      List employees with role
+// @Description  Returns employees the role is assigned to
+// @Tags         role
+// @Produce      json
+// @Param        id   path      int  true  "role id"
+// @Success      200  {array}   employee.Response
+// @Failure      404  {object}  common.Response[any]
+// @Router       /roles/{id}/employees [get]
+// GetEmployeesByRoleId handles GET /api/v1/roles/:id/employees
+// @Security BearerAuth
+func (c *Controller) GetEmployeesByRoleId(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get employees by role", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resps, err := c.employeeService.FindByRoleId(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get employees by role", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// GetRolesByEmployeeId godoc
+// @Summary      List roles of employee
+// @Description  Returns roles assigned to the employee
+// @Tags         employee
+// @Produce      json
+// @Param        id   path      int  true  "employee id"
+// @Success      200  {array}   role.Response
+// @Router       /employees/{id}/roles [get]
+// GetRolesByEmployeeId handles GET /api/v1/employees/:id/roles
+// @Security BearerAuth
+func (c *Controller) GetRolesByEmployeeId(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get roles by employee", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resps, err := c.roleService.FindByEmployeeId(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get roles by employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// GetEmployeesByOrgUnit godoc
+// @Summary      List employees of org unit
+// @Description  Returns employees of the org unit; with subtree=true also employees of all its descendant units
+// @Tags         orgunit
+// @Produce      json
+// @Param        id       path      int   true   "org unit id"
+// @Param        subtree  query     bool  false  "include descendant units"
+// @Success      200      {array}   employee.Response
+// @Failure      404      {object}  common.Response[any]
+// @Router       /org-units/{id}/employees [get]
+// @Security BearerAuth
+func (c *Controller) GetEmployeesByOrgUnit(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get employees by org unit", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resps, err := c.employeeService.FindByOrgUnit(ctx.UserContext(), id, ctx.QueryBool("subtree"))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get employees by org unit", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// UpdateEmployee godoc
+// @Summary      Update employee
+// @Description  Replaces employee data. With If-Match header the update is applied only if the employee was not modified since
+// @Tags         employee
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int                     true   "employee id"
+// @Param        If-Match  header    string                  false  "ETag returned by GET /employees/{id}"
+// @Param        request   body      employee.UpdateRequest  true   "update employee request"
+// @Success      200       {object}  employee.Response
+// @Failure      412       {object}  common.Response[any]
+// @Failure      404       {object}  common.Response[any]
+// @Router       /employees/{id} [put]
+// UpdateEmployee handles PUT /api/v1/employees/:id
+// @Security BearerAuth
+func (c *Controller) UpdateEmployee(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Update employee", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	var req UpdateRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Update employee", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	c.logger.Ctx(ctx.UserContext()).Debug("Update employee: received request", zap.Int64("id", id), zap.Any("request", req))
+
+	expectedVersion, err := common.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Update employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	resp, err := c.employeeService.Update(ctx.UserContext(), id, req, expectedVersion)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Update employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	ctx.Set(fiber.HeaderETag, common.ETag(resp.UpdatedAt))
+	return common.OkResponse(ctx, resp)
+}
+
+// PatchEmployee godoc
+// @Summary      Patch employee
+// @Description  Applies JSON Merge Patch (RFC 7386) to employee. With If-Match header the patch is applied only if the employee was not modified since
+// @Tags         employee
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id        path      int                     true   "employee id"
+// @Param        If-Match  header    string                  false  "ETag returned by GET /employees/{id}"
+// @Param        request   body      employee.UpdateRequest  true   "merge patch"
+// @Success      200       {object}  employee.Response
+// @Failure      412       {object}  common.Response[any]
+// @Failure      404       {object}  common.Response[any]
+// @Router       /employees/{id} [patch]
+// PatchEmployee handles PATCH /api/v1/employees/:id
+// @Security BearerAuth
+func (c *Controller) PatchEmployee(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Patch employee", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	c.logger.Ctx(ctx.UserContext()).Debug("Patch employee: received request", zap.Int64("id", id), zap.ByteString("patch", ctx.Body()))
+
+	expectedVersion, err := common.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Patch employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	resp, err := c.employeeService.Patch(ctx.UserContext(), id, ctx.Body(), expectedVersion)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Patch employee", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	ctx.Set(fiber.HeaderETag, common.ETag(resp.UpdatedAt))
+	return common.OkResponse(ctx, resp)
+}
+
+// attributeFilterPrefix - префикс параметров запроса с фильтром по дополнительным атрибутам
+const attributeFilterPrefix = "attr."
+
+// attributeFilter собирает фильтр по атрибутам из параметров attr.<name>=<value>;
+// значения приводятся к типам атрибутов в сервисе
+func attributeFilter(ctx *fiber.Ctx) Attributes {
+	var filter Attributes
+	for key, value := range ctx.Queries() {
+		if name, ok := strings.CutPrefix(key, attributeFilterPrefix); ok {
+			if filter == nil {
+				filter = Attributes{}
+			}
+			filter[name] = value
+		}
+	}
+	return filter
+}
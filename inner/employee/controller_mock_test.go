@@ -1,9 +1,12 @@
 package employee
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"idm/inner/common"
+	"idm/inner/policy"
+	"idm/inner/role"
 	"idm/inner/web"
 	"io"
 	"net/http"
@@ -23,57 +26,132 @@ type MockService struct {
 	mock.Mock
 }
 
-func (svc *MockService) Add(req CreateRequest) error {
-	args := svc.Called(req)
+// MockRoleService - мок сервиса ролей, к которому обращаются маршруты назначения ролей
+type MockRoleService struct {
+	mock.Mock
+}
+
+func (svc *MockRoleService) FindByEmployeeId(ctx context.Context, employeeId int64) ([]role.Response, error) {
+	args := svc.Called(ctx, employeeId)
+	return args.Get(0).([]role.Response), args.Error(1)
+}
+
+func (svc *MockService) Add(ctx context.Context, req CreateRequest) error {
+	args := svc.Called(ctx, req)
 	return args.Error(0)
 }
 
-func (svc *MockService) Save(req CreateRequest) (int64, error) {
-	args := svc.Called(req)
+func (svc *MockService) Save(ctx context.Context, req CreateRequest) (int64, error) {
+	args := svc.Called(ctx, req)
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (svc *MockService) FindAll() ([]Response, error) {
-	args := svc.Called()
+func (svc *MockService) FindAll(ctx context.Context) ([]Response, error) {
+	args := svc.Called(ctx)
 	return args.Get(0).([]Response), args.Error(1)
 }
 
-func (svc *MockService) FindByIds(ids []int64) ([]Response, error) {
-	args := svc.Called(ids)
+func (svc *MockService) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
+	args := svc.Called(ctx, ids)
 	return args.Get(0).([]Response), args.Error(1)
 }
 
-func (svc *MockService) DeleteById(id int64) error {
-	args := svc.Called(id)
+func (svc *MockService) Transition(ctx context.Context, id int64, req TransitionRequest) (Response, error) {
+	args := svc.Called(ctx, id, req)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) GetStatusHistory(ctx context.Context, id int64) ([]StatusHistoryResponse, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).([]StatusHistoryResponse), args.Error(1)
+}
+
+func (svc *MockService) Restore(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) DeleteById(ctx context.Context, id int64) error {
+	args := svc.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (svc *MockService) DeleteByIds(ids []int64) error {
-	args := svc.Called(ids)
+func (svc *MockService) DeleteByIds(ctx context.Context, ids []int64) error {
+	args := svc.Called(ctx, ids)
 	return args.Error(0)
 }
 
-func (svc *MockService) SaveWithTransaction(e CreateRequest) (int64, error) {
-	args := svc.Called(e.ToEntity())
+func (svc *MockService) SaveWithTransaction(ctx context.Context, e CreateRequest) (int64, error) {
+	args := svc.Called(ctx, e.ToEntity())
 	return args.Get(0).(int64), args.Error(1)
 }
 
 // Реализуем функции мок-сервиса
-func (svc *MockService) FindById(id int64) (Response, error) {
-	args := svc.Called(id)
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(ctx, id)
 	return args.Get(0).(Response), args.Error(1)
 }
 
-func (svc *MockService) CreateEmployee(request CreateRequest) (int64, error) {
-	args := svc.Called(request)
+func (svc *MockService) CreateEmployee(ctx context.Context, request CreateRequest) (int64, error) {
+	args := svc.Called(ctx, request)
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (svc *MockService) GetEmployeesPage(req PageRequest) (PageResponse, error) {
-	args := svc.Called(req)
+func (svc *MockService) GetEmployeesPage(ctx context.Context, req PageRequest) (PageResponse, error) {
+	args := svc.Called(ctx, req)
 	return args.Get(0).(PageResponse), args.Error(1)
 }
 
+func (svc *MockService) AssignRoles(ctx context.Context, employeeId int64, req AssignRolesRequest) error {
+	args := svc.Called(ctx, employeeId, req)
+	return args.Error(0)
+}
+
+func (svc *MockService) RevokeRoles(ctx context.Context, employeeId int64, req RolesRequest) error {
+	args := svc.Called(ctx, employeeId, req)
+	return args.Error(0)
+}
+
+func (svc *MockService) FindByRoleId(ctx context.Context, roleId int64) ([]Response, error) {
+	args := svc.Called(ctx, roleId)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindByOrgUnit(ctx context.Context, orgUnitId int64, subtree bool) ([]Response, error) {
+	args := svc.Called(ctx, orgUnitId, subtree)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindManagers(ctx context.Context, id int64) ([]Response, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindReports(ctx context.Context, id int64, transitive bool) ([]Response, error) {
+	args := svc.Called(ctx, id, transitive)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) OrgChart(ctx context.Context, req OrgChartRequest) ([]ChartNode, error) {
+	args := svc.Called(ctx, req)
+	return args.Get(0).([]ChartNode), args.Error(1)
+}
+
+func (svc *MockService) FindGrants(ctx context.Context, employeeId int64) ([]GrantResponse, error) {
+	args := svc.Called(ctx, employeeId)
+	return args.Get(0).([]GrantResponse), args.Error(1)
+}
+
+func (svc *MockService) Update(ctx context.Context, id int64, req UpdateRequest, expectedVersion *time.Time) (Response, error) {
+	args := svc.Called(ctx, id, req, expectedVersion)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Patch(ctx context.Context, id int64, patch []byte, expectedVersion *time.Time) (Response, error) {
+	args := svc.Called(ctx, id, patch, expectedVersion)
+	return args.Get(0).(Response), args.Error(1)
+}
+
 func TestCreateEmployee(t *testing.T) {
 	a := assert.New(t)
 
@@ -88,7 +166,7 @@ func TestCreateEmployee(t *testing.T) {
 			name: "should return created employee id",
 			body: `{"name": "john doe"}`,
 			mockSetup: func(svc *MockService) {
-				svc.On("SaveWithTransaction", mock.AnythingOfType("*employee.Entity")).Return(int64(123), nil)
+				svc.On("SaveWithTransaction", mock.Anything, mock.AnythingOfType("*employee.Entity")).Return(int64(123), nil)
 			},
 			wantStatus: http.StatusOK,
 			wantID:     123,
@@ -108,11 +186,11 @@ func TestCreateEmployee(t *testing.T) {
 			}
 
 			server := web.NewServer()
-			server.GroupApiV1.Use(auth)
+			server.GroupApiV1.Use(auth, policyMiddleware())
 
 			svc := new(MockService)
 			logger := common.NewLogger(common.GetConfig(".env"))
-			controller := NewController(server, svc, logger)
+			controller := NewController(server, svc, nil, logger)
 			controller.RegisterRoutes()
 
 			if tt.mockSetup != nil {
@@ -153,7 +231,7 @@ func TestAddEmployee(t *testing.T) {
 			name: "should add employee successfully",
 			body: `{"name":"alice"}`,
 			mockSetup: func(svc *MockService) {
-				svc.On("Add", mock.AnythingOfType("employee.CreateRequest")).Return(nil)
+				svc.On("Add", mock.Anything, mock.AnythingOfType("employee.CreateRequest")).Return(nil)
 			},
 			wantStatus: http.StatusOK,
 		},
@@ -161,7 +239,7 @@ func TestAddEmployee(t *testing.T) {
 			name: "should return bad request on validation error",
 			body: `{}`,
 			mockSetup: func(svc *MockService) {
-				svc.On("Add", mock.AnythingOfType("employee.CreateRequest")).Return(common.RequestValidationError{Message: "Add emploee"})
+				svc.On("Add", mock.Anything, mock.AnythingOfType("employee.CreateRequest")).Return(common.RequestValidationError{Message: "Add emploee"})
 			},
 			wantStatus: fiber.StatusBadRequest,
 		},
@@ -169,7 +247,7 @@ func TestAddEmployee(t *testing.T) {
 			name: "should return internal error on service failure",
 			body: `{"name":"alice"}`,
 			mockSetup: func(svc *MockService) {
-				svc.On("Add", mock.AnythingOfType("employee.CreateRequest")).Return(errors.New("fail"))
+				svc.On("Add", mock.Anything, mock.AnythingOfType("employee.CreateRequest")).Return(errors.New("fail"))
 			},
 			wantStatus: fiber.StatusInternalServerError,
 		},
@@ -188,10 +266,10 @@ func TestAddEmployee(t *testing.T) {
 			}
 
 			server := web.NewServer()
-			server.GroupApiV1.Use(auth)
+			server.GroupApiV1.Use(auth, policyMiddleware())
 
 			svc := new(MockService)
-			controller := NewController(server, svc, common.NewLogger(common.GetConfig(".env")))
+			controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
 			controller.RegisterRoutes()
 
 			if tt.mockSetup != nil {
@@ -232,7 +310,7 @@ func TestSaveEmployee(t *testing.T) {
 			name: "should save employee and return id",
 			body: `{"name":"bob"}`,
 			mockSetup: func(svc *MockService) {
-				svc.On("Save", mock.AnythingOfType("employee.CreateRequest")).Return(int64(42), nil)
+				svc.On("Save", mock.Anything, mock.AnythingOfType("employee.CreateRequest")).Return(int64(42), nil)
 			},
 			wantStatus: http.StatusOK,
 			wantID:     42,
@@ -242,7 +320,7 @@ func TestSaveEmployee(t *testing.T) {
 			name: "should return bad request on validation error",
 			body: `{}`,
 			mockSetup: func(svc *MockService) {
-				svc.On("Save", mock.AnythingOfType("employee.CreateRequest")).Return(int64(0), common.RequestValidationError{Message: "Save employee"})
+				svc.On("Save", mock.Anything, mock.AnythingOfType("employee.CreateRequest")).Return(int64(0), common.RequestValidationError{Message: "Save employee"})
 			},
 			wantStatus: fiber.StatusBadRequest,
 		},
@@ -250,7 +328,7 @@ func TestSaveEmployee(t *testing.T) {
 			name: "should return internal error on service failure",
 			body: `{"name":"bob"}`,
 			mockSetup: func(svc *MockService) {
-				svc.On("Save", mock.AnythingOfType("employee.CreateRequest")).Return(int64(0), errors.New("fail"))
+				svc.On("Save", mock.Anything, mock.AnythingOfType("employee.CreateRequest")).Return(int64(0), errors.New("fail"))
 			},
 			wantStatus: fiber.StatusInternalServerError,
 		},
@@ -269,10 +347,10 @@ func TestSaveEmployee(t *testing.T) {
 			}
 
 			server := web.NewServer()
-			server.GroupApiV1.Use(auth)
+			server.GroupApiV1.Use(auth, policyMiddleware())
 
 			svc := new(MockService)
-			controller := NewController(server, svc, common.NewLogger(common.GetConfig(".env")))
+			controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
 			controller.RegisterRoutes()
 
 			if tt.mockSetup != nil {
@@ -312,7 +390,7 @@ func TestGetEmployee(t *testing.T) {
 			name: "should return employee",
 			url:  "/api/v1/employees/7",
 			mockSetup: func(svc *MockService) {
-				svc.On("FindById", int64(7)).Return(Response{Id: 7, Name: "E"}, nil)
+				svc.On("FindById", mock.Anything, int64(7)).Return(Response{Id: 7, Name: "E"}, nil)
 			},
 			wantStatus: http.StatusOK,
 			wantID:     7,
@@ -328,10 +406,18 @@ func TestGetEmployee(t *testing.T) {
 			name: "should return internal error on service failure",
 			url:  "/api/v1/employees/8",
 			mockSetup: func(svc *MockService) {
-				svc.On("FindById", int64(8)).Return(Response{}, errors.New("fail"))
+				svc.On("FindById", mock.Anything, int64(8)).Return(Response{}, errors.New("fail"))
 			},
 			wantStatus: fiber.StatusInternalServerError,
 		},
+		{
+			name: "should return not found for missing employee",
+			url:  "/api/v1/employees/9",
+			mockSetup: func(svc *MockService) {
+				svc.On("FindById", mock.Anything, int64(9)).Return(Response{}, common.NotFoundError{Message: "employee with id 9 not found"})
+			},
+			wantStatus: fiber.StatusNotFound,
+		},
 	}
 
 	for _, tt := range tests {
@@ -347,10 +433,10 @@ func TestGetEmployee(t *testing.T) {
 			}
 
 			server := web.NewServer()
-			server.GroupApiV1.Use(auth)
+			server.GroupApiV1.Use(auth, policyMiddleware())
 
 			svc := new(MockService)
-			controller := NewController(server, svc, common.NewLogger(common.GetConfig(".env")))
+			controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
 			controller.RegisterRoutes()
 
 			if tt.mockSetup != nil {
@@ -399,11 +485,11 @@ func TestGetEmployeesPageValidation(t *testing.T) {
 			}
 
 			server := web.NewServer()
-			server.GroupApiV1.Use(auth)
+			server.GroupApiV1.Use(auth, policyMiddleware())
 
 			repo := new(MockRepo)
 			svc := newTestService(repo)
-			controller := NewController(server, svc, common.NewLogger(common.GetConfig(".env")))
+			controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
 			controller.RegisterRoutes()
 
 			req := httptest.NewRequest(http.MethodGet, "/api/v1/employees/page"+tt.query, nil)
@@ -470,11 +556,11 @@ func TestAuthorization_AdminEndpoints(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			server := web.NewServer()
 			// Навешиваем тестовый JWT-мидлвар
-			server.GroupApiV1.Use(testJWTMiddleware(secret))
+			server.GroupApiV1.Use(testJWTMiddleware(secret), policyMiddleware())
 
 			svc := new(MockService)
 			logger := common.NewLogger(common.GetConfig(".env"))
-			ctrl := NewController(server, svc, logger)
+			ctrl := NewController(server, svc, nil, logger)
 			ctrl.RegisterRoutes()
 
 			if tt.setupMocks != nil {
@@ -548,14 +634,14 @@ func TestAuthorization_ReadEndpoints(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			server := web.NewServer()
-			server.GroupApiV1.Use(testJWTMiddleware(secret))
+			server.GroupApiV1.Use(testJWTMiddleware(secret), policyMiddleware())
 
 			svc := new(MockService)
-			ctrl := NewController(server, svc, common.NewLogger(common.GetConfig(".env")))
+			ctrl := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
 			ctrl.RegisterRoutes()
 
 			// мок для успешного чтения, он не должен вызываться при 401/403
-			svc.On("FindById", int64(7)).Return(Response{Id: 7, Name: "E"}, nil).Maybe()
+			svc.On("FindById", mock.Anything, int64(7)).Return(Response{Id: 7, Name: "E"}, nil).Maybe()
 
 			req := httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7", nil)
 			if tt.authHeader != "" {
@@ -573,7 +659,7 @@ func testJWTMiddleware(secret []byte) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		auth := c.Get("Authorization")
 		if auth == "" || !strings.HasPrefix(strings.ToLower(auth), "bearer ") {
-			// не ставим locals — дальше requireRoles вернёт 401
+			// не ставим locals — дальше политика вернёт 401
 			return c.Next()
 		}
 		raw := strings.TrimSpace(auth[len("Bearer "):])
@@ -591,7 +677,7 @@ func testJWTMiddleware(secret []byte) fiber.Handler {
 			// токен невалидный — locals не ставим
 			return c.Next()
 		}
-		// токен валиден — кладём в Locals для requireRoles(...)
+		// токен валиден — кладём в Locals для проверки политикой
 		c.Locals(web.JwtKey, tkn)
 		return c.Next()
 	}
@@ -635,3 +721,609 @@ func makeHS256Token(secret []byte, roles []string, withExp bool, expired bool) (
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, m)
 	return t.SignedString(secret)
 }
+
+func TestAssignRoles(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		name       string
+		url        string
+		body       string
+		mockSetup  func(*MockService)
+		wantStatus int
+	}{
+		{
+			name: "should assign roles",
+			url:  "/api/v1/employees/3/roles",
+			body: `{"role_ids":[1,2]}`,
+			mockSetup: func(svc *MockService) {
+				svc.On("AssignRoles", mock.Anything, int64(3), AssignRolesRequest{RolesRequest: RolesRequest{RoleIds: []int64{1, 2}}}).Return(nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "should assign roles for a period",
+			url:  "/api/v1/employees/3/roles",
+			body: `{"role_ids":[1],"valid_from":"2026-01-01T09:00:00Z","valid_to":"2026-01-31T18:00:00Z"}`,
+			mockSetup: func(svc *MockService) {
+				from := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+				to := time.Date(2026, 1, 31, 18, 0, 0, 0, time.UTC)
+				svc.On("AssignRoles", mock.Anything, int64(3), AssignRolesRequest{
+					RolesRequest: RolesRequest{RoleIds: []int64{1}}, ValidFrom: &from, ValidTo: &to,
+				}).Return(nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should return bad request on invalid id",
+			url:        "/api/v1/employees/abc/roles",
+			body:       `{"role_ids":[1]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "should return bad request on validation error",
+			url:  "/api/v1/employees/3/roles",
+			body: `{"role_ids":[]}`,
+			mockSetup: func(svc *MockService) {
+				svc.On("AssignRoles", mock.Anything, int64(3), mock.Anything).Return(common.RequestValidationError{Message: "roles"})
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "should return internal error on service failure",
+			url:  "/api/v1/employees/3/roles",
+			body: `{"role_ids":[1]}`,
+			mockSetup: func(svc *MockService) {
+				svc.On("AssignRoles", mock.Anything, int64(3), mock.Anything).Return(errors.New("fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims = &web.IdmClaims{
+				RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmAdmin}},
+			}
+			var auth = func(c *fiber.Ctx) error {
+				c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+				return c.Next()
+			}
+
+			server := web.NewServer()
+			server.GroupApiV1.Use(auth, policyMiddleware())
+
+			svc := new(MockService)
+			controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+			controller.RegisterRoutes()
+
+			if tt.mockSetup != nil {
+				tt.mockSetup(svc)
+			}
+
+			req := httptest.NewRequest(fiber.MethodPost, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := server.App.Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestUpdateEmployee(t *testing.T) {
+	a := assert.New(t)
+	version := time.Date(2025, 6, 24, 12, 0, 0, 0, time.UTC)
+	updated := Response{Id: 3, Name: "bob", UpdatedAt: version.Add(time.Minute)}
+
+	tests := []struct {
+		name       string
+		method     string
+		ifMatch    string
+		body       string
+		mockSetup  func(*MockService)
+		wantStatus int
+		wantETag   string
+	}{
+		{
+			name:    "put with matching version",
+			method:  fiber.MethodPut,
+			ifMatch: common.ETag(version),
+			body:    `{"name":"bob"}`,
+			mockSetup: func(svc *MockService) {
+				svc.On("Update", mock.Anything, int64(3), UpdateRequest{Name: "bob"}, mock.MatchedBy(func(v *time.Time) bool {
+					return v != nil && v.Equal(version)
+				})).Return(updated, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantETag:   common.ETag(updated.UpdatedAt),
+		},
+		{
+			name:    "put with stale version",
+			method:  fiber.MethodPut,
+			ifMatch: common.ETag(version),
+			body:    `{"name":"bob"}`,
+			mockSetup: func(svc *MockService) {
+				svc.On("Update", mock.Anything, int64(3), UpdateRequest{Name: "bob"}, mock.Anything).
+					Return(Response{}, common.PreconditionFailedError{Message: "modified"})
+			},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "put with malformed If-Match",
+			method:     fiber.MethodPut,
+			ifMatch:    `"abc"`,
+			body:       `{"name":"bob"}`,
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:   "patch without If-Match",
+			method: fiber.MethodPatch,
+			body:   `{"name":"bob"}`,
+			mockSetup: func(svc *MockService) {
+				svc.On("Patch", mock.Anything, int64(3), []byte(`{"name":"bob"}`), (*time.Time)(nil)).Return(updated, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantETag:   common.ETag(updated.UpdatedAt),
+		},
+		{
+			name:   "patch validation error",
+			method: fiber.MethodPatch,
+			body:   `{"name":null}`,
+			mockSetup: func(svc *MockService) {
+				svc.On("Patch", mock.Anything, int64(3), mock.Anything, mock.Anything).
+					Return(Response{}, common.RequestValidationError{Message: "name"})
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims = &web.IdmClaims{
+				RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmAdmin}},
+			}
+			var auth = func(c *fiber.Ctx) error {
+				c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+				return c.Next()
+			}
+
+			server := web.NewServer()
+			server.GroupApiV1.Use(auth, policyMiddleware())
+
+			svc := new(MockService)
+			controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+			controller.RegisterRoutes()
+
+			if tt.mockSetup != nil {
+				tt.mockSetup(svc)
+			}
+
+			req := httptest.NewRequest(tt.method, "/api/v1/employees/3", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			resp, err := server.App.Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+			a.Equal(tt.wantETag, resp.Header.Get("ETag"))
+		})
+	}
+}
+
+// policyMiddleware применяет к тестовому серверу ту же политику доступа, что и в приложении
+func policyMiddleware() fiber.Handler {
+	routePolicy, err := policy.Load("../../policies.yaml")
+	if err != nil {
+		panic(err)
+	}
+	enforcer, err := policy.NewEnforcer(routePolicy, "")
+	if err != nil {
+		panic(err)
+	}
+	return enforcer.Middleware()
+}
+
+func TestRestoreEmployee(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		name       string
+		url        string
+		roles      []string
+		mockSetup  func(*MockService)
+		wantStatus int
+	}{
+		{
+			name:  "should restore employee",
+			url:   "/api/v1/employees/3/restore",
+			roles: []string{web.IdmAdmin},
+			mockSetup: func(svc *MockService) {
+				svc.On("Restore", mock.Anything, int64(3)).Return(Response{Id: 3, Name: "bob"}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "should return not found if employee is not deleted",
+			url:   "/api/v1/employees/3/restore",
+			roles: []string{web.IdmAdmin},
+			mockSetup: func(svc *MockService) {
+				svc.On("Restore", mock.Anything, int64(3)).
+					Return(Response{}, common.NotFoundError{Message: "deleted employee with id 3 not found"})
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:  "should return bad request if name is taken",
+			url:   "/api/v1/employees/3/restore",
+			roles: []string{web.IdmAdmin},
+			mockSetup: func(svc *MockService) {
+				svc.On("Restore", mock.Anything, int64(3)).
+					Return(Response{}, common.AlreadyExistsError{Message: "employee already exists"})
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should return bad request on invalid id",
+			url:        "/api/v1/employees/abc/restore",
+			roles:      []string{web.IdmAdmin},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should forbid user without admin role",
+			url:        "/api/v1/employees/3/restore",
+			roles:      []string{web.IdmUser},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims = &web.IdmClaims{
+				RealmAccess: web.RealmAccessClaims{Roles: tt.roles},
+			}
+			var auth = func(c *fiber.Ctx) error {
+				c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+				return c.Next()
+			}
+
+			server := web.NewServer()
+			server.GroupApiV1.Use(auth, policyMiddleware())
+
+			svc := new(MockService)
+			controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+			controller.RegisterRoutes()
+
+			if tt.mockSetup != nil {
+				tt.mockSetup(svc)
+			}
+
+			resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, tt.url, nil), -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetEmployeesPage_IncludeDeleted(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		name       string
+		roles      []string
+		mockSetup  func(*MockService)
+		wantStatus int
+	}{
+		{
+			name:  "admin sees deleted employees",
+			roles: []string{web.IdmAdmin},
+			mockSetup: func(svc *MockService) {
+				svc.On("GetEmployeesPage", mock.Anything, PageRequest{PageSize: 10, IncludeDeleted: true}).
+					Return(PageResponse{}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "user is forbidden",
+			roles:      []string{web.IdmUser},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims = &web.IdmClaims{
+				RealmAccess: web.RealmAccessClaims{Roles: tt.roles},
+			}
+			var auth = func(c *fiber.Ctx) error {
+				c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+				return c.Next()
+			}
+
+			server := web.NewServer()
+			server.GroupApiV1.Use(auth, policyMiddleware())
+
+			svc := new(MockService)
+			controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+			controller.RegisterRoutes()
+
+			if tt.mockSetup != nil {
+				tt.mockSetup(svc)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/employees/page?pageSize=10&pageNumber=0&includeDeleted=true", nil)
+			resp, err := server.App.Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTransitionEmployee(t *testing.T) {
+	a := assert.New(t)
+	leave := TransitionRequest{Status: StatusOnLeave, EffectiveDate: "2025-08-01", Reason: "vacation"}
+
+	tests := []struct {
+		name       string
+		roles      []string
+		body       string
+		mockSetup  func(*MockService)
+		wantStatus int
+	}{
+		{
+			name:  "should change status",
+			roles: []string{web.IdmAdmin},
+			body:  `{"status":"on_leave","effective_date":"2025-08-01","reason":"vacation"}`,
+			mockSetup: func(svc *MockService) {
+				svc.On("Transition", mock.Anything, int64(3), leave).
+					Return(Response{Id: 3, Name: "bob", Status: StatusOnLeave}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "should return conflict on forbidden transition",
+			roles: []string{web.IdmAdmin},
+			body:  `{"status":"on_leave","effective_date":"2025-08-01","reason":"vacation"}`,
+			mockSetup: func(svc *MockService) {
+				svc.On("Transition", mock.Anything, int64(3), leave).
+					Return(Response{}, common.ConflictError{Message: "employee with id 3 cannot change status from terminated to on_leave"})
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "should return bad request on malformed body",
+			roles:      []string{web.IdmAdmin},
+			body:       `{"status":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should forbid user without admin role",
+			roles:      []string{web.IdmUser},
+			body:       `{"status":"on_leave"}`,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims = &web.IdmClaims{
+				RealmAccess: web.RealmAccessClaims{Roles: tt.roles},
+			}
+			var auth = func(c *fiber.Ctx) error {
+				c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+				return c.Next()
+			}
+
+			server := web.NewServer()
+			server.GroupApiV1.Use(auth, policyMiddleware())
+
+			svc := new(MockService)
+			controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+			controller.RegisterRoutes()
+
+			if tt.mockSetup != nil {
+				tt.mockSetup(svc)
+			}
+
+			req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/3/status", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := server.App.Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetStatusHistory(t *testing.T) {
+	a := assert.New(t)
+	server := web.NewServer()
+	svc := new(MockService)
+	controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+	controller.RegisterRoutes()
+
+	history := []StatusHistoryResponse{{Id: 1, FromStatus: StatusPreHire, ToStatus: StatusActive, EffectiveDate: "2025-07-01"}}
+	svc.On("GetStatusHistory", mock.Anything, int64(3)).Return(history, nil)
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/3/status-history", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	a.NoError(err)
+	var got common.Response[[]StatusHistoryResponse]
+	a.NoError(json.Unmarshal(body, &got))
+	a.True(got.Success)
+	a.Equal(history, got.Data)
+}
+
+func TestGetEmployeesByOrgUnit(t *testing.T) {
+	a := assert.New(t)
+	server := web.NewServer()
+	svc := new(MockService)
+	controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+	controller.RegisterRoutes()
+
+	unitId := int64(4)
+	employees := []Response{{Id: 1, Name: "Ann", OrgUnitId: &unitId}}
+	svc.On("FindByOrgUnit", mock.Anything, unitId, false).Return(employees, nil)
+	svc.On("FindByOrgUnit", mock.Anything, unitId, true).Return(employees, nil)
+
+	for _, url := range []string{"/api/v1/org-units/4/employees", "/api/v1/org-units/4/employees?subtree=true"} {
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, url, nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		a.NoError(err)
+		var got common.Response[[]Response]
+		a.NoError(json.Unmarshal(body, &got))
+		a.Equal(employees, got.Data)
+	}
+	svc.AssertExpectations(t)
+}
+
+func TestGetOrgChart(t *testing.T) {
+	a := assert.New(t)
+	server := web.NewServer()
+	svc := new(MockService)
+	controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+	controller.RegisterRoutes()
+
+	chart := []ChartNode{{Id: 1, Name: "Alice", Reports: []ChartNode{{Id: 2, Name: "Bob"}}}}
+	svc.On("OrgChart", mock.Anything, OrgChartRequest{}).Return(chart, nil)
+	svc.On("OrgChart", mock.Anything, OrgChartRequest{Root: 1, Format: OrgChartDot}).Return(chart, nil)
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/org-chart", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	a.NoError(err)
+	var got common.Response[[]ChartNode]
+	a.NoError(json.Unmarshal(body, &got))
+	a.Equal(chart, got.Data)
+
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/org-chart?root=1&format=dot", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	a.Equal("text/vnd.graphviz; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+	body, err = io.ReadAll(resp.Body)
+	a.NoError(err)
+	a.Equal(renderDot(chart), string(body))
+	svc.AssertExpectations(t)
+}
+
+func TestGetReports(t *testing.T) {
+	a := assert.New(t)
+	server := web.NewServer()
+	svc := new(MockService)
+	controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+	controller.RegisterRoutes()
+
+	svc.On("FindReports", mock.Anything, int64(1), true).Return([]Response{{Id: 2, Name: "Bob"}}, nil)
+	svc.On("FindManagers", mock.Anything, int64(9)).Return([]Response(nil), common.NotFoundError{Message: "employee with id 9 not found"})
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/1/reports?transitive=true", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/9/managers", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusNotFound, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
+
+func TestGetGrants(t *testing.T) {
+	a := assert.New(t)
+	server := web.NewServer()
+	svc := new(MockService)
+	controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+	controller.RegisterRoutes()
+
+	validTo := time.Date(2026, 1, 31, 18, 0, 0, 0, time.UTC)
+	grants := []GrantResponse{{
+		EmployeeId: 1, RoleId: 2, RoleName: "On-call",
+		ValidFrom: time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC), ValidTo: &validTo, Active: true,
+	}}
+	svc.On("FindGrants", mock.Anything, int64(1)).Return(grants, nil)
+	svc.On("FindGrants", mock.Anything, int64(9)).Return([]GrantResponse(nil), common.NotFoundError{Message: "employee with id 9 not found"})
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/1/grants", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	a.NoError(err)
+	var got common.Response[[]GrantResponse]
+	a.NoError(json.Unmarshal(body, &got))
+	a.Equal(grants, got.Data)
+
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/9/grants", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusNotFound, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
+
+func TestGetRolesByEmployeeId(t *testing.T) {
+	a := assert.New(t)
+	server := web.NewServer()
+	roleSvc := new(MockRoleService)
+	controller := NewController(server, new(MockService), roleSvc, common.NewLogger(common.GetConfig(".env")))
+	controller.RegisterRoutes()
+
+	roles := []role.Response{{Id: 2, Name: "Admin"}}
+	roleSvc.On("FindByEmployeeId", mock.Anything, int64(1)).Return(roles, nil)
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/1/roles", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	a.NoError(err)
+	var got common.Response[[]role.Response]
+	a.NoError(json.Unmarshal(body, &got))
+	a.Equal(roles, got.Data)
+	roleSvc.AssertExpectations(t)
+}
+
+func TestGetEmployeesByRoleId(t *testing.T) {
+	a := assert.New(t)
+	server := web.NewServer()
+	svc := new(MockService)
+	controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+	controller.RegisterRoutes()
+
+	svc.On("FindByRoleId", mock.Anything, int64(2)).Return([]Response{{Id: 1, Name: "Ann"}}, nil)
+	svc.On("FindByRoleId", mock.Anything, int64(9)).Return([]Response(nil), common.NotFoundError{Message: "role with id 9 not found"})
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/2/employees", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/9/employees", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusNotFound, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
+
+func TestGetEmployeesPage_AttributeFilter(t *testing.T) {
+	a := assert.New(t)
+	server := web.NewServer()
+	svc := new(MockService)
+	controller := NewController(server, svc, nil, common.NewLogger(common.GetConfig(".env")))
+	controller.RegisterRoutes()
+
+	// значения передаются сервису строками, к типам атрибутов их приводит сервис
+	svc.On("GetEmployeesPage", mock.Anything, PageRequest{
+		PageSize:   10,
+		TextFilter: "ann",
+		Attributes: Attributes{"clearance_level": "3", "cost_center": "CC-0042"},
+	}).Return(PageResponse{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/v1/employees/page?pageSize=10&textFilter=ann&attr.clearance_level=3&attr.cost_center=CC-0042", nil)
+	resp, err := server.App.Test(req, -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
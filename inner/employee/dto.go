@@ -7,6 +7,27 @@ type Entity struct {
 	Name      string    `db:"name"`
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
+	// DeletedAt и DeletedBy заполнены у мягко удалённых сотрудников
+	DeletedAt *time.Time `db:"deleted_at"`
+	DeletedBy *string    `db:"deleted_by"`
+	// Status - статус жизненного цикла, меняется только через Service.Transition
+	Status    string     `db:"status"`
+	StartDate *time.Time `db:"start_date"`
+	EndDate   *time.Time `db:"end_date"`
+	// профиль: незаполненные поля хранятся как NULL, email, login и табельный номер уникальны
+	Email          *string `db:"email"`
+	Login          *string `db:"login"`
+	FirstName      *string `db:"first_name"`
+	LastName       *string `db:"last_name"`
+	Phone          *string `db:"phone"`
+	Title          *string `db:"title"`
+	EmployeeNumber *string `db:"employee_number"`
+	// Attributes - дополнительные атрибуты, описанные в AttributeSchema
+	Attributes Attributes `db:"attributes"`
+	// OrgUnitId - подразделение, в котором работает сотрудник
+	OrgUnitId *int64 `db:"org_unit_id"`
+	// ManagerId - непосредственный руководитель; цепочка руководителей не может замыкаться
+	ManagerId *int64 `db:"manager_id"`
 }
 
 func (e *Entity) toResponse() Response {
@@ -15,28 +36,204 @@ func (e *Entity) toResponse() Response {
 		Name:      e.Name,
 		CreatedAt: e.CreatedAt,
 		UpdatedAt: e.UpdatedAt,
+		DeletedAt: e.DeletedAt,
+		DeletedBy: e.DeletedBy,
+		Status:    e.Status,
+		StartDate: formatDate(e.StartDate),
+		EndDate:   formatDate(e.EndDate),
+
+		Email:          valueOf(e.Email),
+		Login:          valueOf(e.Login),
+		FirstName:      valueOf(e.FirstName),
+		LastName:       valueOf(e.LastName),
+		Phone:          valueOf(e.Phone),
+		Title:          valueOf(e.Title),
+		EmployeeNumber: valueOf(e.EmployeeNumber),
+		Attributes:     e.Attributes,
+		OrgUnitId:      e.OrgUnitId,
+		ManagerId:      e.ManagerId,
 	}
 }
 
 type Response struct {
-	Id        int64     `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Id        int64      `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *string    `json:"deleted_by,omitempty"`
+	Status    string     `json:"status"`
+	StartDate string     `json:"start_date,omitempty"`
+	EndDate   string     `json:"end_date,omitempty"`
+
+	Email          string         `json:"email,omitempty"`
+	Login          string         `json:"login,omitempty"`
+	FirstName      string         `json:"first_name,omitempty"`
+	LastName       string         `json:"last_name,omitempty"`
+	Phone          string         `json:"phone,omitempty"`
+	Title          string         `json:"title,omitempty"`
+	EmployeeNumber string         `json:"employee_number,omitempty"`
+	Attributes     map[string]any `json:"attributes,omitempty"`
+	OrgUnitId      *int64         `json:"org_unit_id,omitempty"`
+	ManagerId      *int64         `json:"manager_id,omitempty"`
 }
 
 type CreateRequest struct {
 	Name string `json:"name" validate:"required,min=2,max=155"`
+	// Status - начальный статус: pre_hire для будущих сотрудников, по умолчанию active
+	Status    string `json:"status" validate:"omitempty,oneof=pre_hire active"`
+	StartDate string `json:"start_date" validate:"omitempty,datetime=2006-01-02"`
+	Profile
 }
 
 func (req *CreateRequest) ToEntity() *Entity {
-	return &Entity{Name: req.Name}
+	status := req.Status
+	if status == "" {
+		status = StatusActive
+	}
+	entity := &Entity{Name: req.Name, Status: status, StartDate: parseDate(req.StartDate)}
+	req.Profile.applyTo(entity)
+	return entity
+}
+
+// Profile - профильные поля сотрудника, общие для создания и изменения
+type Profile struct {
+	Email          string `json:"email,omitempty" validate:"omitempty,email,max=255"`
+	Login          string `json:"login,omitempty" validate:"omitempty,min=2,max=64,login"`
+	FirstName      string `json:"first_name,omitempty" validate:"omitempty,max=155"`
+	LastName       string `json:"last_name,omitempty" validate:"omitempty,max=155"`
+	Phone          string `json:"phone,omitempty" validate:"omitempty,e164"`
+	Title          string `json:"title,omitempty" validate:"omitempty,max=255"`
+	EmployeeNumber string `json:"employee_number,omitempty" validate:"omitempty,max=32,printascii"`
+	// Attributes - дополнительные атрибуты; допустимые имена, типы и правила задаются в конфигурации
+	Attributes map[string]any `json:"attributes,omitempty"`
+	OrgUnitId  *int64         `json:"org_unit_id,omitempty" validate:"omitempty,gt=0"`
+	ManagerId  *int64         `json:"manager_id,omitempty" validate:"omitempty,gt=0"`
+}
+
+func (p *Profile) applyTo(e *Entity) {
+	e.Email = nullable(p.Email)
+	e.Login = nullable(p.Login)
+	e.FirstName = nullable(p.FirstName)
+	e.LastName = nullable(p.LastName)
+	e.Phone = nullable(p.Phone)
+	e.Title = nullable(p.Title)
+	e.EmployeeNumber = nullable(p.EmployeeNumber)
+	e.Attributes = p.Attributes
+	e.OrgUnitId = p.OrgUnitId
+	e.ManagerId = p.ManagerId
+}
+
+func (e *Entity) toProfile() Profile {
+	return Profile{
+		Email:          valueOf(e.Email),
+		Login:          valueOf(e.Login),
+		FirstName:      valueOf(e.FirstName),
+		LastName:       valueOf(e.LastName),
+		Phone:          valueOf(e.Phone),
+		Title:          valueOf(e.Title),
+		EmployeeNumber: valueOf(e.EmployeeNumber),
+		Attributes:     e.Attributes,
+		OrgUnitId:      e.OrgUnitId,
+		ManagerId:      e.ManagerId,
+	}
+}
+
+// nullable превращает пустую строку в NULL, чтобы незаполненные поля не нарушали уникальность
+func nullable(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// TransitionRequest - перевод сотрудника в другой статус жизненного цикла
+type TransitionRequest struct {
+	Status string `json:"status" validate:"required,oneof=pre_hire active suspended on_leave terminated"`
+	// EffectiveDate - дата, с которой действует статус: не позже текущей, по умолчанию текущая
+	EffectiveDate string `json:"effective_date" validate:"omitempty,datetime=2006-01-02"`
+	Reason        string `json:"reason" validate:"max=255"`
+}
+
+// StatusChangedEvent - данные события о смене статуса сотрудника
+type StatusChangedEvent struct {
+	FromStatus    string `json:"from_status"`
+	ToStatus      string `json:"to_status"`
+	EffectiveDate string `json:"effective_date"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// StatusHistoryEntity - запись истории смены статусов сотрудника
+type StatusHistoryEntity struct {
+	Id            int64     `db:"id"`
+	EmployeeId    int64     `db:"employee_id"`
+	FromStatus    string    `db:"from_status"`
+	ToStatus      string    `db:"to_status"`
+	EffectiveDate time.Time `db:"effective_date"`
+	Reason        string    `db:"reason"`
+	ChangedBy     string    `db:"changed_by"`
+	ChangedAt     time.Time `db:"changed_at"`
+}
+
+func (e *StatusHistoryEntity) toResponse() StatusHistoryResponse {
+	return StatusHistoryResponse{
+		Id:            e.Id,
+		FromStatus:    e.FromStatus,
+		ToStatus:      e.ToStatus,
+		EffectiveDate: e.EffectiveDate.Format(dateLayout),
+		Reason:        e.Reason,
+		ChangedBy:     e.ChangedBy,
+		ChangedAt:     e.ChangedAt,
+	}
+}
+
+type StatusHistoryResponse struct {
+	Id            int64     `json:"id"`
+	FromStatus    string    `json:"from_status"`
+	ToStatus      string    `json:"to_status"`
+	EffectiveDate string    `json:"effective_date"`
+	Reason        string    `json:"reason,omitempty"`
+	ChangedBy     string    `json:"changed_by,omitempty"`
+	ChangedAt     time.Time `json:"changed_at"`
+}
+
+// UpdateRequest полное описание сотрудника для замены (PUT) и результат применения merge patch (PATCH)
+type UpdateRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=155"`
+	Profile
+}
+
+func (req *UpdateRequest) toEntity(id int64) *Entity {
+	entity := &Entity{Id: id, Name: req.Name}
+	req.Profile.applyTo(entity)
+	return entity
+}
+
+func (e *Entity) toUpdateRequest() UpdateRequest {
+	return UpdateRequest{Name: e.Name, Profile: e.toProfile()}
+}
+
+// RolesRequest список ролей для назначения сотруднику или отзыва у него
+type RolesRequest struct {
+	RoleIds []int64 `json:"role_ids" validate:"required,min=1,dive,gt=0"`
 }
 
 type PageRequest struct {
 	PageSize   int `validate:"min=1,max=100"`
 	PageNumber int `validate:"min=0"`
 	TextFilter string
+	// IncludeDeleted - показывать и мягко удалённых сотрудников (только для администраторов)
+	IncludeDeleted bool
+	// Attributes - фильтр по дополнительным атрибутам (точное совпадение);
+	// в запросе передаётся параметрами attr.<name>=<value>
+	Attributes Attributes `query:"-" swaggerignore:"true"`
 }
 
 type PageResponse struct {
@@ -45,3 +242,35 @@ type PageResponse struct {
 	PageNumber int   `json:"page_number"`
 	Total      int64 `json:"total"`
 }
+
+// auditEntityType - тип сущности сотрудника в журнале аудита
+const auditEntityType = "employee"
+
+// auditState - состояние сотрудника, которое сохраняется в журнале аудита
+type auditState struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+	Profile
+}
+
+func (e *Entity) toAuditState() auditState {
+	return auditState{Id: e.Id, Name: e.Name, Profile: e.toProfile()}
+}
+
+// auditLifecycle - статус сотрудника и даты работы в журнале аудита
+type auditLifecycle struct {
+	Status    string `json:"status"`
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+}
+
+func (e *Entity) toAuditLifecycle() auditLifecycle {
+	return auditLifecycle{Status: e.Status, StartDate: formatDate(e.StartDate), EndDate: formatDate(e.EndDate)}
+}
+
+// auditRoles - изменение ролей сотрудника в журнале аудита
+type auditRoles struct {
+	RoleIds   []int64    `json:"role_ids"`
+	ValidFrom *time.Time `json:"valid_from,omitempty"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+}
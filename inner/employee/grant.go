@@ -0,0 +1,66 @@
+package employee
+
+import (
+	"time"
+
+	"idm/inner/common"
+)
+
+// AssignRolesRequest - роли для назначения сотруднику и срок действия назначения.
+// Без valid_from назначение действует сразу, без valid_to - бессрочно.
+// Повторное назначение уже выданной роли заменяет её срок действия
+type AssignRolesRequest struct {
+	RolesRequest
+	ValidFrom *time.Time `json:"valid_from,omitempty"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+}
+
+// checkValidity проверяет, что срок действия назначения закончится позже его начала и позже now
+func (req *AssignRolesRequest) checkValidity(now time.Time) error {
+	if req.ValidTo == nil {
+		return nil
+	}
+	if req.ValidFrom != nil && !req.ValidTo.After(*req.ValidFrom) {
+		return common.RequestValidationError{Message: "valid_to must be after valid_from"}
+	}
+	if !req.ValidTo.After(now) {
+		return common.RequestValidationError{Message: "valid_to must be in the future"}
+	}
+	return nil
+}
+
+// GrantEntity - назначение роли сотруднику вместе со сроком его действия
+type GrantEntity struct {
+	EmployeeId int64      `db:"employee_id"`
+	RoleId     int64      `db:"role_id"`
+	RoleName   string     `db:"role_name"`
+	ValidFrom  time.Time  `db:"valid_from"`
+	ValidTo    *time.Time `db:"valid_to"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// active сообщает, действует ли назначение в момент now
+func (e *GrantEntity) active(now time.Time) bool {
+	return !e.ValidFrom.After(now) && (e.ValidTo == nil || e.ValidTo.After(now))
+}
+
+func (e *GrantEntity) toResponse(now time.Time) GrantResponse {
+	return GrantResponse{
+		EmployeeId: e.EmployeeId,
+		RoleId:     e.RoleId,
+		RoleName:   e.RoleName,
+		ValidFrom:  e.ValidFrom,
+		ValidTo:    e.ValidTo,
+		Active:     e.active(now),
+	}
+}
+
+// GrantResponse - назначение роли сотруднику; Active - действует ли оно сейчас
+type GrantResponse struct {
+	EmployeeId int64      `json:"employee_id"`
+	RoleId     int64      `json:"role_id"`
+	RoleName   string     `json:"role_name,omitempty"`
+	ValidFrom  time.Time  `json:"valid_from"`
+	ValidTo    *time.Time `json:"valid_to,omitempty"`
+	Active     bool       `json:"active"`
+}
@@ -0,0 +1,64 @@
+package employee
+
+import "time"
+
+// статусы жизненного цикла сотрудника
+const (
+	StatusPreHire    = "pre_hire"
+	StatusActive     = "active"
+	StatusSuspended  = "suspended"
+	StatusOnLeave    = "on_leave"
+	StatusTerminated = "terminated"
+)
+
+// dateLayout - формат дат начала и окончания работы в запросах и ответах
+const dateLayout = "2006-01-02"
+
+// transitions - допустимые переходы между статусами; terminated - конечный статус
+var transitions = map[string][]string{
+	StatusPreHire:   {StatusActive, StatusTerminated},
+	StatusActive:    {StatusSuspended, StatusOnLeave, StatusTerminated},
+	StatusSuspended: {StatusActive, StatusTerminated},
+	StatusOnLeave:   {StatusActive, StatusTerminated},
+}
+
+// canTransition сообщает, разрешён ли переход сотрудника из статуса from в статус to
+func canTransition(from, to string) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTransition возвращает копию сотрудника в статусе to.
+// Дата начала работы проставляется при первом переходе в active, дата окончания - при увольнении
+func applyTransition(current *Entity, to string, effective time.Time) *Entity {
+	next := *current
+	next.Status = to
+	if to == StatusActive && next.StartDate == nil {
+		next.StartDate = &effective
+	}
+	if to == StatusTerminated {
+		next.EndDate = &effective
+	}
+	return &next
+}
+
+// formatDate форматирует дату для ответа; nil - пустая строка
+func formatDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(dateLayout)
+}
+
+// parseDate разбирает дату из запроса, уже проверенную валидатором; пустая строка - nil
+func parseDate(value string) *time.Time {
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil
+	}
+	return &date
+}
@@ -0,0 +1,86 @@
+package employee
+
+import (
+	"fmt"
+	"strings"
+)
+
+// форматы выгрузки оргструктуры
+const (
+	OrgChartJson = "json"
+	OrgChartDot  = "dot"
+)
+
+// OrgChartRequest - параметры выгрузки оргструктуры
+type OrgChartRequest struct {
+	// Root - сотрудник, с которого строится дерево; по умолчанию - вся организация
+	Root int64 `validate:"min=0"`
+	// Format - json (по умолчанию) или dot (Graphviz)
+	Format string `validate:"omitempty,oneof=json dot"`
+}
+
+// ChartNode - сотрудник в дереве оргструктуры вместе с подчинёнными
+type ChartNode struct {
+	Id      int64       `json:"id"`
+	Name    string      `json:"name"`
+	Title   string      `json:"title,omitempty"`
+	Reports []ChartNode `json:"reports,omitempty"`
+}
+
+// buildOrgChart собирает дерево из сотрудников, упорядоченных по id.
+// Корнями становятся сотрудники без руководителя среди переданных: у верхнего уровня организации
+// руководителя нет, у корня поддерева он остаётся за пределами выборки
+func buildOrgChart(employees []Entity) []ChartNode {
+	present := make(map[int64]bool, len(employees))
+	for i := range employees {
+		present[employees[i].Id] = true
+	}
+	reports := make(map[int64][]*Entity)
+	var roots []*Entity
+	for i := range employees {
+		e := &employees[i]
+		if e.ManagerId != nil && present[*e.ManagerId] {
+			reports[*e.ManagerId] = append(reports[*e.ManagerId], e)
+		} else {
+			roots = append(roots, e)
+		}
+	}
+	var build func(e *Entity) ChartNode
+	build = func(e *Entity) ChartNode {
+		node := ChartNode{Id: e.Id, Name: e.Name, Title: valueOf(e.Title)}
+		for _, report := range reports[e.Id] {
+			node.Reports = append(node.Reports, build(report))
+		}
+		return node
+	}
+	var chart []ChartNode
+	for _, root := range roots {
+		chart = append(chart, build(root))
+	}
+	return chart
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// renderDot выводит оргструктуру в формате Graphviz DOT; рёбра направлены от руководителя к подчинённому
+func renderDot(chart []ChartNode) string {
+	var b strings.Builder
+	b.WriteString("digraph org_chart {\n\tnode [shape=box];\n")
+	var write func(node ChartNode)
+	write = func(node ChartNode) {
+		label := dotEscaper.Replace(node.Name)
+		if node.Title != "" {
+			label += `\n` + dotEscaper.Replace(node.Title)
+		}
+		fmt.Fprintf(&b, "\t%d [label=\"%s\"];\n", node.Id, label)
+		for _, report := range node.Reports {
+			fmt.Fprintf(&b, "\t%d -> %d;\n", node.Id, report.Id)
+			write(report)
+		}
+	}
+	for _, root := range chart {
+		write(root)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package employee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildOrgChart(t *testing.T) {
+	ceo, cto := int64(1), int64(2)
+	title := "CTO"
+	employees := []Entity{
+		{Id: 1, Name: "Alice"},
+		{Id: 2, Name: "Bob", Title: &title, ManagerId: &ceo},
+		{Id: 3, Name: "Carol", ManagerId: &cto},
+		{Id: 4, Name: "Dave", ManagerId: &ceo},
+		// руководитель удалён и в выборку не попал - сотрудник становится корнем
+		{Id: 5, Name: "Eve", ManagerId: new(int64)},
+	}
+
+	chart := buildOrgChart(employees)
+	assert.Equal(t, []ChartNode{
+		{Id: 1, Name: "Alice", Reports: []ChartNode{
+			{Id: 2, Name: "Bob", Title: "CTO", Reports: []ChartNode{{Id: 3, Name: "Carol"}}},
+			{Id: 4, Name: "Dave"},
+		}},
+		{Id: 5, Name: "Eve"},
+	}, chart)
+	assert.Nil(t, buildOrgChart(nil))
+}
+
+func TestRenderDot(t *testing.T) {
+	chart := []ChartNode{{Id: 1, Name: `Alice "Al"`, Reports: []ChartNode{{Id: 2, Name: "Bob", Title: "CTO"}}}}
+
+	assert.Equal(t, "digraph org_chart {\n"+
+		"\tnode [shape=box];\n"+
+		"\t1 [label=\"Alice \\\"Al\\\"\"];\n"+
+		"\t1 -> 2;\n"+
+		"\t2 [label=\"Bob\\nCTO\"];\n"+
+		"}\n", renderDot(chart))
+	assert.Equal(t, "digraph org_chart {\n\tnode [shape=box];\n}\n", renderDot(nil))
+}
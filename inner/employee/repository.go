@@ -1,8 +1,19 @@
 package employee
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/role"
+	"idm/inner/tracing"
+	"sort"
 	"strings"
+	"time"
 )
 
 type Repository struct {
@@ -13,86 +24,168 @@ func NewEmployeeRepository(database *sqlx.DB) *Repository {
 	return &Repository{db: database}
 }
 
-func (r *Repository) FindById(id int64) (*Entity, error) {
+func (r *Repository) FindById(ctx context.Context, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindById")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
 	var entity Entity
-	err := r.db.Get(&entity, "SELECT * FROM employee WHERE id = $1", id)
+	err = r.db.GetContext(ctx, &entity, "SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(id)
+	}
 	return &entity, err
 }
 
-func (r *Repository) Add(employee *Entity) error {
-	_, err := r.db.NamedExec(`INSERT INTO employee (name, created_at, updated_at) 
+func (r *Repository) Add(ctx context.Context, employee *Entity) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.Add")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	_, err = r.db.NamedExecContext(ctx, `INSERT INTO employee (name, created_at, updated_at) 
 		VALUES (:name, :created_at, :updated_at)`, employee)
 	return err
 }
 
-func (r *Repository) Save(employee *Entity) (int64, error) {
-	var id int64
+func (r *Repository) Save(ctx context.Context, employee *Entity) (id int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.Save")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
 	query := `INSERT INTO employee (name, created_at, updated_at)
 			  VALUES (:name, :created_at, :updated_at)
 			  RETURNING id`
-	stmt, err := r.db.PrepareNamed(query)
+	stmt, err := r.db.PrepareNamedContext(ctx, query)
 	if err != nil {
 		return 0, err
 	}
-	err = stmt.QueryRowx(employee).Scan(&id)
+	err = stmt.QueryRowxContext(ctx, employee).Scan(&id)
 	return id, err
 }
 
-func (r *Repository) FindAll() ([]Entity, error) {
-	var employees []Entity
-	err := r.db.Select(&employees, "SELECT * FROM employee")
+func (r *Repository) FindAll(ctx context.Context) (employees []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindAll")
+	defer func() { span.Finish(int64(len(employees)), err) }()
+	err = r.db.SelectContext(ctx, &employees, "SELECT * FROM employee WHERE deleted_at IS NULL")
 	return employees, err
 }
 
-func (r *Repository) FindByIds(ids []int64) ([]Entity, error) {
-	query, args, err := sqlx.In("SELECT * FROM employee WHERE id IN (?)", ids)
+func (r *Repository) FindByIds(ctx context.Context, ids []int64) (employees []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindByIds")
+	defer func() { span.Finish(int64(len(employees)), err) }()
+	query, args, err := sqlx.In("SELECT * FROM employee WHERE id IN (?) AND deleted_at IS NULL", ids)
 	if err != nil {
 		return nil, err
 	}
 	query = r.db.Rebind(query)
-	var employees []Entity
-	err = r.db.Select(&employees, query, args...)
+	err = r.db.SelectContext(ctx, &employees, query, args...)
 	return employees, err
 }
 
-func (r *Repository) DeleteById(id int64) error {
-	_, err := r.db.Exec("DELETE FROM employee WHERE id = $1", id)
+// DeleteById мягко удаляет сотрудника: запись остаётся в БД с заполненными deleted_at и deleted_by
+func (r *Repository) DeleteById(ctx context.Context, id int64) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "employee.DeleteById")
+	defer func() { span.Finish(affected, err) }()
+	result, err := r.db.ExecContext(ctx, "UPDATE employee SET deleted_at = now(), deleted_by = $1 WHERE id = $2 AND deleted_at IS NULL",
+		common.ActorFromContext(ctx), id)
+	if err != nil {
+		return err
+	}
+	affected, err = checkAffected(result, fmt.Sprintf("employee with id %d not found", id))
 	return err
 }
 
-func (r *Repository) DeleteByIds(ids []int64) error {
-	query, args, err := sqlx.In("DELETE FROM employee WHERE id IN (?)", ids)
+// DeleteByIds мягко удаляет сотрудников
+func (r *Repository) DeleteByIds(ctx context.Context, ids []int64) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "employee.DeleteByIds")
+	defer func() { span.Finish(affected, err) }()
+	query, args, err := sqlx.In("UPDATE employee SET deleted_at = now(), deleted_by = ? WHERE id IN (?) AND deleted_at IS NULL",
+		common.ActorFromContext(ctx), ids)
 	if err != nil {
 		return err
 	}
 	query = r.db.Rebind(query)
-	_, err = r.db.Exec(query, args...)
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err = checkAffected(result, fmt.Sprintf("employees with ids %v not found", ids))
 	return err
 }
 
-func (r *Repository) BeginTransaction() (tx *sqlx.Tx, err error) {
-	return r.db.Beginx()
+func (r *Repository) BeginTransaction(ctx context.Context) (tx *sqlx.Tx, err error) {
+	return r.db.BeginTxx(ctx, nil)
 }
 
-func (r *Repository) FindByNameTx(tx *sqlx.Tx, name string) (isExists bool, err error) {
-	err = tx.Get(
+func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindByNameTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(
+		ctx,
 		&isExists,
-		"select exists(select 1 from employee where name = $1)",
+		"select exists(select 1 from employee where name = $1 and deleted_at is null)",
 		name,
 	)
 	return isExists, err
 }
 
-func (r *Repository) SaveTx(tx *sqlx.Tx, employee *Entity) (employeeId int64, err error) {
-	err = tx.Get(
+func (r *Repository) SaveTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (employeeId int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.SaveTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(
+		ctx,
 		&employeeId,
-		`insert into employee (name) values ($1) returning id`,
-		employee.Name,
+		`insert into employee (name, status, start_date, email, login, first_name, last_name, phone, title,
+		employee_number, attributes, org_unit_id, manager_id)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) returning id`,
+		employee.Name, employee.Status, employee.StartDate, employee.Email, employee.Login, employee.FirstName,
+		employee.LastName, employee.Phone, employee.Title, employee.EmployeeNumber, employee.Attributes,
+		employee.OrgUnitId, employee.ManagerId,
 	)
-	return employeeId, err
+	return employeeId, uniqueViolation(err)
+}
+
+// FindIdByLogin возвращает id неудалённого сотрудника с логином login или 0, если такого нет.
+// Логин уникален без учёта регистра, поэтому и ищется без его учёта. db - подключение или транзакция:
+// по логину другие пакеты находят сотрудника текущего пользователя
+func FindIdByLogin(ctx context.Context, db sqlx.QueryerContext, login string) (id int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindIdByLogin")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = sqlx.GetContext(ctx, db, &id,
+		"SELECT coalesce((SELECT id FROM employee WHERE lower(login) = lower($1) AND deleted_at IS NULL), 0)", login)
+	return id, err
+}
+
+// FindTakenUniqueFieldsTx возвращает уникальные поля профиля (email, login, employee_number),
+// значения которых уже заняты другими неудалёнными сотрудниками
+func (r *Repository) FindTakenUniqueFieldsTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (fields []string, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindTakenUniqueFieldsTx")
+	defer func() { span.Finish(int64(len(fields)), err) }()
+	var taken struct {
+		Email          bool `db:"email"`
+		Login          bool `db:"login"`
+		EmployeeNumber bool `db:"employee_number"`
+	}
+	err = tx.GetContext(ctx, &taken,
+		`SELECT
+			exists(select 1 from employee where lower(email) = lower($1) and id <> $4 and deleted_at is null) AS email,
+			exists(select 1 from employee where lower(login) = lower($2) and id <> $4 and deleted_at is null) AS login,
+			exists(select 1 from employee where employee_number = $3 and id <> $4 and deleted_at is null) AS employee_number`,
+		employee.Email, employee.Login, employee.EmployeeNumber, employee.Id)
+	if err != nil {
+		return nil, err
+	}
+	if taken.Email {
+		fields = append(fields, "email")
+	}
+	if taken.Login {
+		fields = append(fields, "login")
+	}
+	if taken.EmployeeNumber {
+		fields = append(fields, "employee_number")
+	}
+	return fields, nil
 }
 
-func (r *Repository) FindEmployeesPage(req PageRequest) ([]Entity, int64, error) {
+func (r *Repository) FindEmployeesPage(ctx context.Context, req PageRequest) (entities []Entity, total int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindEmployeesPage")
+	defer func() { span.Finish(int64(len(entities)), err) }()
 	var offset = req.PageNumber * req.PageSize
 	var limit = req.PageSize
 	var filter = req.TextFilter
@@ -100,18 +193,400 @@ func (r *Repository) FindEmployeesPage(req PageRequest) ([]Entity, int64, error)
 	if len(strings.TrimSpace(filter)) >= 3 {
 		partQueryFilter = filter
 	}
-	var entities []Entity
-	err := r.db.Select(&entities,
-		`SELECT id, name FROM employee WHERE ($1 = '' OR name ILIKE '%' || $1 || '%') ORDER BY id LIMIT $2 OFFSET $3`, partQueryFilter, limit, offset)
+	// пустой фильтр по атрибутам ({}) содержится в любом объекте
+	err = r.db.SelectContext(ctx, &entities,
+		`SELECT id, name, deleted_at, deleted_by, status, start_date, end_date, email, login, first_name, last_name,
+		phone, title, employee_number, attributes, org_unit_id, manager_id FROM employee
+		WHERE ($1 = '' OR name ILIKE '%' || $1 || '%') AND ($4 OR deleted_at IS NULL) AND attributes @> $5::jsonb
+		ORDER BY id LIMIT $2 OFFSET $3`, partQueryFilter, limit, offset, req.IncludeDeleted, req.Attributes)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	var total int64
-	err = r.db.Get(&total, `SELECT COUNT(*) FROM employee where ($1 = '' OR name ILIKE '%' || $1 || '%')`, partQueryFilter)
+	err = r.db.GetContext(ctx, &total,
+		`SELECT COUNT(*) FROM employee
+		where ($1 = '' OR name ILIKE '%' || $1 || '%') AND ($2 OR deleted_at IS NULL) AND attributes @> $3::jsonb`,
+		partQueryFilter, req.IncludeDeleted, req.Attributes)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	return entities, total, nil
 }
+
+func (r *Repository) ExistsByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.ExistsByIdTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(
+		ctx,
+		&isExists,
+		"select exists(select 1 from employee where id = $1 and deleted_at is null)",
+		id,
+	)
+	return isExists, err
+}
+
+// FindExistingRoleIdsTx возвращает те id из переданных, для которых существует роль
+func (r *Repository) FindExistingRoleIdsTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]int64, error) {
+	return role.FindExistingIds(ctx, tx, roleIds)
+}
+
+// RoleExists проверяет, что неудалённая роль с таким id существует
+func (r *Repository) RoleExists(ctx context.Context, roleId int64) (bool, error) {
+	ids, err := role.FindExistingIds(ctx, r.db, []int64{roleId})
+	return len(ids) > 0, err
+}
+
+// AssignRolesTx назначает сотруднику роли на срок от validFrom (nil - с текущего момента) до validTo (nil - бессрочно);
+// у уже назначенных ролей срок действия заменяется
+func (r *Repository) AssignRolesTx(
+	ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64, validFrom, validTo *time.Time,
+) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "employee.AssignRolesTx")
+	defer func() { span.Finish(affected, err) }()
+	for _, roleId := range roleIds {
+		result, err := tx.ExecContext(
+			ctx,
+			`insert into employee_role (employee_id, role_id, valid_from, valid_to) values ($1, $2, coalesce($3, now()), $4)
+			on conflict (employee_id, role_id) do update set valid_from = excluded.valid_from, valid_to = excluded.valid_to`,
+			employeeId, roleId, validFrom, validTo,
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			affected += n
+		}
+	}
+	return nil
+}
+
+func (r *Repository) RevokeRolesTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "employee.RevokeRolesTx")
+	defer func() { span.Finish(affected, err) }()
+	query, args, err := sqlx.In("DELETE FROM employee_role WHERE employee_id = ? AND role_id IN (?)", employeeId, roleIds)
+	if err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	affected, _ = result.RowsAffected()
+	return nil
+}
+
+// FindGrants возвращает все назначения ролей сотруднику, включая ещё не начавшиеся и истёкшие, но не отозванные
+func (r *Repository) FindGrants(ctx context.Context, employeeId int64) (grants []GrantEntity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindGrants")
+	defer func() { span.Finish(int64(len(grants)), err) }()
+	err = r.db.SelectContext(ctx, &grants,
+		`SELECT er.employee_id, er.role_id, r.name AS role_name, er.valid_from, er.valid_to, er.created_at
+		FROM employee_role er JOIN role r ON r.id = er.role_id
+		WHERE er.employee_id = $1 AND r.deleted_at IS NULL ORDER BY er.role_id`,
+		employeeId)
+	return grants, err
+}
+
+// TryLockGrantExpiryTx берёт advisory-блокировку отзыва истёкших назначений до конца транзакции.
+// Возвращает false, если блокировку уже держит другой экземпляр сервиса
+func (r *Repository) TryLockGrantExpiryTx(ctx context.Context, tx *sqlx.Tx) (locked bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.TryLockGrantExpiryTx")
+	defer func() { span.Finish(0, err) }()
+	err = tx.GetContext(ctx, &locked, "SELECT pg_try_advisory_xact_lock(hashtext('employee_role.valid_to'))")
+	return locked, err
+}
+
+// DeleteExpiredGrantsTx удаляет назначения ролей, срок действия которых истёк к моменту now, и возвращает их
+func (r *Repository) DeleteExpiredGrantsTx(ctx context.Context, tx *sqlx.Tx, now time.Time) (grants []GrantEntity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.DeleteExpiredGrantsTx")
+	defer func() { span.Finish(int64(len(grants)), err) }()
+	err = tx.SelectContext(ctx, &grants,
+		`DELETE FROM employee_role WHERE valid_to <= $1
+		RETURNING employee_id, role_id, valid_from, valid_to, created_at`,
+		now)
+	return grants, err
+}
+
+// OrgUnitExistsTx проверяет, что подразделение, в которое переводится сотрудник, существует
+func (r *Repository) OrgUnitExistsTx(ctx context.Context, tx *sqlx.Tx, orgUnitId int64) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.OrgUnitExistsTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &isExists, "select exists(select 1 from org_unit where id = $1)", orgUnitId)
+	return isExists, err
+}
+
+// OrgUnitExists проверяет, что подразделение существует
+func (r *Repository) OrgUnitExists(ctx context.Context, orgUnitId int64) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.OrgUnitExists")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = r.db.GetContext(ctx, &isExists, "select exists(select 1 from org_unit where id = $1)", orgUnitId)
+	return isExists, err
+}
+
+// FindByOrgUnit возвращает неудалённых сотрудников подразделения; при subtree - и всех его дочерних подразделений
+func (r *Repository) FindByOrgUnit(ctx context.Context, orgUnitId int64, subtree bool) (employees []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindByOrgUnit")
+	defer func() { span.Finish(int64(len(employees)), err) }()
+	err = r.db.SelectContext(ctx, &employees,
+		`SELECT e.* FROM employee e JOIN org_unit u ON u.id = e.org_unit_id
+		WHERE e.deleted_at IS NULL AND (u.id = $1 OR $2 AND u.path LIKE (select path from org_unit where id = $1) || '%')
+		ORDER BY e.id`,
+		orgUnitId, subtree)
+	return employees, err
+}
+
+// FindByRoleId возвращает сотрудников, которым роль назначена и назначение действует сейчас
+func (r *Repository) FindByRoleId(ctx context.Context, roleId int64) (employees []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindByRoleId")
+	defer func() { span.Finish(int64(len(employees)), err) }()
+	err = r.db.SelectContext(ctx, &employees,
+		`SELECT e.* FROM employee e JOIN employee_role er ON er.employee_id = e.id
+		WHERE er.role_id = $1 AND e.deleted_at IS NULL
+		AND er.valid_from <= now() AND (er.valid_to IS NULL OR er.valid_to > now())
+		ORDER BY e.id`,
+		roleId)
+	return employees, err
+}
+
+// FindByIdForUpdateTx читает сотрудника и блокирует строку до конца транзакции
+func (r *Repository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindByIdForUpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity, "SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(id)
+	}
+	return &entity, err
+}
+
+// UpdateTx обновляет сотрудника; updated_at выставляется на стороне БД
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.UpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		`UPDATE employee SET name = $1, email = $2, login = $3, first_name = $4, last_name = $5, phone = $6,
+		title = $7, employee_number = $8, attributes = $9, org_unit_id = $10, manager_id = $11, updated_at = now()
+		WHERE id = $12 RETURNING *`,
+		employee.Name, employee.Email, employee.Login, employee.FirstName, employee.LastName, employee.Phone,
+		employee.Title, employee.EmployeeNumber, employee.Attributes, employee.OrgUnitId, employee.ManagerId, employee.Id)
+	return &entity, uniqueViolation(err)
+}
+
+// FindByIdsForUpdateTx читает сотрудников по id и блокирует их строки до конца транзакции
+func (r *Repository) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) (employees []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindByIdsForUpdateTx")
+	defer func() { span.Finish(int64(len(employees)), err) }()
+	query, args, err := sqlx.In("SELECT * FROM employee WHERE id IN (?) AND deleted_at IS NULL ORDER BY id FOR UPDATE", ids)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.SelectContext(ctx, &employees, tx.Rebind(query), args...)
+	return employees, err
+}
+
+// DeleteByIdTx мягко удаляет сотрудника в транзакции
+func (r *Repository) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "employee.DeleteByIdTx")
+	defer func() { span.Finish(affected, err) }()
+	result, err := tx.ExecContext(ctx, "UPDATE employee SET deleted_at = now(), deleted_by = $1 WHERE id = $2 AND deleted_at IS NULL",
+		common.ActorFromContext(ctx), id)
+	if err != nil {
+		return err
+	}
+	affected, err = checkAffected(result, fmt.Sprintf("employee with id %d not found", id))
+	return err
+}
+
+// DeleteByIdsTx мягко удаляет сотрудников в транзакции
+func (r *Repository) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "employee.DeleteByIdsTx")
+	defer func() { span.Finish(affected, err) }()
+	query, args, err := sqlx.In("UPDATE employee SET deleted_at = now(), deleted_by = ? WHERE id IN (?) AND deleted_at IS NULL",
+		common.ActorFromContext(ctx), ids)
+	if err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	affected, err = checkAffected(result, fmt.Sprintf("employees with ids %v not found", ids))
+	return err
+}
+
+// FindDeletedByIdForUpdateTx читает мягко удалённого сотрудника и блокирует строку до конца транзакции
+func (r *Repository) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindDeletedByIdForUpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity, "SELECT * FROM employee WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("deleted employee with id %d not found", id)}
+	}
+	return &entity, err
+}
+
+// RestoreTx снимает с сотрудника отметку об удалении
+func (r *Repository) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.RestoreTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		`UPDATE employee SET deleted_at = NULL, deleted_by = NULL, updated_at = now() WHERE id = $1 RETURNING *`, id)
+	return &entity, uniqueViolation(err)
+}
+
+// PurgeDeletedTx окончательно удаляет сотрудников, мягко удалённых раньше before, и возвращает их
+func (r *Repository) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, before time.Time) (employees []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.PurgeDeletedTx")
+	defer func() { span.Finish(int64(len(employees)), err) }()
+	err = tx.SelectContext(ctx, &employees,
+		"DELETE FROM employee WHERE deleted_at < $1 RETURNING *", before)
+	return employees, err
+}
+
+func notFound(id int64) error {
+	return common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+}
+
+// uniqueViolation превращает нарушение уникального индекса в AlreadyExistsError.
+// Сервис проверяет уникальность заранее, индекс срабатывает только при одновременных изменениях
+func uniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return common.AlreadyExistsError{Message: "employee already exists"}
+	}
+	return err
+}
+
+// checkAffected возвращает число затронутых строк и NotFoundError, если запрос не затронул ни одной
+func checkAffected(result sql.Result, message string) (int64, error) {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, common.NotFoundError{Message: message}
+	}
+	return affected, nil
+}
+
+// UpdateStatusTx сохраняет статус сотрудника и даты начала и окончания работы
+func (r *Repository) UpdateStatusTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.UpdateStatusTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		`UPDATE employee SET status = $1, start_date = $2, end_date = $3, updated_at = now() WHERE id = $4 RETURNING *`,
+		employee.Status, employee.StartDate, employee.EndDate, employee.Id)
+	return &entity, err
+}
+
+// AddStatusHistoryTx добавляет запись в историю смены статусов сотрудника
+func (r *Repository) AddStatusHistoryTx(ctx context.Context, tx *sqlx.Tx, history *StatusHistoryEntity) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.AddStatusHistoryTx")
+	defer func() { span.Finish(1, err) }()
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO employee_status_history (employee_id, from_status, to_status, effective_date, reason, changed_by)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		history.EmployeeId, history.FromStatus, history.ToStatus, history.EffectiveDate, history.Reason, history.ChangedBy)
+	return err
+}
+
+// FindStatusHistory возвращает историю смены статусов сотрудника в порядке изменений
+func (r *Repository) FindStatusHistory(ctx context.Context, employeeId int64) (history []StatusHistoryEntity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindStatusHistory")
+	defer func() { span.Finish(int64(len(history)), err) }()
+	err = r.db.SelectContext(ctx, &history,
+		"SELECT * FROM employee_status_history WHERE employee_id = $1 ORDER BY id", employeeId)
+	return history, err
+}
+
+// RevokeAllRolesTx отзывает у сотрудника все роли и возвращает отозванные назначения в порядке id ролей
+func (r *Repository) RevokeAllRolesTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (grants []GrantEntity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.RevokeAllRolesTx")
+	defer func() { span.Finish(int64(len(grants)), err) }()
+	err = tx.SelectContext(ctx, &grants,
+		`DELETE FROM employee_role WHERE employee_id = $1
+		RETURNING employee_id, role_id, valid_from, valid_to, created_at`, employeeId)
+	sort.Slice(grants, func(i, j int) bool { return grants[i].RoleId < grants[j].RoleId })
+	return grants, err
+}
+
+// maxManagementDepth ограничивает обход иерархии руководителей, чтобы повреждённые данные с циклом не зациклили запрос
+const maxManagementDepth = 64
+
+// FindManagers возвращает цепочку руководителей сотрудника от непосредственного руководителя вверх.
+// Удалённые руководители пропускаются, но обход продолжается через них
+func (r *Repository) FindManagers(ctx context.Context, id int64) (managers []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindManagers")
+	defer func() { span.Finish(int64(len(managers)), err) }()
+	err = r.db.SelectContext(ctx, &managers,
+		`WITH RECURSIVE chain (id, level) AS (
+			SELECT manager_id, 1 FROM employee WHERE id = $1 AND manager_id IS NOT NULL
+			UNION ALL
+			SELECT e.manager_id, c.level + 1 FROM chain c JOIN employee e ON e.id = c.id
+			WHERE e.manager_id IS NOT NULL AND c.level < $2
+		)
+		SELECT e.* FROM chain c JOIN employee e ON e.id = c.id WHERE e.deleted_at IS NULL ORDER BY c.level`,
+		id, maxManagementDepth)
+	return managers, err
+}
+
+// FindReports возвращает подчинённых сотрудника не глубже depth уровней: при depth = 1 - только прямых.
+// Подчинённые упорядочены по уровню, внутри уровня - по id
+func (r *Repository) FindReports(ctx context.Context, id int64, depth int) (reports []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindReports")
+	defer func() { span.Finish(int64(len(reports)), err) }()
+	err = r.db.SelectContext(ctx, &reports,
+		`WITH RECURSIVE reports (id, level) AS (
+			SELECT id, 1 FROM employee WHERE manager_id = $1
+			UNION ALL
+			SELECT e.id, r.level + 1 FROM reports r JOIN employee e ON e.manager_id = r.id WHERE r.level < $2
+		)
+		SELECT e.* FROM reports r JOIN employee e ON e.id = r.id WHERE e.deleted_at IS NULL ORDER BY r.level, e.id`,
+		id, min(depth, maxManagementDepth))
+	return reports, err
+}
+
+// FindOrgChartMembers возвращает всех неудалённых сотрудников для построения оргструктуры
+func (r *Repository) FindOrgChartMembers(ctx context.Context) (employees []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.FindOrgChartMembers")
+	defer func() { span.Finish(int64(len(employees)), err) }()
+	err = r.db.SelectContext(ctx, &employees,
+		"SELECT id, name, title, manager_id FROM employee WHERE deleted_at IS NULL ORDER BY id")
+	return employees, err
+}
+
+// IsInManagementChainTx сообщает, входит ли сотрудник employeeId в цепочку руководителей managerId,
+// включая самого managerId. Назначение такого руководителя замкнуло бы цикл
+func (r *Repository) IsInManagementChainTx(ctx context.Context, tx *sqlx.Tx, employeeId, managerId int64) (isInChain bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.IsInManagementChainTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &isInChain,
+		`WITH RECURSIVE chain (id, level) AS (
+			SELECT $1::bigint, 0
+			UNION ALL
+			SELECT e.manager_id, c.level + 1 FROM chain c JOIN employee e ON e.id = c.id
+			WHERE e.manager_id IS NOT NULL AND c.level < $3
+		)
+		SELECT exists(SELECT 1 FROM chain WHERE id = $2)`,
+		managerId, employeeId, maxManagementDepth)
+	return isInChain, err
+}
+
+// LockManagementTx сериализует смену руководителей до конца транзакции: две одновременные смены
+// (A подчиняется B и B подчиняется A) по отдельности не создают цикла, а вместе - создают
+func (r *Repository) LockManagementTx(ctx context.Context, tx *sqlx.Tx) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "employee.LockManagementTx")
+	defer func() { span.Finish(0, err) }()
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('employee.manager_id'))")
+	return err
+}
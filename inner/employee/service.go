@@ -1,43 +1,107 @@
 package employee
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/jmoiron/sqlx"
+	"idm/inner/audit"
 	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/events"
+	"idm/inner/metrics"
+	"idm/inner/tracing"
 	"idm/inner/validator"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// типы событий о жизненном цикле сотрудника
+const (
+	EventStatusChanged = "employee.status_changed"
+	// EventGrantRevoked - назначение роли отозвано при увольнении сотрудника
+	EventGrantRevoked = "employee.role_grant_revoked"
 )
 
 // структура Service, которая будет инкапсулировать бизнес-логику
 type Service struct {
-	repo      Repo
-	validator *validator.Validator
+	repo       Repo
+	auditor    Auditor
+	publisher  events.Publisher
+	logger     *common.Logger
+	validator  *validator.Validator
+	attributes AttributeSchema
+}
+
+// Auditor записывает изменения сотрудников в журнал аудита в той же транзакции, что и само изменение
+type Auditor interface {
+	RecordTx(ctx context.Context, tx *sqlx.Tx, record audit.Record) error
 }
 
 // интерфейс репозитория
 // определяет, какие методы требуются от реализации репозитория
 // (здесь все из employee.Repository)
 type Repo interface {
-	FindById(id int64) (*Entity, error)
-	Add(e *Entity) error
-	Save(e *Entity) (int64, error)
-	FindAll() ([]Entity, error)
-	FindByIds(ids []int64) ([]Entity, error)
-	DeleteById(id int64) error
-	DeleteByIds(ids []int64) error
-	BeginTransaction() (*sqlx.Tx, error)
-	FindByNameTx(tx *sqlx.Tx, name string) (bool, error)
-	SaveTx(tx *sqlx.Tx, employee *Entity) (int64, error)
-	FindEmployeesPage(req PageRequest) ([]Entity, int64, error)
+	FindById(ctx context.Context, id int64) (*Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	FindByIds(ctx context.Context, ids []int64) ([]Entity, error)
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+	FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error)
+	SaveTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (int64, error)
+	FindEmployeesPage(ctx context.Context, req PageRequest) ([]Entity, int64, error)
+	ExistsByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	FindExistingRoleIdsTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]int64, error)
+	RoleExists(ctx context.Context, roleId int64) (bool, error)
+	AssignRolesTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64, validFrom, validTo *time.Time) error
+	RevokeRolesTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) error
+	FindByRoleId(ctx context.Context, roleId int64) ([]Entity, error)
+	FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (*Entity, error)
+	FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error)
+	DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error
+	DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error
+	FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error)
+	RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error)
+	PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, before time.Time) ([]Entity, error)
+	UpdateStatusTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (*Entity, error)
+	AddStatusHistoryTx(ctx context.Context, tx *sqlx.Tx, history *StatusHistoryEntity) error
+	FindStatusHistory(ctx context.Context, employeeId int64) ([]StatusHistoryEntity, error)
+	RevokeAllRolesTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) ([]GrantEntity, error)
+	FindTakenUniqueFieldsTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) ([]string, error)
+	OrgUnitExistsTx(ctx context.Context, tx *sqlx.Tx, orgUnitId int64) (bool, error)
+	OrgUnitExists(ctx context.Context, orgUnitId int64) (bool, error)
+	FindByOrgUnit(ctx context.Context, orgUnitId int64, subtree bool) ([]Entity, error)
+	FindManagers(ctx context.Context, id int64) ([]Entity, error)
+	FindReports(ctx context.Context, id int64, depth int) ([]Entity, error)
+	FindOrgChartMembers(ctx context.Context) ([]Entity, error)
+	IsInManagementChainTx(ctx context.Context, tx *sqlx.Tx, employeeId, managerId int64) (bool, error)
+	LockManagementTx(ctx context.Context, tx *sqlx.Tx) error
+	FindGrants(ctx context.Context, employeeId int64) ([]GrantEntity, error)
+	TryLockGrantExpiryTx(ctx context.Context, tx *sqlx.Tx) (bool, error)
+	DeleteExpiredGrantsTx(ctx context.Context, tx *sqlx.Tx, now time.Time) ([]GrantEntity, error)
 }
 
-// функция-конструктор
-func NewService(repo Repo) *Service {
-	return &Service{repo: repo, validator: validator.New()}
+// функция-конструктор; attributes описывает допустимые дополнительные атрибуты сотрудников
+func NewService(repo Repo, auditor Auditor, publisher events.Publisher, logger *common.Logger, attributes AttributeSchema) *Service {
+	return &Service{
+		repo:       repo,
+		auditor:    auditor,
+		publisher:  publisher,
+		logger:     logger,
+		validator:  validator.New(),
+		attributes: attributes,
+	}
 }
 
 // бизнес-логика получения одного работника по id
-func (svc *Service) FindById(id int64) (Response, error) {
-	employee, err := svc.repo.FindById(id)
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.FindById")
+	defer span.End()
+	employee, err := svc.repo.FindById(ctx, id)
 	if err != nil {
 		return Response{}, fmt.Errorf("error finding employee with id %d: %w", id, err)
 	}
@@ -45,24 +109,47 @@ func (svc *Service) FindById(id int64) (Response, error) {
 }
 
 // добавление работника без возврата id
-func (svc *Service) Add(req CreateRequest) error {
-	if err := svc.validator.Validate(req); err != nil {
-		return common.RequestValidationError{Message: err.Error()}
+func (svc *Service) Add(ctx context.Context, req CreateRequest) error {
+	ctx, span := tracing.Start(ctx, "employee.Service.Add")
+	defer span.End()
+	if err := svc.validate(req, req.Attributes); err != nil {
+		return err
+	}
+	err := database.InTransaction(ctx, svc.repo, "adding employee", func(tx *sqlx.Tx) error {
+		_, err := svc.createTx(ctx, tx, req.ToEntity())
+		return err
+	})
+	if err != nil {
+		return err
 	}
-	return svc.repo.Add(req.ToEntity())
+	metrics.EmployeesCreated.Inc()
+	return nil
 }
 
 // добавление с возвратом id
-func (svc *Service) Save(req CreateRequest) (int64, error) {
-	if err := svc.validator.Validate(req); err != nil {
-		return 0, common.RequestValidationError{Message: err.Error()}
+func (svc *Service) Save(ctx context.Context, req CreateRequest) (int64, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.Save")
+	defer span.End()
+	if err := svc.validate(req, req.Attributes); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := database.InTransaction(ctx, svc.repo, "saving employee", func(tx *sqlx.Tx) (err error) {
+		id, err = svc.createTx(ctx, tx, req.ToEntity())
+		return err
+	})
+	if err != nil {
+		return 0, err
 	}
-	return svc.repo.Save(req.ToEntity())
+	metrics.EmployeesCreated.Inc()
+	return id, nil
 }
 
 // получить всех работников
-func (svc *Service) FindAll() ([]Response, error) {
-	entities, err := svc.repo.FindAll()
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.FindAll")
+	defer span.End()
+	entities, err := svc.repo.FindAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -74,8 +161,10 @@ func (svc *Service) FindAll() ([]Response, error) {
 }
 
 // получить работников по слайсу id
-func (svc *Service) FindByIds(ids []int64) ([]Response, error) {
-	entities, err := svc.repo.FindByIds(ids)
+func (svc *Service) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.FindByIds")
+	defer span.End()
+	entities, err := svc.repo.FindByIds(ctx, ids)
 	if err != nil {
 		return nil, err
 	}
@@ -87,67 +176,151 @@ func (svc *Service) FindByIds(ids []int64) ([]Response, error) {
 }
 
 // удалить одного по id
-func (svc *Service) DeleteById(id int64) error {
-	return svc.repo.DeleteById(id)
+func (svc *Service) DeleteById(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "employee.Service.DeleteById")
+	defer span.End()
+	err := database.InTransaction(ctx, svc.repo, "deleting employee", func(tx *sqlx.Tx) error {
+		current, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding employee with id %d: %w", id, err)
+		}
+		if err = svc.repo.DeleteByIdTx(ctx, tx, id); err != nil {
+			return fmt.Errorf("error deleting employee with id %d: %w", id, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionDelete, id, current.toAuditState(), nil)
+	})
+	if err != nil {
+		return err
+	}
+	metrics.EmployeesDeleted.Inc()
+	return nil
 }
 
-// удалить всех по слайсу id
-func (svc *Service) DeleteByIds(ids []int64) error {
-	return svc.repo.DeleteByIds(ids)
+// удалить всех по слайсу id; если какого-то из сотрудников нет, не удаляется никто и возвращается NotFoundError
+func (svc *Service) DeleteByIds(ctx context.Context, ids []int64) error {
+	ctx, span := tracing.Start(ctx, "employee.Service.DeleteByIds")
+	defer span.End()
+	var deleted []Entity
+	err := database.InTransaction(ctx, svc.repo, "deleting employees", func(tx *sqlx.Tx) (err error) {
+		deleted, err = svc.repo.FindByIdsForUpdateTx(ctx, tx, ids)
+		if err != nil {
+			return fmt.Errorf("error finding employees with ids %v: %w", ids, err)
+		}
+		if missing := common.MissingIds(ids, entityIds(deleted)); len(missing) > 0 {
+			return common.NotFoundError{Message: fmt.Sprintf("employees with ids %v not found", missing)}
+		}
+		if err = svc.repo.DeleteByIdsTx(ctx, tx, ids); err != nil {
+			return fmt.Errorf("error deleting employees with ids %v: %w", ids, err)
+		}
+		for i := range deleted {
+			if err = svc.recordTx(ctx, tx, audit.ActionDelete, deleted[i].Id, deleted[i].toAuditState(), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	metrics.EmployeesDeleted.Add(float64(len(deleted)))
+	return nil
 }
 
-// SaveWithTransaction проверяет дубликаты и создаёт запись в рамках одной транзакции.
-func (svc *Service) SaveWithTransaction(e CreateRequest) (int64, error) {
-
-	if err := svc.validator.Validate(e); err != nil {
-		return 0, common.RequestValidationError{Message: err.Error()}
-	}
-	tx, err := svc.repo.BeginTransaction()
+// Restore снимает с сотрудника отметку об удалении.
+// Если имя за это время занял другой сотрудник, возвращается AlreadyExistsError
+func (svc *Service) Restore(ctx context.Context, id int64) (Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.Restore")
+	defer span.End()
+	var restored *Entity
+	err := database.InTransaction(ctx, svc.repo, "restoring employee", func(tx *sqlx.Tx) error {
+		deleted, err := svc.repo.FindDeletedByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding deleted employee with id %d: %w", id, err)
+		}
+		isExist, err := svc.repo.FindByNameTx(ctx, tx, deleted.Name)
+		if err != nil {
+			return fmt.Errorf("error finding employee by name: %s, %w", deleted.Name, err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{Message: "employee already exists"}
+		}
+		if err = svc.checkUniqueTx(ctx, tx, deleted); err != nil {
+			return err
+		}
+		restored, err = svc.repo.RestoreTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error restoring employee with id %d: %w", id, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionRestore, id, nil, restored.toAuditState())
+	})
 	if err != nil {
-		return 0, fmt.Errorf("error creating transaction: %w", err)
+		return Response{}, err
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("creating employee panic: %v", r)
-			errTx := tx.Rollback()
-			if errTx != nil {
-				err = fmt.Errorf("creating employee: rolling back transaction errors: %w, %w", err, errTx)
-			}
-		} else if err != nil {
-			errTx := tx.Rollback()
-			if errTx != nil {
-				err = fmt.Errorf("creating employee: rolling back transaction errors: %w, %w", err, errTx)
-			}
-		} else {
-			errTx := tx.Commit()
-			if errTx != nil {
-				err = fmt.Errorf("creating employee: commiting transaction error: %w", errTx)
+	return restored.toResponse(), nil
+}
+
+// Purge окончательно удаляет сотрудников, мягко удалённых раньше before, и возвращает их число
+func (svc *Service) Purge(ctx context.Context, before time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.Purge")
+	defer span.End()
+	var purged []Entity
+	err := database.InTransaction(ctx, svc.repo, "purging employees", func(tx *sqlx.Tx) (err error) {
+		purged, err = svc.repo.PurgeDeletedTx(ctx, tx, before)
+		if err != nil {
+			return fmt.Errorf("error purging employees deleted before %s: %w", before.Format(time.RFC3339), err)
+		}
+		for i := range purged {
+			if err = svc.recordTx(ctx, tx, audit.ActionPurge, purged[i].Id, purged[i].toAuditState(), nil); err != nil {
+				return err
 			}
 		}
-	}()
+		return nil
+	})
 	if err != nil {
-		return 0, fmt.Errorf("error create employee: error creating transaction: %w", err)
-	}
-	isExist, err := svc.repo.FindByNameTx(tx, e.Name)
-	if err != nil {
-		return 0, fmt.Errorf("error finding employee by name: %s, %w", e.Name, err)
+		return 0, err
 	}
-	if isExist {
-		err = common.AlreadyExistsError{Message: "employee already exists"}
+	return len(purged), nil
+}
+
+// SaveWithTransaction проверяет дубликаты и создаёт запись в рамках одной транзакции.
+func (svc *Service) SaveWithTransaction(ctx context.Context, e CreateRequest) (int64, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.SaveWithTransaction")
+	defer span.End()
+
+	if err := svc.validate(e, e.Attributes); err != nil {
 		return 0, err
 	}
-	newEmployeeId, err := svc.repo.SaveTx(tx, e.ToEntity())
+	var id int64
+	err := database.InTransaction(ctx, svc.repo, "creating employee", func(tx *sqlx.Tx) (err error) {
+		isExist, err := svc.repo.FindByNameTx(ctx, tx, e.Name)
+		if err != nil {
+			return fmt.Errorf("error finding employee by name: %s, %w", e.Name, err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{Message: "employee already exists"}
+		}
+		id, err = svc.createTx(ctx, tx, e.ToEntity())
+		return err
+	})
 	if err != nil {
-		err = fmt.Errorf("error creating employee with name: %s %v", e.Name, err)
+		return 0, err
 	}
-	return newEmployeeId, err
+	metrics.EmployeesCreated.Inc()
+	return id, nil
 }
 
-func (svc *Service) GetEmployeesPage(req PageRequest) (PageResponse, error) {
+func (svc *Service) GetEmployeesPage(ctx context.Context, req PageRequest) (PageResponse, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.GetEmployeesPage")
+	defer span.End()
 	if err := svc.validator.Validate(req); err != nil {
-		return PageResponse{}, common.RequestValidationError{Message: err.Error()}
+		return PageResponse{}, err
 	}
-	entities, total, err := svc.repo.FindEmployeesPage(req)
+	filter, err := svc.attributes.parseFilter(req.Attributes)
+	if err != nil {
+		return PageResponse{}, err
+	}
+	req.Attributes = filter
+	entities, total, err := svc.repo.FindEmployeesPage(ctx, req)
 	if err != nil {
 		return PageResponse{}, err
 	}
@@ -164,3 +337,606 @@ func (svc *Service) GetEmployeesPage(req PageRequest) (PageResponse, error) {
 		Total:      total,
 	}, nil
 }
+
+// AssignRoles назначает сотруднику роли в рамках одной транзакции; срок действия назначения необязателен
+func (svc *Service) AssignRoles(ctx context.Context, employeeId int64, req AssignRolesRequest) error {
+	ctx, span := tracing.Start(ctx, "employee.Service.AssignRoles")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return err
+	}
+	if err := req.checkValidity(time.Now()); err != nil {
+		return err
+	}
+	err := database.InTransaction(ctx, svc.repo, "assigning roles", func(tx *sqlx.Tx) error {
+		// строка блокируется, чтобы назначение не разошлось с одновременным увольнением
+		employee, err := svc.repo.FindByIdForUpdateTx(ctx, tx, employeeId)
+		if err != nil {
+			return fmt.Errorf("error finding employee with id %d: %w", employeeId, err)
+		}
+		if employee.Status == StatusTerminated {
+			return common.ConflictError{Message: fmt.Sprintf("employee with id %d is terminated", employeeId)}
+		}
+		existing, err := svc.repo.FindExistingRoleIdsTx(ctx, tx, req.RoleIds)
+		if err != nil {
+			return fmt.Errorf("error finding roles %v: %w", req.RoleIds, err)
+		}
+		if missing := common.MissingIds(req.RoleIds, existing); len(missing) > 0 {
+			return common.RequestValidationError{Message: fmt.Sprintf("roles not found: %v", missing)}
+		}
+		if err = svc.repo.AssignRolesTx(ctx, tx, employeeId, req.RoleIds, req.ValidFrom, req.ValidTo); err != nil {
+			return fmt.Errorf("error assigning roles to employee with id %d: %w", employeeId, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionAssignRoles, employeeId, nil,
+			auditRoles{RoleIds: req.RoleIds, ValidFrom: req.ValidFrom, ValidTo: req.ValidTo})
+	})
+	if err != nil {
+		return err
+	}
+	metrics.RoleAssignments.WithLabelValues("assign").Add(float64(len(req.RoleIds)))
+	return nil
+}
+
+// RevokeRoles отзывает у сотрудника роли в рамках одной транзакции
+func (svc *Service) RevokeRoles(ctx context.Context, employeeId int64, req RolesRequest) error {
+	ctx, span := tracing.Start(ctx, "employee.Service.RevokeRoles")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return err
+	}
+	err := database.InTransaction(ctx, svc.repo, "revoking roles", func(tx *sqlx.Tx) error {
+		if err := svc.checkEmployeeExistsTx(ctx, tx, employeeId); err != nil {
+			return err
+		}
+		if err := svc.repo.RevokeRolesTx(ctx, tx, employeeId, req.RoleIds); err != nil {
+			return fmt.Errorf("error revoking roles from employee with id %d: %w", employeeId, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionRevokeRoles, employeeId, auditRoles{RoleIds: req.RoleIds}, nil)
+	})
+	if err != nil {
+		return err
+	}
+	metrics.RoleAssignments.WithLabelValues("revoke").Add(float64(len(req.RoleIds)))
+	return nil
+}
+
+// FindGrants возвращает назначения ролей сотруднику вместе со сроками их действия
+func (svc *Service) FindGrants(ctx context.Context, employeeId int64) ([]GrantResponse, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.FindGrants")
+	defer span.End()
+	if _, err := svc.repo.FindById(ctx, employeeId); err != nil {
+		return nil, fmt.Errorf("error finding employee with id %d: %w", employeeId, err)
+	}
+	entities, err := svc.repo.FindGrants(ctx, employeeId)
+	if err != nil {
+		return nil, fmt.Errorf("error finding role grants of employee with id %d: %w", employeeId, err)
+	}
+	now := time.Now()
+	result := make([]GrantResponse, 0, len(entities))
+	for i := range entities {
+		result = append(result, entities[i].toResponse(now))
+	}
+	return result, nil
+}
+
+// ExpireGrants отзывает назначения ролей, срок действия которых истёк к моменту now, и записывает отзыв в журнал аудита.
+// Если отзыв уже выполняет другой экземпляр сервиса, ничего не делает
+func (svc *Service) ExpireGrants(ctx context.Context, now time.Time) ([]GrantResponse, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.ExpireGrants")
+	defer span.End()
+	var expired []GrantEntity
+	err := database.InTransaction(ctx, svc.repo, "expiring role grants", func(tx *sqlx.Tx) error {
+		locked, err := svc.repo.TryLockGrantExpiryTx(ctx, tx)
+		if err != nil || !locked {
+			return err
+		}
+		expired, err = svc.repo.DeleteExpiredGrantsTx(ctx, tx, now)
+		if err != nil {
+			return fmt.Errorf("error deleting role grants expired by %s: %w", now.Format(time.RFC3339), err)
+		}
+		var employeeIds []int64
+		roleIds := make(map[int64][]int64)
+		for i := range expired {
+			id := expired[i].EmployeeId
+			if _, ok := roleIds[id]; !ok {
+				employeeIds = append(employeeIds, id)
+			}
+			roleIds[id] = append(roleIds[id], expired[i].RoleId)
+		}
+		slices.Sort(employeeIds)
+		for _, id := range employeeIds {
+			slices.Sort(roleIds[id])
+			if err = svc.recordTx(ctx, tx, audit.ActionExpireRoles, id, auditRoles{RoleIds: roleIds[id]}, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]GrantResponse, 0, len(expired))
+	for i := range expired {
+		result = append(result, expired[i].toResponse(now))
+	}
+	metrics.RoleAssignments.WithLabelValues("expire").Add(float64(len(expired)))
+	return result, nil
+}
+
+// Transition переводит сотрудника в другой статус жизненного цикла.
+// Недопустимый переход возвращает ConflictError. Переход применяется сразу, поэтому дата вступления в силу
+// не может быть в будущем. Каждый переход пишется в историю статусов и журнал аудита;
+// при увольнении у сотрудника отзываются все роли. После фиксации публикуются события о смене статуса и отзыве ролей
+func (svc *Service) Transition(ctx context.Context, id int64, req TransitionRequest) (Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.Transition")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return Response{}, err
+	}
+	now := time.Now().UTC()
+	effective := now.Truncate(24 * time.Hour)
+	if req.EffectiveDate != "" {
+		date := *parseDate(req.EffectiveDate)
+		if date.After(effective) {
+			return Response{}, common.RequestValidationError{Message: "effective_date must not be in the future"}
+		}
+		effective = date
+	}
+	var from string
+	var updated *Entity
+	var revoked []GrantEntity
+	err := database.InTransaction(ctx, svc.repo, "changing employee status", func(tx *sqlx.Tx) (err error) {
+		current, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding employee with id %d: %w", id, err)
+		}
+		from = current.Status
+		if !canTransition(from, req.Status) {
+			return common.ConflictError{
+				Message: fmt.Sprintf("employee with id %d cannot change status from %s to %s", id, from, req.Status),
+			}
+		}
+		next := applyTransition(current, req.Status, effective)
+		if next.StartDate != nil && next.EndDate != nil && next.EndDate.Before(*next.StartDate) {
+			return common.RequestValidationError{Message: "effective_date must not be before start_date"}
+		}
+		updated, err = svc.repo.UpdateStatusTx(ctx, tx, next)
+		if err != nil {
+			return fmt.Errorf("error changing status of employee with id %d: %w", id, err)
+		}
+		err = svc.repo.AddStatusHistoryTx(ctx, tx, &StatusHistoryEntity{
+			EmployeeId:    id,
+			FromStatus:    from,
+			ToStatus:      req.Status,
+			EffectiveDate: effective,
+			Reason:        req.Reason,
+			ChangedBy:     common.ActorFromContext(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("error writing status history of employee with id %d: %w", id, err)
+		}
+		if req.Status == StatusTerminated {
+			// уволенный сотрудник не должен сохранять доступы
+			if revoked, err = svc.repo.RevokeAllRolesTx(ctx, tx, id); err != nil {
+				return fmt.Errorf("error revoking roles from employee with id %d: %w", id, err)
+			}
+		}
+		// события аудита пишутся после всех изменений: запись блокирует цепочку журнала до конца транзакции
+		err = svc.recordTx(ctx, tx, audit.ActionTransition, id, current.toAuditLifecycle(), updated.toAuditLifecycle())
+		if err != nil || len(revoked) == 0 {
+			return err
+		}
+		roleIds := make([]int64, 0, len(revoked))
+		for i := range revoked {
+			roleIds = append(roleIds, revoked[i].RoleId)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionRevokeRoles, id, auditRoles{RoleIds: roleIds}, nil)
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	metrics.EmployeeTransitions.WithLabelValues(from, req.Status).Inc()
+	metrics.RoleAssignments.WithLabelValues("revoke").Add(float64(len(revoked)))
+	svc.publish(ctx, EventStatusChanged, id, now, StatusChangedEvent{
+		FromStatus:    from,
+		ToStatus:      req.Status,
+		EffectiveDate: effective.Format(dateLayout),
+		Reason:        req.Reason,
+	})
+	for i := range revoked {
+		grant := revoked[i].toResponse(now)
+		// назначение уже удалено, даже если срок его действия ещё не истёк
+		grant.Active = false
+		svc.publish(ctx, EventGrantRevoked, id, now, grant)
+	}
+	return updated.toResponse(), nil
+}
+
+// publish сообщает об изменении сотрудника после фиксации транзакции; ошибка публикации только журналируется
+func (svc *Service) publish(ctx context.Context, eventType string, id int64, occurredAt time.Time, data any) {
+	event := events.Event{
+		Type:       eventType,
+		OccurredAt: occurredAt,
+		EntityType: auditEntityType,
+		EntityId:   id,
+		Data:       data,
+	}
+	if err := svc.publisher.Publish(ctx, event); err != nil {
+		svc.logger.Ctx(ctx).Error("publish employee event", zap.String("event_type", eventType),
+			zap.Int64("employee_id", id), zap.Error(err))
+	}
+}
+
+// GetStatusHistory возвращает историю смены статусов сотрудника
+func (svc *Service) GetStatusHistory(ctx context.Context, id int64) ([]StatusHistoryResponse, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.GetStatusHistory")
+	defer span.End()
+	if _, err := svc.repo.FindById(ctx, id); err != nil {
+		return nil, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+	entities, err := svc.repo.FindStatusHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error finding status history of employee with id %d: %w", id, err)
+	}
+	result := make([]StatusHistoryResponse, 0, len(entities))
+	for i := range entities {
+		result = append(result, entities[i].toResponse())
+	}
+	return result, nil
+}
+
+// FindByRoleId возвращает сотрудников, которым назначена роль; для несуществующей роли - NotFoundError
+func (svc *Service) FindByRoleId(ctx context.Context, roleId int64) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.FindByRoleId")
+	defer span.End()
+	exists, err := svc.repo.RoleExists(ctx, roleId)
+	if err != nil {
+		return nil, fmt.Errorf("error finding role with id %d: %w", roleId, err)
+	}
+	if !exists {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", roleId)}
+	}
+	entities, err := svc.repo.FindByRoleId(ctx, roleId)
+	if err != nil {
+		return nil, fmt.Errorf("error finding employees with role id %d: %w", roleId, err)
+	}
+	var result []Response
+	for _, e := range entities {
+		result = append(result, e.toResponse())
+	}
+	return result, nil
+}
+
+// FindByOrgUnit возвращает сотрудников подразделения; при subtree - вместе с сотрудниками дочерних подразделений
+func (svc *Service) FindByOrgUnit(ctx context.Context, orgUnitId int64, subtree bool) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.FindByOrgUnit")
+	defer span.End()
+	exists, err := svc.repo.OrgUnitExists(ctx, orgUnitId)
+	if err != nil {
+		return nil, fmt.Errorf("error finding org unit with id %d: %w", orgUnitId, err)
+	}
+	if !exists {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("org unit with id %d not found", orgUnitId)}
+	}
+	entities, err := svc.repo.FindByOrgUnit(ctx, orgUnitId, subtree)
+	if err != nil {
+		return nil, fmt.Errorf("error finding employees of org unit with id %d: %w", orgUnitId, err)
+	}
+	result := make([]Response, 0, len(entities))
+	for i := range entities {
+		result = append(result, entities[i].toResponse())
+	}
+	return result, nil
+}
+
+// FindManagers возвращает цепочку руководителей сотрудника от непосредственного руководителя до верхнего уровня
+func (svc *Service) FindManagers(ctx context.Context, id int64) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.FindManagers")
+	defer span.End()
+	if _, err := svc.repo.FindById(ctx, id); err != nil {
+		return nil, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+	entities, err := svc.repo.FindManagers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error finding managers of employee with id %d: %w", id, err)
+	}
+	var result []Response
+	for _, e := range entities {
+		result = append(result, e.toResponse())
+	}
+	return result, nil
+}
+
+// FindReports возвращает прямых подчинённых сотрудника, а при transitive - всех подчинённых по иерархии
+func (svc *Service) FindReports(ctx context.Context, id int64, transitive bool) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.FindReports")
+	defer span.End()
+	if _, err := svc.repo.FindById(ctx, id); err != nil {
+		return nil, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+	depth := 1
+	if transitive {
+		depth = maxManagementDepth
+	}
+	entities, err := svc.repo.FindReports(ctx, id, depth)
+	if err != nil {
+		return nil, fmt.Errorf("error finding reports of employee with id %d: %w", id, err)
+	}
+	var result []Response
+	for _, e := range entities {
+		result = append(result, e.toResponse())
+	}
+	return result, nil
+}
+
+// OrgChart строит дерево подчинения всей организации или, если задан req.Root, поддерево этого сотрудника
+func (svc *Service) OrgChart(ctx context.Context, req OrgChartRequest) ([]ChartNode, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.OrgChart")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return nil, err
+	}
+	if req.Root == 0 {
+		members, err := svc.repo.FindOrgChartMembers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error finding org chart members: %w", err)
+		}
+		return buildOrgChart(members), nil
+	}
+	root, err := svc.repo.FindById(ctx, req.Root)
+	if err != nil {
+		return nil, fmt.Errorf("error finding employee with id %d: %w", req.Root, err)
+	}
+	reports, err := svc.repo.FindReports(ctx, req.Root, maxManagementDepth)
+	if err != nil {
+		return nil, fmt.Errorf("error finding reports of employee with id %d: %w", req.Root, err)
+	}
+	members := append([]Entity{*root}, reports...)
+	slices.SortFunc(members, func(a, b Entity) int { return cmp.Compare(a.Id, b.Id) })
+	return buildOrgChart(members), nil
+}
+
+func (svc *Service) checkEmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	isExist, err := svc.repo.ExistsByIdTx(ctx, tx, employeeId)
+	if err != nil {
+		return fmt.Errorf("error finding employee with id %d: %w", employeeId, err)
+	}
+	if !isExist {
+		return common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", employeeId)}
+	}
+	return nil
+}
+
+// entityIds возвращает id сотрудников
+func entityIds(employees []Entity) []int64 {
+	ids := make([]int64, 0, len(employees))
+	for i := range employees {
+		ids = append(ids, employees[i].Id)
+	}
+	return ids
+}
+
+// Update полностью заменяет данные сотрудника.
+// Если передана ожидаемая версия (updated_at), а запись уже изменена, возвращается PreconditionFailedError.
+func (svc *Service) Update(ctx context.Context, id int64, req UpdateRequest, expectedVersion *time.Time) (Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.Update")
+	defer span.End()
+	if err := svc.validate(req, req.Attributes); err != nil {
+		return Response{}, err
+	}
+	var updated *Entity
+	err := database.InTransaction(ctx, svc.repo, "updating employee", func(tx *sqlx.Tx) (err error) {
+		current, err := svc.lockForUpdateTx(ctx, tx, id, expectedVersion)
+		if err != nil {
+			return err
+		}
+		updated, err = svc.updateTx(ctx, tx, current, req)
+		return err
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return updated.toResponse(), nil
+}
+
+// Patch применяет к сотруднику JSON Merge Patch (RFC 7386) с той же проверкой версии, что и Update
+func (svc *Service) Patch(ctx context.Context, id int64, patch []byte, expectedVersion *time.Time) (Response, error) {
+	ctx, span := tracing.Start(ctx, "employee.Service.Patch")
+	defer span.End()
+	var updated *Entity
+	err := database.InTransaction(ctx, svc.repo, "patching employee", func(tx *sqlx.Tx) (err error) {
+		current, err := svc.lockForUpdateTx(ctx, tx, id, expectedVersion)
+		if err != nil {
+			return err
+		}
+		original, err := json.Marshal(current.toUpdateRequest())
+		if err != nil {
+			return fmt.Errorf("error marshaling employee with id %d: %w", id, err)
+		}
+		merged, err := common.MergePatch(original, patch)
+		if err != nil {
+			return err
+		}
+		var req UpdateRequest
+		if err = json.Unmarshal(merged, &req); err != nil {
+			return common.RequestValidationError{Message: common.InvalidBodyMessage, Err: err}
+		}
+		if err = svc.validate(req, req.Attributes); err != nil {
+			return err
+		}
+		updated, err = svc.updateTx(ctx, tx, current, req)
+		return err
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return updated.toResponse(), nil
+}
+
+// lockForUpdateTx блокирует запись сотрудника и сверяет её версию с ожидаемой
+func (svc *Service) lockForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64, expectedVersion *time.Time) (*Entity, error) {
+	current, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+	if expectedVersion != nil && !current.UpdatedAt.Equal(*expectedVersion) {
+		return nil, common.PreconditionFailedError{Message: fmt.Sprintf("employee with id %d was modified concurrently", id)}
+	}
+	return current, nil
+}
+
+func (svc *Service) updateTx(ctx context.Context, tx *sqlx.Tx, current *Entity, req UpdateRequest) (*Entity, error) {
+	if req.Name != current.Name {
+		isExist, err := svc.repo.FindByNameTx(ctx, tx, req.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error finding employee by name: %s, %w", req.Name, err)
+		}
+		if isExist {
+			return nil, common.AlreadyExistsError{Message: "employee already exists"}
+		}
+	}
+	next := req.toEntity(current.Id)
+	if uniqueFieldsChanged(current, next) {
+		if err := svc.checkUniqueTx(ctx, tx, next); err != nil {
+			return nil, err
+		}
+	}
+	if !equalIds(current.OrgUnitId, next.OrgUnitId) {
+		if err := svc.checkOrgUnitTx(ctx, tx, next.OrgUnitId); err != nil {
+			return nil, err
+		}
+	}
+	if !equalIds(current.ManagerId, next.ManagerId) {
+		if err := svc.checkManagerTx(ctx, tx, current.Id, next.ManagerId); err != nil {
+			return nil, err
+		}
+	}
+	updated, err := svc.repo.UpdateTx(ctx, tx, next)
+	if err != nil {
+		return nil, fmt.Errorf("error updating employee with id %d: %w", current.Id, err)
+	}
+	err = svc.recordTx(ctx, tx, audit.ActionUpdate, current.Id, current.toAuditState(), updated.toAuditState())
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// createTx создаёт сотрудника и записывает создание в журнал аудита
+func (svc *Service) createTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (int64, error) {
+	if err := svc.checkUniqueTx(ctx, tx, employee); err != nil {
+		return 0, err
+	}
+	if err := svc.checkOrgUnitTx(ctx, tx, employee.OrgUnitId); err != nil {
+		return 0, err
+	}
+	// у нового сотрудника нет подчинённых, поэтому цикл возникнуть не может
+	if err := svc.checkManagerTx(ctx, tx, 0, employee.ManagerId); err != nil {
+		return 0, err
+	}
+	id, err := svc.repo.SaveTx(ctx, tx, employee)
+	if err != nil {
+		return 0, fmt.Errorf("error creating employee with name: %s %w", employee.Name, err)
+	}
+	employee.Id = id
+	if err = svc.recordTx(ctx, tx, audit.ActionCreate, id, nil, employee.toAuditState()); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// validate проверяет запрос и его дополнительные атрибуты по схеме из конфигурации
+func (svc *Service) validate(req any, attributes Attributes) error {
+	if err := svc.validator.Validate(req); err != nil {
+		return err
+	}
+	return svc.attributes.validate(attributes)
+}
+
+// checkUniqueTx проверяет, что email, login и табельный номер сотрудника не заняты другими сотрудниками
+func (svc *Service) checkUniqueTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) error {
+	if employee.Email == nil && employee.Login == nil && employee.EmployeeNumber == nil {
+		return nil
+	}
+	taken, err := svc.repo.FindTakenUniqueFieldsTx(ctx, tx, employee)
+	if err != nil {
+		return fmt.Errorf("error checking unique fields of employee: %w", err)
+	}
+	if len(taken) > 0 {
+		return common.AlreadyExistsError{
+			Message: fmt.Sprintf("employee with the same %s already exists", strings.Join(taken, ", ")),
+		}
+	}
+	return nil
+}
+
+// checkOrgUnitTx проверяет, что подразделение сотрудника существует
+func (svc *Service) checkOrgUnitTx(ctx context.Context, tx *sqlx.Tx, orgUnitId *int64) error {
+	if orgUnitId == nil {
+		return nil
+	}
+	isExists, err := svc.repo.OrgUnitExistsTx(ctx, tx, *orgUnitId)
+	if err != nil {
+		return fmt.Errorf("error finding org unit with id %d: %w", *orgUnitId, err)
+	}
+	if !isExists {
+		return common.RequestValidationError{Message: fmt.Sprintf("org unit %d not found", *orgUnitId)}
+	}
+	return nil
+}
+
+// checkManagerTx проверяет, что руководитель существует и что его назначение сотруднику employeeId
+// не замкнёт цепочку руководителей; для создаваемого сотрудника employeeId равен 0
+func (svc *Service) checkManagerTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, managerId *int64) error {
+	if managerId == nil {
+		return nil
+	}
+	isExists, err := svc.repo.ExistsByIdTx(ctx, tx, *managerId)
+	if err != nil {
+		return fmt.Errorf("error finding employee with id %d: %w", *managerId, err)
+	}
+	if !isExists {
+		return common.RequestValidationError{Message: fmt.Sprintf("manager employee %d not found", *managerId)}
+	}
+	if employeeId == 0 {
+		return nil
+	}
+	if err = svc.repo.LockManagementTx(ctx, tx); err != nil {
+		return fmt.Errorf("error locking management hierarchy: %w", err)
+	}
+	isInChain, err := svc.repo.IsInManagementChainTx(ctx, tx, employeeId, *managerId)
+	if err != nil {
+		return fmt.Errorf("error checking management chain of employee with id %d: %w", *managerId, err)
+	}
+	if isInChain {
+		return common.ConflictError{Message: fmt.Sprintf(
+			"employee %d cannot report to employee %d: management chain would form a cycle", employeeId, *managerId)}
+	}
+	return nil
+}
+
+func equalIds(a, b *int64) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+// uniqueFieldsChanged сообщает, изменились ли уникальные поля профиля; email и login сравниваются без учёта регистра
+func uniqueFieldsChanged(current, next *Entity) bool {
+	return !strings.EqualFold(valueOf(current.Email), valueOf(next.Email)) ||
+		!strings.EqualFold(valueOf(current.Login), valueOf(next.Login)) ||
+		valueOf(current.EmployeeNumber) != valueOf(next.EmployeeNumber)
+}
+
+// recordTx записывает изменение сотрудника в журнал аудита; before и after - nil, если состояния нет
+func (svc *Service) recordTx(ctx context.Context, tx *sqlx.Tx, action string, id int64, before any, after any) error {
+	err := svc.auditor.RecordTx(ctx, tx, audit.Record{
+		Action:     action,
+		EntityType: auditEntityType,
+		EntityId:   id,
+		Before:     before,
+		After:      after,
+	})
+	if err != nil {
+		return fmt.Errorf("error recording audit event for employee with id %d: %w", id, err)
+	}
+	return nil
+}
@@ -1,13 +1,20 @@
 package employee
 
 import (
+	"context"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"idm/inner/audit"
 	"idm/inner/common"
+	"idm/inner/events"
+	"idm/inner/metrics"
 	"regexp"
 	"testing"
 	"time"
@@ -18,32 +25,32 @@ type MockRepo struct {
 	mock.Mock
 }
 
-func (m *MockRepo) BeginTransaction() (*sqlx.Tx, error) {
-	args := m.Called()
+func (m *MockRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	args := m.Called(ctx)
 	return args.Get(0).(*sqlx.Tx), args.Error(1)
 }
 
-func (m *MockRepo) FindByNameTx(tx *sqlx.Tx, name string) (bool, error) {
-	args := m.Called(tx, name)
+func (m *MockRepo) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
+	args := m.Called(ctx, tx, name)
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockRepo) SaveTx(tx *sqlx.Tx, employee *Entity) (int64, error) {
-	args := m.Called(tx, employee)
+func (m *MockRepo) SaveTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (int64, error) {
+	args := m.Called(ctx, tx, employee)
 	return args.Get(0).(int64), args.Error(1)
 }
 
 // ErrEmployeeAlreadyExists возвращается, если работник с таким именем уже существует
 var ErrEmployeeAlreadyExists = fmt.Errorf("employee already exists")
 
-func (m *MockRepo) TransactionalCreate(e *Entity) (int64, error) {
-	args := m.Called(e)
+func (m *MockRepo) TransactionalCreate(ctx context.Context, e *Entity) (int64, error) {
+	args := m.Called(ctx, e)
 	// Optionally mutate e in success cases via Run in tests
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockRepo) FindById(id int64) (*Entity, error) {
-	args := m.Called(id)
+func (m *MockRepo) FindById(ctx context.Context, id int64) (*Entity, error) {
+	args := m.Called(ctx, id)
 	// Может быть nil, поэтому проверяем
 	if ent, ok := args.Get(0).(*Entity); ok {
 		return ent, args.Error(1)
@@ -51,44 +58,254 @@ func (m *MockRepo) FindById(id int64) (*Entity, error) {
 	return nil, args.Error(1)
 }
 
-func (m *MockRepo) Add(e *Entity) error {
-	args := m.Called(e)
-	return args.Error(0)
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) Save(e *Entity) (int64, error) {
-	args := m.Called(e)
-	return args.Get(0).(int64), args.Error(1)
+func (m *MockRepo) FindByIds(ctx context.Context, ids []int64) ([]Entity, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) FindAll() ([]Entity, error) {
-	args := m.Called()
-	return args.Get(0).([]Entity), args.Error(1)
+func (m *MockRepo) FindEmployeesPage(ctx context.Context, req PageRequest) ([]Entity, int64, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]Entity), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockRepo) FindByIds(ids []int64) ([]Entity, error) {
-	args := m.Called(ids)
+func (m *MockRepo) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error) {
+	args := m.Called(ctx, tx, ids)
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) FindEmployeesPage(req PageRequest) ([]Entity, int64, error) {
-	args := m.Called(req)
-	return args.Get(0).([]Entity), args.Get(1).(int64), args.Error(2)
+func (m *MockRepo) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
 }
 
-func (m *MockRepo) DeleteById(id int64) error {
-	args := m.Called(id)
+func (m *MockRepo) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error {
+	args := m.Called(ctx, tx, ids)
 	return args.Error(0)
 }
 
-func (m *MockRepo) DeleteByIds(ids []int64) error {
-	args := m.Called(ids)
+func (m *MockRepo) ExistsByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindExistingRoleIdsTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]int64, error) {
+	args := m.Called(ctx, tx, roleIds)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) AssignRolesTx(
+	ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64, validFrom, validTo *time.Time,
+) error {
+	args := m.Called(ctx, tx, employeeId, roleIds, validFrom, validTo)
 	return args.Error(0)
 }
 
+func (m *MockRepo) RevokeRolesTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) error {
+	args := m.Called(ctx, tx, employeeId, roleIds)
+	return args.Error(0)
+}
+
+func (m *MockRepo) RoleExists(ctx context.Context, roleId int64) (bool, error) {
+	args := m.Called(ctx, roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindByRoleId(ctx context.Context, roleId int64) ([]Entity, error) {
+	args := m.Called(ctx, roleId)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	args := m.Called(ctx, tx, id)
+	if ent, ok := args.Get(0).(*Entity); ok {
+		return ent, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (*Entity, error) {
+	args := m.Called(ctx, tx, employee)
+	if ent, ok := args.Get(0).(*Entity); ok {
+		return ent, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	args := m.Called(ctx, tx, id)
+	if ent, ok := args.Get(0).(*Entity); ok {
+		return ent, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	args := m.Called(ctx, tx, id)
+	if ent, ok := args.Get(0).(*Entity); ok {
+		return ent, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, before time.Time) ([]Entity, error) {
+	args := m.Called(ctx, tx, before)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) UpdateStatusTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (*Entity, error) {
+	args := m.Called(ctx, tx, employee)
+	if ent, ok := args.Get(0).(*Entity); ok {
+		return ent, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) AddStatusHistoryTx(ctx context.Context, tx *sqlx.Tx, history *StatusHistoryEntity) error {
+	return m.Called(ctx, tx, history).Error(0)
+}
+
+func (m *MockRepo) FindStatusHistory(ctx context.Context, employeeId int64) ([]StatusHistoryEntity, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]StatusHistoryEntity), args.Error(1)
+}
+
+func (m *MockRepo) RevokeAllRolesTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) ([]GrantEntity, error) {
+	args := m.Called(ctx, tx, employeeId)
+	return args.Get(0).([]GrantEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindTakenUniqueFieldsTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) ([]string, error) {
+	args := m.Called(ctx, tx, employee)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRepo) OrgUnitExists(ctx context.Context, orgUnitId int64) (bool, error) {
+	args := m.Called(ctx, orgUnitId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) OrgUnitExistsTx(ctx context.Context, tx *sqlx.Tx, orgUnitId int64) (bool, error) {
+	args := m.Called(ctx, tx, orgUnitId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindByOrgUnit(ctx context.Context, orgUnitId int64, subtree bool) ([]Entity, error) {
+	args := m.Called(ctx, orgUnitId, subtree)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindManagers(ctx context.Context, id int64) ([]Entity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindReports(ctx context.Context, id int64, depth int) ([]Entity, error) {
+	args := m.Called(ctx, id, depth)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindOrgChartMembers(ctx context.Context) ([]Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) IsInManagementChainTx(ctx context.Context, tx *sqlx.Tx, employeeId, managerId int64) (bool, error) {
+	args := m.Called(ctx, tx, employeeId, managerId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) LockManagementTx(ctx context.Context, tx *sqlx.Tx) error {
+	return m.Called(ctx, tx).Error(0)
+}
+
+func (m *MockRepo) FindGrants(ctx context.Context, employeeId int64) ([]GrantEntity, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]GrantEntity), args.Error(1)
+}
+
+func (m *MockRepo) TryLockGrantExpiryTx(ctx context.Context, tx *sqlx.Tx) (bool, error) {
+	args := m.Called(ctx, tx)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) DeleteExpiredGrantsTx(ctx context.Context, tx *sqlx.Tx, now time.Time) ([]GrantEntity, error) {
+	args := m.Called(ctx, tx, now)
+	return args.Get(0).([]GrantEntity), args.Error(1)
+}
+
+// chainLockQuery подменяет блокировку цепочки журнала, чтобы sqlmock проверял, где в транзакции пишется аудит
+const chainLockQuery = "SELECT audit_chain_lock"
+
+// stubAuditor запоминает события аудита вместо записи в БД.
+// С lockChain каждое событие выполняет в транзакции chainLockQuery
+type stubAuditor struct {
+	records   []audit.Record
+	err       error
+	lockChain bool
+}
+
+func (a *stubAuditor) RecordTx(ctx context.Context, tx *sqlx.Tx, record audit.Record) error {
+	if a.err != nil {
+		return a.err
+	}
+	if a.lockChain {
+		if _, err := tx.ExecContext(ctx, chainLockQuery); err != nil {
+			return err
+		}
+	}
+	a.records = append(a.records, record)
+	return nil
+}
+
+// stubPublisher запоминает опубликованные события
+type stubPublisher struct {
+	events []events.Event
+}
+
+func (p *stubPublisher) Publish(ctx context.Context, event events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+var nopLogger = &common.Logger{Logger: zap.NewNop()}
+
 // --- 2. Сам сервис для тестов ---
 func newTestService(repo Repo) *Service {
-	return NewService(repo)
+	return NewService(repo, &stubAuditor{}, &stubPublisher{}, nopLogger, AttributeSchema{})
+}
+
+// newSqlmockService создаёт сервис с настоящим репозиторием поверх sqlmock
+func newSqlmockService(t *testing.T) (*Service, sqlmock.Sqlmock, *stubAuditor) {
+	dbMock, m, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = dbMock.Close() })
+	auditor := &stubAuditor{}
+	return NewService(NewEmployeeRepository(sqlx.NewDb(dbMock, "postgres")), auditor, &stubPublisher{}, nopLogger, AttributeSchema{}), m, auditor
+}
+
+// запросы создания и изменения сотрудника со всеми полями профиля
+var (
+	insertEmployeeQuery = regexp.QuoteMeta("insert into employee (name, status, start_date, email, login, first_name, " +
+		"last_name, phone, title, employee_number, attributes, org_unit_id, manager_id) " +
+		"values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) returning id")
+	updateEmployeeQuery = regexp.QuoteMeta("UPDATE employee SET name = $1, email = $2, login = $3, first_name = $4, " +
+		"last_name = $5, phone = $6, title = $7, employee_number = $8, attributes = $9, org_unit_id = $10, " +
+		"manager_id = $11, updated_at = now() WHERE id = $12 RETURNING *")
+)
+
+// insertEmployeeArgs - аргументы вставки активного сотрудника без профиля и атрибутов
+func insertEmployeeArgs(name string) []driver.Value {
+	return []driver.Value{name, StatusActive, nil, nil, nil, nil, nil, nil, nil, nil, "{}", nil, nil}
+}
+
+// updateEmployeeArgs - аргументы изменения имени сотрудника без профиля и атрибутов
+func updateEmployeeArgs(name string, id int64) []driver.Value {
+	return []driver.Value{name, nil, nil, nil, nil, nil, nil, nil, "{}", nil, nil, id}
 }
 
 // --- 3. Тесты ---
@@ -101,67 +318,93 @@ func TestService_FindById(t *testing.T) {
 	resp := Response{Id: 1, Name: "John", CreatedAt: now, UpdatedAt: now}
 
 	// Успешный кейс
-	repo.On("FindById", int64(1)).Return(ent, nil)
-	got, err := svc.FindById(1)
+	repo.On("FindById", mock.Anything, int64(1)).Return(ent, nil)
+	got, err := svc.FindById(context.Background(), 1)
 	assert.NoError(t, err)
 	assert.Equal(t, resp, got)
-	repo.AssertCalled(t, "FindById", int64(1))
+	repo.AssertCalled(t, "FindById", mock.Anything, int64(1))
 
 	// Ошибка из репо
 	repo = new(MockRepo)
 	svc = newTestService(repo)
-	repo.On("FindById", int64(2)).Return((*Entity)(nil), errors.New("not found"))
-	_, err = svc.FindById(2)
+	repo.On("FindById", mock.Anything, int64(2)).Return((*Entity)(nil), errors.New("not found"))
+	_, err = svc.FindById(context.Background(), 2)
 	assert.Error(t, err)
 	repo.AssertNumberOfCalls(t, "FindById", 1)
 }
 
 func TestService_Add(t *testing.T) {
-	repo := new(MockRepo)
-	svc := newTestService(repo)
+	svc, m, auditor := newSqlmockService(t)
 	req := CreateRequest{Name: "Jane"}
-	repo.On("Add", req.ToEntity()).Return(nil)
+	m.ExpectBegin()
+	m.ExpectQuery(insertEmployeeQuery).
+		WithArgs(insertEmployeeArgs("Jane")...).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	m.ExpectCommit()
 
-	err := svc.Add(req)
+	err := svc.Add(context.Background(), req)
 	assert.NoError(t, err)
-	repo.AssertCalled(t, "Add", req.ToEntity())
+	assert.NoError(t, m.ExpectationsWereMet())
+	assert.Equal(t, []audit.Record{{
+		Action:     audit.ActionCreate,
+		EntityType: "employee",
+		EntityId:   3,
+		After:      auditState{Id: 3, Name: "Jane"},
+	}}, auditor.records)
 
 	// Validation error for empty name in Add
 	t.Run("should return validation error on Add with empty name", func(t *testing.T) {
 		repo := new(MockRepo)
 		svc := newTestService(repo)
 		req := CreateRequest{Name: ""}
-		err := svc.Add(req)
+		err := svc.Add(context.Background(), req)
 		assert.Error(t, err)
 		var valErr common.RequestValidationError
 		assert.True(t, errors.As(err, &valErr))
-		repo.AssertNotCalled(t, "Add", mock.Anything)
+		repo.AssertNotCalled(t, "BeginTransaction", mock.Anything)
 	})
 }
 
 func TestService_Save(t *testing.T) {
-	repo := new(MockRepo)
-	svc := newTestService(repo)
-
-	ent := CreateRequest{Name: "Bob"}
-	repo.On("Save", ent.ToEntity()).Return(int64(42), nil)
+	svc, m, auditor := newSqlmockService(t)
+	m.ExpectBegin()
+	m.ExpectQuery(insertEmployeeQuery).
+		WithArgs(insertEmployeeArgs("Bob")...).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	m.ExpectCommit()
 
-	id, err := svc.Save(ent)
+	id, err := svc.Save(context.Background(), CreateRequest{Name: "Bob"})
 	assert.NoError(t, err)
 	assert.Equal(t, int64(42), id)
-	repo.AssertCalled(t, "Save", ent.ToEntity())
+	assert.NoError(t, m.ExpectationsWereMet())
+	assert.Len(t, auditor.records, 1)
 
 	// Validation error for empty name in Save
 	t.Run("should return validation error on Save with empty name", func(t *testing.T) {
 		repo := new(MockRepo)
 		svc := newTestService(repo)
 		req := CreateRequest{Name: ""}
-		id, err := svc.Save(req)
+		id, err := svc.Save(context.Background(), req)
 		assert.Error(t, err)
 		var valErr common.RequestValidationError
 		assert.True(t, errors.As(err, &valErr))
 		assert.Equal(t, int64(0), id)
-		repo.AssertNotCalled(t, "Save", mock.Anything)
+		repo.AssertNotCalled(t, "BeginTransaction", mock.Anything)
+	})
+
+	// изменение откатывается, если событие аудита не удалось записать
+	t.Run("audit error rolls back", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		auditor.err = errors.New("audit failed")
+		m.ExpectBegin()
+		m.ExpectQuery(insertEmployeeQuery).
+			WithArgs(insertEmployeeArgs("Bob")...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+		m.ExpectRollback()
+
+		_, err := svc.Save(context.Background(), CreateRequest{Name: "Bob"})
+		assert.ErrorContains(t, err, "audit failed")
+		assert.NoError(t, m.ExpectationsWereMet())
 	})
 }
 
@@ -174,9 +417,9 @@ func TestService_FindAll(t *testing.T) {
 		{Id: 1, Name: "A", CreatedAt: now, UpdatedAt: now},
 		{Id: 2, Name: "B", CreatedAt: now, UpdatedAt: now},
 	}
-	repo.On("FindAll").Return(ents, nil)
+	repo.On("FindAll", mock.Anything).Return(ents, nil)
 
-	got, err := svc.FindAll()
+	got, err := svc.FindAll(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, got, 2)
 	assert.Equal(t, ents[0].toResponse(), got[0])
@@ -194,33 +437,103 @@ func TestService_FindByIds(t *testing.T) {
 		{Id: 1, Name: "X", CreatedAt: now, UpdatedAt: now},
 		{Id: 3, Name: "Y", CreatedAt: now, UpdatedAt: now},
 	}
-	repo.On("FindByIds", ids).Return(ents, nil)
+	repo.On("FindByIds", mock.Anything, ids).Return(ents, nil)
 
-	got, err := svc.FindByIds(ids)
+	got, err := svc.FindByIds(context.Background(), ids)
 	assert.NoError(t, err)
 	assert.Len(t, got, 2)
-	repo.AssertCalled(t, "FindByIds", ids)
+	repo.AssertCalled(t, "FindByIds", mock.Anything, ids)
 }
 
 func TestService_DeleteById(t *testing.T) {
-	repo := new(MockRepo)
-	svc := newTestService(repo)
+	columns := []string{"id", "name", "created_at", "updated_at"}
+	now := time.Now()
+	deletedBefore := testutil.ToFloat64(metrics.EmployeesDeleted)
+
+	svc, m, auditor := newSqlmockService(t)
+	m.ExpectBegin()
+	m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "Alice", now, now))
+	m.ExpectExec(regexp.QuoteMeta("UPDATE employee SET deleted_at = now(), deleted_by = $1 WHERE id = $2 AND deleted_at IS NULL")).
+		WithArgs("alice", int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	m.ExpectCommit()
+	m.ExpectBegin()
+	m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+		WithArgs(int64(6)).
+		WillReturnError(errors.New("db error"))
+	m.ExpectRollback()
 
-	repo.On("DeleteById", int64(5)).Return(nil)
-	err := svc.DeleteById(5)
+	// в deleted_by попадает логин пользователя, выполнившего удаление
+	ctx := common.WithUser(context.Background(), common.User{Subject: "sub-1", Username: "alice"})
+	err := svc.DeleteById(ctx, 5)
 	assert.NoError(t, err)
-	repo.AssertNumberOfCalls(t, "DeleteById", 1)
+	err = svc.DeleteById(context.Background(), 6)
+	assert.Error(t, err)
+	assert.NoError(t, m.ExpectationsWereMet())
+	assert.Equal(t, []audit.Record{{
+		Action:     audit.ActionDelete,
+		EntityType: "employee",
+		EntityId:   5,
+		Before:     auditState{Id: 5, Name: "Alice"},
+	}}, auditor.records)
+	// неудачное удаление не учитывается в метрике
+	assert.Equal(t, deletedBefore+1, testutil.ToFloat64(metrics.EmployeesDeleted))
 }
 
 func TestService_DeleteByIds(t *testing.T) {
-	repo := new(MockRepo)
-	svc := newTestService(repo)
+	columns := []string{"id", "name", "created_at", "updated_at"}
+	now := time.Now()
+	deletedBefore := testutil.ToFloat64(metrics.EmployeesDeleted)
+
+	svc, m, auditor := newSqlmockService(t)
+	m.ExpectBegin()
+	m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id IN ($1, $2, $3) AND deleted_at IS NULL ORDER BY id FOR UPDATE")).
+		WithArgs(int64(7), int64(8), int64(9)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(7, "A", now, now).AddRow(8, "B", now, now).AddRow(9, "C", now, now))
+	m.ExpectExec(regexp.QuoteMeta("UPDATE employee SET deleted_at = now(), deleted_by = $1 WHERE id IN ($2, $3, $4) AND deleted_at IS NULL")).
+		WithArgs("", int64(7), int64(8), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	m.ExpectCommit()
 
-	ids := []int64{7, 8}
-	repo.On("DeleteByIds", ids).Return(nil)
-	err := svc.DeleteByIds(ids)
+	err := svc.DeleteByIds(context.Background(), []int64{7, 8, 9})
 	assert.NoError(t, err)
-	repo.AssertCalled(t, "DeleteByIds", ids)
+	assert.NoError(t, m.ExpectationsWereMet())
+	// событие пишется на каждого удалённого сотрудника
+	assert.Len(t, auditor.records, 3)
+	assert.Equal(t, int64(8), auditor.records[1].EntityId)
+	assert.Equal(t, deletedBefore+3, testutil.ToFloat64(metrics.EmployeesDeleted))
+
+	t.Run("some missing", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id IN ($1, $2, $3) AND deleted_at IS NULL ORDER BY id FOR UPDATE")).
+			WithArgs(int64(7), int64(8), int64(9)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(7, "A", now, now).AddRow(8, "B", now, now))
+		m.ExpectRollback()
+
+		err := svc.DeleteByIds(context.Background(), []int64{7, 8, 9})
+		var notFound common.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		assert.Contains(t, notFound.Message, "[9]")
+		assert.NoError(t, m.ExpectationsWereMet())
+		// не удаляется никто
+		assert.Empty(t, auditor.records)
+	})
+
+	t.Run("none found", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id IN ($1) AND deleted_at IS NULL ORDER BY id FOR UPDATE")).
+			WithArgs(int64(404)).
+			WillReturnRows(sqlmock.NewRows(columns))
+		m.ExpectRollback()
+
+		err := svc.DeleteByIds(context.Background(), []int64{404})
+		assert.ErrorAs(t, err, &common.NotFoundError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
 }
 
 func TestService_SaveWithTransaction(t *testing.T) {
@@ -259,6 +572,13 @@ func TestService_SaveWithTransaction(t *testing.T) {
 			verify: func(t *testing.T) {
 			},
 		},
+		{
+			name: "commit error",
+			setup: func() {
+			},
+			verify: func(t *testing.T) {
+			},
+		},
 		{
 			name: "success creation",
 			setup: func() {
@@ -278,7 +598,7 @@ func TestService_SaveWithTransaction(t *testing.T) {
 			// wrap sql.DB into sqlx.DB and create new svc
 			db := sqlx.NewDb(dbMock, "sqlmock")
 			repo := NewEmployeeRepository(db)
-			svc := NewService(repo)
+			svc := NewService(repo, &stubAuditor{}, &stubPublisher{}, nopLogger, AttributeSchema{})
 
 			// common entity
 			entity := CreateRequest{Name: "Alice"}
@@ -290,39 +610,49 @@ func TestService_SaveWithTransaction(t *testing.T) {
 					mock.ExpectBegin().WillReturnError(errors.New("begin failed"))
 				case "check existence error":
 					mock.ExpectBegin()
-					mock.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1)")).
+					mock.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1 and deleted_at is null)")).
 						WithArgs(entity.Name).
 						WillReturnError(errors.New("select failed"))
 					mock.ExpectRollback()
 				case "duplicate employee":
 					mock.ExpectBegin()
-					mock.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1)")).
+					mock.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1 and deleted_at is null)")).
 						WithArgs(entity.Name).
 						WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 					mock.ExpectRollback()
 				case "insert error":
 					mock.ExpectBegin()
-					mock.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1)")).
+					mock.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1 and deleted_at is null)")).
 						WithArgs(entity.Name).
 						WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
-					mock.ExpectQuery(regexp.QuoteMeta("insert into employee (name) values ($1) returning id")).
-						WithArgs(entity.Name).
+					mock.ExpectQuery(insertEmployeeQuery).
+						WithArgs(insertEmployeeArgs(entity.Name)...).
 						WillReturnError(errors.New("insert failed"))
 					mock.ExpectRollback()
-				case "success creation":
+				case "commit error":
 					mock.ExpectBegin()
-					mock.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1)")).
+					mock.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1 and deleted_at is null)")).
 						WithArgs(entity.Name).
 						WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
-					mock.ExpectQuery(regexp.QuoteMeta("insert into employee (name) values ($1) returning id")).
+					mock.ExpectQuery(insertEmployeeQuery).
+						WithArgs(insertEmployeeArgs(entity.Name)...).
+						WillReturnRows(sqlmock.NewRows([]string{"employeeid"}).AddRow(123))
+					mock.ExpectCommit().WillReturnError(errors.New("commit failed"))
+				case "success creation":
+					mock.ExpectBegin()
+					mock.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1 and deleted_at is null)")).
 						WithArgs(entity.Name).
+						WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+					mock.ExpectQuery(insertEmployeeQuery).
+						WithArgs(insertEmployeeArgs(entity.Name)...).
 						WillReturnRows(sqlmock.NewRows([]string{"employeeid"}).AddRow(123))
 					mock.ExpectCommit()
 				}
 			}
 
 			tc.verify = func(t *testing.T) {
-				id, err := svc.SaveWithTransaction(entity)
+				createdBefore := testutil.ToFloat64(metrics.EmployeesCreated)
+				id, err := svc.SaveWithTransaction(context.Background(), entity)
 				switch tc.name {
 				case "begin transaction error":
 					assert.Error(t, err)
@@ -336,9 +666,15 @@ func TestService_SaveWithTransaction(t *testing.T) {
 				case "insert error":
 					assert.Error(t, err)
 					assert.Contains(t, err.Error(), "error creating employee with name")
+				case "commit error":
+					// сотрудник не сохранён: id не возвращается, счётчик созданных не растёт
+					assert.ErrorContains(t, err, "commit failed")
+					assert.Zero(t, id)
+					assert.Equal(t, createdBefore, testutil.ToFloat64(metrics.EmployeesCreated))
 				case "success creation":
 					assert.NoError(t, err)
 					assert.Equal(t, int64(123), id)
+					assert.Equal(t, createdBefore+1, testutil.ToFloat64(metrics.EmployeesCreated))
 				}
 			}
 
@@ -350,3 +686,989 @@ func TestService_SaveWithTransaction(t *testing.T) {
 		})
 	}
 }
+
+func TestService_AssignRoles(t *testing.T) {
+	columns := []string{"id", "name", "status"}
+	insertGrant := "insert into employee_role (employee_id, role_id, valid_from, valid_to) values ($1, $2, coalesce($3, now()), $4)"
+	from := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	to := from.Add(24 * time.Hour)
+	expired := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name    string
+		req     AssignRolesRequest
+		setup   func(m sqlmock.Sqlmock)
+		wantErr string
+		wantAs  any
+	}{
+		{
+			name:    "empty role ids",
+			req:     AssignRolesRequest{RolesRequest: RolesRequest{}},
+			setup:   func(m sqlmock.Sqlmock) {},
+			wantErr: "role_ids",
+			wantAs:  &common.RequestValidationError{},
+		},
+		{
+			name:    "valid_to before valid_from",
+			req:     AssignRolesRequest{RolesRequest: RolesRequest{RoleIds: []int64{1}}, ValidFrom: &to, ValidTo: &from},
+			setup:   func(m sqlmock.Sqlmock) {},
+			wantErr: "valid_to must be after valid_from",
+			wantAs:  &common.RequestValidationError{},
+		},
+		{
+			name:    "valid_to in the past",
+			req:     AssignRolesRequest{RolesRequest: RolesRequest{RoleIds: []int64{1}}, ValidTo: &expired},
+			setup:   func(m sqlmock.Sqlmock) {},
+			wantErr: "valid_to must be in the future",
+			wantAs:  &common.RequestValidationError{},
+		},
+		{
+			name: "employee not found",
+			req:  AssignRolesRequest{RolesRequest: RolesRequest{RoleIds: []int64{1}}},
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+					WithArgs(int64(10)).
+					WillReturnRows(sqlmock.NewRows(columns))
+				m.ExpectRollback()
+			},
+			wantErr: "employee with id 10 not found",
+			wantAs:  &common.NotFoundError{},
+		},
+		{
+			name: "terminated employee",
+			req:  AssignRolesRequest{RolesRequest: RolesRequest{RoleIds: []int64{1}}},
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+					WithArgs(int64(10)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(10, "Alice", StatusTerminated))
+				m.ExpectRollback()
+			},
+			wantErr: "employee with id 10 is terminated",
+			wantAs:  &common.ConflictError{},
+		},
+		{
+			name: "role not found",
+			req:  AssignRolesRequest{RolesRequest: RolesRequest{RoleIds: []int64{1, 2}}},
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+					WithArgs(int64(10)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(10, "Alice", StatusActive))
+				m.ExpectQuery(regexp.QuoteMeta("SELECT id FROM role WHERE id IN ($1, $2) AND deleted_at IS NULL")).
+					WithArgs(int64(1), int64(2)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				m.ExpectRollback()
+			},
+			wantErr: "roles not found: [2]",
+			wantAs:  &common.RequestValidationError{},
+		},
+		{
+			name: "insert error rolls back",
+			req:  AssignRolesRequest{RolesRequest: RolesRequest{RoleIds: []int64{1}}},
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+					WithArgs(int64(10)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(10, "Alice", StatusActive))
+				m.ExpectQuery(regexp.QuoteMeta("SELECT id FROM role WHERE id IN ($1) AND deleted_at IS NULL")).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				m.ExpectExec(regexp.QuoteMeta(insertGrant)).
+					WithArgs(int64(10), int64(1), nil, nil).
+					WillReturnError(errors.New("insert failed"))
+				m.ExpectRollback()
+			},
+			wantErr: "error assigning roles to employee with id 10",
+		},
+		{
+			name: "success",
+			req:  AssignRolesRequest{RolesRequest: RolesRequest{RoleIds: []int64{1, 2}}},
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+					WithArgs(int64(10)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(10, "Alice", StatusActive))
+				m.ExpectQuery(regexp.QuoteMeta("SELECT id FROM role WHERE id IN ($1, $2) AND deleted_at IS NULL")).
+					WithArgs(int64(1), int64(2)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+				for _, roleId := range []int64{1, 2} {
+					m.ExpectExec(regexp.QuoteMeta(insertGrant)).
+						WithArgs(int64(10), roleId, nil, nil).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "time-bound grant",
+			req:  AssignRolesRequest{RolesRequest: RolesRequest{RoleIds: []int64{1}}, ValidFrom: &from, ValidTo: &to},
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+					WithArgs(int64(10)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(10, "Alice", StatusActive))
+				m.ExpectQuery(regexp.QuoteMeta("SELECT id FROM role WHERE id IN ($1) AND deleted_at IS NULL")).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				m.ExpectExec(regexp.QuoteMeta(insertGrant)).
+					WithArgs(int64(10), int64(1), from, to).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dbMock, m, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer dbMock.Close()
+
+			svc := NewService(NewEmployeeRepository(sqlx.NewDb(dbMock, "postgres")), &stubAuditor{}, &stubPublisher{}, nopLogger, AttributeSchema{})
+			tc.setup(m)
+
+			err = svc.AssignRoles(context.Background(), 10, tc.req)
+			if tc.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				if tc.wantAs != nil {
+					assert.ErrorAs(t, err, tc.wantAs)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestService_RevokeRoles(t *testing.T) {
+	dbMock, m, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer dbMock.Close()
+
+	svc := NewService(NewEmployeeRepository(sqlx.NewDb(dbMock, "postgres")), &stubAuditor{}, &stubPublisher{}, nopLogger, AttributeSchema{})
+	m.ExpectBegin()
+	m.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where id = $1 and deleted_at is null)")).
+		WithArgs(int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	m.ExpectExec(regexp.QuoteMeta("DELETE FROM employee_role WHERE employee_id = $1 AND role_id IN ($2, $3)")).
+		WithArgs(int64(10), int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	m.ExpectCommit()
+
+	assert.NoError(t, svc.RevokeRoles(context.Background(), 10, RolesRequest{RoleIds: []int64{1, 2}}))
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestService_Update(t *testing.T) {
+	version := time.Date(2025, 6, 24, 12, 0, 0, 0, time.UTC)
+	stale := version.Add(-time.Second)
+	updatedAt := version.Add(time.Minute)
+	columns := []string{"id", "name", "created_at", "updated_at"}
+
+	tests := []struct {
+		name     string
+		req      UpdateRequest
+		expected *time.Time
+		setup    func(m sqlmock.Sqlmock)
+		wantErr  any
+	}{
+		{
+			name:    "validation error",
+			req:     UpdateRequest{Name: ""},
+			setup:   func(m sqlmock.Sqlmock) {},
+			wantErr: &common.RequestValidationError{},
+		},
+		{
+			name:     "stale version",
+			req:      UpdateRequest{Name: "Bob"},
+			expected: &stale,
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version))
+				m.ExpectRollback()
+			},
+			wantErr: &common.PreconditionFailedError{},
+		},
+		{
+			name:     "success with matching version",
+			req:      UpdateRequest{Name: "Bob"},
+			expected: &version,
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version))
+				m.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1 and deleted_at is null)")).
+					WithArgs("Bob").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				m.ExpectQuery(updateEmployeeQuery).
+					WithArgs(updateEmployeeArgs("Bob", 1)...).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Bob", version, updatedAt))
+				m.ExpectCommit()
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dbMock, m, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer dbMock.Close()
+
+			svc := NewService(NewEmployeeRepository(sqlx.NewDb(dbMock, "postgres")), &stubAuditor{}, &stubPublisher{}, nopLogger, AttributeSchema{})
+			tc.setup(m)
+
+			resp, err := svc.Update(context.Background(), 1, tc.req, tc.expected)
+			if tc.wantErr != nil {
+				assert.ErrorAs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "Bob", resp.Name)
+				assert.Equal(t, updatedAt, resp.UpdatedAt)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestService_Patch(t *testing.T) {
+	version := time.Date(2025, 6, 24, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "name", "created_at", "updated_at"}
+
+	t.Run("invalid result of patch", func(t *testing.T) {
+		dbMock, m, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer dbMock.Close()
+
+		svc := NewService(NewEmployeeRepository(sqlx.NewDb(dbMock, "postgres")), &stubAuditor{}, &stubPublisher{}, nopLogger, AttributeSchema{})
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version))
+		m.ExpectRollback()
+
+		_, err = svc.Patch(context.Background(), 1, []byte(`{"name":null}`), nil)
+		assert.ErrorAs(t, err, &common.RequestValidationError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("unchanged name skips duplicate check", func(t *testing.T) {
+		dbMock, m, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer dbMock.Close()
+
+		svc := NewService(NewEmployeeRepository(sqlx.NewDb(dbMock, "postgres")), &stubAuditor{}, &stubPublisher{}, nopLogger, AttributeSchema{})
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version))
+		m.ExpectQuery(updateEmployeeQuery).
+			WithArgs(updateEmployeeArgs("Alice", 1)...).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version.Add(time.Second)))
+		m.ExpectCommit()
+
+		resp, err := svc.Patch(context.Background(), 1, []byte(`{}`), &version)
+		assert.NoError(t, err)
+		assert.Equal(t, "Alice", resp.Name)
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_NotFound(t *testing.T) {
+	dbMock, m, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer dbMock.Close()
+	repo := NewEmployeeRepository(sqlx.NewDb(dbMock, "postgres"))
+
+	m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL")).
+		WithArgs(int64(404)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at"}))
+	_, err = NewService(repo, &stubAuditor{}, &stubPublisher{}, nopLogger, AttributeSchema{}).FindById(context.Background(), 404)
+	assert.ErrorAs(t, err, &common.NotFoundError{})
+
+	m.ExpectExec(regexp.QuoteMeta("UPDATE employee SET deleted_at = now(), deleted_by = $1 WHERE id = $2 AND deleted_at IS NULL")).
+		WithArgs("", int64(404)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	err = repo.DeleteById(context.Background(), 404)
+	assert.ErrorAs(t, err, &common.NotFoundError{})
+
+	m.ExpectExec(regexp.QuoteMeta("UPDATE employee SET deleted_at = now(), deleted_by = $1 WHERE id IN ($2, $3) AND deleted_at IS NULL")).
+		WithArgs("", int64(404), int64(405)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	err = repo.DeleteByIds(context.Background(), []int64{404, 405})
+	assert.ErrorAs(t, err, &common.NotFoundError{})
+
+	assert.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestRepository_ContextCancellation(t *testing.T) {
+	dbMock, m, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer dbMock.Close()
+	repo := NewEmployeeRepository(sqlx.NewDb(dbMock, "postgres"))
+
+	// запрос выполняется дольше, чем живёт контекст запроса
+	m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee")).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = repo.FindAll(ctx)
+	assert.Error(t, err)
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+}
+
+func TestService_Restore(t *testing.T) {
+	deletedAt := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	version := deletedAt.Add(-time.Hour)
+	columns := []string{"id", "name", "created_at", "updated_at", "deleted_at", "deleted_by"}
+
+	tests := []struct {
+		name    string
+		setup   func(m sqlmock.Sqlmock)
+		wantErr any
+	}{
+		{
+			name: "employee is not deleted",
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE")).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows(columns))
+				m.ExpectRollback()
+			},
+			wantErr: &common.NotFoundError{},
+		},
+		{
+			name: "name is taken by another employee",
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE")).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version, deletedAt, "admin"))
+				m.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1 and deleted_at is null)")).
+					WithArgs("Alice").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				m.ExpectRollback()
+			},
+			wantErr: &common.AlreadyExistsError{},
+		},
+		{
+			name: "success",
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE")).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version, deletedAt, "admin"))
+				m.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where name = $1 and deleted_at is null)")).
+					WithArgs("Alice").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				m.ExpectQuery(regexp.QuoteMeta("UPDATE employee SET deleted_at = NULL, deleted_by = NULL, updated_at = now() WHERE id = $1 RETURNING *")).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, deletedAt.Add(time.Hour), nil, nil))
+				m.ExpectCommit()
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, m, auditor := newSqlmockService(t)
+			tc.setup(m)
+
+			resp, err := svc.Restore(context.Background(), 1)
+			if tc.wantErr != nil {
+				assert.ErrorAs(t, err, tc.wantErr)
+				assert.Empty(t, auditor.records)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "Alice", resp.Name)
+				assert.Nil(t, resp.DeletedAt)
+				assert.Equal(t, []audit.Record{{
+					Action:     audit.ActionRestore,
+					EntityType: "employee",
+					EntityId:   1,
+					After:      auditState{Id: 1, Name: "Alice"},
+				}}, auditor.records)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestService_Purge(t *testing.T) {
+	before := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	deletedAt := before.Add(-time.Hour)
+	columns := []string{"id", "name", "created_at", "updated_at", "deleted_at", "deleted_by"}
+
+	svc, m, auditor := newSqlmockService(t)
+	m.ExpectBegin()
+	m.ExpectQuery(regexp.QuoteMeta("DELETE FROM employee WHERE deleted_at < $1 RETURNING *")).
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(4, "A", deletedAt, deletedAt, deletedAt, "admin").
+			AddRow(5, "B", deletedAt, deletedAt, deletedAt, "admin"))
+	m.ExpectCommit()
+
+	purged, err := svc.Purge(context.Background(), before)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.NoError(t, m.ExpectationsWereMet())
+	// окончательное удаление тоже попадает в журнал аудита, по событию на запись
+	assert.Len(t, auditor.records, 2)
+	assert.Equal(t, audit.ActionPurge, auditor.records[0].Action)
+	assert.Equal(t, auditState{Id: 5, Name: "B"}, auditor.records[1].Before)
+}
+
+func TestService_ExpireGrants(t *testing.T) {
+	now := time.Date(2025, 12, 20, 12, 0, 0, 0, time.UTC)
+	validFrom := now.Add(-48 * time.Hour)
+	validTo := now.Add(-time.Minute)
+	lockQuery := regexp.QuoteMeta("SELECT pg_try_advisory_xact_lock(hashtext('employee_role.valid_to'))")
+	deleteQuery := regexp.QuoteMeta("DELETE FROM employee_role WHERE valid_to <= $1")
+	columns := []string{"employee_id", "role_id", "valid_from", "valid_to", "created_at"}
+
+	t.Run("another replica holds the lock", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+		m.ExpectCommit()
+
+		expired, err := svc.ExpireGrants(context.Background(), now)
+		assert.NoError(t, err)
+		assert.Empty(t, expired)
+		assert.Empty(t, auditor.records)
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("expired grants are revoked and audited per employee", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		m.ExpectQuery(deleteQuery).
+			WithArgs(now).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(7, 3, validFrom, validTo, validFrom).
+				AddRow(4, 1, validFrom, validTo, validFrom).
+				AddRow(7, 2, validFrom, validTo, validFrom))
+		m.ExpectCommit()
+
+		expired, err := svc.ExpireGrants(context.Background(), now)
+		assert.NoError(t, err)
+		assert.Len(t, expired, 3)
+		assert.Equal(t, GrantResponse{EmployeeId: 7, RoleId: 3, ValidFrom: validFrom, ValidTo: &validTo}, expired[0])
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Len(t, auditor.records, 2)
+		assert.Equal(t, audit.ActionExpireRoles, auditor.records[0].Action)
+		assert.Equal(t, int64(4), auditor.records[0].EntityId)
+		assert.Equal(t, auditRoles{RoleIds: []int64{2, 3}}, auditor.records[1].Before)
+	})
+
+	t.Run("delete error rolls back", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		m.ExpectQuery(deleteQuery).WithArgs(now).WillReturnError(errors.New("db down"))
+		m.ExpectRollback()
+
+		_, err := svc.ExpireGrants(context.Background(), now)
+		assert.ErrorContains(t, err, "error deleting role grants expired by")
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPreHire, StatusActive, true},
+		{StatusPreHire, StatusSuspended, false},
+		{StatusActive, StatusOnLeave, true},
+		{StatusActive, StatusActive, false},
+		{StatusOnLeave, StatusActive, true},
+		{StatusSuspended, StatusTerminated, true},
+		{StatusTerminated, StatusActive, false},
+		{"", StatusActive, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.from+"->"+tc.to, func(t *testing.T) {
+			assert.Equal(t, tc.want, canTransition(tc.from, tc.to))
+		})
+	}
+}
+
+func TestService_Transition(t *testing.T) {
+	version := time.Date(2025, 6, 24, 12, 0, 0, 0, time.UTC)
+	startDate := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "name", "created_at", "updated_at", "status", "start_date", "end_date"}
+	lockQuery := regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")
+	updateQuery := regexp.QuoteMeta("UPDATE employee SET status = $1, start_date = $2, end_date = $3, updated_at = now() WHERE id = $4 RETURNING *")
+	historyQuery := regexp.QuoteMeta("INSERT INTO employee_status_history (employee_id, from_status, to_status, effective_date, reason, changed_by)")
+	ctx := common.WithUser(context.Background(), common.User{Subject: "sub-1", Username: "hr"})
+
+	t.Run("invalid transition", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version, StatusPreHire, nil, nil))
+		m.ExpectRollback()
+
+		_, err := svc.Transition(ctx, 1, TransitionRequest{Status: StatusOnLeave})
+		assert.ErrorAs(t, err, &common.ConflictError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Empty(t, auditor.records)
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+
+		_, err := svc.Transition(ctx, 1, TransitionRequest{Status: "fired", EffectiveDate: "31.12.2025"})
+		var validationErr common.RequestValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 2)
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("joiner becomes active", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version, StatusPreHire, nil, nil))
+		// дата начала работы проставляется при первом переходе в active
+		m.ExpectQuery(updateQuery).WithArgs(StatusActive, startDate, nil, int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version, StatusActive, startDate, nil))
+		m.ExpectExec(historyQuery).WithArgs(int64(1), StatusPreHire, StatusActive, startDate, "", "hr").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		m.ExpectCommit()
+
+		resp, err := svc.Transition(ctx, 1, TransitionRequest{Status: StatusActive, EffectiveDate: "2025-07-01"})
+		assert.NoError(t, err)
+		assert.Equal(t, StatusActive, resp.Status)
+		assert.Equal(t, "2025-07-01", resp.StartDate)
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Equal(t, []audit.Record{{
+			Action:     audit.ActionTransition,
+			EntityType: "employee",
+			EntityId:   1,
+			Before:     auditLifecycle{Status: StatusPreHire},
+			After:      auditLifecycle{Status: StatusActive, StartDate: "2025-07-01"},
+		}}, auditor.records)
+	})
+
+	t.Run("leaver loses roles", func(t *testing.T) {
+		revokedBefore := testutil.ToFloat64(metrics.RoleAssignments.WithLabelValues("revoke"))
+		svc, m, auditor := newSqlmockService(t)
+		auditor.lockChain = true
+		publisher := &stubPublisher{}
+		svc.publisher = publisher
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version, StatusActive, startDate, nil))
+		m.ExpectQuery(updateQuery).WithArgs(StatusTerminated, startDate, endDate, int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version, StatusTerminated, startDate, endDate))
+		m.ExpectExec(historyQuery).WithArgs(int64(1), StatusActive, StatusTerminated, endDate, "resigned", "hr").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		grantedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		m.ExpectQuery(regexp.QuoteMeta("DELETE FROM employee_role WHERE employee_id = $1")).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"employee_id", "role_id", "valid_from", "valid_to", "created_at"}).
+				AddRow(1, 3, grantedAt, nil, grantedAt).
+				AddRow(1, 2, grantedAt, nil, grantedAt))
+		// блокировка цепочки аудита берётся только после удаления назначений - как в ExpireGrants
+		m.ExpectExec(chainLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		m.ExpectExec(chainLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		m.ExpectCommit()
+
+		resp, err := svc.Transition(ctx, 1, TransitionRequest{Status: StatusTerminated, EffectiveDate: "2025-12-31", Reason: "resigned"})
+		assert.NoError(t, err)
+		assert.Equal(t, "2025-12-31", resp.EndDate)
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Len(t, auditor.records, 2)
+		assert.Equal(t, audit.Record{
+			Action:     audit.ActionRevokeRoles,
+			EntityType: "employee",
+			EntityId:   1,
+			Before:     auditRoles{RoleIds: []int64{2, 3}},
+		}, auditor.records[1])
+		assert.Equal(t, revokedBefore+2, testutil.ToFloat64(metrics.RoleAssignments.WithLabelValues("revoke")))
+
+		// после фиксации публикуются смена статуса и отзыв каждого назначения
+		assert.Len(t, publisher.events, 3)
+		assert.Equal(t, EventStatusChanged, publisher.events[0].Type)
+		assert.Equal(t, int64(1), publisher.events[0].EntityId)
+		assert.Equal(t, StatusChangedEvent{
+			FromStatus: StatusActive, ToStatus: StatusTerminated, EffectiveDate: "2025-12-31", Reason: "resigned",
+		}, publisher.events[0].Data)
+		assert.Equal(t, EventGrantRevoked, publisher.events[1].Type)
+		assert.Equal(t, GrantResponse{EmployeeId: 1, RoleId: 2, ValidFrom: grantedAt}, publisher.events[1].Data)
+		assert.Equal(t, EventGrantRevoked, publisher.events[2].Type)
+		assert.Equal(t, int64(3), publisher.events[2].Data.(GrantResponse).RoleId)
+	})
+
+	t.Run("future effective date", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		publisher := &stubPublisher{}
+		svc.publisher = publisher
+		tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+
+		_, err := svc.Transition(ctx, 1, TransitionRequest{Status: StatusTerminated, EffectiveDate: tomorrow})
+		assert.ErrorAs(t, err, &common.RequestValidationError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Empty(t, auditor.records)
+		assert.Empty(t, publisher.events)
+	})
+
+	t.Run("end date before start date", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Alice", version, version, StatusActive, startDate, nil))
+		m.ExpectRollback()
+
+		_, err := svc.Transition(ctx, 1, TransitionRequest{Status: StatusTerminated, EffectiveDate: "2025-06-30"})
+		assert.ErrorAs(t, err, &common.RequestValidationError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+}
+
+func TestService_GetStatusHistory(t *testing.T) {
+	changedAt := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	repo := new(MockRepo)
+	svc := NewService(repo, &stubAuditor{}, &stubPublisher{}, nopLogger, AttributeSchema{})
+	repo.On("FindById", mock.Anything, int64(1)).Return(&Entity{Id: 1, Name: "Alice"}, nil)
+	repo.On("FindStatusHistory", mock.Anything, int64(1)).Return([]StatusHistoryEntity{{
+		Id: 7, EmployeeId: 1, FromStatus: StatusPreHire, ToStatus: StatusActive,
+		EffectiveDate: changedAt.Truncate(24 * time.Hour), ChangedBy: "hr", ChangedAt: changedAt,
+	}}, nil)
+	repo.On("FindById", mock.Anything, int64(2)).Return(nil, common.NotFoundError{Message: "employee with id 2 not found"})
+
+	history, err := svc.GetStatusHistory(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []StatusHistoryResponse{{
+		Id: 7, FromStatus: StatusPreHire, ToStatus: StatusActive, EffectiveDate: "2025-07-01", ChangedBy: "hr", ChangedAt: changedAt,
+	}}, history)
+
+	_, err = svc.GetStatusHistory(context.Background(), 2)
+	assert.ErrorAs(t, err, &common.NotFoundError{})
+	repo.AssertNotCalled(t, "FindStatusHistory", mock.Anything, int64(2))
+}
+
+func TestService_SaveProfile(t *testing.T) {
+	takenQuery := regexp.QuoteMeta("SELECT exists(select 1 from employee where lower(email) = lower($1)")
+	takenColumns := []string{"email", "login", "employee_number"}
+	req := CreateRequest{Name: "Ann", Profile: Profile{
+		Email: "ann@example.com", Login: "ann", FirstName: "Ann", Attributes: map[string]any{"remote": true},
+	}}
+	newService := func(t *testing.T) (*Service, sqlmock.Sqlmock, *stubAuditor) {
+		dbMock, m, err := sqlmock.New()
+		assert.NoError(t, err)
+		t.Cleanup(func() { _ = dbMock.Close() })
+		auditor := &stubAuditor{}
+		return NewService(NewEmployeeRepository(sqlx.NewDb(dbMock, "postgres")), auditor, &stubPublisher{}, nopLogger, testAttributeSchema(t)), m, auditor
+	}
+
+	t.Run("success", func(t *testing.T) {
+		svc, m, auditor := newService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(takenQuery).WithArgs("ann@example.com", "ann", nil, int64(0)).
+			WillReturnRows(sqlmock.NewRows(takenColumns).AddRow(false, false, false))
+		m.ExpectQuery(insertEmployeeQuery).
+			WithArgs("Ann", StatusActive, nil, "ann@example.com", "ann", "Ann", nil, nil, nil, nil, `{"remote":true}`, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		m.ExpectCommit()
+
+		id, err := svc.Save(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), id)
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Equal(t, auditState{Id: 7, Name: "Ann", Profile: req.Profile}, auditor.records[0].After)
+	})
+
+	t.Run("email is taken", func(t *testing.T) {
+		svc, m, _ := newService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(takenQuery).WithArgs("ann@example.com", "ann", nil, int64(0)).
+			WillReturnRows(sqlmock.NewRows(takenColumns).AddRow(true, false, false))
+		m.ExpectRollback()
+
+		_, err := svc.Save(context.Background(), req)
+		var existsErr common.AlreadyExistsError
+		assert.ErrorAs(t, err, &existsErr)
+		assert.Equal(t, "employee with the same email already exists", existsErr.Message)
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("invalid attribute", func(t *testing.T) {
+		svc, m, _ := newService(t)
+
+		_, err := svc.Save(context.Background(), CreateRequest{Name: "Ann", Profile: Profile{
+			Attributes: map[string]any{"clearance_level": 9.0},
+		}})
+		var validationErr common.RequestValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "attributes.clearance_level", validationErr.Fields[0].Field)
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("changed login is checked on update", func(t *testing.T) {
+		version := time.Date(2025, 6, 24, 12, 0, 0, 0, time.UTC)
+		svc, m, _ := newService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at", "login"}).
+				AddRow(1, "Ann", version, version, "ann"))
+		m.ExpectQuery(takenQuery).WithArgs(nil, "anna", nil, int64(1)).
+			WillReturnRows(sqlmock.NewRows(takenColumns).AddRow(false, true, false))
+		m.ExpectRollback()
+
+		_, err := svc.Patch(context.Background(), 1, []byte(`{"login":"anna"}`), nil)
+		assert.ErrorAs(t, err, &common.AlreadyExistsError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+}
+
+func TestService_OrgUnit(t *testing.T) {
+	orgUnitQuery := regexp.QuoteMeta("select exists(select 1 from org_unit where id = $1)")
+	unitId := int64(4)
+
+	t.Run("employee is created in existing unit", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(orgUnitQuery).WithArgs(unitId).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		m.ExpectQuery(insertEmployeeQuery).
+			WithArgs("Ann", StatusActive, nil, nil, nil, nil, nil, nil, nil, nil, "{}", unitId, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		m.ExpectCommit()
+
+		_, err := svc.Save(context.Background(), CreateRequest{Name: "Ann", Profile: Profile{OrgUnitId: &unitId}})
+		assert.NoError(t, err)
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Equal(t, &unitId, auditor.records[0].After.(auditState).OrgUnitId)
+	})
+
+	t.Run("unknown unit", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(orgUnitQuery).WithArgs(unitId).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		m.ExpectRollback()
+
+		_, err := svc.Save(context.Background(), CreateRequest{Name: "Ann", Profile: Profile{OrgUnitId: &unitId}})
+		var validationErr common.RequestValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "org unit 4 not found", validationErr.Message)
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	// неизменённое подразделение повторно не проверяется
+	t.Run("unchanged unit on update", func(t *testing.T) {
+		version := time.Date(2025, 6, 24, 12, 0, 0, 0, time.UTC)
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at", "org_unit_id"}).
+				AddRow(1, "Ann", version, version, unitId))
+		m.ExpectQuery(updateEmployeeQuery).
+			WithArgs("Ann", nil, nil, nil, nil, nil, "Engineer", nil, "{}", unitId, nil, int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at", "title", "org_unit_id"}).
+				AddRow(1, "Ann", version, version, "Engineer", unitId))
+		m.ExpectCommit()
+
+		resp, err := svc.Patch(context.Background(), 1, []byte(`{"title":"Engineer"}`), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, &unitId, resp.OrgUnitId)
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+}
+
+func TestService_FindByOrgUnit(t *testing.T) {
+	unitId := int64(4)
+
+	t.Run("unit exists", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := newTestService(repo)
+		repo.On("OrgUnitExists", mock.Anything, unitId).Return(true, nil)
+		repo.On("FindByOrgUnit", mock.Anything, unitId, true).
+			Return([]Entity{{Id: 1, Name: "Ann", OrgUnitId: &unitId}}, nil)
+
+		got, err := svc.FindByOrgUnit(context.Background(), unitId, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []Response{{Id: 1, Name: "Ann", OrgUnitId: &unitId}}, got)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("empty unit", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := newTestService(repo)
+		repo.On("OrgUnitExists", mock.Anything, unitId).Return(true, nil)
+		repo.On("FindByOrgUnit", mock.Anything, unitId, false).Return([]Entity(nil), nil)
+
+		got, err := svc.FindByOrgUnit(context.Background(), unitId, false)
+		assert.NoError(t, err)
+		// пустой список, а не nil: в ответе должен быть [], а не null
+		assert.NotNil(t, got)
+		assert.Empty(t, got)
+	})
+
+	t.Run("unknown unit", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := newTestService(repo)
+		repo.On("OrgUnitExists", mock.Anything, int64(99)).Return(false, nil)
+
+		_, err := svc.FindByOrgUnit(context.Background(), 99, false)
+		var notFound common.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		repo.AssertNotCalled(t, "FindByOrgUnit", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_FindByRoleId(t *testing.T) {
+	t.Run("role exists", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := newTestService(repo)
+		repo.On("RoleExists", mock.Anything, int64(2)).Return(true, nil)
+		repo.On("FindByRoleId", mock.Anything, int64(2)).Return([]Entity{{Id: 1, Name: "Ann"}}, nil)
+
+		got, err := svc.FindByRoleId(context.Background(), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []Response{{Id: 1, Name: "Ann"}}, got)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := newTestService(repo)
+		repo.On("RoleExists", mock.Anything, int64(99)).Return(false, nil)
+
+		_, err := svc.FindByRoleId(context.Background(), 99)
+		var notFound common.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		repo.AssertNotCalled(t, "FindByRoleId", mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_Manager(t *testing.T) {
+	version := time.Date(2025, 6, 24, 12, 0, 0, 0, time.UTC)
+	lockQuery := regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")
+	existsQuery := regexp.QuoteMeta("select exists(select 1 from employee where id = $1 and deleted_at is null)")
+	chainQuery := regexp.QuoteMeta("WITH RECURSIVE chain (id, level) AS ( SELECT $1::bigint, 0")
+	advisoryLockQuery := regexp.QuoteMeta("SELECT pg_advisory_xact_lock(hashtext('employee.manager_id'))")
+	managerId := int64(3)
+
+	t.Run("report cannot become manager", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at"}).AddRow(1, "Ann", version, version))
+		m.ExpectQuery(existsQuery).WithArgs(managerId).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		m.ExpectExec(advisoryLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		// сотрудник 1 находится в цепочке руководителей сотрудника 3
+		m.ExpectQuery(chainQuery).WithArgs(managerId, int64(1), maxManagementDepth).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		m.ExpectRollback()
+
+		_, err := svc.Patch(context.Background(), 1, []byte(`{"manager_id":3}`), nil)
+		var conflictErr common.ConflictError
+		assert.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, "employee 1 cannot report to employee 3: management chain would form a cycle", conflictErr.Message)
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("manager is assigned", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at"}).AddRow(1, "Ann", version, version))
+		m.ExpectQuery(existsQuery).WithArgs(managerId).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		m.ExpectExec(advisoryLockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		m.ExpectQuery(chainQuery).WithArgs(managerId, int64(1), maxManagementDepth).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		m.ExpectQuery(updateEmployeeQuery).
+			WithArgs("Ann", nil, nil, nil, nil, nil, nil, nil, "{}", nil, managerId, int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at", "manager_id"}).
+				AddRow(1, "Ann", version, version, managerId))
+		m.ExpectCommit()
+
+		resp, err := svc.Patch(context.Background(), 1, []byte(`{"manager_id":3}`), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, &managerId, resp.ManagerId)
+		assert.Equal(t, &managerId, auditor.records[0].After.(auditState).ManagerId)
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("unknown manager on create", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(existsQuery).WithArgs(managerId).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		m.ExpectRollback()
+
+		_, err := svc.Save(context.Background(), CreateRequest{Name: "Ann", Profile: Profile{ManagerId: &managerId}})
+		assert.ErrorAs(t, err, &common.RequestValidationError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+}
+
+func TestService_Hierarchy(t *testing.T) {
+	repo := new(MockRepo)
+	svc := newTestService(repo)
+	ceo := int64(1)
+	alice := Entity{Id: 1, Name: "Alice"}
+	bob := Entity{Id: 2, Name: "Bob", ManagerId: &ceo}
+	repo.On("FindById", mock.Anything, int64(1)).Return(&alice, nil)
+	repo.On("FindById", mock.Anything, int64(2)).Return(&bob, nil)
+	repo.On("FindById", mock.Anything, int64(9)).Return(nil, notFound(9))
+	repo.On("FindManagers", mock.Anything, int64(2)).Return([]Entity{alice}, nil)
+	repo.On("FindReports", mock.Anything, int64(1), 1).Return([]Entity{bob}, nil)
+	repo.On("FindReports", mock.Anything, int64(1), maxManagementDepth).Return([]Entity{bob}, nil)
+
+	managers, err := svc.FindManagers(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []Response{alice.toResponse()}, managers)
+
+	_, err = svc.FindReports(context.Background(), 1, false)
+	assert.NoError(t, err)
+	_, err = svc.FindReports(context.Background(), 1, true)
+	assert.NoError(t, err)
+
+	_, err = svc.FindManagers(context.Background(), 9)
+	assert.ErrorAs(t, err, &common.NotFoundError{})
+
+	// поддерево строится от корня, даже если у корня есть свой руководитель
+	chart, err := svc.OrgChart(context.Background(), OrgChartRequest{Root: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []ChartNode{{Id: 1, Name: "Alice", Reports: []ChartNode{{Id: 2, Name: "Bob"}}}}, chart)
+
+	_, err = svc.OrgChart(context.Background(), OrgChartRequest{Format: "svg"})
+	assert.ErrorAs(t, err, &common.RequestValidationError{})
+	repo.AssertExpectations(t)
+}
+
+func TestService_GetEmployeesPage_AttributeFilter(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewService(repo, &stubAuditor{}, &stubPublisher{}, nopLogger, testAttributeSchema(t))
+	repo.On("FindEmployeesPage", mock.Anything, PageRequest{
+		PageSize:   10,
+		Attributes: Attributes{"clearance_level": 3.0, "remote": true},
+	}).Return([]Entity{{Id: 1, Name: "Ann", Attributes: Attributes{"clearance_level": 3.0, "remote": true}}}, int64(1), nil)
+
+	page, err := svc.GetEmployeesPage(context.Background(), PageRequest{
+		PageSize:   10,
+		Attributes: Attributes{"clearance_level": "3", "remote": "true"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	repo.AssertExpectations(t)
+
+	_, err = svc.GetEmployeesPage(context.Background(), PageRequest{PageSize: 10, Attributes: Attributes{"shoe_size": "42"}})
+	assert.ErrorAs(t, err, &common.RequestValidationError{})
+	repo.AssertNumberOfCalls(t, "FindEmployeesPage", 1)
+}
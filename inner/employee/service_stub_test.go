@@ -1,6 +1,7 @@
 package employee
 
 import (
+	"context"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -10,28 +11,22 @@ import (
 // stub-репозиторий, реализующий только FindAll
 type StubRepo struct{}
 
-func (s *StubRepo) BeginTransaction() (*sqlx.Tx, error) {
+func (s *StubRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
 	panic("implement me")
 }
 
-func (s *StubRepo) FindByNameTx(tx *sqlx.Tx, name string) (bool, error) {
+func (s *StubRepo) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
 	panic("implement me")
 }
 
-func (s *StubRepo) SaveTx(tx *sqlx.Tx, employee *Entity) (int64, error) {
+func (s *StubRepo) SaveTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (int64, error) {
 	panic("implement me")
 }
 
-func (s *StubRepo) FindById(id int64) (*Entity, error) {
+func (s *StubRepo) FindById(ctx context.Context, id int64) (*Entity, error) {
 	panic("not implemented")
 }
-func (s *StubRepo) Add(e *Entity) error {
-	panic("not implemented")
-}
-func (s *StubRepo) Save(e *Entity) (int64, error) {
-	panic("not implemented")
-}
-func (s *StubRepo) FindAll() ([]Entity, error) {
+func (s *StubRepo) FindAll(ctx context.Context) ([]Entity, error) {
 	// жёстко зашитые данные
 	now := time.Date(2025, 6, 24, 12, 0, 0, 0, time.UTC)
 	return []Entity{
@@ -39,21 +34,110 @@ func (s *StubRepo) FindAll() ([]Entity, error) {
 		{Id: 20, Name: "Stub B", CreatedAt: now, UpdatedAt: now},
 	}, nil
 }
-func (s *StubRepo) FindByIds(ids []int64) ([]Entity, error) {
+func (s *StubRepo) FindByIds(ctx context.Context, ids []int64) ([]Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) FindEmployeesPage(ctx context.Context, req PageRequest) ([]Entity, int64, error) {
+	panic("implement me")
+}
+func (s *StubRepo) ExistsByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error) {
+	panic("implement me")
+}
+func (s *StubRepo) FindExistingRoleIdsTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]int64, error) {
+	panic("implement me")
+}
+func (s *StubRepo) RoleExists(ctx context.Context, roleId int64) (bool, error) {
+	panic("implement me")
+}
+func (s *StubRepo) AssignRolesTx(
+	ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64, validFrom, validTo *time.Time,
+) error {
+	panic("implement me")
+}
+func (s *StubRepo) RevokeRolesTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) error {
+	panic("implement me")
+}
+func (s *StubRepo) FindByRoleId(ctx context.Context, roleId int64) ([]Entity, error) {
+	panic("implement me")
+}
+func (s *StubRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	panic("implement me")
+}
+func (s *StubRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (*Entity, error) {
+	panic("implement me")
+}
+func (s *StubRepo) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error) {
+	panic("implement me")
+}
+func (s *StubRepo) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	panic("implement me")
+}
+func (s *StubRepo) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error {
+	panic("implement me")
+}
+func (s *StubRepo) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, before time.Time) ([]Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) UpdateStatusTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) (*Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) AddStatusHistoryTx(ctx context.Context, tx *sqlx.Tx, history *StatusHistoryEntity) error {
+	panic("not implemented")
+}
+func (s *StubRepo) FindStatusHistory(ctx context.Context, employeeId int64) ([]StatusHistoryEntity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) RevokeAllRolesTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) ([]GrantEntity, error) {
+	panic("not implemented")
+}
+
+func (s *StubRepo) FindTakenUniqueFieldsTx(ctx context.Context, tx *sqlx.Tx, employee *Entity) ([]string, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) OrgUnitExistsTx(ctx context.Context, tx *sqlx.Tx, orgUnitId int64) (bool, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) OrgUnitExists(ctx context.Context, orgUnitId int64) (bool, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) FindByOrgUnit(ctx context.Context, orgUnitId int64, subtree bool) ([]Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) FindManagers(ctx context.Context, id int64) ([]Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) FindReports(ctx context.Context, id int64, depth int) ([]Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) FindOrgChartMembers(ctx context.Context) ([]Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) IsInManagementChainTx(ctx context.Context, tx *sqlx.Tx, employeeId, managerId int64) (bool, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) LockManagementTx(ctx context.Context, tx *sqlx.Tx) error {
+	panic("not implemented")
+}
+func (s *StubRepo) FindGrants(ctx context.Context, employeeId int64) ([]GrantEntity, error) {
 	panic("not implemented")
 }
-func (s *StubRepo) DeleteById(id int64) error {
+func (s *StubRepo) TryLockGrantExpiryTx(ctx context.Context, tx *sqlx.Tx) (bool, error) {
 	panic("not implemented")
 }
-func (s *StubRepo) DeleteByIds(ids []int64) error {
+func (s *StubRepo) DeleteExpiredGrantsTx(ctx context.Context, tx *sqlx.Tx, now time.Time) ([]GrantEntity, error) {
 	panic("not implemented")
 }
-func (s *StubRepo) FindEmployeesPage(req PageRequest) ([]Entity, int64, error) { panic("implement me") }
 
 func TestFindAll_WithStub(t *testing.T) {
-	svc := NewService(&StubRepo{})
+	svc := NewService(&StubRepo{}, nil, &stubPublisher{}, nopLogger, AttributeSchema{})
 
-	resps, err := svc.FindAll()
+	resps, err := svc.FindAll(context.Background())
 	assert.NoError(t, err)
 	// Должны получить ровно две записи из stub
 	assert.Len(t, resps, 2)
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"idm/inner/common"
+	"idm/inner/metrics"
+
+	"go.uber.org/zap"
+)
+
+// Event - доменное событие, о котором сервис сообщает внешним потребителям
+type Event struct {
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	EntityType string    `json:"entity_type"`
+	EntityId   int64     `json:"entity_id"`
+	Data       any       `json:"data,omitempty"`
+}
+
+// Publisher доставляет события потребителям. Событие публикуется после фиксации изменения,
+// поэтому ошибка публикации не отменяет само изменение
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// LogPublisher публикует события в журнал приложения, откуда их забирает сборщик логов
+type LogPublisher struct {
+	logger *common.Logger
+}
+
+func NewLogPublisher(logger *common.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, event Event) error {
+	p.logger.Ctx(ctx).Info("event",
+		zap.String("event_type", event.Type),
+		zap.Time("occurred_at", event.OccurredAt),
+		zap.String("entity_type", event.EntityType),
+		zap.Int64("entity_id", event.EntityId),
+		zap.Any("data", event.Data))
+	metrics.EventsPublished.WithLabelValues(event.Type).Inc()
+	return nil
+}
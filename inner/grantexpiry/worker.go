@@ -0,0 +1,69 @@
+package grantexpiry
+
+import (
+	"context"
+	"time"
+
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/events"
+
+	"go.uber.org/zap"
+)
+
+// EventGrantExpired - тип события об отзыве назначения роли по истечении срока действия
+const EventGrantExpired = "employee.role_grant_expired"
+
+// Target - сервис, отзывающий назначения ролей, срок действия которых истёк к моменту now.
+// При нескольких экземплярах сервиса отзыв выполняет только один из них, остальные получают пустой список
+type Target interface {
+	ExpireGrants(ctx context.Context, now time.Time) ([]employee.GrantResponse, error)
+}
+
+// Worker периодически отзывает истёкшие назначения ролей и публикует событие о каждом отзыве
+type Worker struct {
+	logger    *common.Logger
+	target    Target
+	publisher events.Publisher
+	now       func() time.Time
+
+	*common.Periodic
+}
+
+func NewWorker(logger *common.Logger, interval time.Duration, target Target, publisher events.Publisher) *Worker {
+	w := &Worker{
+		logger:    logger,
+		target:    target,
+		publisher: publisher,
+		now:       time.Now,
+	}
+	w.Periodic = common.NewPeriodic(interval, w.RunOnce)
+	return w
+}
+
+// RunOnce отзывает назначения, истёкшие к текущему моменту. Событие публикуется после фиксации отзыва;
+// ошибка публикации одного события не мешает публикации остальных
+func (w *Worker) RunOnce(ctx context.Context) {
+	now := w.now()
+	expired, err := w.target.ExpireGrants(ctx, now)
+	if err != nil {
+		w.logger.Error("expire role grants", zap.Error(err))
+		return
+	}
+	for i := range expired {
+		event := events.Event{
+			Type:       EventGrantExpired,
+			OccurredAt: now,
+			EntityType: "employee",
+			EntityId:   expired[i].EmployeeId,
+			Data:       expired[i],
+		}
+		if err := w.publisher.Publish(ctx, event); err != nil {
+			w.logger.Error("publish role grant expiry", zap.Int64("employee_id", expired[i].EmployeeId),
+				zap.Int64("role_id", expired[i].RoleId), zap.Error(err))
+		}
+	}
+	if len(expired) > 0 {
+		w.logger.Info("expired role grants", zap.Int("count", len(expired)), zap.Time("expired_by", now))
+	}
+}
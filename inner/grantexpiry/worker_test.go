@@ -0,0 +1,84 @@
+package grantexpiry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"idm/inner/employee"
+	"idm/inner/events"
+	"idm/inner/logtest"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// stubTarget запоминает моменты, к которым его просили отозвать истёкшие назначения
+type stubTarget struct {
+	mu      sync.Mutex
+	nows    []time.Time
+	expired []employee.GrantResponse
+	err     error
+}
+
+func (s *stubTarget) ExpireGrants(ctx context.Context, now time.Time) ([]employee.GrantResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nows = append(s.nows, now)
+	return s.expired, s.err
+}
+
+// stubPublisher запоминает опубликованные события; публикация события с EntityId == failFor завершается ошибкой
+type stubPublisher struct {
+	published []events.Event
+	failFor   int64
+}
+
+func (p *stubPublisher) Publish(ctx context.Context, event events.Event) error {
+	if event.EntityId == p.failFor {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+func newTestWorker(target Target, publisher events.Publisher) (*Worker, *observer.ObservedLogs) {
+	logger, logs := logtest.New()
+	return NewWorker(logger, time.Minute, target, publisher), logs
+}
+
+func TestWorker_RunOnce(t *testing.T) {
+	now := time.Date(2025, 12, 20, 12, 0, 0, 0, time.UTC)
+	expired := []employee.GrantResponse{{EmployeeId: 4, RoleId: 1}, {EmployeeId: 7, RoleId: 2}, {EmployeeId: 7, RoleId: 3}}
+
+	t.Run("publishes an event per revoked grant", func(t *testing.T) {
+		target := &stubTarget{expired: expired}
+		publisher := &stubPublisher{failFor: 4}
+		worker, logs := newTestWorker(target, publisher)
+		worker.now = func() time.Time { return now }
+
+		worker.RunOnce(context.Background())
+
+		assert.Equal(t, []time.Time{now}, target.nows)
+		// ошибка публикации одного события не мешает публикации остальных
+		assert.Equal(t, []events.Event{
+			{Type: EventGrantExpired, OccurredAt: now, EntityType: "employee", EntityId: 7, Data: expired[1]},
+			{Type: EventGrantExpired, OccurredAt: now, EntityType: "employee", EntityId: 7, Data: expired[2]},
+		}, publisher.published)
+		assert.Equal(t, 1, logs.FilterMessage("publish role grant expiry").Len())
+		assert.Equal(t, 1, logs.FilterMessage("expired role grants").FilterField(zap.Int("count", 3)).Len())
+	})
+
+	t.Run("target error is logged", func(t *testing.T) {
+		publisher := &stubPublisher{}
+		worker, logs := newTestWorker(&stubTarget{err: errors.New("db down")}, publisher)
+
+		worker.RunOnce(context.Background())
+
+		assert.Empty(t, publisher.published)
+		assert.Equal(t, 1, logs.FilterMessage("expire role grants").Len())
+	})
+}
@@ -1,20 +1,24 @@
 package info
 
 import (
-	"github.com/gofiber/fiber/v2"
 	"idm/inner/common"
 	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
 )
 
 type Controller struct {
 	server *web.Server
 	cfg    common.Config
+	// проверки зависимостей для readyz: имя компонента -> проверка
+	checks map[string]Check
 }
 
-func NewController(server *web.Server, cfg common.Config) *Controller {
+func NewController(server *web.Server, cfg common.Config, checks map[string]Check) *Controller {
 	return &Controller{
 		server: server,
 		cfg:    cfg,
+		checks: checks,
 	}
 }
 
@@ -23,9 +27,13 @@ type InfoResponse struct {
 	Version string `json:"version"`
 }
 
+// RegisterRoutes регистрирует маршруты без аутентификации: info и health - на служебном порту,
+// пробы - на отдельном порту проб
 func (c *Controller) RegisterRoutes() {
-	c.server.GroupInternal.Get("/info", c.GetInfo)
-	c.server.GroupInternal.Get("/health", c.GetHealth)
+	c.server.Management.Get("/info", c.GetInfo)
+	c.server.Management.Get("/health", c.GetHealth)
+	c.server.Probes.Get("/livez", c.GetLivez)
+	c.server.Probes.Get("/readyz", c.GetReadyz)
 }
 
 func (c *Controller) GetInfo(ctx *fiber.Ctx) error {
@@ -42,3 +50,19 @@ func (c *Controller) GetInfo(ctx *fiber.Ctx) error {
 func (c *Controller) GetHealth(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).SendString("OK")
 }
+
+// GetLivez отвечает, что процесс жив; зависимости не проверяются,
+// чтобы их недоступность не приводила к перезапуску сервиса
+func (c *Controller) GetLivez(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusOK).JSON(&ReadinessResponse{Status: StatusOk})
+}
+
+// GetReadyz проверяет зависимости и возвращает отчёт по каждой; 503 - если хотя бы одна недоступна
+func (c *Controller) GetReadyz(ctx *fiber.Ctx) error {
+	report := runChecks(ctx.UserContext(), c.checks)
+	status := fiber.StatusOK
+	if report.Status != StatusOk {
+		status = fiber.StatusServiceUnavailable
+	}
+	return ctx.Status(status).JSON(&report)
+}
@@ -0,0 +1,154 @@
+package info
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer создаёт сервер, где всё под /api закрыто аутентификацией
+func newTestServer(checks map[string]Check) *web.Server {
+	server := web.NewServer()
+	server.GroupApi.Use(func(c *fiber.Ctx) error {
+		return common.ErrResponse(c, fiber.StatusUnauthorized, "unauthorized")
+	})
+	NewController(server, common.Config{}, checks).RegisterRoutes()
+	return server
+}
+
+func TestGetLivez(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer(nil)
+
+	resp, err := server.Probes.Test(httptest.NewRequest(fiber.MethodGet, "/livez", nil), -1)
+	a.NoError(err)
+	a.Equal(fiber.StatusOK, resp.StatusCode)
+}
+
+func TestGetReadyz(t *testing.T) {
+	a := assert.New(t)
+
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"keys":[]}`))
+	}))
+	defer jwks.Close()
+	brokenJwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer brokenJwks.Close()
+
+	tests := []struct {
+		name         string
+		dbPingErr    error
+		jwksUrl      string
+		shuttingDown bool
+		wantStatus   int
+		wantFailed   []string
+	}{
+		{
+			name:       "all dependencies are ready",
+			jwksUrl:    jwks.URL,
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "database is unavailable",
+			dbPingErr:  errors.New("connection refused"),
+			jwksUrl:    jwks.URL,
+			wantStatus: fiber.StatusServiceUnavailable,
+			wantFailed: []string{"database"},
+		},
+		{
+			name:       "jwks is unavailable",
+			jwksUrl:    brokenJwks.URL,
+			wantStatus: fiber.StatusServiceUnavailable,
+			wantFailed: []string{"jwks"},
+		},
+		{
+			name:         "shutdown in progress",
+			jwksUrl:      jwks.URL,
+			shuttingDown: true,
+			wantStatus:   fiber.StatusServiceUnavailable,
+			wantFailed:   []string{"shutdown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+			require.NoError(t, err)
+			defer db.Close()
+			mock.ExpectPing().WillReturnError(tt.dbPingErr)
+
+			server := web.NewServer()
+			checks := map[string]Check{
+				"database": DbCheck(sqlx.NewDb(db, "sqlmock")),
+				"jwks":     JwksCheck(http.DefaultClient, []string{tt.jwksUrl}),
+				"shutdown": ShutdownCheck(server),
+			}
+			server.GroupApi.Use(func(c *fiber.Ctx) error {
+				return common.ErrResponse(c, fiber.StatusUnauthorized, "unauthorized")
+			})
+			NewController(server, common.Config{}, checks).RegisterRoutes()
+			if tt.shuttingDown {
+				server.BeginShutdown()
+			}
+
+			resp, err := server.Probes.Test(httptest.NewRequest(fiber.MethodGet, "/readyz", nil), -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+
+			var report ReadinessResponse
+			a.NoError(json.NewDecoder(resp.Body).Decode(&report))
+			a.Len(report.Components, 3)
+			for name, component := range report.Components {
+				if slices.Contains(tt.wantFailed, name) {
+					a.Equal(StatusFail, component.Status, name)
+					a.NotEmpty(component.Error, name)
+				} else {
+					a.Equal(StatusOk, component.Status, name)
+				}
+			}
+			a.NoError(mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestManagementRoutesAreNotPublic(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer(nil)
+
+	routes := map[*fiber.App][]string{
+		server.Management: {"/info", "/health"},
+		server.Probes:     {"/livez", "/readyz"},
+	}
+	for app, paths := range routes {
+		for _, path := range paths {
+			resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, path, nil), -1)
+			a.NoError(err)
+			a.Equal(fiber.StatusNotFound, resp.StatusCode, path)
+
+			resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, path, nil), -1)
+			a.NoError(err)
+			a.Equal(fiber.StatusOK, resp.StatusCode, path)
+		}
+	}
+
+	// порт проб доступен снаружи узла, поэтому служебных маршрутов на нём нет
+	for _, path := range []string{"/info", "/health"} {
+		resp, err := server.Probes.Test(httptest.NewRequest(fiber.MethodGet, path, nil), -1)
+		a.NoError(err)
+		a.Equal(fiber.StatusNotFound, resp.StatusCode, path)
+	}
+}
@@ -0,0 +1,112 @@
+package info
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"idm/inner/web"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+)
+
+// ReadinessTimeout ограничивает время проверки каждой зависимости
+const ReadinessTimeout = 2 * time.Second
+
+// Check проверяет доступность одной зависимости сервиса
+type Check func(ctx context.Context) error
+
+// ComponentStatus - результат проверки одной зависимости
+type ComponentStatus struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReadinessResponse - отчёт о готовности сервиса принимать запросы
+type ReadinessResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// DbCheck проверяет соединение с БД
+func DbCheck(db *sqlx.DB) Check {
+	return db.PingContext
+}
+
+// JwksCheck проверяет, что все адреса JWKS отвечают 200 OK
+func JwksCheck(client *http.Client, urls []string) Check {
+	return func(ctx context.Context) error {
+		if len(urls) == 0 {
+			return errors.New("JWKS url is not configured")
+		}
+		for _, url := range urls {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("JWKS %s responded with status %d", url, resp.StatusCode)
+			}
+		}
+		return nil
+	}
+}
+
+// ShutdownCheck сообщает об ошибке, если началась остановка сервера
+func ShutdownCheck(server *web.Server) Check {
+	return func(ctx context.Context) error {
+		if server.ShuttingDown() {
+			return errors.New("shutdown in progress")
+		}
+		return nil
+	}
+}
+
+// runChecks параллельно выполняет проверки и собирает отчёт
+func runChecks(ctx context.Context, checks map[string]Check) ReadinessResponse {
+	report := ReadinessResponse{Status: StatusOk, Components: make(map[string]ComponentStatus, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := runCheck(ctx, check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[name] = status
+			if status.Status != StatusOk {
+				report.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func runCheck(ctx context.Context, check Check) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, ReadinessTimeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	status := ComponentStatus{Status: StatusOk, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		status.Status = StatusFail
+		status.Error = err.Error()
+	}
+	return status
+}
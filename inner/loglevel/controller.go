@@ -0,0 +1,104 @@
+package loglevel
+
+import (
+	"time"
+
+	"idm/inner/common"
+	"idm/inner/validator"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// MaxTtl - максимальное время, на которое можно временно изменить уровень логирования
+const MaxTtl = 24 * time.Hour
+
+type Controller struct {
+	server    *web.Server
+	logger    *common.Logger
+	validator *validator.Validator
+}
+
+// SetRequest - запрос на изменение уровня логирования.
+// Ttl - необязательная длительность в формате time.ParseDuration ("15m"), по истечении которой
+// уровень вернётся к прежнему
+type SetRequest struct {
+	Level string `json:"level" validate:"required,oneof=debug info warn error"`
+	Ttl   string `json:"ttl"`
+}
+
+func NewController(server *web.Server, logger *common.Logger) *Controller {
+	return &Controller{
+		server:    server,
+		logger:    logger,
+		validator: validator.New(),
+	}
+}
+
+// RegisterRoutes регистрирует маршруты; права доступа к ним задаются политикой (policies.yaml)
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupInternal.Get("/log-level", c.GetLogLevel)
+	c.server.GroupInternal.Put("/log-level", c.SetLogLevel)
+}
+
+// GetLogLevel возвращает текущий уровень логирования
+func (c *Controller) GetLogLevel(ctx *fiber.Ctx) error {
+	return common.OkResponse(ctx, c.logger.Level().State())
+}
+
+// SetLogLevel меняет уровень логирования без перезапуска сервиса
+func (c *Controller) SetLogLevel(ctx *fiber.Ctx) error {
+	var request SetRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("set log level", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	if err := c.validator.Validate(request); err != nil {
+		return common.ServiceErrResponse(ctx, err)
+	}
+	ttl, err := parseTtl(request.Ttl)
+	if err != nil {
+		return common.ServiceErrResponse(ctx, err)
+	}
+	level, err := zapcore.ParseLevel(request.Level)
+	if err != nil {
+		return common.ServiceErrResponse(ctx, err)
+	}
+
+	previous := c.logger.Level().State().Level
+	state := c.logger.Level().Set(level, ttl)
+	// пишем на уровне warn, чтобы изменение попало в лог при любом уровне
+	c.logger.Ctx(ctx.UserContext()).Warn("log level changed",
+		zap.String("from", previous),
+		zap.String("to", state.Level),
+		zap.Duration("ttl", ttl),
+	)
+	return common.OkResponse(ctx, state)
+}
+
+// parseTtl разбирает ttl запроса; пустая строка означает постоянное изменение
+func parseTtl(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 || ttl > MaxTtl {
+		message := "ttl must be a positive duration not greater than " + MaxTtl.String()
+		return 0, common.RequestValidationError{
+			Message: message,
+			Fields: []common.FieldError{{
+				Field:   "ttl",
+				Tag:     "duration",
+				Message: message,
+				Messages: map[string]string{
+					common.LanguageEn: message,
+					common.LanguageRu: "ttl должен быть положительной длительностью не больше " + MaxTtl.String(),
+				},
+			}},
+			Err: err,
+		}
+	}
+	return ttl, nil
+}
@@ -0,0 +1,85 @@
+package loglevel
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"idm/inner/common"
+	"idm/inner/policy"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestServer(t *testing.T, roles ...string) (*web.Server, *common.Logger) {
+	routePolicy, err := policy.Load("../../policies.yaml")
+	require.NoError(t, err)
+	enforcer, err := policy.NewEnforcer(routePolicy, "idm")
+	require.NoError(t, err)
+
+	var claims = &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: roles}}
+	var auth = func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+
+	server := web.NewServer()
+	server.GroupApi.Use(auth, enforcer.Middleware())
+	logger := common.NewLogger(common.Config{LogLevel: "info"})
+	NewController(server, logger).RegisterRoutes()
+	return server, logger
+}
+
+func TestSetLogLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		roles      []string
+		body       string
+		wantStatus int
+		wantLevel  string
+	}{
+		{"admin sets level", []string{web.IdmAdmin}, `{"level":"debug"}`, fiber.StatusOK, "debug"},
+		{"admin sets level with ttl", []string{web.IdmAdmin}, `{"level":"debug","ttl":"10m"}`, fiber.StatusOK, "debug"},
+		{"unknown level", []string{web.IdmAdmin}, `{"level":"trace"}`, fiber.StatusBadRequest, "info"},
+		{"invalid ttl", []string{web.IdmAdmin}, `{"level":"debug","ttl":"soon"}`, fiber.StatusBadRequest, "info"},
+		{"ttl too long", []string{web.IdmAdmin}, `{"level":"debug","ttl":"48h"}`, fiber.StatusBadRequest, "info"},
+		{"user is forbidden", []string{web.IdmUser}, `{"level":"debug"}`, fiber.StatusForbidden, "info"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+			server, logger := newTestServer(t, tt.roles...)
+
+			req := httptest.NewRequest(fiber.MethodPut, "/api/internal/log-level", strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := server.App.Test(req)
+			a.Nil(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+			a.Equal(tt.wantLevel, logger.Level().State().Level)
+		})
+	}
+}
+
+func TestGetLogLevel(t *testing.T) {
+	a := assert.New(t)
+	server, logger := newTestServer(t, web.IdmAdmin)
+	logger.Level().Set(zapcore.DebugLevel, 0)
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/internal/log-level", nil))
+	a.Nil(err)
+	a.Equal(fiber.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	a.Nil(err)
+	var response common.Response[common.LogLevelState]
+	a.Nil(json.Unmarshal(body, &response))
+	a.True(response.Success)
+	a.Equal("debug", response.Data.Level)
+}
@@ -0,0 +1,16 @@
+// Package logtest создаёт логгеры для проверки записей в тестах
+package logtest
+
+import (
+	"idm/inner/common"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// New возвращает логгер, записи которого можно проверить в тесте
+func New() (*common.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	return &common.Logger{Logger: zap.New(core)}, logs
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace - префикс имён всех метрик сервиса
+const Namespace = "idm"
+
+// Registry - реестр метрик сервиса, отдаётся на /metrics служебного порта
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+// бизнес-метрики
+var (
+	EmployeesCreated = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "employees_created_total",
+		Help:      "Number of created employees.",
+	})
+	EmployeesDeleted = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "employees_deleted_total",
+		Help:      "Number of employees requested for deletion and deleted without error.",
+	})
+	RolesCreated = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "roles_created_total",
+		Help:      "Number of created roles.",
+	})
+	RolesDeleted = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "roles_deleted_total",
+		Help:      "Number of roles requested for deletion and deleted without error.",
+	})
+	// RoleAssignments считает назначенные и отозванные роли сотрудников, operation: assign, revoke
+	// или expire (отзыв по истечении срока действия)
+	RoleAssignments = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "role_assignments_total",
+		Help:      "Number of employee role assignment changes.",
+	}, []string{"operation"})
+	// EmployeeTransitions считает смены статуса жизненного цикла сотрудников
+	EmployeeTransitions = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "employee_transitions_total",
+		Help:      "Number of employee lifecycle status transitions.",
+	}, []string{"from", "to"})
+	// RecordsPurged считает мягко удалённые записи, окончательно удалённые фоновой очисткой, entity: employee или role
+	RecordsPurged = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "records_purged_total",
+		Help:      "Number of soft-deleted records removed after the retention period.",
+	}, []string{"entity"})
+	// EventsPublished считает опубликованные доменные события по типу
+	EventsPublished = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "events_published_total",
+		Help:      "Number of domain events published.",
+	}, []string{"type"})
+	// AccessRequests считает заявки на доступ по статусу, в который они перешли: pending (создана),
+	// approved, rejected, cancelled или expired
+	AccessRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "access_requests_total",
+		Help:      "Number of access request status changes.",
+	}, []string{"status"})
+	// AccessReviewDecisions считает решения по назначениям в кампаниях пересмотра доступа: keep, revoke или delegate
+	AccessReviewDecisions = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "access_review_decisions_total",
+		Help:      "Number of access review decisions.",
+	}, []string{"decision"})
+)
+
+// AuthFailures считает отклонённые токены по причине отказа
+var AuthFailures = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: Namespace,
+	Name:      "auth_failures_total",
+	Help:      "Number of rejected JWT authentication attempts.",
+}, []string{"reason"})
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDbStats добавляет метрики пула соединений БД (sql.DBStats)
+func RegisterDbStats(db *sql.DB, dbName string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry}))
+}
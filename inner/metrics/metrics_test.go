@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	a := assert.New(t)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/api/v1/employees/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "0" {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid id")
+		}
+		if c.Params("id") == "500" {
+			return errors.New("fail")
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	ok := httpRequests.WithLabelValues(fiber.MethodGet, "/api/v1/employees/:id", "200")
+	badRequest := httpRequests.WithLabelValues(fiber.MethodGet, "/api/v1/employees/:id", "400")
+	internal := httpRequests.WithLabelValues(fiber.MethodGet, "/api/v1/employees/:id", "500")
+	okBefore, badBefore, internalBefore := testutil.ToFloat64(ok), testutil.ToFloat64(badRequest), testutil.ToFloat64(internal)
+
+	for _, path := range []string{"/api/v1/employees/1", "/api/v1/employees/2", "/api/v1/employees/0", "/api/v1/employees/500"} {
+		_, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil), -1)
+		a.NoError(err)
+	}
+
+	// метка route - шаблон маршрута, поэтому оба запроса с разными id попадают в один ряд
+	a.Equal(okBefore+2, testutil.ToFloat64(ok))
+	a.Equal(badBefore+1, testutil.ToFloat64(badRequest))
+	a.Equal(internalBefore+1, testutil.ToFloat64(internal))
+}
+
+func TestHandler(t *testing.T) {
+	a := assert.New(t)
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	RegisterDbStats(db, "test")
+	defer Registry.Unregister(collectors.NewDBStatsCollector(db, "test"))
+
+	EmployeesCreated.Inc()
+
+	app := fiber.New()
+	app.Get("/metrics", Handler())
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/metrics", nil), -1)
+	a.NoError(err)
+	a.Equal(fiber.StatusOK, resp.StatusCode)
+
+	body, _ := io.ReadAll(resp.Body)
+	a.Contains(string(body), "idm_employees_created_total")
+	a.Contains(string(body), `go_sql_open_connections{db_name="test"}`)
+	a.Contains(string(body), "go_goroutines")
+}
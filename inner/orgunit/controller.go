@@ -0,0 +1,253 @@
+package orgunit
+
+import (
+	"context"
+	"strconv"
+
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	server         *web.Server
+	orgUnitService Svc
+	logger         *common.Logger
+}
+
+// Svc описывает набор методов бизнес-логики по работе с подразделениями
+type Svc interface {
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	FindSubtree(ctx context.Context, id int64) ([]Response, error)
+	FindAncestors(ctx context.Context, id int64) ([]Response, error)
+	Create(ctx context.Context, req CreateRequest) (int64, error)
+	Update(ctx context.Context, id int64, req UpdateRequest) (Response, error)
+	Move(ctx context.Context, id int64, req MoveRequest) (Response, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+func NewController(server *web.Server, orgUnitService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:         server,
+		orgUnitService: orgUnitService,
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes регистрирует маршруты; права доступа к ним задаются политикой (policies.yaml)
+func (c *Controller) RegisterRoutes() {
+
+	grp := c.server.GroupApiV1.Group("/org-units")
+
+	grp.Post("/", c.CreateOrgUnit)
+	grp.Put("/:id", c.UpdateOrgUnit)
+	grp.Post("/:id/move", c.MoveOrgUnit)
+	grp.Delete("/:id", c.DeleteOrgUnit)
+
+	grp.Get("/", c.GetAllOrgUnits)
+	grp.Get("/:id", c.GetOrgUnit)
+	grp.Get("/:id/subtree", c.GetSubtree)
+	grp.Get("/:id/ancestors", c.GetAncestors)
+}
+
+// CreateOrgUnit godoc
+// @Summary      Create org unit
+// @Description  Creates a department under the given parent (or a root one) and returns its id
+// @Tags         orgunit
+// @Accept       json
+// @Produce      json
+// @Param        request  body      orgunit.CreateRequest  true  "create org unit request"
+// @Success      200      {object}  common.Response[int64]
+// @Failure      400      {object}  common.Response[any]  "invalid request, parent or manager not found, or name is taken in the parent"
+// @Router       /org-units [post]
+// @Security BearerAuth
+func (c *Controller) CreateOrgUnit(ctx *fiber.Ctx) error {
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("create org unit", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	c.logger.Ctx(ctx.UserContext()).Debug("create org unit: received request", zap.Any("request", request))
+
+	id, err := c.orgUnitService.Create(ctx.UserContext(), request)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("create org unit", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, id)
+}
+
+// GetOrgUnit godoc
+// @Summary      Get org unit by id
+// @Description  Returns org unit by id
+// @Tags         orgunit
+// @Produce      json
+// @Param        id   path      int  true  "org unit id"
+// @Success      200  {object}  common.Response[orgunit.Response]
+// @Failure      404  {object}  common.Response[any]
+// @Router       /org-units/{id} [get]
+// @Security BearerAuth
+func (c *Controller) GetOrgUnit(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resp, err := c.orgUnitService.FindById(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get org unit", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// GetAllOrgUnits godoc
+// @Summary      List org units
+// @Description  Returns all org units ordered so that every parent precedes its descendants
+// @Tags         orgunit
+// @Produce      json
+// @Success      200  {object}  common.Response[[]orgunit.Response]
+// @Router       /org-units [get]
+// @Security BearerAuth
+func (c *Controller) GetAllOrgUnits(ctx *fiber.Ctx) error {
+	resps, err := c.orgUnitService.FindAll(ctx.UserContext())
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get all org units", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// GetSubtree godoc
+// @Summary      Get org unit subtree
+// @Description  Returns the org unit and all its descendants
+// @Tags         orgunit
+// @Produce      json
+// @Param        id   path      int  true  "org unit id"
+// @Success      200  {object}  common.Response[[]orgunit.Response]
+// @Failure      404  {object}  common.Response[any]
+// @Router       /org-units/{id}/subtree [get]
+// @Security BearerAuth
+func (c *Controller) GetSubtree(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resps, err := c.orgUnitService.FindSubtree(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get org unit subtree", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// GetAncestors godoc
+// @Summary      Get org unit ancestors
+// @Description  Returns the chain of ancestors from the root down to the direct parent
+// @Tags         orgunit
+// @Produce      json
+// @Param        id   path      int  true  "org unit id"
+// @Success      200  {object}  common.Response[[]orgunit.Response]
+// @Failure      404  {object}  common.Response[any]
+// @Router       /org-units/{id}/ancestors [get]
+// @Security BearerAuth
+func (c *Controller) GetAncestors(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resps, err := c.orgUnitService.FindAncestors(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get org unit ancestors", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// UpdateOrgUnit godoc
+// @Summary      Update org unit
+// @Description  Renames the org unit and sets its manager; use move to change the parent
+// @Tags         orgunit
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                    true  "org unit id"
+// @Param        request  body      orgunit.UpdateRequest  true  "update org unit request"
+// @Success      200      {object}  common.Response[orgunit.Response]
+// @Failure      400      {object}  common.Response[any]  "invalid request, manager not found, or name is taken in the parent"
+// @Failure      404      {object}  common.Response[any]
+// @Router       /org-units/{id} [put]
+// @Security BearerAuth
+func (c *Controller) UpdateOrgUnit(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("update org unit", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	resp, err := c.orgUnitService.Update(ctx.UserContext(), id, request)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("update org unit", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// MoveOrgUnit godoc
+// @Summary      Move org unit
+// @Description  Moves the org unit with its whole subtree under another parent; without parent_id the unit becomes a root
+// @Tags         orgunit
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                  true  "org unit id"
+// @Param        request  body      orgunit.MoveRequest  true  "move org unit request"
+// @Success      200      {object}  common.Response[orgunit.Response]
+// @Failure      400      {object}  common.Response[any]  "parent not found or name is taken in the new parent"
+// @Failure      404      {object}  common.Response[any]
+// @Failure      409      {object}  common.Response[any]  "new parent is inside the unit's own subtree"
+// @Router       /org-units/{id}/move [post]
+// @Security BearerAuth
+func (c *Controller) MoveOrgUnit(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	var request MoveRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("move org unit", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	resp, err := c.orgUnitService.Move(ctx.UserContext(), id, request)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("move org unit", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// DeleteOrgUnit godoc
+// @Summary      Delete org unit
+// @Description  Deletes an org unit that has no child units and no employees
+// @Tags         orgunit
+// @Produce      json
+// @Param        id   path      int  true  "org unit id"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  common.Response[any]
+// @Failure      409  {object}  common.Response[any]  "org unit has child units or employees"
+// @Router       /org-units/{id} [delete]
+// @Security BearerAuth
+func (c *Controller) DeleteOrgUnit(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	if err := c.orgUnitService.Delete(ctx.UserContext(), id); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Delete org unit", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, fiber.Map{"message": "deleted"})
+}
@@ -0,0 +1,177 @@
+package orgunit
+
+import (
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/policy"
+	"idm/inner/web"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Объявляем структуру мока сервиса orgunit.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindAll(ctx context.Context) ([]Response, error) {
+	args := svc.Called(ctx)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindSubtree(ctx context.Context, id int64) ([]Response, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindAncestors(ctx context.Context, id int64) ([]Response, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) Create(ctx context.Context, req CreateRequest) (int64, error) {
+	args := svc.Called(ctx, req)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (svc *MockService) Update(ctx context.Context, id int64, req UpdateRequest) (Response, error) {
+	args := svc.Called(ctx, id, req)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Move(ctx context.Context, id int64, req MoveRequest) (Response, error) {
+	args := svc.Called(ctx, id, req)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Delete(ctx context.Context, id int64) error {
+	return svc.Called(ctx, id).Error(0)
+}
+
+// newTestServer регистрирует маршруты подразделений за политикой доступа по умолчанию
+func newTestServer(t *testing.T, roles ...string) (*web.Server, *MockService) {
+	routePolicy, err := policy.Load("../../policies.yaml")
+	assert.NoError(t, err)
+	enforcer, err := policy.NewEnforcer(routePolicy, "")
+	assert.NoError(t, err)
+
+	server := web.NewServer()
+	server.GroupApiV1.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: roles}}})
+		return c.Next()
+	}, enforcer.Middleware())
+
+	svc := new(MockService)
+	NewController(server, svc, common.NewLogger(common.GetConfig(".env"))).RegisterRoutes()
+	return server, svc
+}
+
+func TestCreateOrgUnit(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("admin creates unit", func(t *testing.T) {
+		server, svc := newTestServer(t, web.IdmAdmin)
+		svc.On("Create", mock.Anything, CreateRequest{Name: "Sales", ParentId: ptr(1)}).Return(int64(2), nil)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/org-units", strings.NewReader(`{"name":"Sales","parent_id":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req, -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		a.NoError(err)
+		var got common.Response[int64]
+		a.NoError(json.Unmarshal(body, &got))
+		a.Equal(int64(2), got.Data)
+	})
+
+	t.Run("user is forbidden", func(t *testing.T) {
+		server, svc := newTestServer(t, web.IdmUser)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/org-units", strings.NewReader(`{"name":"Sales"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req, -1)
+		a.NoError(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMoveOrgUnit(t *testing.T) {
+	a := assert.New(t)
+	tests := []struct {
+		name       string
+		body       string
+		result     error
+		wantStatus int
+	}{
+		{name: "moved", body: `{"parent_id":3}`, wantStatus: http.StatusOK},
+		{name: "cycle", body: `{"parent_id":3}`, wantStatus: http.StatusConflict,
+			result: common.ConflictError{Message: "org unit 2 cannot be moved under its own subtree"}},
+		{name: "unknown unit", body: `{"parent_id":3}`, wantStatus: http.StatusNotFound, result: notFound(2)},
+		{name: "unknown parent", body: `{"parent_id":3}`, wantStatus: http.StatusBadRequest,
+			result: common.RequestValidationError{Message: "parent org unit 3 not found"}},
+		{name: "malformed body", body: `{"parent_id":"x"}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, svc := newTestServer(t, web.IdmAdmin)
+			svc.On("Move", mock.Anything, int64(2), MoveRequest{ParentId: ptr(3)}).
+				Return(Response{Id: 2, ParentId: ptr(3), Path: "/3/2/", Depth: 1}, tt.result)
+
+			req := httptest.NewRequest(fiber.MethodPost, "/api/v1/org-units/2/move", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := server.App.Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestGetOrgUnitTree(t *testing.T) {
+	a := assert.New(t)
+	server, svc := newTestServer(t, web.IdmUser)
+	units := []Response{{Id: 1, Name: "Company", Path: "/1/"}, {Id: 2, Name: "Sales", ParentId: ptr(1), Path: "/1/2/", Depth: 1}}
+	svc.On("FindSubtree", mock.Anything, int64(1)).Return(units, nil)
+	svc.On("FindAncestors", mock.Anything, int64(2)).Return(units[:1], nil)
+
+	for url, want := range map[string][]Response{
+		"/api/v1/org-units/1/subtree":   units,
+		"/api/v1/org-units/2/ancestors": units[:1],
+	} {
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, url, nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		a.NoError(err)
+		var got common.Response[[]Response]
+		a.NoError(json.Unmarshal(body, &got))
+		a.Equal(want, got.Data)
+	}
+}
+
+func TestDeleteOrgUnit(t *testing.T) {
+	a := assert.New(t)
+	server, svc := newTestServer(t, web.IdmAdmin)
+	svc.On("Delete", mock.Anything, int64(2)).Return(common.ConflictError{Message: "org unit 2 has 1 child units and 0 employees"})
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/org-units/2", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusConflict, resp.StatusCode)
+}
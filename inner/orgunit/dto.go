@@ -0,0 +1,90 @@
+package orgunit
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entity - подразделение в дереве организационной структуры
+type Entity struct {
+	Id       int64  `db:"id"`
+	Name     string `db:"name"`
+	ParentId *int64 `db:"parent_id"`
+	// Path - материализованный путь из id от корня до самого подразделения: /1/4/9/
+	Path      string    `db:"path"`
+	ManagerId *int64    `db:"manager_id"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:        e.Id,
+		Name:      e.Name,
+		ParentId:  e.ParentId,
+		Path:      e.Path,
+		Depth:     depth(e.Path),
+		ManagerId: e.ManagerId,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+type Response struct {
+	Id       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentId *int64 `json:"parent_id,omitempty"`
+	Path     string `json:"path"`
+	// Depth - уровень вложенности, у корневых подразделений 0
+	Depth     int       `json:"depth"`
+	ManagerId *int64    `json:"manager_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=155"`
+	// ParentId - родительское подразделение; без него подразделение создаётся корневым
+	ParentId  *int64 `json:"parent_id,omitempty" validate:"omitempty,gt=0"`
+	ManagerId *int64 `json:"manager_id,omitempty" validate:"omitempty,gt=0"`
+}
+
+type UpdateRequest struct {
+	Name      string `json:"name" validate:"required,min=2,max=155"`
+	ManagerId *int64 `json:"manager_id,omitempty" validate:"omitempty,gt=0"`
+}
+
+// MoveRequest - перенос подразделения вместе с поддеревом; без ParentId подразделение становится корневым
+type MoveRequest struct {
+	ParentId *int64 `json:"parent_id,omitempty" validate:"omitempty,gt=0"`
+}
+
+// rootPath - путь родителя для корневых подразделений
+const rootPath = "/"
+
+// childPath возвращает путь подразделения id внутри родителя с путём parentPath
+func childPath(parentPath string, id int64) string {
+	return parentPath + strconv.FormatInt(id, 10) + "/"
+}
+
+// depth возвращает уровень вложенности подразделения по его пути
+func depth(path string) int {
+	return max(strings.Count(path, "/")-2, 0)
+}
+
+// auditEntityType - тип сущности подразделения в журнале аудита
+const auditEntityType = "org_unit"
+
+// auditState - состояние подразделения, которое сохраняется в журнале аудита
+type auditState struct {
+	Id        int64  `json:"id"`
+	Name      string `json:"name"`
+	ParentId  *int64 `json:"parent_id,omitempty"`
+	Path      string `json:"path"`
+	ManagerId *int64 `json:"manager_id,omitempty"`
+}
+
+func (e *Entity) toAuditState() auditState {
+	return auditState{Id: e.Id, Name: e.Name, ParentId: e.ParentId, Path: e.Path, ManagerId: e.ManagerId}
+}
@@ -0,0 +1,203 @@
+package orgunit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/tracing"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.FindById")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = r.db.GetContext(ctx, &entity, "SELECT * FROM org_unit WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(id)
+	}
+	return &entity, err
+}
+
+// FindAll возвращает все подразделения в порядке обхода дерева
+func (r *Repository) FindAll(ctx context.Context) (units []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.FindAll")
+	defer func() { span.Finish(int64(len(units)), err) }()
+	err = r.db.SelectContext(ctx, &units, "SELECT * FROM org_unit ORDER BY path")
+	return units, err
+}
+
+// FindSubtree возвращает подразделение с путём path и всех его потомков в порядке обхода дерева
+func (r *Repository) FindSubtree(ctx context.Context, path string) (units []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.FindSubtree")
+	defer func() { span.Finish(int64(len(units)), err) }()
+	err = r.db.SelectContext(ctx, &units, "SELECT * FROM org_unit WHERE path LIKE $1 || '%' ORDER BY path", path)
+	return units, err
+}
+
+// FindAncestors возвращает предков подразделения с путём path от корня к непосредственному родителю
+func (r *Repository) FindAncestors(ctx context.Context, path string) (units []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.FindAncestors")
+	defer func() { span.Finish(int64(len(units)), err) }()
+	err = r.db.SelectContext(ctx, &units,
+		"SELECT * FROM org_unit WHERE $1 LIKE path || '%' AND path <> $1 ORDER BY length(path)", path)
+	return units, err
+}
+
+func (r *Repository) BeginTransaction(ctx context.Context) (tx *sqlx.Tx, err error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// FindByIdForUpdateTx читает подразделение и блокирует строку до конца транзакции
+func (r *Repository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.FindByIdForUpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity, "SELECT * FROM org_unit WHERE id = $1 FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(id)
+	}
+	return &entity, err
+}
+
+// FindByIdsForUpdateTx читает подразделения по id и блокирует их строки в порядке id, чтобы избежать взаимных блокировок
+func (r *Repository) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) (units []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.FindByIdsForUpdateTx")
+	defer func() { span.Finish(int64(len(units)), err) }()
+	query, args, err := sqlx.In("SELECT * FROM org_unit WHERE id IN (?) ORDER BY id FOR UPDATE", ids)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.SelectContext(ctx, &units, tx.Rebind(query), args...)
+	return units, err
+}
+
+// ExistsByNameTx проверяет, есть ли у родителя parentId другое подразделение с таким же именем
+func (r *Repository) ExistsByNameTx(ctx context.Context, tx *sqlx.Tx, parentId *int64, name string, excludeId int64) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.ExistsByNameTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &isExists,
+		`select exists(select 1 from org_unit
+		where coalesce(parent_id, 0) = coalesce($1, 0) and lower(name) = lower($2) and id <> $3)`,
+		parentId, name, excludeId)
+	return isExists, err
+}
+
+// EmployeeExistsTx проверяет, что сотрудник, назначаемый руководителем, существует и не удалён
+func (r *Repository) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.EmployeeExistsTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &isExists,
+		"select exists(select 1 from employee where id = $1 and deleted_at is null)", id)
+	return isExists, err
+}
+
+// CreateTx создаёт подразделение внутри родителя с путём parentPath.
+// Путь содержит id нового подразделения, поэтому заполняется вторым запросом
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, unit *Entity, parentPath string) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.CreateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var id int64
+	err = tx.GetContext(ctx, &id,
+		`INSERT INTO org_unit (name, parent_id, manager_id, path) VALUES ($1, $2, $3, '') RETURNING id`,
+		unit.Name, unit.ParentId, unit.ManagerId)
+	if err != nil {
+		return nil, uniqueViolation(err)
+	}
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		"UPDATE org_unit SET path = $1 WHERE id = $2 RETURNING *", childPath(parentPath, id), id)
+	return &entity, err
+}
+
+// UpdateTx изменяет название и руководителя подразделения
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, unit *Entity) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.UpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		"UPDATE org_unit SET name = $1, manager_id = $2, updated_at = now() WHERE id = $3 RETURNING *",
+		unit.Name, unit.ManagerId, unit.Id)
+	return &entity, uniqueViolation(err)
+}
+
+// MoveTx переносит подразделение к новому родителю и переписывает пути всего поддерева.
+// Строки поддерева блокируются заранее, чтобы одновременно созданные дочерние подразделения
+// получили уже новый путь родителя
+func (r *Repository) MoveTx(ctx context.Context, tx *sqlx.Tx, unit *Entity, parentId *int64, newPath string) (_ *Entity, err error) {
+	var moved int64
+	ctx, span := tracing.StartQuery(ctx, "orgunit.MoveTx")
+	defer func() { span.Finish(moved, err) }()
+	var locked []int64
+	err = tx.SelectContext(ctx, &locked,
+		"SELECT id FROM org_unit WHERE path LIKE $1 || '%' ORDER BY id FOR UPDATE", unit.Path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx,
+		"UPDATE org_unit SET path = $1 || substr(path, length($2) + 1) WHERE path LIKE $2 || '%'",
+		newPath, unit.Path)
+	if err != nil {
+		return nil, err
+	}
+	moved, _ = result.RowsAffected()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		"UPDATE org_unit SET parent_id = $1, updated_at = now() WHERE id = $2 RETURNING *", parentId, unit.Id)
+	return &entity, uniqueViolation(err)
+}
+
+// CountDependentsTx возвращает число дочерних подразделений и неудалённых сотрудников подразделения
+func (r *Repository) CountDependentsTx(ctx context.Context, tx *sqlx.Tx, id int64) (children int64, members int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "orgunit.CountDependentsTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var counts struct {
+		Children int64 `db:"children"`
+		Members  int64 `db:"members"`
+	}
+	err = tx.GetContext(ctx, &counts,
+		`SELECT (select count(*) from org_unit where parent_id = $1) AS children,
+		(select count(*) from employee where org_unit_id = $1 and deleted_at is null) AS members`, id)
+	return counts.Children, counts.Members, err
+}
+
+func (r *Repository) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "orgunit.DeleteByIdTx")
+	defer func() { span.Finish(affected, err) }()
+	result, err := tx.ExecContext(ctx, "DELETE FROM org_unit WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	affected, err = result.RowsAffected()
+	if err == nil && affected == 0 {
+		return notFound(id)
+	}
+	return err
+}
+
+func notFound(id int64) error {
+	return common.NotFoundError{Message: fmt.Sprintf("org unit with id %d not found", id)}
+}
+
+// uniqueViolation превращает нарушение уникальности имени среди подразделений одного родителя в AlreadyExistsError.
+// Сервис проверяет имя заранее, индекс срабатывает только при одновременных изменениях
+func uniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return common.AlreadyExistsError{Message: "org unit already exists"}
+	}
+	return err
+}
@@ -0,0 +1,313 @@
+package orgunit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/tracing"
+	"idm/inner/validator"
+	"slices"
+	"strings"
+)
+
+type Service struct {
+	repo      Repo
+	auditor   Auditor
+	validator *validator.Validator
+}
+
+// Auditor записывает изменения подразделений в журнал аудита в той же транзакции, что и само изменение
+type Auditor interface {
+	RecordTx(ctx context.Context, tx *sqlx.Tx, record audit.Record) error
+}
+
+type Repo interface {
+	FindById(ctx context.Context, id int64) (*Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	FindSubtree(ctx context.Context, path string) ([]Entity, error)
+	FindAncestors(ctx context.Context, path string) ([]Entity, error)
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+	FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error)
+	FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error)
+	ExistsByNameTx(ctx context.Context, tx *sqlx.Tx, parentId *int64, name string, excludeId int64) (bool, error)
+	EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, unit *Entity, parentPath string) (*Entity, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, unit *Entity) (*Entity, error)
+	MoveTx(ctx context.Context, tx *sqlx.Tx, unit *Entity, parentId *int64, newPath string) (*Entity, error)
+	CountDependentsTx(ctx context.Context, tx *sqlx.Tx, id int64) (int64, int64, error)
+	DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error
+}
+
+func NewService(repo Repo, auditor Auditor) *Service {
+	return &Service{repo: repo, auditor: auditor, validator: validator.New()}
+}
+
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	ctx, span := tracing.Start(ctx, "orgunit.Service.FindById")
+	defer span.End()
+	e, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to find org unit with id %d: %w", id, err)
+	}
+	return e.toResponse(), nil
+}
+
+// FindAll возвращает все подразделения в порядке обхода дерева: родитель идёт перед своими потомками
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "orgunit.Service.FindAll")
+	defer span.End()
+	entities, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toResponses(entities), nil
+}
+
+// FindSubtree возвращает подразделение и всех его потомков
+func (svc *Service) FindSubtree(ctx context.Context, id int64) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "orgunit.Service.FindSubtree")
+	defer span.End()
+	unit, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find org unit with id %d: %w", id, err)
+	}
+	entities, err := svc.repo.FindSubtree(ctx, unit.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find subtree of org unit with id %d: %w", id, err)
+	}
+	return toResponses(entities), nil
+}
+
+// FindAncestors возвращает цепочку предков подразделения от корня к непосредственному родителю
+func (svc *Service) FindAncestors(ctx context.Context, id int64) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "orgunit.Service.FindAncestors")
+	defer span.End()
+	unit, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find org unit with id %d: %w", id, err)
+	}
+	entities, err := svc.repo.FindAncestors(ctx, unit.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find ancestors of org unit with id %d: %w", id, err)
+	}
+	return toResponses(entities), nil
+}
+
+// Create создаёт подразделение и возвращает его id.
+// Имя должно быть уникальным среди подразделений одного родителя
+func (svc *Service) Create(ctx context.Context, req CreateRequest) (id int64, err error) {
+	ctx, span := tracing.Start(ctx, "orgunit.Service.Create")
+	defer span.End()
+	if err = svc.validator.Validate(req); err != nil {
+		return 0, err
+	}
+	err = database.InTransaction(ctx, svc.repo, "creating org unit", func(tx *sqlx.Tx) error {
+		parentPath := rootPath
+		if req.ParentId != nil {
+			// родитель блокируется, чтобы его не перенесли, пока создаётся дочернее подразделение
+			parent, err := svc.repo.FindByIdForUpdateTx(ctx, tx, *req.ParentId)
+			if err != nil {
+				return parentNotFound(*req.ParentId, err)
+			}
+			parentPath = parent.Path
+		}
+		if err := svc.checkNameTx(ctx, tx, req.ParentId, req.Name, 0); err != nil {
+			return err
+		}
+		if err := svc.checkManagerTx(ctx, tx, req.ManagerId); err != nil {
+			return err
+		}
+		created, err := svc.repo.CreateTx(ctx, tx, &Entity{Name: req.Name, ParentId: req.ParentId, ManagerId: req.ManagerId}, parentPath)
+		if err != nil {
+			return fmt.Errorf("error creating org unit with name: %s %w", req.Name, err)
+		}
+		id = created.Id
+		return svc.recordTx(ctx, tx, audit.ActionCreate, id, nil, created.toAuditState())
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update изменяет название и руководителя подразделения; положение в дереве меняет Move
+func (svc *Service) Update(ctx context.Context, id int64, req UpdateRequest) (Response, error) {
+	ctx, span := tracing.Start(ctx, "orgunit.Service.Update")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return Response{}, err
+	}
+	var updated *Entity
+	err := database.InTransaction(ctx, svc.repo, "updating org unit", func(tx *sqlx.Tx) error {
+		current, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding org unit with id %d: %w", id, err)
+		}
+		if err = svc.checkNameTx(ctx, tx, current.ParentId, req.Name, id); err != nil {
+			return err
+		}
+		if err = svc.checkManagerTx(ctx, tx, req.ManagerId); err != nil {
+			return err
+		}
+		updated, err = svc.repo.UpdateTx(ctx, tx, &Entity{Id: id, Name: req.Name, ManagerId: req.ManagerId})
+		if err != nil {
+			return fmt.Errorf("error updating org unit with id %d: %w", id, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionUpdate, id, current.toAuditState(), updated.toAuditState())
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return updated.toResponse(), nil
+}
+
+// Move переносит подразделение вместе с поддеревом к другому родителю.
+// Перенос внутрь собственного поддерева создал бы цикл и возвращает ConflictError
+func (svc *Service) Move(ctx context.Context, id int64, req MoveRequest) (Response, error) {
+	ctx, span := tracing.Start(ctx, "orgunit.Service.Move")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return Response{}, err
+	}
+	var moved *Entity
+	err := database.InTransaction(ctx, svc.repo, "moving org unit", func(tx *sqlx.Tx) error {
+		ids := []int64{id}
+		if req.ParentId != nil && *req.ParentId != id {
+			ids = append(ids, *req.ParentId)
+		}
+		locked, err := svc.repo.FindByIdsForUpdateTx(ctx, tx, ids)
+		if err != nil {
+			return fmt.Errorf("error finding org units with ids %v: %w", ids, err)
+		}
+		current := findUnit(locked, id)
+		if current == nil {
+			return notFound(id)
+		}
+		parentPath := rootPath
+		if req.ParentId != nil {
+			if *req.ParentId == id {
+				return cycle(id)
+			}
+			parent := findUnit(locked, *req.ParentId)
+			if parent == nil {
+				return parentNotFound(*req.ParentId, notFound(*req.ParentId))
+			}
+			if strings.HasPrefix(parent.Path, current.Path) {
+				return cycle(id)
+			}
+			parentPath = parent.Path
+		}
+		if err = svc.checkNameTx(ctx, tx, req.ParentId, current.Name, id); err != nil {
+			return err
+		}
+		moved, err = svc.repo.MoveTx(ctx, tx, current, req.ParentId, childPath(parentPath, id))
+		if err != nil {
+			return fmt.Errorf("error moving org unit with id %d: %w", id, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionMove, id, current.toAuditState(), moved.toAuditState())
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return moved.toResponse(), nil
+}
+
+// Delete удаляет подразделение. Подразделение с дочерними подразделениями или сотрудниками
+// удалить нельзя: их сначала нужно перенести
+func (svc *Service) Delete(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "orgunit.Service.Delete")
+	defer span.End()
+	return database.InTransaction(ctx, svc.repo, "deleting org unit", func(tx *sqlx.Tx) error {
+		current, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding org unit with id %d: %w", id, err)
+		}
+		children, members, err := svc.repo.CountDependentsTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error counting dependents of org unit with id %d: %w", id, err)
+		}
+		if children > 0 || members > 0 {
+			return common.ConflictError{Message: fmt.Sprintf(
+				"org unit %d has %d child units and %d employees", id, children, members)}
+		}
+		if err = svc.repo.DeleteByIdTx(ctx, tx, id); err != nil {
+			return fmt.Errorf("error deleting org unit with id %d: %w", id, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionDelete, id, current.toAuditState(), nil)
+	})
+}
+
+// checkNameTx возвращает AlreadyExistsError, если у родителя уже есть подразделение с таким именем
+func (svc *Service) checkNameTx(ctx context.Context, tx *sqlx.Tx, parentId *int64, name string, excludeId int64) error {
+	isExists, err := svc.repo.ExistsByNameTx(ctx, tx, parentId, name, excludeId)
+	if err != nil {
+		return fmt.Errorf("error finding org unit by name: %s, %w", name, err)
+	}
+	if isExists {
+		return common.AlreadyExistsError{Message: "org unit with the same name already exists in the parent unit"}
+	}
+	return nil
+}
+
+// checkManagerTx проверяет, что назначаемый руководитель существует
+func (svc *Service) checkManagerTx(ctx context.Context, tx *sqlx.Tx, managerId *int64) error {
+	if managerId == nil {
+		return nil
+	}
+	isExists, err := svc.repo.EmployeeExistsTx(ctx, tx, *managerId)
+	if err != nil {
+		return fmt.Errorf("error finding employee with id %d: %w", *managerId, err)
+	}
+	if !isExists {
+		return common.RequestValidationError{Message: fmt.Sprintf("manager employee %d not found", *managerId)}
+	}
+	return nil
+}
+
+// recordTx записывает изменение подразделения в журнал аудита; before и after - nil, если состояния нет
+func (svc *Service) recordTx(ctx context.Context, tx *sqlx.Tx, action string, id int64, before any, after any) error {
+	err := svc.auditor.RecordTx(ctx, tx, audit.Record{
+		Action:     action,
+		EntityType: auditEntityType,
+		EntityId:   id,
+		Before:     before,
+		After:      after,
+	})
+	if err != nil {
+		return fmt.Errorf("error recording audit event for org unit with id %d: %w", id, err)
+	}
+	return nil
+}
+
+// parentNotFound превращает отсутствие родителя в ошибку валидации запроса.
+// Исходная NotFoundError не оборачивается: 404 относится только к самому подразделению из пути запроса
+func parentNotFound(parentId int64, err error) error {
+	if errors.As(err, &common.NotFoundError{}) {
+		return common.RequestValidationError{Message: fmt.Sprintf("parent org unit %d not found", parentId)}
+	}
+	return fmt.Errorf("error finding parent org unit with id %d: %w", parentId, err)
+}
+
+func cycle(id int64) error {
+	return common.ConflictError{Message: fmt.Sprintf("org unit %d cannot be moved under its own subtree", id)}
+}
+
+func findUnit(units []Entity, id int64) *Entity {
+	i := slices.IndexFunc(units, func(e Entity) bool { return e.Id == id })
+	if i < 0 {
+		return nil
+	}
+	return &units[i]
+}
+
+func toResponses(entities []Entity) []Response {
+	var result []Response
+	for i := range entities {
+		result = append(result, entities[i].toResponse())
+	}
+	return result
+}
@@ -0,0 +1,342 @@
+package orgunit
+
+import (
+	"context"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// ---- 1. Мок-репозиторий ----
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (*Entity, error) {
+	args := m.Called(ctx, id)
+	if ent, ok := args.Get(0).(*Entity); ok {
+		return ent, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindSubtree(ctx context.Context, path string) ([]Entity, error) {
+	args := m.Called(ctx, path)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAncestors(ctx context.Context, path string) ([]Entity, error) {
+	args := m.Called(ctx, path)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (m *MockRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	args := m.Called(ctx, tx, id)
+	if ent, ok := args.Get(0).(*Entity); ok {
+		return ent, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error) {
+	args := m.Called(ctx, tx, ids)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) ExistsByNameTx(ctx context.Context, tx *sqlx.Tx, parentId *int64, name string, excludeId int64) (bool, error) {
+	args := m.Called(ctx, tx, parentId, name, excludeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) CreateTx(ctx context.Context, tx *sqlx.Tx, unit *Entity, parentPath string) (*Entity, error) {
+	args := m.Called(ctx, tx, unit, parentPath)
+	return args.Get(0).(*Entity), args.Error(1)
+}
+
+func (m *MockRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, unit *Entity) (*Entity, error) {
+	args := m.Called(ctx, tx, unit)
+	return args.Get(0).(*Entity), args.Error(1)
+}
+
+func (m *MockRepo) MoveTx(ctx context.Context, tx *sqlx.Tx, unit *Entity, parentId *int64, newPath string) (*Entity, error) {
+	args := m.Called(ctx, tx, unit, parentId, newPath)
+	return args.Get(0).(*Entity), args.Error(1)
+}
+
+func (m *MockRepo) CountDependentsTx(ctx context.Context, tx *sqlx.Tx, id int64) (int64, int64, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRepo) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	return m.Called(ctx, tx, id).Error(0)
+}
+
+// stubAuditor запоминает события аудита вместо записи в БД
+type stubAuditor struct {
+	records []audit.Record
+}
+
+func (a *stubAuditor) RecordTx(ctx context.Context, tx *sqlx.Tx, record audit.Record) error {
+	a.records = append(a.records, record)
+	return nil
+}
+
+// newSqlmockService создаёт сервис с настоящим репозиторием поверх sqlmock
+func newSqlmockService(t *testing.T) (*Service, sqlmock.Sqlmock, *stubAuditor) {
+	dbMock, m, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = dbMock.Close() })
+	auditor := &stubAuditor{}
+	return NewService(NewRepository(sqlx.NewDb(dbMock, "postgres")), auditor), m, auditor
+}
+
+var (
+	columns       = []string{"id", "name", "parent_id", "path", "manager_id", "created_at", "updated_at"}
+	lockQuery     = regexp.QuoteMeta("SELECT * FROM org_unit WHERE id = $1 FOR UPDATE")
+	lockManyQuery = regexp.QuoteMeta("SELECT * FROM org_unit WHERE id IN ($1, $2) ORDER BY id FOR UPDATE")
+	nameQuery     = regexp.QuoteMeta("select exists(select 1 from org_unit where coalesce(parent_id, 0) = coalesce($1, 0)")
+)
+
+func ptr(id int64) *int64 {
+	return &id
+}
+
+// ---- 2. Тесты для Service ----
+func TestService_FindTree(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewService(repo, &stubAuditor{})
+	root := Entity{Id: 1, Name: "Company", Path: "/1/"}
+	sales := Entity{Id: 2, Name: "Sales", ParentId: ptr(1), Path: "/1/2/"}
+	emea := Entity{Id: 5, Name: "EMEA", ParentId: ptr(2), Path: "/1/2/5/"}
+	repo.On("FindById", mock.Anything, int64(2)).Return(&sales, nil)
+	repo.On("FindById", mock.Anything, int64(5)).Return(&emea, nil)
+	repo.On("FindById", mock.Anything, int64(9)).Return(nil, notFound(9))
+	repo.On("FindSubtree", mock.Anything, "/1/2/").Return([]Entity{sales, emea}, nil)
+	repo.On("FindAncestors", mock.Anything, "/1/2/5/").Return([]Entity{root, sales}, nil)
+
+	t.Run("subtree", func(t *testing.T) {
+		subtree, err := svc.FindSubtree(context.Background(), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []Response{sales.toResponse(), emea.toResponse()}, subtree)
+		assert.Equal(t, 2, subtree[1].Depth)
+	})
+
+	t.Run("ancestors from root", func(t *testing.T) {
+		ancestors, err := svc.FindAncestors(context.Background(), 5)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, []int64{ancestors[0].Id, ancestors[1].Id})
+		assert.Equal(t, 0, ancestors[0].Depth)
+	})
+
+	t.Run("unknown unit", func(t *testing.T) {
+		_, err := svc.FindSubtree(context.Background(), 9)
+		assert.ErrorAs(t, err, &common.NotFoundError{})
+		_, err = svc.FindAncestors(context.Background(), 9)
+		assert.ErrorAs(t, err, &common.NotFoundError{})
+	})
+}
+
+func TestService_Create(t *testing.T) {
+	now := time.Now()
+	insertQuery := regexp.QuoteMeta("INSERT INTO org_unit (name, parent_id, manager_id, path) VALUES ($1, $2, $3, '') RETURNING id")
+	pathQuery := regexp.QuoteMeta("UPDATE org_unit SET path = $1 WHERE id = $2 RETURNING *")
+
+	t.Run("child of existing unit", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Company", nil, "/1/", nil, now, now))
+		m.ExpectQuery(nameQuery).WithArgs(int64(1), "Sales", int64(0)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		m.ExpectQuery(insertQuery).WithArgs("Sales", int64(1), nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		m.ExpectQuery(pathQuery).WithArgs("/1/2/", int64(2)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "Sales", 1, "/1/2/", nil, now, now))
+		m.ExpectCommit()
+
+		id, err := svc.Create(context.Background(), CreateRequest{Name: "Sales", ParentId: ptr(1)})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), id)
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Equal(t, audit.ActionCreate, auditor.records[0].Action)
+		assert.Equal(t, "/1/2/", auditor.records[0].After.(auditState).Path)
+	})
+
+	t.Run("unknown parent", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(9)).WillReturnRows(sqlmock.NewRows(columns))
+		m.ExpectRollback()
+
+		_, err := svc.Create(context.Background(), CreateRequest{Name: "Sales", ParentId: ptr(9)})
+		// отсутствующий родитель - ошибка запроса, а не 404 самого подразделения
+		assert.ErrorAs(t, err, &common.RequestValidationError{})
+		assert.Equal(t, 400, common.ErrorStatus(err))
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("name is taken among siblings", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(nameQuery).WithArgs(nil, "Company", int64(0)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		m.ExpectRollback()
+
+		_, err := svc.Create(context.Background(), CreateRequest{Name: "Company"})
+		assert.ErrorAs(t, err, &common.AlreadyExistsError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("unknown manager", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(nameQuery).WithArgs(nil, "Company", int64(0)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		m.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from employee where id = $1 and deleted_at is null)")).
+			WithArgs(int64(42)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		m.ExpectRollback()
+
+		_, err := svc.Create(context.Background(), CreateRequest{Name: "Company", ManagerId: ptr(42)})
+		assert.ErrorAs(t, err, &common.RequestValidationError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+}
+
+func TestService_Move(t *testing.T) {
+	now := time.Now()
+
+	t.Run("subtree paths are rewritten", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockManyQuery).WithArgs(int64(2), int64(3)).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(2, "Sales", 1, "/1/2/", nil, now, now).
+				AddRow(3, "Commercial", nil, "/3/", nil, now, now))
+		m.ExpectQuery(nameQuery).WithArgs(int64(3), "Sales", int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		m.ExpectQuery(regexp.QuoteMeta("SELECT id FROM org_unit WHERE path LIKE $1 || '%' ORDER BY id FOR UPDATE")).
+			WithArgs("/1/2/").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(5))
+		m.ExpectExec(regexp.QuoteMeta("UPDATE org_unit SET path = $1 || substr(path, length($2) + 1) WHERE path LIKE $2 || '%'")).
+			WithArgs("/3/2/", "/1/2/").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		m.ExpectQuery(regexp.QuoteMeta("UPDATE org_unit SET parent_id = $1, updated_at = now() WHERE id = $2 RETURNING *")).
+			WithArgs(int64(3), int64(2)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "Sales", 3, "/3/2/", nil, now, now))
+		m.ExpectCommit()
+
+		resp, err := svc.Move(context.Background(), 2, MoveRequest{ParentId: ptr(3)})
+		assert.NoError(t, err)
+		assert.Equal(t, "/3/2/", resp.Path)
+		assert.Equal(t, 1, resp.Depth)
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Equal(t, audit.ActionMove, auditor.records[0].Action)
+		assert.Equal(t, "/1/2/", auditor.records[0].Before.(auditState).Path)
+	})
+
+	t.Run("under own descendant", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockManyQuery).WithArgs(int64(2), int64(5)).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(2, "Sales", 1, "/1/2/", nil, now, now).
+				AddRow(5, "EMEA", 2, "/1/2/5/", nil, now, now))
+		m.ExpectRollback()
+
+		_, err := svc.Move(context.Background(), 2, MoveRequest{ParentId: ptr(5)})
+		var conflictErr common.ConflictError
+		assert.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, "org unit 2 cannot be moved under its own subtree", conflictErr.Message)
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("under itself", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM org_unit WHERE id IN ($1) ORDER BY id FOR UPDATE")).WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "Sales", 1, "/1/2/", nil, now, now))
+		m.ExpectRollback()
+
+		_, err := svc.Move(context.Background(), 2, MoveRequest{ParentId: ptr(2)})
+		assert.ErrorAs(t, err, &common.ConflictError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("unknown unit", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockManyQuery).WithArgs(int64(7), int64(3)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "Commercial", nil, "/3/", nil, now, now))
+		m.ExpectRollback()
+
+		_, err := svc.Move(context.Background(), 7, MoveRequest{ParentId: ptr(3)})
+		assert.ErrorAs(t, err, &common.NotFoundError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+}
+
+func TestService_Delete(t *testing.T) {
+	now := time.Now()
+	countQuery := regexp.QuoteMeta("SELECT (select count(*) from org_unit where parent_id = $1) AS children")
+
+	t.Run("unit with children", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "Sales", 1, "/1/2/", nil, now, now))
+		m.ExpectQuery(countQuery).WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"children", "members"}).AddRow(1, 3))
+		m.ExpectRollback()
+
+		err := svc.Delete(context.Background(), 2)
+		assert.ErrorAs(t, err, &common.ConflictError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+
+	t.Run("empty unit", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "Sales", 1, "/1/2/", nil, now, now))
+		m.ExpectQuery(countQuery).WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"children", "members"}).AddRow(0, 0))
+		m.ExpectExec(regexp.QuoteMeta("DELETE FROM org_unit WHERE id = $1")).WithArgs(int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		m.ExpectCommit()
+
+		assert.NoError(t, svc.Delete(context.Background(), 2))
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Equal(t, audit.ActionDelete, auditor.records[0].Action)
+	})
+}
+
+func TestDepth(t *testing.T) {
+	assert.Equal(t, 0, depth("/1/"))
+	assert.Equal(t, 2, depth("/1/2/5/"))
+	assert.Equal(t, "/1/2/", childPath("/1/", 2))
+	assert.Equal(t, "/7/", childPath(rootPath, 7))
+}
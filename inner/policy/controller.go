@@ -0,0 +1,29 @@
+package policy
+
+import (
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	server   *web.Server
+	enforcer *Enforcer
+}
+
+func NewController(server *web.Server, enforcer *Enforcer) *Controller {
+	return &Controller{
+		server:   server,
+		enforcer: enforcer,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupInternal.Get("/policies", c.GetPolicies)
+}
+
+// GetPolicies возвращает действующую политику доступа (только чтение)
+func (c *Controller) GetPolicies(ctx *fiber.Ctx) error {
+	return common.OkResponse(ctx, c.enforcer.Policy())
+}
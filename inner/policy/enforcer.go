@@ -0,0 +1,110 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Enforcer проверяет доступ к маршрутам API по правилам политики
+type Enforcer struct {
+	policy   Policy
+	handlers []fiber.Handler
+}
+
+// NewEnforcer готовит проверки для каждого правила политики.
+// clientId - клиент Keycloak, чьи роли проверяются в client_roles и any_client_roles
+func NewEnforcer(policy Policy, clientId string) (*Enforcer, error) {
+	handlers := make([]fiber.Handler, 0, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		requirements, err := rule.requirements(clientId)
+		if err != nil {
+			return nil, fmt.Errorf("rule #%d %s %s: %w", i+1, rule.Method, rule.Path, err)
+		}
+		handlers = append(handlers, web.Require(requirements...))
+	}
+	return &Enforcer{policy: policy, handlers: handlers}, nil
+}
+
+// Policy возвращает загруженную политику
+func (e *Enforcer) Policy() Policy {
+	return e.policy
+}
+
+// Middleware применяет к запросу первое подходящее правило.
+// Запросы, не подходящие ни под одно правило, запрещаются
+func (e *Enforcer) Middleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		for i := range e.policy.Rules {
+			if e.policy.Rules[i].Match(ctx.Method(), ctx.Path()) {
+				return e.handlers[i](ctx)
+			}
+		}
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "forbidden")
+	}
+}
+
+// Validate сверяет политику с зарегистрированными маршрутами: каждый маршрут с префиксом prefix
+// должен подпадать под какое-либо правило, а каждое правило - хотя бы под один маршрут
+func (e *Enforcer) Validate(routes []fiber.Route, prefix string) error {
+	var errs []error
+	used := make([]bool, len(e.policy.Rules))
+	for _, route := range routes {
+		// HEAD Fiber регистрирует сам для каждого GET, он проверяется по правилам для GET
+		if route.Method == fiber.MethodHead || !hasPrefix(route.Path, prefix) {
+			continue
+		}
+		covered := false
+		for i := range e.policy.Rules {
+			if e.policy.Rules[i].Match(route.Method, route.Path) {
+				used[i], covered = true, true
+			}
+		}
+		if !covered {
+			errs = append(errs, fmt.Errorf("route %s %s is not covered by policy", route.Method, route.Path))
+		}
+	}
+	for i, rule := range e.policy.Rules {
+		if !used[i] {
+			errs = append(errs, fmt.Errorf("rule #%d %s %s does not match any route", i+1, rule.Method, rule.Path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// requirements переводит требования правила в проверки claims
+func (r *Rule) requirements(clientId string) ([]web.Requirement, error) {
+	if (len(r.ClientRoles) > 0 || len(r.AnyClientRoles) > 0) && clientId == "" {
+		return nil, errors.New("client roles require KEYCLOAK_CLIENT_ID")
+	}
+	var requirements []web.Requirement
+	if len(r.Roles) > 0 {
+		requirements = append(requirements, web.HasAllRoles(r.Roles...))
+	}
+	if len(r.AnyRoles) > 0 {
+		requirements = append(requirements, web.HasAnyRole(r.AnyRoles...))
+	}
+	if len(r.ClientRoles) > 0 {
+		requirements = append(requirements, web.HasAllClientRoles(clientId, r.ClientRoles...))
+	}
+	if len(r.AnyClientRoles) > 0 {
+		requirements = append(requirements, web.HasAnyClientRole(clientId, r.AnyClientRoles...))
+	}
+	if len(r.Scopes) > 0 {
+		requirements = append(requirements, web.HasAllScopes(r.Scopes...))
+	}
+	if len(r.AnyScopes) > 0 {
+		requirements = append(requirements, web.HasAnyScope(r.AnyScopes...))
+	}
+	return requirements, nil
+}
+
+func hasPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,160 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// MethodAny - правило применяется к любому HTTP-методу
+const MethodAny = "*"
+
+// Policy - набор правил доступа к маршрутам API.
+// Правила проверяются по порядку, применяется первое подходящее
+type Policy struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule - правило доступа к маршрутам, подходящим под метод и шаблон пути.
+// Шаблон пути записывается как в Fiber: ":param" - один сегмент, "*" - весь остаток пути.
+// Все заданные требования должны выполняться одновременно; правило без требований
+// пропускает любого аутентифицированного пользователя
+type Rule struct {
+	Method string `json:"method" yaml:"method"`
+	Path   string `json:"path" yaml:"path"`
+	// все перечисленные роли realm
+	Roles []string `json:"roles,omitempty" yaml:"roles"`
+	// хотя бы одна из ролей realm
+	AnyRoles []string `json:"any_roles,omitempty" yaml:"any_roles"`
+	// все перечисленные роли клиента KEYCLOAK_CLIENT_ID
+	ClientRoles []string `json:"client_roles,omitempty" yaml:"client_roles"`
+	// хотя бы одна из ролей клиента KEYCLOAK_CLIENT_ID
+	AnyClientRoles []string `json:"any_client_roles,omitempty" yaml:"any_client_roles"`
+	// все перечисленные OAuth scopes
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes"`
+	// хотя бы один из OAuth scopes
+	AnyScopes []string `json:"any_scopes,omitempty" yaml:"any_scopes"`
+
+	segments []string
+}
+
+// Load читает политику из файла; формат определяется по расширению (.json, иначе YAML)
+func Load(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("reading policy file: %w", err)
+	}
+	return Parse(data, strings.EqualFold(filepath.Ext(path), ".json"))
+}
+
+// Parse разбирает политику в формате JSON или YAML и проверяет правила.
+// Неизвестные поля считаются ошибкой, чтобы опечатка не ослабила политику
+func Parse(data []byte, isJson bool) (Policy, error) {
+	var policy Policy
+	if isJson {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&policy); err != nil {
+			return Policy{}, fmt.Errorf("parsing policy: %w", err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&policy); err != nil {
+			return Policy{}, fmt.Errorf("parsing policy: %w", err)
+		}
+	}
+	if len(policy.Rules) == 0 {
+		return Policy{}, errors.New("policy has no rules")
+	}
+	for i := range policy.Rules {
+		if err := policy.Rules[i].compile(); err != nil {
+			return Policy{}, fmt.Errorf("rule #%d: %w", i+1, err)
+		}
+	}
+	return policy, nil
+}
+
+// compile нормализует метод и разбивает шаблон пути на сегменты
+func (r *Rule) compile() error {
+	r.Method = strings.ToUpper(strings.TrimSpace(r.Method))
+	if r.Method == "" {
+		r.Method = MethodAny
+	}
+	if r.Method != MethodAny && !slices.Contains(fiber.DefaultMethods, r.Method) {
+		return fmt.Errorf("unknown method %q", r.Method)
+	}
+	r.Path = strings.TrimSpace(r.Path)
+	if !strings.HasPrefix(r.Path, "/") {
+		return fmt.Errorf("path %q must start with /", r.Path)
+	}
+	r.segments = splitPath(r.Path)
+	for i, segment := range r.segments {
+		if segment == "*" && i != len(r.segments)-1 {
+			return fmt.Errorf("path %q: wildcard is allowed only as the last segment", r.Path)
+		}
+		if segment == ":" {
+			return fmt.Errorf("path %q: parameter must have a name", r.Path)
+		}
+	}
+	return nil
+}
+
+// Match проверяет, подходит ли запрос под правило; HEAD проверяется по правилам для GET
+func (r *Rule) Match(method, path string) bool {
+	if method == fiber.MethodHead {
+		method = fiber.MethodGet
+	}
+	if r.Method != MethodAny && r.Method != method {
+		return false
+	}
+	return matchSegments(r.segments, splitPath(path))
+}
+
+// Find возвращает первое правило, подходящее под запрос
+func (p *Policy) Find(method, path string) (*Rule, bool) {
+	for i := range p.Rules {
+		if p.Rules[i].Match(method, path) {
+			return &p.Rules[i], true
+		}
+	}
+	return nil, false
+}
+
+func matchSegments(pattern, path []string) bool {
+	for i, segment := range pattern {
+		if segment == "*" {
+			return true
+		}
+		if i >= len(path) {
+			return false
+		}
+		if strings.HasPrefix(segment, ":") {
+			if path[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != path[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(path)
+}
+
+// splitPath разбивает путь на сегменты, игнорируя завершающий слэш: "/api/v1/roles/" -> [api v1 roles]
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
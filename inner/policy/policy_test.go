@@ -0,0 +1,250 @@
+package policy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"idm/inner/accessrequest"
+	"idm/inner/accessreview"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/info"
+	"idm/inner/loglevel"
+	"idm/inner/orgunit"
+	"idm/inner/role"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+rules:
+  - { method: POST, path: /api/v1/roles/batch, any_roles: [IDM_ADMIN, IDM_USER] }
+  - { method: POST, path: /api/v1/roles, roles: [IDM_ADMIN] }
+  - { method: GET, path: /api/v1/roles/*, any_roles: [IDM_ADMIN, IDM_USER] }
+  - { method: DELETE, path: /api/v1/roles/:id, client_roles: [roles-admin], scopes: [roles.write] }
+  - { path: /api/internal/info }
+`
+
+func TestParse(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("yaml", func(t *testing.T) {
+		policy, err := Parse([]byte(testPolicy), false)
+		a.NoError(err)
+		a.Len(policy.Rules, 5)
+		a.Equal(MethodAny, policy.Rules[4].Method)
+		a.Equal([]string{"roles-admin"}, policy.Rules[3].ClientRoles)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		policy, err := Parse([]byte(`{"rules":[{"method":"get","path":"/api/v1/roles","any_scopes":["roles.read"]}]}`), true)
+		a.NoError(err)
+		a.Equal(fiber.MethodGet, policy.Rules[0].Method)
+		a.Equal([]string{"roles.read"}, policy.Rules[0].AnyScopes)
+	})
+
+	errorCases := []struct {
+		name   string
+		data   string
+		isJson bool
+	}{
+		{"unknown field", "rules:\n  - { method: GET, path: /api, role: [IDM_ADMIN] }", false},
+		{"unknown json field", `{"rules":[{"method":"GET","path":"/api","role":["IDM_ADMIN"]}]}`, true},
+		{"empty policy", "rules: []", false},
+		{"unknown method", "rules:\n  - { method: FETCH, path: /api }", false},
+		{"relative path", "rules:\n  - { method: GET, path: api/v1 }", false},
+		{"wildcard in the middle", "rules:\n  - { method: GET, path: /api/*/roles }", false},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), tt.isJson)
+			a.Error(err)
+		})
+	}
+}
+
+func TestRule_Match(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy), false)
+	require.NoError(t, err)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{fiber.MethodPost, "/api/v1/roles/batch", 0},
+		{fiber.MethodPost, "/api/v1/roles", 1},
+		{fiber.MethodPost, "/api/v1/roles/", 1},
+		{fiber.MethodGet, "/api/v1/roles", 2},
+		{fiber.MethodGet, "/api/v1/roles/3/employees", 2},
+		{fiber.MethodHead, "/api/v1/roles/3", 2},
+		{fiber.MethodDelete, "/api/v1/roles/3", 3},
+		{fiber.MethodDelete, "/api/v1/roles", -1},
+		{fiber.MethodDelete, "/api/v1/roles/3/employees", -1},
+		{fiber.MethodPut, "/api/internal/info", 4},
+		{fiber.MethodGet, "/api/internal/health", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.path, func(t *testing.T) {
+			rule, ok := policy.Find(tt.method, tt.path)
+			if tt.want < 0 {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Same(t, &policy.Rules[tt.want], rule)
+		})
+	}
+}
+
+func TestEnforcer_Middleware(t *testing.T) {
+	a := assert.New(t)
+	policy, err := Parse([]byte(testPolicy), false)
+	require.NoError(t, err)
+	enforcer, err := NewEnforcer(policy, "idm")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		claims     *web.IdmClaims
+		wantStatus int
+	}{
+		{
+			name:       "any role allows user",
+			method:     fiber.MethodGet,
+			path:       "/api/v1/roles/3",
+			claims:     &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmUser}}},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "missing required role",
+			method:     fiber.MethodPost,
+			path:       "/api/v1/roles",
+			claims:     &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmUser}}},
+			wantStatus: fiber.StatusForbidden,
+		},
+		{
+			name:   "client role and scope",
+			method: fiber.MethodDelete,
+			path:   "/api/v1/roles/3",
+			claims: &web.IdmClaims{
+				ResourceAccess: map[string]web.RealmAccessClaims{"idm": {Roles: []string{"roles-admin"}}},
+				Scope:          "openid roles.write",
+			},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:   "client role without scope",
+			method: fiber.MethodDelete,
+			path:   "/api/v1/roles/3",
+			claims: &web.IdmClaims{
+				ResourceAccess: map[string]web.RealmAccessClaims{"idm": {Roles: []string{"roles-admin"}}},
+				Scope:          "openid",
+			},
+			wantStatus: fiber.StatusForbidden,
+		},
+		{
+			name:       "rule without requirements allows any authenticated user",
+			method:     fiber.MethodGet,
+			path:       "/api/internal/info",
+			claims:     &web.IdmClaims{},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "unauthenticated",
+			method:     fiber.MethodGet,
+			path:       "/api/internal/info",
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "route not covered by policy is denied",
+			method:     fiber.MethodGet,
+			path:       "/api/internal/health",
+			claims:     &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmAdmin}}},
+			wantStatus: fiber.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				if tt.claims != nil {
+					c.Locals(web.JwtKey, &jwt.Token{Claims: tt.claims})
+				}
+				return c.Next()
+			}, enforcer.Middleware())
+			app.All("/*", func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil), -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestNewEnforcer_ClientRolesRequireClientId(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy), false)
+	require.NoError(t, err)
+	_, err = NewEnforcer(policy, "")
+	assert.Error(t, err)
+}
+
+func TestEnforcer_Validate(t *testing.T) {
+	a := assert.New(t)
+	policy, err := Parse([]byte(testPolicy), false)
+	require.NoError(t, err)
+	enforcer, err := NewEnforcer(policy, "idm")
+	require.NoError(t, err)
+
+	handler := func(c *fiber.Ctx) error { return nil }
+	server := web.NewServer()
+	roles := server.GroupApiV1.Group("/roles")
+	roles.Post("/batch", handler)
+	roles.Post("/", handler)
+	roles.Get("/", handler)
+	roles.Get("/:id", handler)
+	roles.Delete("/:id", handler)
+	server.GroupInternal.Get("/info", handler)
+	server.App.Get("/swagger/*", handler)
+
+	a.NoError(enforcer.Validate(server.App.GetRoutes(true), "/api"))
+
+	// маршрут без правила и правило без маршрута
+	server = web.NewServer()
+	server.GroupApiV1.Group("/roles").Delete("/", handler)
+	err = enforcer.Validate(server.App.GetRoutes(true), "/api")
+	a.ErrorContains(err, "route DELETE /api/v1/roles/ is not covered by policy")
+	a.ErrorContains(err, "rule #1 POST /api/v1/roles/batch does not match any route")
+}
+
+func TestDefaultPolicyMatchesRoutes(t *testing.T) {
+	policy, err := Load("../../policies.yaml")
+	require.NoError(t, err)
+	enforcer, err := NewEnforcer(policy, "idm")
+	require.NoError(t, err)
+
+	cfg := common.Config{}
+	logger := common.NewLogger(cfg)
+	server := web.NewServer()
+	employee.NewController(server, nil, nil, logger).RegisterRoutes()
+	role.NewController(server, nil, logger).RegisterRoutes()
+	orgunit.NewController(server, nil, logger).RegisterRoutes()
+	accessrequest.NewController(server, nil, logger).RegisterRoutes()
+	accessreview.NewController(server, nil, logger).RegisterRoutes()
+	audit.NewController(server, nil).RegisterRoutes()
+	info.NewController(server, cfg, nil).RegisterRoutes()
+	NewController(server, enforcer).RegisterRoutes()
+	loglevel.NewController(server, logger).RegisterRoutes()
+
+	assert.NoError(t, enforcer.Validate(server.App.GetRoutes(true), "/api"))
+}
@@ -0,0 +1,65 @@
+package purge
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"idm/inner/common"
+	"idm/inner/metrics"
+
+	"go.uber.org/zap"
+)
+
+// Target - сервис, умеющий окончательно удалять записи, мягко удалённые раньше before
+type Target interface {
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// Worker периодически удаляет мягко удалённые записи, срок хранения которых истёк
+type Worker struct {
+	logger    *common.Logger
+	retention time.Duration
+	targets   map[string]Target
+	now       func() time.Time
+
+	*common.Periodic
+}
+
+// NewWorker создаёт очистку; ключ targets - тип сущности, он попадает в логи и метрики
+func NewWorker(logger *common.Logger, retention, interval time.Duration, targets map[string]Target) *Worker {
+	w := &Worker{
+		logger:    logger,
+		retention: retention,
+		targets:   targets,
+		now:       time.Now,
+	}
+	w.Periodic = common.NewPeriodic(interval, w.RunOnce)
+	return w
+}
+
+// RunOnce удаляет записи всех сущностей, мягко удалённые раньше, чем retention назад.
+// Ошибка по одной сущности не мешает очистке остальных
+func (w *Worker) RunOnce(ctx context.Context) {
+	before := w.now().Add(-w.retention)
+	names := make([]string, 0, len(w.targets))
+	for name := range w.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return
+		}
+		purged, err := w.targets[name].Purge(ctx, before)
+		if err != nil {
+			w.logger.Error("purge soft-deleted records", zap.String("entity", name), zap.Error(err))
+			continue
+		}
+		metrics.RecordsPurged.WithLabelValues(name).Add(float64(purged))
+		if purged > 0 {
+			w.logger.Info("purged soft-deleted records", zap.String("entity", name),
+				zap.Int("count", purged), zap.Time("deleted_before", before))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"idm/inner/logtest"
+	"idm/inner/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// stubTarget запоминает моменты, до которых его просили очистить записи
+type stubTarget struct {
+	mu      sync.Mutex
+	befores []time.Time
+	purged  int
+	err     error
+}
+
+func (s *stubTarget) Purge(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.befores = append(s.befores, before)
+	return s.purged, s.err
+}
+
+func (s *stubTarget) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.befores)
+}
+
+func newTestWorker(targets map[string]Target) (*Worker, *observer.ObservedLogs) {
+	logger, logs := logtest.New()
+	worker := NewWorker(logger, 24*time.Hour, time.Hour, targets)
+	return worker, logs
+}
+
+func TestWorker_RunOnce(t *testing.T) {
+	now := time.Date(2025, 11, 10, 12, 0, 0, 0, time.UTC)
+	employees := &stubTarget{purged: 3}
+	roles := &stubTarget{err: errors.New("db down")}
+	worker, logs := newTestWorker(map[string]Target{"employee": employees, "role": roles})
+	worker.now = func() time.Time { return now }
+	purgedBefore := testutil.ToFloat64(metrics.RecordsPurged.WithLabelValues("employee"))
+
+	worker.RunOnce(context.Background())
+
+	// граница считается от текущего времени за вычетом срока хранения
+	assert.Equal(t, []time.Time{now.Add(-24 * time.Hour)}, employees.befores)
+	// ошибка по одной сущности не мешает очистке остальных
+	assert.Equal(t, 1, roles.calls())
+	assert.Equal(t, purgedBefore+3, testutil.ToFloat64(metrics.RecordsPurged.WithLabelValues("employee")))
+	assert.Equal(t, 1, logs.FilterMessage("purged soft-deleted records").Len())
+	assert.Equal(t, 1, logs.FilterMessage("purge soft-deleted records").FilterField(zap.String("entity", "role")).Len())
+}
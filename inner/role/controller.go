@@ -0,0 +1,286 @@
+package role
+
+import (
+	"context"
+	"strconv"
+
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	server      *web.Server
+	roleService Svc
+	logger      *common.Logger
+}
+
+// Svc описывает набор методов бизнес-логики по работе с ролями
+type Svc interface {
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	FindByIds(ctx context.Context, ids []int64) ([]Response, error)
+	DeleteById(ctx context.Context, id int64) error
+	DeleteByIds(ctx context.Context, ids []int64) error
+	SaveWithTransaction(ctx context.Context, req CreateRequest) (int64, error)
+	GetRolesPage(ctx context.Context, req PageRequest) (PageResponse, error)
+	Restore(ctx context.Context, id int64) (Response, error)
+	SetOwner(ctx context.Context, id int64, req OwnerRequest) (Response, error)
+}
+
+func NewController(server *web.Server, roleService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:      server,
+		roleService: roleService,
+		logger:      logger,
+	}
+}
+
+// RegisterRoutes регистрирует маршруты; права доступа к ним задаются политикой (policies.yaml)
+func (c *Controller) RegisterRoutes() {
+
+	grp := c.server.GroupApiV1.Group("/roles")
+
+	grp.Post("/", c.CreateRole)
+	grp.Delete("/", c.DeleteRolesByIds)
+	grp.Delete("/:id", c.DeleteRoleById)
+	grp.Post("/:id/restore", c.RestoreRole)
+	grp.Put("/:id/owner", c.SetRoleOwner)
+
+	grp.Get("/", c.GetAllRoles)
+	grp.Get("/page", c.GetRolesPage)
+	grp.Post("/batch", c.GetRolesByIds)
+	grp.Get("/:id", c.GetRole)
+}
+
+// CreateRole godoc
+// @Summary      Create role
+// @Description  Create a new role and return its id
+// @Tags         role
+// @Accept       json
+// @Produce      json
+// @Param        request  body      role.CreateRequest  true  "create role request"
+// @Success      200      {object}  common.Response[int64]
+// @Router       /roles [post]
+// CreateRole handles POST /api/v1/roles
+// @Security BearerAuth
+func (c *Controller) CreateRole(ctx *fiber.Ctx) error {
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("create role", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	c.logger.Ctx(ctx.UserContext()).Debug("create role: received request", zap.Any("request", request))
+
+	newRoleId, err := c.roleService.SaveWithTransaction(ctx.UserContext(), request)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("create role", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, newRoleId)
+}
+
+// GetRole godoc
+// @Summary      Get role by id
+// @Description  Returns role by id
+// @Tags         role
+// @Produce      json
+// @Param        id   path      int  true  "role id"
+// @Success      200  {object}  role.Response
+// @Failure      404  {object}  common.Response[any]
+// @Router       /roles/{id} [get]
+// GetRole handles GET /api/v1/roles/:id
+// @Security BearerAuth
+func (c *Controller) GetRole(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	c.logger.Ctx(ctx.UserContext()).Debug("Get role", zap.String("id", idParam))
+
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get role", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resp, err := c.roleService.FindById(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get role", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// GetAllRoles godoc
+// @Summary      List roles
+// @Description  Returns all roles
+// @Tags         role
+// @Produce      json
+// @Success      200  {array}  role.Response
+// @Router       /roles [get]
+// GetAllRoles handles GET /api/v1/roles
+// @Security BearerAuth
+func (c *Controller) GetAllRoles(ctx *fiber.Ctx) error {
+	resps, err := c.roleService.FindAll(ctx.UserContext())
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get all roles", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// GetRolesPage godoc
+// @Summary      Get roles page
+// @Description  Returns paginated list of roles
+// @Tags         role
+// @Produce      json
+// @Param        request  query     role.PageRequest  true  "page request"
+// @Success      200      {object}  role.PageResponse
+// @Router       /roles/page [get]
+// @Security BearerAuth
+func (c *Controller) GetRolesPage(ctx *fiber.Ctx) error {
+	var req PageRequest
+	if err := ctx.QueryParser(&req); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "bad query params")
+	}
+	// удалённые роли видят только администраторы
+	if req.IncludeDeleted && !web.Satisfies(ctx, web.HasAnyRole(web.IdmAdmin)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "forbidden")
+	}
+	pageResp, err := c.roleService.GetRolesPage(ctx.UserContext(), req)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get roles page", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, pageResp)
+}
+
+// GetRolesByIds godoc
+// @Summary      Get roles by ids
+// @Description  Returns roles by the given ids
+// @Tags         role
+// @Accept       json
+// @Produce      json
+// @Param        ids  body      []int64  true  "role ids"
+// @Success      200  {array}   role.Response
+// @Router       /roles/batch [post]
+// GetRolesByIds handles POST /api/v1/roles/batch
+// @Security BearerAuth
+func (c *Controller) GetRolesByIds(ctx *fiber.Ctx) error {
+	var ids []int64
+	if err := ctx.BodyParser(&ids); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get roles by ids", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	resps, err := c.roleService.FindByIds(ctx.UserContext(), ids)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Get roles by ids", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resps)
+}
+
+// DeleteRoleById godoc
+// @Summary      Delete role by id
+// @Description  Deletes role with the specified id
+// @Tags         role
+// @Produce      json
+// @Param        id   path      int  true  "role id"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  common.Response[any]
+// @Router       /roles/{id} [delete]
+// DeleteRoleById handles DELETE /api/v1/roles/:id
+// @Security BearerAuth
+func (c *Controller) DeleteRoleById(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	c.logger.Ctx(ctx.UserContext()).Debug("Delete role", zap.String("id", idParam))
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Delete role", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	if err := c.roleService.DeleteById(ctx.UserContext(), id); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Delete role", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, fiber.Map{"message": "deleted"})
+}
+
+// RestoreRole godoc
+// @Summary      Restore role
+// @Description  Restores soft-deleted role with the specified id
+// @Tags         role
+// @Produce      json
+// @Param        id   path      int  true  "role id"
+// @Success      200  {object}  common.Response[role.Response]
+// @Failure      404  {object}  common.Response[any]  "deleted role not found"
+// @Failure      400  {object}  common.Response[any]  "name is taken by another role"
+// @Router       /roles/{id}/restore [post]
+// @Security BearerAuth
+func (c *Controller) RestoreRole(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	resp, err := c.roleService.Restore(ctx.UserContext(), id)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Restore role", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// SetRoleOwner godoc
+// @Summary      Set role owner
+// @Description  Sets the employee responsible for the role; without owner_id the owner is removed
+// @Tags         role
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                true  "role id"
+// @Param        request  body      role.OwnerRequest  true  "role owner"
+// @Success      200      {object}  common.Response[role.Response]
+// @Failure      400      {object}  common.Response[any]  "owner employee not found"
+// @Failure      404      {object}  common.Response[any]
+// @Router       /roles/{id}/owner [put]
+// @Security BearerAuth
+func (c *Controller) SetRoleOwner(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid id")
+	}
+	var request OwnerRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("set role owner", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	resp, err := c.roleService.SetOwner(ctx.UserContext(), id, request)
+	if err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("set role owner", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, resp)
+}
+
+// DeleteRolesByIds godoc
+// @Summary      Delete roles by ids
+// @Description  Deletes roles with the specified ids; if any of them is not found, none is deleted
+// @Tags         role
+// @Accept       json
+// @Produce      json
+// @Param        ids  body      []int64  true  "role ids"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  common.Response[any]
+// @Router       /roles [delete]
+// DeleteRolesByIds handles DELETE /api/v1/roles
+// @Security BearerAuth
+func (c *Controller) DeleteRolesByIds(ctx *fiber.Ctx) error {
+	var ids []int64
+	if err := ctx.BodyParser(&ids); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, common.InvalidBodyMessage)
+	}
+	c.logger.Ctx(ctx.UserContext()).Debug("Delete roles by ids", zap.Int64s("ids", ids))
+	if err := c.roleService.DeleteByIds(ctx.UserContext(), ids); err != nil {
+		c.logger.Ctx(ctx.UserContext()).Error("Delete roles", zap.Error(err))
+		return common.ServiceErrResponse(ctx, err)
+	}
+	return common.OkResponse(ctx, fiber.Map{"message": "deleted"})
+}
@@ -0,0 +1,434 @@
+package role
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/policy"
+	"idm/inner/web"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Объявляем структуру мока сервиса role.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindAll(ctx context.Context) ([]Response, error) {
+	args := svc.Called(ctx)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
+	args := svc.Called(ctx, ids)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) Restore(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) DeleteById(ctx context.Context, id int64) error {
+	return svc.Called(ctx, id).Error(0)
+}
+
+func (svc *MockService) DeleteByIds(ctx context.Context, ids []int64) error {
+	return svc.Called(ctx, ids).Error(0)
+}
+
+func (svc *MockService) SaveWithTransaction(ctx context.Context, req CreateRequest) (int64, error) {
+	args := svc.Called(ctx, req)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (svc *MockService) GetRolesPage(ctx context.Context, req PageRequest) (PageResponse, error) {
+	args := svc.Called(ctx, req)
+	return args.Get(0).(PageResponse), args.Error(1)
+}
+
+func (svc *MockService) SetOwner(ctx context.Context, id int64, req OwnerRequest) (Response, error) {
+	args := svc.Called(ctx, id, req)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+// newTestServer создаёт сервер с stub-аутентификацией пользователя с переданными ролями
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	var claims = &web.IdmClaims{
+		RealmAccess: web.RealmAccessClaims{Roles: roles},
+	}
+	var auth = func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+	server := web.NewServer()
+	server.GroupApiV1.Use(auth, policyMiddleware())
+	controller := NewController(server, svc, common.NewLogger(common.GetConfig(".env")))
+	controller.RegisterRoutes()
+	return server
+}
+
+func TestCreateRole(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		name       string
+		body       string
+		roles      []string
+		mockSetup  func(*MockService)
+		wantStatus int
+		wantID     int64
+	}{
+		{
+			name:  "should return created role id",
+			body:  `{"name":"auditor"}`,
+			roles: []string{web.IdmAdmin},
+			mockSetup: func(svc *MockService) {
+				svc.On("SaveWithTransaction", mock.Anything, CreateRequest{Name: "auditor"}).Return(int64(5), nil)
+			},
+			wantStatus: http.StatusOK,
+			wantID:     5,
+		},
+		{
+			name:  "should return bad request on duplicate",
+			body:  `{"name":"auditor"}`,
+			roles: []string{web.IdmAdmin},
+			mockSetup: func(svc *MockService) {
+				svc.On("SaveWithTransaction", mock.Anything, CreateRequest{Name: "auditor"}).
+					Return(int64(0), common.AlreadyExistsError{Message: "role already exists"})
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "should return internal error on service failure",
+			body:  `{"name":"auditor"}`,
+			roles: []string{web.IdmAdmin},
+			mockSetup: func(svc *MockService) {
+				svc.On("SaveWithTransaction", mock.Anything, CreateRequest{Name: "auditor"}).Return(int64(0), errors.New("fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "should forbid user without admin role",
+			body:       `{"name":"auditor"}`,
+			roles:      []string{web.IdmUser},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockService)
+			if tt.mockSetup != nil {
+				tt.mockSetup(svc)
+			}
+			server := newTestServer(svc, tt.roles...)
+
+			req := httptest.NewRequest(fiber.MethodPost, "/api/v1/roles", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := server.App.Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+
+			if tt.wantStatus == http.StatusOK {
+				data, _ := io.ReadAll(resp.Body)
+				var rb common.Response[int64]
+				a.NoError(json.Unmarshal(data, &rb))
+				a.True(rb.Success)
+				a.Equal(tt.wantID, rb.Data)
+			}
+		})
+	}
+}
+
+func TestCreateRole_MalformedBody(t *testing.T) {
+	a := assert.New(t)
+	svc := new(MockService)
+	server := newTestServer(svc, web.IdmAdmin)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/roles", strings.NewReader(`{"name":`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.App.Test(req, -1)
+	a.NoError(err)
+	a.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	// текст ошибки разбора JSON клиенту не передаётся
+	data, _ := io.ReadAll(resp.Body)
+	var rb common.Response[any]
+	a.NoError(json.Unmarshal(data, &rb))
+	a.Equal(common.InvalidBodyMessage, rb.Message)
+	svc.AssertNotCalled(t, "SaveWithTransaction", mock.Anything, mock.Anything)
+}
+
+func TestGetRole(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		name       string
+		url        string
+		mockSetup  func(*MockService)
+		wantStatus int
+	}{
+		{
+			name: "should return role",
+			url:  "/api/v1/roles/3",
+			mockSetup: func(svc *MockService) {
+				svc.On("FindById", mock.Anything, int64(3)).Return(Response{Id: 3, Name: "R"}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should return bad request on invalid id",
+			url:        "/api/v1/roles/abc",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "should return internal error on service failure",
+			url:  "/api/v1/roles/4",
+			mockSetup: func(svc *MockService) {
+				svc.On("FindById", mock.Anything, int64(4)).Return(Response{}, errors.New("fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockService)
+			if tt.mockSetup != nil {
+				tt.mockSetup(svc)
+			}
+			server := newTestServer(svc, web.IdmUser)
+
+			resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, tt.url, nil), -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestGetRolesPageValidation(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		statusCode int
+	}{
+		{"page size too small", "?pageSize=0&pageNumber=1", fiber.StatusBadRequest},
+		{"page size too large", "?pageSize=101&pageNumber=1", fiber.StatusBadRequest},
+		{"page number negative", "?pageSize=10&pageNumber=-1", fiber.StatusBadRequest},
+		{"bad query params", "?pageSize=bad", fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(NewService(new(MockRepo), nil), web.IdmUser)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/roles/page"+tt.query, nil)
+			resp, err := server.App.Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.statusCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestDeleteRoles(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("delete by id", func(t *testing.T) {
+		svc := new(MockService)
+		svc.On("DeleteById", mock.Anything, int64(9)).Return(nil)
+		server := newTestServer(svc, web.IdmAdmin)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/9", nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertCalled(t, "DeleteById", mock.Anything, int64(9))
+	})
+
+	t.Run("delete by ids", func(t *testing.T) {
+		svc := new(MockService)
+		svc.On("DeleteByIds", mock.Anything, []int64{1, 2}).Return(nil)
+		server := newTestServer(svc, web.IdmAdmin)
+
+		req := httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles", strings.NewReader(`[1,2]`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req, -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertCalled(t, "DeleteByIds", mock.Anything, []int64{1, 2})
+	})
+
+	t.Run("delete missing role", func(t *testing.T) {
+		svc := new(MockService)
+		svc.On("DeleteById", mock.Anything, int64(10)).Return(common.NotFoundError{Message: "role with id 10 not found"})
+		server := newTestServer(svc, web.IdmAdmin)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/10", nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("user cannot delete", func(t *testing.T) {
+		svc := new(MockService)
+		server := newTestServer(svc, web.IdmUser)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/9", nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "DeleteById", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRestoreRole(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("restore", func(t *testing.T) {
+		svc := new(MockService)
+		svc.On("Restore", mock.Anything, int64(9)).Return(Response{Id: 9, Name: "auditor"}, nil)
+		server := newTestServer(svc, web.IdmAdmin)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/roles/9/restore", nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		data, _ := io.ReadAll(resp.Body)
+		var rb common.Response[Response]
+		a.NoError(json.Unmarshal(data, &rb))
+		a.Equal("auditor", rb.Data.Name)
+		a.Empty(rb.Data.DeletedAt)
+	})
+
+	t.Run("role is not deleted", func(t *testing.T) {
+		svc := new(MockService)
+		svc.On("Restore", mock.Anything, int64(10)).
+			Return(Response{}, common.NotFoundError{Message: "deleted role with id 10 not found"})
+		server := newTestServer(svc, web.IdmAdmin)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/roles/10/restore", nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("user cannot restore", func(t *testing.T) {
+		svc := new(MockService)
+		server := newTestServer(svc, web.IdmUser)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/roles/9/restore", nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSetRoleOwner(t *testing.T) {
+	a := assert.New(t)
+	ownerId := int64(5)
+
+	t.Run("admin sets owner", func(t *testing.T) {
+		svc := new(MockService)
+		svc.On("SetOwner", mock.Anything, int64(3), OwnerRequest{OwnerId: &ownerId}).
+			Return(Response{Id: 3, Name: "deployer", OwnerId: &ownerId}, nil)
+		server := newTestServer(svc, web.IdmAdmin)
+
+		req := httptest.NewRequest(fiber.MethodPut, "/api/v1/roles/3/owner", strings.NewReader(`{"owner_id":5}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req, -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		data, _ := io.ReadAll(resp.Body)
+		var rb common.Response[Response]
+		a.NoError(json.Unmarshal(data, &rb))
+		a.Equal(&ownerId, rb.Data.OwnerId)
+	})
+
+	t.Run("user cannot set owner", func(t *testing.T) {
+		svc := new(MockService)
+		server := newTestServer(svc, web.IdmUser)
+
+		req := httptest.NewRequest(fiber.MethodPut, "/api/v1/roles/3/owner", strings.NewReader(`{"owner_id":5}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req, -1)
+		a.NoError(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "SetOwner", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetRolesPage_IncludeDeleted(t *testing.T) {
+	a := assert.New(t)
+	const url = "/api/v1/roles/page?pageSize=10&pageNumber=0&includeDeleted=true"
+
+	t.Run("admin sees deleted roles", func(t *testing.T) {
+		svc := new(MockService)
+		svc.On("GetRolesPage", mock.Anything, PageRequest{PageSize: 10, IncludeDeleted: true}).Return(PageResponse{}, nil)
+		server := newTestServer(svc, web.IdmAdmin)
+
+		resp, err := server.App.Test(httptest.NewRequest(http.MethodGet, url, nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("user is forbidden", func(t *testing.T) {
+		svc := new(MockService)
+		server := newTestServer(svc, web.IdmUser)
+
+		resp, err := server.App.Test(httptest.NewRequest(http.MethodGet, url, nil), -1)
+		a.NoError(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "GetRolesPage", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetRole_PassesRequestContext(t *testing.T) {
+	a := assert.New(t)
+	svc := new(MockService)
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+	svc.On("FindById", hasDeadline, int64(3)).Return(Response{Id: 3, Name: "R"}, nil)
+
+	server := web.NewServer()
+	server.App.Use(server.RequestContext(time.Second))
+	server.GroupApiV1.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmUser}}}})
+		return c.Next()
+	})
+	NewController(server, svc, common.NewLogger(common.GetConfig(".env"))).RegisterRoutes()
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/3", nil), -1)
+	a.NoError(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
+
+// policyMiddleware применяет к тестовому серверу ту же политику доступа, что и в приложении
+func policyMiddleware() fiber.Handler {
+	routePolicy, err := policy.Load("../../policies.yaml")
+	if err != nil {
+		panic(err)
+	}
+	enforcer, err := policy.NewEnforcer(routePolicy, "")
+	if err != nil {
+		panic(err)
+	}
+	return enforcer.Middleware()
+}
@@ -5,13 +5,67 @@ type Response struct {
 	Name      string `json:"name"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
+	DeletedAt string `json:"deleted_at,omitempty"`
+	DeletedBy string `json:"deleted_by,omitempty"`
+	OwnerId   *int64 `json:"owner_id,omitempty"`
 }
 
 func (e *Entity) toResponse() Response {
-	return Response{
+	resp := Response{
 		Id:        e.Id,
 		Name:      e.Name,
 		CreatedAt: e.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt: e.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		OwnerId:   e.OwnerId,
 	}
+	if e.DeletedAt != nil {
+		resp.DeletedAt = e.DeletedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if e.DeletedBy != nil {
+		resp.DeletedBy = *e.DeletedBy
+	}
+	return resp
+}
+
+type CreateRequest struct {
+	Name    string `json:"name" validate:"required,min=2,max=155"`
+	OwnerId *int64 `json:"owner_id,omitempty" validate:"omitempty,gt=0"`
+}
+
+func (req *CreateRequest) ToEntity() *Entity {
+	return &Entity{Name: req.Name, OwnerId: req.OwnerId}
+}
+
+// OwnerRequest - новый владелец роли; без owner_id владелец снимается
+type OwnerRequest struct {
+	OwnerId *int64 `json:"owner_id" validate:"omitempty,gt=0"`
+}
+
+type PageRequest struct {
+	PageSize   int `validate:"min=1,max=100"`
+	PageNumber int `validate:"min=0"`
+	TextFilter string
+	// IncludeDeleted - показывать и мягко удалённые роли (только для администраторов)
+	IncludeDeleted bool
+}
+
+type PageResponse struct {
+	Result     any   `json:"result"`
+	PageSize   int   `json:"page_size"`
+	PageNumber int   `json:"page_number"`
+	Total      int64 `json:"total"`
+}
+
+// auditEntityType - тип сущности роли в журнале аудита
+const auditEntityType = "role"
+
+// auditState - состояние роли, которое сохраняется в журнале аудита
+type auditState struct {
+	Id      int64  `json:"id"`
+	Name    string `json:"name"`
+	OwnerId *int64 `json:"owner_id,omitempty"`
+}
+
+func (e *Entity) toAuditState() auditState {
+	return auditState{Id: e.Id, Name: e.Name, OwnerId: e.OwnerId}
 }
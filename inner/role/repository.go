@@ -1,7 +1,15 @@
 package role
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/jmoiron/sqlx"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/tracing"
+	"strings"
 	"time"
 )
 
@@ -18,47 +26,277 @@ type Entity struct {
 	Name      string    `db:"name"`
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
+	// DeletedAt и DeletedBy заполнены у мягко удалённых ролей
+	DeletedAt *time.Time `db:"deleted_at"`
+	DeletedBy *string    `db:"deleted_by"`
+	// OwnerId - сотрудник, отвечающий за роль: согласует заявки на неё и пересматривает её назначения
+	OwnerId *int64 `db:"owner_id"`
 }
 
-func (r *Repository) Add(role *Entity) error {
-	_, err := r.db.NamedExec(`INSERT INTO role (name, created_at, updated_at) 
+func (r *Repository) Add(ctx context.Context, role *Entity) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.Add")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	_, err = r.db.NamedExecContext(ctx, `INSERT INTO role (name, created_at, updated_at) 
 		VALUES (:name, :created_at, :updated_at)`, role)
 	return err
 }
 
-func (r *Repository) FindById(id int64) (*Entity, error) {
+func (r *Repository) FindById(ctx context.Context, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.FindById")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
 	var entity Entity
-	err := r.db.Get(&entity, "SELECT * FROM role WHERE id = $1", id)
+	err = r.db.GetContext(ctx, &entity, "SELECT * FROM role WHERE id = $1 AND deleted_at IS NULL", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", id)}
+	}
 	return &entity, err
 }
 
-func (r *Repository) FindAll() (roles []Entity, err error) {
-	err = r.db.Select(&roles, "SELECT * FROM role")
+func (r *Repository) FindAll(ctx context.Context) (roles []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.FindAll")
+	defer func() { span.Finish(int64(len(roles)), err) }()
+	err = r.db.SelectContext(ctx, &roles, "SELECT * FROM role WHERE deleted_at IS NULL")
 	return roles, err
 }
 
-func (r *Repository) FindByIds(ids []int64) ([]Entity, error) {
-	query, args, err := sqlx.In("SELECT * FROM role WHERE id IN (?)", ids)
+func (r *Repository) FindByIds(ctx context.Context, ids []int64) (roles []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.FindByIds")
+	defer func() { span.Finish(int64(len(roles)), err) }()
+	query, args, err := sqlx.In("SELECT * FROM role WHERE id IN (?) AND deleted_at IS NULL", ids)
 	if err != nil {
 		return nil, err
 	}
 	query = r.db.Rebind(query)
-	var roles []Entity
-	err = r.db.Select(&roles, query, args...)
+	err = r.db.SelectContext(ctx, &roles, query, args...)
 	return roles, err
 }
 
-func (r *Repository) DeleteById(id int64) error {
-	_, err := r.db.Exec("DELETE FROM role WHERE id = $1", id)
+// DeleteById мягко удаляет роль: запись остаётся в БД с заполненными deleted_at и deleted_by
+func (r *Repository) DeleteById(ctx context.Context, id int64) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "role.DeleteById")
+	defer func() { span.Finish(affected, err) }()
+	result, err := r.db.ExecContext(ctx, "UPDATE role SET deleted_at = now(), deleted_by = $1 WHERE id = $2 AND deleted_at IS NULL",
+		common.ActorFromContext(ctx), id)
+	if err != nil {
+		return err
+	}
+	affected, err = checkAffected(result, fmt.Sprintf("role with id %d not found", id))
 	return err
 }
 
-func (r *Repository) DeleteByIds(ids []int64) error {
-	query, args, err := sqlx.In("DELETE FROM role WHERE id IN (?)", ids)
+// DeleteByIds мягко удаляет роли
+func (r *Repository) DeleteByIds(ctx context.Context, ids []int64) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "role.DeleteByIds")
+	defer func() { span.Finish(affected, err) }()
+	query, args, err := sqlx.In("UPDATE role SET deleted_at = now(), deleted_by = ? WHERE id IN (?) AND deleted_at IS NULL",
+		common.ActorFromContext(ctx), ids)
 	if err != nil {
 		return err
 	}
 	query = r.db.Rebind(query)
-	_, err = r.db.Exec(query, args...)
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err = checkAffected(result, fmt.Sprintf("roles with ids %v not found", ids))
+	return err
+}
+
+func (r *Repository) BeginTransaction(ctx context.Context) (tx *sqlx.Tx, err error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.FindByNameTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(
+		ctx,
+		&isExists,
+		"select exists(select 1 from role where name = $1 and deleted_at is null)",
+		name,
+	)
+	return isExists, err
+}
+
+func (r *Repository) SaveTx(ctx context.Context, tx *sqlx.Tx, role *Entity) (roleId int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.SaveTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(
+		ctx,
+		&roleId,
+		`insert into role (name, owner_id) values ($1, $2) returning id`,
+		role.Name, role.OwnerId,
+	)
+	return roleId, err
+}
+
+// EmployeeExistsTx проверяет, что сотрудник, назначаемый владельцем роли, существует и не удалён
+func (r *Repository) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (isExists bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.EmployeeExistsTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	err = tx.GetContext(ctx, &isExists,
+		"select exists(select 1 from employee where id = $1 and deleted_at is null)", employeeId)
+	return isExists, err
+}
+
+// UpdateOwnerTx меняет владельца роли; nil снимает владельца
+func (r *Repository) UpdateOwnerTx(ctx context.Context, tx *sqlx.Tx, id int64, ownerId *int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.UpdateOwnerTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		`UPDATE role SET owner_id = $1, updated_at = now() WHERE id = $2 RETURNING *`, ownerId, id)
+	return &entity, err
+}
+
+// FindByIdForUpdateTx читает роль и блокирует строку до конца транзакции
+func (r *Repository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.FindByIdForUpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity, "SELECT * FROM role WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", id)}
+	}
+	return &entity, err
+}
+
+// FindByIdsForUpdateTx читает роли по id и блокирует их строки до конца транзакции
+func (r *Repository) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) (roles []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.FindByIdsForUpdateTx")
+	defer func() { span.Finish(int64(len(roles)), err) }()
+	query, args, err := sqlx.In("SELECT * FROM role WHERE id IN (?) AND deleted_at IS NULL ORDER BY id FOR UPDATE", ids)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.SelectContext(ctx, &roles, tx.Rebind(query), args...)
+	return roles, err
+}
+
+// DeleteByIdTx мягко удаляет роль в транзакции
+func (r *Repository) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "role.DeleteByIdTx")
+	defer func() { span.Finish(affected, err) }()
+	result, err := tx.ExecContext(ctx, "UPDATE role SET deleted_at = now(), deleted_by = $1 WHERE id = $2 AND deleted_at IS NULL",
+		common.ActorFromContext(ctx), id)
+	if err != nil {
+		return err
+	}
+	affected, err = checkAffected(result, fmt.Sprintf("role with id %d not found", id))
 	return err
 }
+
+// DeleteByIdsTx мягко удаляет роли в транзакции
+func (r *Repository) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) (err error) {
+	var affected int64
+	ctx, span := tracing.StartQuery(ctx, "role.DeleteByIdsTx")
+	defer func() { span.Finish(affected, err) }()
+	query, args, err := sqlx.In("UPDATE role SET deleted_at = now(), deleted_by = ? WHERE id IN (?) AND deleted_at IS NULL",
+		common.ActorFromContext(ctx), ids)
+	if err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	affected, err = checkAffected(result, fmt.Sprintf("roles with ids %v not found", ids))
+	return err
+}
+
+// FindDeletedByIdForUpdateTx читает мягко удалённую роль и блокирует строку до конца транзакции
+func (r *Repository) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.FindDeletedByIdForUpdateTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity, "SELECT * FROM role WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("deleted role with id %d not found", id)}
+	}
+	return &entity, err
+}
+
+// RestoreTx снимает с роли отметку об удалении
+func (r *Repository) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (_ *Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.RestoreTx")
+	defer func() { span.Finish(database.FoundRows(err), err) }()
+	var entity Entity
+	err = tx.GetContext(ctx, &entity,
+		`UPDATE role SET deleted_at = NULL, deleted_by = NULL, updated_at = now() WHERE id = $1 RETURNING *`, id)
+	return &entity, err
+}
+
+// PurgeDeletedTx окончательно удаляет роли, мягко удалённые раньше before, и возвращает их
+func (r *Repository) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, before time.Time) (roles []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.PurgeDeletedTx")
+	defer func() { span.Finish(int64(len(roles)), err) }()
+	err = tx.SelectContext(ctx, &roles, "DELETE FROM role WHERE deleted_at < $1 RETURNING *", before)
+	return roles, err
+}
+
+func (r *Repository) FindRolesPage(ctx context.Context, req PageRequest) (entities []Entity, total int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.FindRolesPage")
+	defer func() { span.Finish(int64(len(entities)), err) }()
+	var offset = req.PageNumber * req.PageSize
+	var limit = req.PageSize
+	var partQueryFilter = ""
+	if len(strings.TrimSpace(req.TextFilter)) >= 3 {
+		partQueryFilter = req.TextFilter
+	}
+	err = r.db.SelectContext(ctx, &entities,
+		`SELECT * FROM role WHERE ($1 = '' OR name ILIKE '%' || $1 || '%') AND ($4 OR deleted_at IS NULL)
+		ORDER BY id LIMIT $2 OFFSET $3`, partQueryFilter, limit, offset, req.IncludeDeleted)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = r.db.GetContext(ctx, &total,
+		`SELECT COUNT(*) FROM role where ($1 = '' OR name ILIKE '%' || $1 || '%') AND ($2 OR deleted_at IS NULL)`,
+		partQueryFilter, req.IncludeDeleted)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entities, total, nil
+}
+
+// FindExistingIds возвращает те id из переданных, для которых существует неудалённая роль.
+// db - подключение или транзакция: по нему другие пакеты проверяют роли, не обращаясь к таблице role напрямую
+func FindExistingIds(ctx context.Context, db sqlx.ExtContext, roleIds []int64) (ids []int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.FindExistingIds")
+	defer func() { span.Finish(int64(len(ids)), err) }()
+	query, args, err := sqlx.In("SELECT id FROM role WHERE id IN (?) AND deleted_at IS NULL", roleIds)
+	if err != nil {
+		return nil, err
+	}
+	err = sqlx.SelectContext(ctx, db, &ids, db.Rebind(query), args...)
+	return ids, err
+}
+
+// FindByEmployeeId возвращает роли, назначенные сотруднику, назначение которых действует сейчас
+func (r *Repository) FindByEmployeeId(ctx context.Context, employeeId int64) (roles []Entity, err error) {
+	ctx, span := tracing.StartQuery(ctx, "role.FindByEmployeeId")
+	defer func() { span.Finish(int64(len(roles)), err) }()
+	err = r.db.SelectContext(ctx, &roles,
+		`SELECT r.* FROM role r JOIN employee_role er ON er.role_id = r.id
+		WHERE er.employee_id = $1 AND r.deleted_at IS NULL
+		AND er.valid_from <= now() AND (er.valid_to IS NULL OR er.valid_to > now())
+		ORDER BY r.id`,
+		employeeId)
+	return roles, err
+}
+
+// checkAffected возвращает число затронутых строк и NotFoundError, если запрос не затронул ни одной
+func checkAffected(result sql.Result, message string) (int64, error) {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, common.NotFoundError{Message: message}
+	}
+	return affected, nil
+}
@@ -1,40 +1,81 @@
 package role
 
 import (
+	"context"
 	"fmt"
+	"github.com/jmoiron/sqlx"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/metrics"
+	"idm/inner/tracing"
+	"idm/inner/validator"
+	"time"
 )
 
 type Service struct {
-	repo Repo
+	repo      Repo
+	auditor   Auditor
+	validator *validator.Validator
+}
+
+// Auditor записывает изменения ролей в журнал аудита в той же транзакции, что и само изменение
+type Auditor interface {
+	RecordTx(ctx context.Context, tx *sqlx.Tx, record audit.Record) error
 }
 
 type Repo interface {
-	Add(e *Entity) error
-	FindById(id int64) (*Entity, error)
-	FindAll() ([]Entity, error)
-	FindByIds(ids []int64) ([]Entity, error)
-	DeleteById(id int64) error
-	DeleteByIds(ids []int64) error
+	FindById(ctx context.Context, id int64) (*Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	FindByIds(ctx context.Context, ids []int64) ([]Entity, error)
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+	FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error)
+	SaveTx(ctx context.Context, tx *sqlx.Tx, role *Entity) (int64, error)
+	FindRolesPage(ctx context.Context, req PageRequest) ([]Entity, int64, error)
+	FindByEmployeeId(ctx context.Context, employeeId int64) ([]Entity, error)
+	FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error)
+	FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error)
+	DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error
+	DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error
+	FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error)
+	RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error)
+	PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, before time.Time) ([]Entity, error)
+	EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (bool, error)
+	UpdateOwnerTx(ctx context.Context, tx *sqlx.Tx, id int64, ownerId *int64) (*Entity, error)
 }
 
-func NewService(repo Repo) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repo, auditor Auditor) *Service {
+	return &Service{repo: repo, auditor: auditor, validator: validator.New()}
 }
 
-func (svc *Service) Add(e Entity) error {
-	return svc.repo.Add(&e)
+func (svc *Service) Add(ctx context.Context, e Entity) error {
+	ctx, span := tracing.Start(ctx, "role.Service.Add")
+	defer span.End()
+	err := database.InTransaction(ctx, svc.repo, "adding role", func(tx *sqlx.Tx) error {
+		_, err := svc.createTx(ctx, tx, &Entity{Name: e.Name, OwnerId: e.OwnerId})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	metrics.RolesCreated.Inc()
+	return nil
 }
 
-func (svc *Service) FindById(id int64) (Response, error) {
-	e, err := svc.repo.FindById(id)
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	ctx, span := tracing.Start(ctx, "role.Service.FindById")
+	defer span.End()
+	e, err := svc.repo.FindById(ctx, id)
 	if err != nil {
 		return Response{}, fmt.Errorf("failed to find role with id %d: %w", id, err)
 	}
 	return e.toResponse(), nil
 }
 
-func (svc *Service) FindAll() ([]Response, error) {
-	entities, err := svc.repo.FindAll()
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "role.Service.FindAll")
+	defer span.End()
+	entities, err := svc.repo.FindAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -45,8 +86,10 @@ func (svc *Service) FindAll() ([]Response, error) {
 	return result, nil
 }
 
-func (svc *Service) FindByIds(ids []int64) ([]Response, error) {
-	entities, err := svc.repo.FindByIds(ids)
+func (svc *Service) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "role.Service.FindByIds")
+	defer span.End()
+	entities, err := svc.repo.FindByIds(ctx, ids)
 	if err != nil {
 		return nil, err
 	}
@@ -57,10 +100,267 @@ func (svc *Service) FindByIds(ids []int64) ([]Response, error) {
 	return result, nil
 }
 
-func (svc *Service) DeleteById(id int64) error {
-	return svc.repo.DeleteById(id)
+func (svc *Service) DeleteById(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "role.Service.DeleteById")
+	defer span.End()
+	err := database.InTransaction(ctx, svc.repo, "deleting role", func(tx *sqlx.Tx) error {
+		current, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding role with id %d: %w", id, err)
+		}
+		if err = svc.repo.DeleteByIdTx(ctx, tx, id); err != nil {
+			return fmt.Errorf("error deleting role with id %d: %w", id, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionDelete, id, current.toAuditState(), nil)
+	})
+	if err != nil {
+		return err
+	}
+	metrics.RolesDeleted.Inc()
+	return nil
+}
+
+// DeleteByIds мягко удаляет роли; если какой-то из них нет, не удаляется ни одна и возвращается NotFoundError
+func (svc *Service) DeleteByIds(ctx context.Context, ids []int64) error {
+	ctx, span := tracing.Start(ctx, "role.Service.DeleteByIds")
+	defer span.End()
+	var deleted []Entity
+	err := database.InTransaction(ctx, svc.repo, "deleting roles", func(tx *sqlx.Tx) (err error) {
+		deleted, err = svc.repo.FindByIdsForUpdateTx(ctx, tx, ids)
+		if err != nil {
+			return fmt.Errorf("error finding roles with ids %v: %w", ids, err)
+		}
+		if missing := common.MissingIds(ids, entityIds(deleted)); len(missing) > 0 {
+			return common.NotFoundError{Message: fmt.Sprintf("roles with ids %v not found", missing)}
+		}
+		if err = svc.repo.DeleteByIdsTx(ctx, tx, ids); err != nil {
+			return fmt.Errorf("error deleting roles with ids %v: %w", ids, err)
+		}
+		for i := range deleted {
+			if err = svc.recordTx(ctx, tx, audit.ActionDelete, deleted[i].Id, deleted[i].toAuditState(), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	metrics.RolesDeleted.Add(float64(len(deleted)))
+	return nil
+}
+
+// Restore снимает с роли отметку об удалении.
+// Если имя за это время заняла другая роль, возвращается AlreadyExistsError
+func (svc *Service) Restore(ctx context.Context, id int64) (Response, error) {
+	ctx, span := tracing.Start(ctx, "role.Service.Restore")
+	defer span.End()
+	var restored *Entity
+	err := database.InTransaction(ctx, svc.repo, "restoring role", func(tx *sqlx.Tx) error {
+		deleted, err := svc.repo.FindDeletedByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding deleted role with id %d: %w", id, err)
+		}
+		isExist, err := svc.repo.FindByNameTx(ctx, tx, deleted.Name)
+		if err != nil {
+			return fmt.Errorf("error finding role by name: %s, %w", deleted.Name, err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{Message: "role already exists"}
+		}
+		restored, err = svc.repo.RestoreTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error restoring role with id %d: %w", id, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionRestore, id, nil, restored.toAuditState())
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return restored.toResponse(), nil
+}
+
+// Purge окончательно удаляет роли, мягко удалённые раньше before, и возвращает их число
+func (svc *Service) Purge(ctx context.Context, before time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "role.Service.Purge")
+	defer span.End()
+	var purged []Entity
+	err := database.InTransaction(ctx, svc.repo, "purging roles", func(tx *sqlx.Tx) (err error) {
+		purged, err = svc.repo.PurgeDeletedTx(ctx, tx, before)
+		if err != nil {
+			return fmt.Errorf("error purging roles deleted before %s: %w", before.Format(time.RFC3339), err)
+		}
+		for i := range purged {
+			if err = svc.recordTx(ctx, tx, audit.ActionPurge, purged[i].Id, purged[i].toAuditState(), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(purged), nil
+}
+
+// SaveWithTransaction проверяет дубликаты и создаёт роль в рамках одной транзакции.
+func (svc *Service) SaveWithTransaction(ctx context.Context, req CreateRequest) (roleId int64, err error) {
+	ctx, span := tracing.Start(ctx, "role.Service.SaveWithTransaction")
+	defer span.End()
+	if err = svc.validator.Validate(req); err != nil {
+		return 0, err
+	}
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error creating transaction: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("creating role panic: %v", r)
+			if errTx := tx.Rollback(); errTx != nil {
+				err = fmt.Errorf("creating role: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			if errTx := tx.Rollback(); errTx != nil {
+				err = fmt.Errorf("creating role: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			if errTx := tx.Commit(); errTx != nil {
+				err = fmt.Errorf("creating role: commiting transaction error: %w", errTx)
+			} else {
+				metrics.RolesCreated.Inc()
+			}
+		}
+	}()
+	isExist, err := svc.repo.FindByNameTx(ctx, tx, req.Name)
+	if err != nil {
+		return 0, fmt.Errorf("error finding role by name: %s, %w", req.Name, err)
+	}
+	if isExist {
+		err = common.AlreadyExistsError{Message: "role already exists"}
+		return 0, err
+	}
+	roleId, err = svc.createTx(ctx, tx, req.ToEntity())
+	return roleId, err
+}
+
+// SetOwner назначает роли владельца или снимает его
+func (svc *Service) SetOwner(ctx context.Context, id int64, req OwnerRequest) (Response, error) {
+	ctx, span := tracing.Start(ctx, "role.Service.SetOwner")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return Response{}, err
+	}
+	var updated *Entity
+	err := database.InTransaction(ctx, svc.repo, "changing role owner", func(tx *sqlx.Tx) error {
+		current, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding role with id %d: %w", id, err)
+		}
+		if err = svc.checkOwnerTx(ctx, tx, req.OwnerId); err != nil {
+			return err
+		}
+		updated, err = svc.repo.UpdateOwnerTx(ctx, tx, id, req.OwnerId)
+		if err != nil {
+			return fmt.Errorf("error changing owner of role with id %d: %w", id, err)
+		}
+		return svc.recordTx(ctx, tx, audit.ActionUpdate, id, current.toAuditState(), updated.toAuditState())
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return updated.toResponse(), nil
+}
+
+func (svc *Service) GetRolesPage(ctx context.Context, req PageRequest) (PageResponse, error) {
+	ctx, span := tracing.Start(ctx, "role.Service.GetRolesPage")
+	defer span.End()
+	if err := svc.validator.Validate(req); err != nil {
+		return PageResponse{}, err
+	}
+	entities, total, err := svc.repo.FindRolesPage(ctx, req)
+	if err != nil {
+		return PageResponse{}, err
+	}
+
+	var respItems []Response
+	for _, e := range entities {
+		respItems = append(respItems, e.toResponse())
+	}
+
+	return PageResponse{
+		Result:     respItems,
+		PageSize:   req.PageSize,
+		PageNumber: req.PageNumber,
+		Total:      total,
+	}, nil
+}
+
+// FindByEmployeeId возвращает роли, назначенные сотруднику
+func (svc *Service) FindByEmployeeId(ctx context.Context, employeeId int64) ([]Response, error) {
+	ctx, span := tracing.Start(ctx, "role.Service.FindByEmployeeId")
+	defer span.End()
+	entities, err := svc.repo.FindByEmployeeId(ctx, employeeId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find roles of employee with id %d: %w", employeeId, err)
+	}
+	var result []Response
+	for _, e := range entities {
+		result = append(result, e.toResponse())
+	}
+	return result, nil
+}
+
+// createTx создаёт роль и записывает создание в журнал аудита
+func (svc *Service) createTx(ctx context.Context, tx *sqlx.Tx, role *Entity) (int64, error) {
+	if err := svc.checkOwnerTx(ctx, tx, role.OwnerId); err != nil {
+		return 0, err
+	}
+	id, err := svc.repo.SaveTx(ctx, tx, role)
+	if err != nil {
+		return 0, fmt.Errorf("error creating role with name: %s %w", role.Name, err)
+	}
+	if err = svc.recordTx(ctx, tx, audit.ActionCreate, id, nil, auditState{Id: id, Name: role.Name, OwnerId: role.OwnerId}); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// checkOwnerTx проверяет, что владелец роли (если задан) существует
+func (svc *Service) checkOwnerTx(ctx context.Context, tx *sqlx.Tx, ownerId *int64) error {
+	if ownerId == nil {
+		return nil
+	}
+	exists, err := svc.repo.EmployeeExistsTx(ctx, tx, *ownerId)
+	if err != nil {
+		return fmt.Errorf("error finding employee with id %d: %w", *ownerId, err)
+	}
+	if !exists {
+		return common.RequestValidationError{Message: fmt.Sprintf("owner employee %d not found", *ownerId)}
+	}
+	return nil
+}
+
+// recordTx записывает изменение роли в журнал аудита; before и after - nil, если состояния нет
+func (svc *Service) recordTx(ctx context.Context, tx *sqlx.Tx, action string, id int64, before any, after any) error {
+	err := svc.auditor.RecordTx(ctx, tx, audit.Record{
+		Action:     action,
+		EntityType: auditEntityType,
+		EntityId:   id,
+		Before:     before,
+		After:      after,
+	})
+	if err != nil {
+		return fmt.Errorf("error recording audit event for role with id %d: %w", id, err)
+	}
+	return nil
 }
 
-func (svc *Service) DeleteByIds(ids []int64) error {
-	return svc.repo.DeleteByIds(ids)
+// entityIds возвращает id ролей
+func entityIds(roles []Entity) []int64 {
+	ids := make([]int64, 0, len(roles))
+	for i := range roles {
+		ids = append(ids, roles[i].Id)
+	}
+	return ids
 }
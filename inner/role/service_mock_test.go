@@ -1,10 +1,16 @@
 package role
 
 import (
+	"context"
 	"errors"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"regexp"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -14,34 +20,118 @@ type MockRepo struct {
 	mock.Mock
 }
 
-func (m *MockRepo) Add(e *Entity) error {
-	return m.Called(e).Error(0)
+func (m *MockRepo) FindById(ctx context.Context, id int64) (*Entity, error) {
+	args := m.Called(ctx, id)
+	if ent, ok := args.Get(0).(*Entity); ok {
+		return ent, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindByIds(ctx context.Context, ids []int64) ([]Entity, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (m *MockRepo) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
+	args := m.Called(ctx, tx, name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) SaveTx(ctx context.Context, tx *sqlx.Tx, role *Entity) (int64, error) {
+	args := m.Called(ctx, tx, role)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (bool, error) {
+	args := m.Called(ctx, tx, employeeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) UpdateOwnerTx(ctx context.Context, tx *sqlx.Tx, id int64, ownerId *int64) (*Entity, error) {
+	args := m.Called(ctx, tx, id, ownerId)
+	return args.Get(0).(*Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindRolesPage(ctx context.Context, req PageRequest) ([]Entity, int64, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]Entity), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRepo) FindByEmployeeId(ctx context.Context, employeeId int64) ([]Entity, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) FindById(id int64) (*Entity, error) {
-	args := m.Called(id)
+func (m *MockRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	args := m.Called(ctx, tx, id)
 	if ent, ok := args.Get(0).(*Entity); ok {
 		return ent, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockRepo) FindAll() ([]Entity, error) {
-	args := m.Called()
+func (m *MockRepo) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error) {
+	args := m.Called(ctx, tx, ids)
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) FindByIds(ids []int64) ([]Entity, error) {
-	args := m.Called(ids)
+func (m *MockRepo) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	return m.Called(ctx, tx, id).Error(0)
+}
+
+func (m *MockRepo) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error {
+	return m.Called(ctx, tx, ids).Error(0)
+}
+
+func (m *MockRepo) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	args := m.Called(ctx, tx, id)
+	if ent, ok := args.Get(0).(*Entity); ok {
+		return ent, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	args := m.Called(ctx, tx, id)
+	if ent, ok := args.Get(0).(*Entity); ok {
+		return ent, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, before time.Time) ([]Entity, error) {
+	args := m.Called(ctx, tx, before)
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) DeleteById(id int64) error {
-	return m.Called(id).Error(0)
+// stubAuditor запоминает события аудита вместо записи в БД
+type stubAuditor struct {
+	records []audit.Record
 }
 
-func (m *MockRepo) DeleteByIds(ids []int64) error {
-	return m.Called(ids).Error(0)
+func (a *stubAuditor) RecordTx(ctx context.Context, tx *sqlx.Tx, record audit.Record) error {
+	a.records = append(a.records, record)
+	return nil
+}
+
+// newSqlmockService создаёт сервис с настоящим репозиторием поверх sqlmock
+func newSqlmockService(t *testing.T) (*Service, sqlmock.Sqlmock, *stubAuditor) {
+	dbMock, m, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = dbMock.Close() })
+	auditor := &stubAuditor{}
+	return NewService(NewRoleRepository(sqlx.NewDb(dbMock, "postgres")), auditor), m, auditor
 }
 
 // ---- 2. Тесты для Service ----
@@ -53,37 +143,29 @@ func TestService_AllMethods_WithMock(t *testing.T) {
 	ents := []Entity{*ent1, *ent2}
 
 	repo := new(MockRepo)
-	svc := NewService(repo)
-
-	t.Run("Add calls repo.Add", func(t *testing.T) {
-		e := Entity{Id: 0, Name: "Guest"}
-		repo.On("Add", &e).Return(nil)
-		err := svc.Add(e)
-		assert.NoError(t, err)
-		repo.AssertCalled(t, "Add", &e)
-	})
+	svc := NewService(repo, &stubAuditor{})
 
 	t.Run("FindById success", func(t *testing.T) {
-		repo.On("FindById", int64(1)).Return(ent1, nil)
-		resp, err := svc.FindById(1)
+		repo.On("FindById", mock.Anything, int64(1)).Return(ent1, nil)
+		resp, err := svc.FindById(context.Background(), 1)
 		assert.NoError(t, err)
 		assert.Equal(t, int64(1), resp.Id)
 		assert.Equal(t, "Admin", resp.Name)
-		repo.AssertCalled(t, "FindById", int64(1))
+		repo.AssertCalled(t, "FindById", mock.Anything, int64(1))
 	})
 
 	t.Run("FindById error", func(t *testing.T) {
 		repoErr := errors.New("not found")
-		repo.On("FindById", int64(99)).Return((*Entity)(nil), repoErr)
-		_, err := svc.FindById(99)
+		repo.On("FindById", mock.Anything, int64(99)).Return((*Entity)(nil), repoErr)
+		_, err := svc.FindById(context.Background(), 99)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to find role")
-		repo.AssertCalled(t, "FindById", int64(99))
+		repo.AssertCalled(t, "FindById", mock.Anything, int64(99))
 	})
 
 	t.Run("FindAll returns all", func(t *testing.T) {
-		repo.On("FindAll").Return(ents, nil)
-		resps, err := svc.FindAll()
+		repo.On("FindAll", mock.Anything).Return(ents, nil)
+		resps, err := svc.FindAll(context.Background())
 		assert.NoError(t, err)
 		assert.Len(t, resps, 2)
 		assert.Equal(t, int64(1), resps[0].Id)
@@ -93,25 +175,305 @@ func TestService_AllMethods_WithMock(t *testing.T) {
 
 	t.Run("FindByIds filters", func(t *testing.T) {
 		ids := []int64{1, 2}
-		repo.On("FindByIds", ids).Return(ents, nil)
-		resps, err := svc.FindByIds(ids)
+		repo.On("FindByIds", mock.Anything, ids).Return(ents, nil)
+		resps, err := svc.FindByIds(context.Background(), ids)
 		assert.NoError(t, err)
 		assert.Len(t, resps, 2)
-		repo.AssertCalled(t, "FindByIds", ids)
+		repo.AssertCalled(t, "FindByIds", mock.Anything, ids)
 	})
+}
+
+func TestService_Add(t *testing.T) {
+	svc, m, auditor := newSqlmockService(t)
+	m.ExpectBegin()
+	m.ExpectQuery(regexp.QuoteMeta("insert into role (name, owner_id) values ($1, $2) returning id")).
+		WithArgs("Guest", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	m.ExpectCommit()
+
+	assert.NoError(t, svc.Add(context.Background(), Entity{Name: "Guest"}))
+	assert.NoError(t, m.ExpectationsWereMet())
+	assert.Equal(t, []audit.Record{{
+		Action:     audit.ActionCreate,
+		EntityType: "role",
+		EntityId:   3,
+		After:      auditState{Id: 3, Name: "Guest"},
+	}}, auditor.records)
+}
+
+func TestService_DeleteById(t *testing.T) {
+	columns := []string{"id", "name", "created_at", "updated_at"}
+	now := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM role WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "User", now, now))
+		m.ExpectExec(regexp.QuoteMeta("UPDATE role SET deleted_at = now(), deleted_by = $1 WHERE id = $2 AND deleted_at IS NULL")).
+			WithArgs("", int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		m.ExpectCommit()
+
+		assert.NoError(t, svc.DeleteById(context.Background(), 2))
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Equal(t, []audit.Record{{
+			Action:     audit.ActionDelete,
+			EntityType: "role",
+			EntityId:   2,
+			Before:     auditState{Id: 2, Name: "User"},
+		}}, auditor.records)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM role WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")).
+			WithArgs(int64(404)).
+			WillReturnRows(sqlmock.NewRows(columns))
+		m.ExpectRollback()
+
+		err := svc.DeleteById(context.Background(), 404)
+		assert.ErrorAs(t, err, &common.NotFoundError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Empty(t, auditor.records)
+	})
+}
+
+func TestService_DeleteByIds(t *testing.T) {
+	columns := []string{"id", "name", "created_at", "updated_at"}
+	now := time.Now()
+
+	svc, m, auditor := newSqlmockService(t)
+	m.ExpectBegin()
+	m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM role WHERE id IN ($1, $2) AND deleted_at IS NULL ORDER BY id FOR UPDATE")).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Admin", now, now).AddRow(2, "User", now, now))
+	m.ExpectExec(regexp.QuoteMeta("UPDATE role SET deleted_at = now(), deleted_by = $1 WHERE id IN ($2, $3) AND deleted_at IS NULL")).
+		WithArgs("", int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	m.ExpectCommit()
+
+	assert.NoError(t, svc.DeleteByIds(context.Background(), []int64{1, 2}))
+	assert.NoError(t, m.ExpectationsWereMet())
+	assert.Len(t, auditor.records, 2)
+
+	t.Run("some missing", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM role WHERE id IN ($1, $2) AND deleted_at IS NULL ORDER BY id FOR UPDATE")).
+			WithArgs(int64(1), int64(404)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Admin", now, now))
+		m.ExpectRollback()
+
+		err := svc.DeleteByIds(context.Background(), []int64{1, 404})
+		var notFound common.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		assert.Contains(t, notFound.Message, "[404]")
+		assert.NoError(t, m.ExpectationsWereMet())
+	})
+}
+
+func TestService_SaveWithTransaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     CreateRequest
+		setup   func(m sqlmock.Sqlmock)
+		wantId  int64
+		wantErr string
+	}{
+		{
+			name:    "validation error",
+			req:     CreateRequest{Name: ""},
+			setup:   func(m sqlmock.Sqlmock) {},
+			wantErr: "name",
+		},
+		{
+			name: "duplicate role",
+			req:  CreateRequest{Name: "Admin"},
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from role where name = $1 and deleted_at is null)")).
+					WithArgs("Admin").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				m.ExpectRollback()
+			},
+			wantErr: "role already exists",
+		},
+		{
+			name: "insert error",
+			req:  CreateRequest{Name: "Admin"},
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from role where name = $1 and deleted_at is null)")).
+					WithArgs("Admin").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				m.ExpectQuery(regexp.QuoteMeta("insert into role (name, owner_id) values ($1, $2) returning id")).
+					WithArgs("Admin", nil).
+					WillReturnError(errors.New("insert failed"))
+				m.ExpectRollback()
+			},
+			wantErr: "error creating role with name",
+		},
+		{
+			name: "success creation",
+			req:  CreateRequest{Name: "Admin"},
+			setup: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from role where name = $1 and deleted_at is null)")).
+					WithArgs("Admin").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				m.ExpectQuery(regexp.QuoteMeta("insert into role (name, owner_id) values ($1, $2) returning id")).
+					WithArgs("Admin", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				m.ExpectCommit()
+			},
+			wantId: 7,
+		},
+	}
 
-	t.Run("DeleteById calls repo", func(t *testing.T) {
-		repo.On("DeleteById", int64(2)).Return(nil)
-		err := svc.DeleteById(2)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dbMock, m, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer dbMock.Close()
+
+			svc := NewService(NewRoleRepository(sqlx.NewDb(dbMock, "sqlmock")), &stubAuditor{})
+			tc.setup(m)
+
+			id, err := svc.SaveWithTransaction(context.Background(), tc.req)
+			if tc.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.wantId, id)
+			}
+			assert.NoError(t, m.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("validation error is typed", func(t *testing.T) {
+		_, err := NewService(new(MockRepo), nil).SaveWithTransaction(context.Background(), CreateRequest{})
+		var valErr common.RequestValidationError
+		assert.True(t, errors.As(err, &valErr))
+	})
+}
+
+func TestService_Restore(t *testing.T) {
+	deletedAt := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	version := deletedAt.Add(-time.Hour)
+	columns := []string{"id", "name", "created_at", "updated_at", "deleted_at", "deleted_by"}
+
+	t.Run("success", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM role WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE")).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "User", version, version, deletedAt, "admin"))
+		m.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from role where name = $1 and deleted_at is null)")).
+			WithArgs("User").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		m.ExpectQuery(regexp.QuoteMeta("UPDATE role SET deleted_at = NULL, deleted_by = NULL, updated_at = now() WHERE id = $1 RETURNING *")).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "User", version, deletedAt.Add(time.Hour), nil, nil))
+		m.ExpectCommit()
+
+		resp, err := svc.Restore(context.Background(), 2)
 		assert.NoError(t, err)
-		repo.AssertCalled(t, "DeleteById", int64(2))
+		assert.Equal(t, "User", resp.Name)
+		assert.Empty(t, resp.DeletedAt)
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Equal(t, []audit.Record{{
+			Action:     audit.ActionRestore,
+			EntityType: "role",
+			EntityId:   2,
+			After:      auditState{Id: 2, Name: "User"},
+		}}, auditor.records)
 	})
 
-	t.Run("DeleteByIds calls repo", func(t *testing.T) {
-		ids := []int64{1, 2}
-		repo.On("DeleteByIds", ids).Return(nil)
-		err := svc.DeleteByIds(ids)
+	t.Run("name is taken by another role", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(regexp.QuoteMeta("SELECT * FROM role WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE")).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "User", version, version, deletedAt, "admin"))
+		m.ExpectQuery(regexp.QuoteMeta("select exists(select 1 from role where name = $1 and deleted_at is null)")).
+			WithArgs("User").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		m.ExpectRollback()
+
+		_, err := svc.Restore(context.Background(), 2)
+		assert.ErrorAs(t, err, &common.AlreadyExistsError{})
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Empty(t, auditor.records)
+	})
+}
+
+func TestService_Purge(t *testing.T) {
+	before := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	deletedAt := before.Add(-time.Hour)
+	columns := []string{"id", "name", "created_at", "updated_at", "deleted_at", "deleted_by"}
+
+	svc, m, auditor := newSqlmockService(t)
+	m.ExpectBegin()
+	m.ExpectQuery(regexp.QuoteMeta("DELETE FROM role WHERE deleted_at < $1 RETURNING *")).
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "Old", deletedAt, deletedAt, deletedAt, "admin"))
+	m.ExpectCommit()
+
+	purged, err := svc.Purge(context.Background(), before)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.NoError(t, m.ExpectationsWereMet())
+	assert.Equal(t, []audit.Record{{
+		Action:     audit.ActionPurge,
+		EntityType: "role",
+		EntityId:   3,
+		Before:     auditState{Id: 3, Name: "Old"},
+	}}, auditor.records)
+}
+
+func TestService_SetOwner(t *testing.T) {
+	lockQuery := regexp.QuoteMeta("SELECT * FROM role WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")
+	existsQuery := regexp.QuoteMeta("select exists(select 1 from employee where id = $1 and deleted_at is null)")
+	columns := []string{"id", "name", "owner_id"}
+	ownerId := int64(5)
+
+	t.Run("owner is set and audited", func(t *testing.T) {
+		svc, m, auditor := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "Deployer", nil))
+		m.ExpectQuery(existsQuery).WithArgs(ownerId).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		m.ExpectQuery(regexp.QuoteMeta("UPDATE role SET owner_id = $1, updated_at = now() WHERE id = $2 RETURNING *")).
+			WithArgs(ownerId, int64(3)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "Deployer", ownerId))
+		m.ExpectCommit()
+
+		resp, err := svc.SetOwner(context.Background(), 3, OwnerRequest{OwnerId: &ownerId})
 		assert.NoError(t, err)
-		repo.AssertCalled(t, "DeleteByIds", ids)
+		assert.Equal(t, &ownerId, resp.OwnerId)
+		assert.NoError(t, m.ExpectationsWereMet())
+		assert.Equal(t, []audit.Record{{
+			Action:     audit.ActionUpdate,
+			EntityType: "role",
+			EntityId:   3,
+			Before:     auditState{Id: 3, Name: "Deployer"},
+			After:      auditState{Id: 3, Name: "Deployer", OwnerId: &ownerId},
+		}}, auditor.records)
+	})
+
+	t.Run("unknown owner", func(t *testing.T) {
+		svc, m, _ := newSqlmockService(t)
+		m.ExpectBegin()
+		m.ExpectQuery(lockQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "Deployer", nil))
+		m.ExpectQuery(existsQuery).WithArgs(ownerId).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		m.ExpectRollback()
+
+		_, err := svc.SetOwner(context.Background(), 3, OwnerRequest{OwnerId: &ownerId})
+		assert.ErrorAs(t, err, &common.RequestValidationError{})
+		assert.ErrorContains(t, err, "owner employee 5 not found")
+		assert.NoError(t, m.ExpectationsWereMet())
 	})
 }
@@ -1,22 +1,21 @@
 package role
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 )
 
 // ---- StubRepo ----
 type StubRepo struct{}
 
-func (s *StubRepo) Add(e *Entity) error {
+func (s *StubRepo) FindById(ctx context.Context, id int64) (*Entity, error) {
 	panic("not implemented")
 }
-func (s *StubRepo) FindById(id int64) (*Entity, error) {
-	panic("not implemented")
-}
-func (s *StubRepo) FindAll() ([]Entity, error) {
+func (s *StubRepo) FindAll(ctx context.Context) ([]Entity, error) {
 	// Жёстко зашитые данные, никакого testify
 	now := time.Date(2025, 6, 24, 15, 0, 0, 0, time.UTC)
 	return []Entity{
@@ -24,21 +23,57 @@ func (s *StubRepo) FindAll() ([]Entity, error) {
 		{Id: 20, Name: "StubRoleB", CreatedAt: now, UpdatedAt: now},
 	}, nil
 }
-func (s *StubRepo) FindByIds(ids []int64) ([]Entity, error) {
+func (s *StubRepo) FindByIds(ctx context.Context, ids []int64) ([]Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) SaveTx(ctx context.Context, tx *sqlx.Tx, role *Entity) (int64, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (bool, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) UpdateOwnerTx(ctx context.Context, tx *sqlx.Tx, id int64, ownerId *int64) (*Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) FindRolesPage(ctx context.Context, req PageRequest) ([]Entity, int64, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) FindByEmployeeId(ctx context.Context, employeeId int64) ([]Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error) {
+	panic("not implemented")
+}
+func (s *StubRepo) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	panic("not implemented")
+}
+func (s *StubRepo) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error {
+	panic("not implemented")
+}
+func (s *StubRepo) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
 	panic("not implemented")
 }
-func (s *StubRepo) DeleteById(id int64) error {
+func (s *StubRepo) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (*Entity, error) {
 	panic("not implemented")
 }
-func (s *StubRepo) DeleteByIds(ids []int64) error {
+func (s *StubRepo) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, before time.Time) ([]Entity, error) {
 	panic("not implemented")
 }
 
 // ---- Тест через stub ----
 func Test_FindAll_WithStub(t *testing.T) {
-	svc := NewService(&StubRepo{})
+	svc := NewService(&StubRepo{}, nil)
 
-	resps, err := svc.FindAll()
+	resps, err := svc.FindAll(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, resps, 2)
 
@@ -1,40 +1,180 @@
 package server
 
 import (
+	"context"
+	"net/http"
+
+	"idm/inner/accessrequest"
+	"idm/inner/accessreview"
+	"idm/inner/audit"
 	"idm/inner/common"
 	"idm/inner/database"
 	"idm/inner/employee"
+	"idm/inner/events"
+	"idm/inner/grantexpiry"
 	"idm/inner/info"
+	"idm/inner/loglevel"
+	"idm/inner/metrics"
+	"idm/inner/orgunit"
+	"idm/inner/policy"
+	"idm/inner/purge"
+	"idm/inner/role"
+	"idm/inner/tracing"
 	"idm/inner/web"
 
+	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/gofiber/swagger"
 	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
 )
 
-func Build() (*web.Server, *sqlx.DB) {
+// Build собирает сервер со всеми зависимостями. Возвращает и созданный логгер: он же получает глобальный лог Fiber,
+// поэтому main пишет в него, а не создаёт второй
+func Build() (*web.Server, *sqlx.DB, *common.Logger) {
 	var cfg = common.GetConfig(".env")
 	//создаём логгер
 	var logger = common.NewLogger(cfg)
 	var server = web.NewServer()
 
-	server.App.Use("/swagger/*", swagger.HandlerDefault)
+	// трассировка: спаны сбрасываются в экспортёр при остановке сервера
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		logger.Panic("failed tracing setup", zap.Error(err))
+	}
+	server.OnShutdown(shutdownTracing)
+
 	server.App.Use(requestid.New())
+	server.App.Use(web.AccessLog(logger, cfg.AccessLogSampleRate))
+	server.App.Use(metrics.Middleware())
 	server.App.Use(recover.New())
-	server.GroupApi.Use(web.AuthMiddleware(logger))
+	server.App.Use(server.RequestContext(cfg.DbQueryTimeout))
+	server.App.Use(tracing.Middleware())
+
+	// служебный порт: swagger, pprof, метрики, а также info (регистрируется в info.Controller)
+	server.Management.Use(recover.New())
+	server.Management.Use(server.RequestContext(cfg.DbQueryTimeout))
+	server.Management.Use(pprof.New())
+	server.Management.Get("/swagger/*", swagger.HandlerDefault)
+	server.Management.Get("/metrics", metrics.Handler())
+
+	// порт проб: только livez и readyz (регистрируются в info.Controller)
+	server.Probes.Use(recover.New())
+	server.Probes.Use(server.RequestContext(cfg.DbQueryTimeout))
+
+	server.GroupApi.Use(web.AuthMiddleware(cfg, logger))
+
+	// политика доступа к маршрутам: загружается из файла, проверяется после регистрации маршрутов
+	var enforcer = newPolicyEnforcer(cfg, logger)
+	server.GroupApi.Use(enforcer.Middleware())
 
 	var db = database.ConnectDbWithCfg(cfg)
+	metrics.RegisterDbStats(db.DB, metrics.Namespace)
+
+	// журнал аудита: сервисы записывают в него изменения в своих транзакциях
+	var auditService = audit.NewService(audit.NewRepository(db))
+	// доменные события: сервисы публикуют их после фиксации изменений
+	var publisher = events.NewLogPublisher(logger)
 
 	var employeeRepo = employee.NewEmployeeRepository(db)
-	var employeeService = employee.NewService(employeeRepo)
+	var employeeService = employee.NewService(employeeRepo, auditService, publisher, logger, loadAttributeSchema(cfg, logger))
+
+	var roleRepo = role.NewRoleRepository(db)
+	var roleService = role.NewService(roleRepo, auditService)
 
-	// создаём контроллер
-	var employeeController = employee.NewController(server, employeeService, logger)
+	// создаём контроллеры; назначения ролей сотрудникам обслуживает контроллер сотрудников
+	var employeeController = employee.NewController(server, employeeService, roleService, logger)
 	employeeController.RegisterRoutes()
 
-	var infoController = info.NewController(server, cfg)
+	var roleController = role.NewController(server, roleService, logger)
+	roleController.RegisterRoutes()
+
+	var orgUnitService = orgunit.NewService(orgunit.NewRepository(db), auditService)
+	var orgUnitController = orgunit.NewController(server, orgUnitService, logger)
+	orgUnitController.RegisterRoutes()
+
+	// окончательное удаление мягко удалённых записей; без срока хранения они хранятся бессрочно
+	if cfg.SoftDeleteRetention > 0 {
+		var purgeWorker = purge.NewWorker(logger, cfg.SoftDeleteRetention, cfg.PurgeInterval, map[string]purge.Target{
+			"employee": employeeService,
+			"role":     roleService,
+		})
+		purgeWorker.Start()
+		server.OnShutdown(purgeWorker.Stop)
+	}
+
+	// отзыв истёкших назначений ролей; при нескольких репликах проход выполняет одна из них
+	var grantExpiryWorker = grantexpiry.NewWorker(logger, cfg.GrantExpiryInterval, employeeService, publisher)
+	grantExpiryWorker.Start()
+	server.OnShutdown(grantExpiryWorker.Stop)
+
+	var accessRequestService = accessrequest.NewService(accessrequest.NewRepository(db), auditService, publisher, logger, cfg.AccessRequestSla)
+	var accessRequestController = accessrequest.NewController(server, accessRequestService, logger)
+	accessRequestController.RegisterRoutes()
+
+	// закрытие заявок на доступ, не согласованных в срок
+	var accessRequestWorker = accessrequest.NewWorker(logger, cfg.AccessRequestCheckInterval, accessRequestService)
+	accessRequestWorker.Start()
+	server.OnShutdown(accessRequestWorker.Stop)
+
+	// отчёты кампаний подписываются при закрытии: без ключа их нельзя было бы проверить при выгрузке
+	if cfg.AccessReviewSigningKey == "" {
+		logger.Panic("ACCESS_REVIEW_SIGNING_KEY is required to sign access review reports")
+	}
+	var accessReviewService = accessreview.NewService(accessreview.NewRepository(db), auditService, publisher, logger,
+		cfg.AccessReviewSigningKey, cfg.AccessReviewReminderInterval)
+	var accessReviewController = accessreview.NewController(server, accessReviewService, logger)
+	accessReviewController.RegisterRoutes()
+
+	// закрытие просроченных кампаний пересмотра доступа и напоминания проверяющим
+	var accessReviewWorker = accessreview.NewWorker(logger, cfg.AccessReviewCheckInterval, accessReviewService)
+	accessReviewWorker.Start()
+	server.OnShutdown(accessReviewWorker.Stop)
+
+	var auditController = audit.NewController(server, auditService)
+	auditController.RegisterRoutes()
+
+	var infoController = info.NewController(server, cfg, map[string]info.Check{
+		"database": info.DbCheck(db),
+		"jwks":     info.JwksCheck(&http.Client{Timeout: info.ReadinessTimeout}, common.SplitList(cfg.KeycloakJwkUrl)),
+		"shutdown": info.ShutdownCheck(server),
+	})
 	infoController.RegisterRoutes()
 
-	return server, db
+	var policyController = policy.NewController(server, enforcer)
+	policyController.RegisterRoutes()
+
+	var logLevelController = loglevel.NewController(server, logger)
+	logLevelController.RegisterRoutes()
+
+	if err := enforcer.Validate(server.App.GetRoutes(true), "/api"); err != nil {
+		logger.Panic("policy does not match registered routes", zap.Error(err))
+	}
+
+	return server, db, logger
+}
+
+// loadAttributeSchema загружает схему дополнительных атрибутов сотрудников, если файл задан в конфигурации
+func loadAttributeSchema(cfg common.Config, logger *common.Logger) employee.AttributeSchema {
+	if cfg.EmployeeAttributesFile == "" {
+		return employee.AttributeSchema{}
+	}
+	schema, err := employee.LoadAttributeSchema(cfg.EmployeeAttributesFile)
+	if err != nil {
+		logger.Panic("failed employee attribute schema loading", zap.String("file", cfg.EmployeeAttributesFile), zap.Error(err))
+	}
+	return schema
+}
+
+func newPolicyEnforcer(cfg common.Config, logger *common.Logger) *policy.Enforcer {
+	routePolicy, err := policy.Load(cfg.PolicyFile)
+	if err != nil {
+		logger.Panic("failed policy loading", zap.String("file", cfg.PolicyFile), zap.Error(err))
+	}
+	enforcer, err := policy.NewEnforcer(routePolicy, cfg.KeycloakClientId)
+	if err != nil {
+		logger.Panic("invalid policy", zap.String("file", cfg.PolicyFile), zap.Error(err))
+	}
+	return enforcer
 }
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"errors"
+
+	"idm/inner/common"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware открывает серверный спан на каждый запрос, продолжая трейс из заголовка traceparent.
+// Спан кладётся в UserContext, поэтому должен идти после web.Server.RequestContext
+func Middleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		carrier := propagation.HeaderCarrier{}
+		ctx.Request().Header.VisitAll(func(key, value []byte) {
+			carrier.Set(string(key), string(value))
+		})
+		parent := otel.GetTextMapPropagator().Extract(ctx.UserContext(), carrier)
+
+		spanCtx, span := Tracer().Start(parent, ctx.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", ctx.Method()),
+				attribute.String("url.path", ctx.Path()),
+				attribute.String("request.id", common.RequestId(ctx)),
+			),
+		)
+		defer span.End()
+		ctx.SetUserContext(spanCtx)
+
+		err := ctx.Next()
+
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+			span.RecordError(err)
+		}
+		// имя спана по шаблону маршрута известно только после маршрутизации
+		route := ctx.Route().Path
+		span.SetName(ctx.Method() + " " + route)
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", status),
+		)
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fiber.ErrInternalServerError.Message)
+		}
+		return err
+	}
+}
@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"idm/inner/common"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// экспортёры трейсов, выбираются переменной TRACING_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterOtlp   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// TracerName - имя трейсера сервиса
+const TracerName = "idm"
+
+// Setup настраивает глобальный TracerProvider и W3C-пропагацию (traceparent, baggage).
+// При выключенной трассировке спаны создаются, но никуда не отправляются.
+// Возвращаемая функция сбрасывает накопленные спаны и останавливает экспортёр
+func Setup(ctx context.Context, cfg common.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.TracingExporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOtlp:
+		// адрес коллектора и заголовки берутся из стандартных переменных OTEL_EXPORTER_OTLP_*
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.TracingExporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", cfg.TracingExporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.AppName),
+		attribute.String("service.version", cfg.AppVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer возвращает трейсер сервиса из глобального TracerProvider
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Start открывает дочерний спан, например для метода сервиса
+func Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name)
+}
+
+// QuerySpan - спан запроса к БД
+type QuerySpan struct {
+	span trace.Span
+}
+
+// StartQuery открывает спан запроса к БД; name - имя запроса вида "employee.FindById"
+func StartQuery(ctx context.Context, name string) (context.Context, QuerySpan) {
+	ctx, span := Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", name),
+		),
+	)
+	return ctx, QuerySpan{span: span}
+}
+
+// Finish записывает число прочитанных или изменённых строк и ошибку, затем закрывает спан
+func (q QuerySpan) Finish(rows int64, err error) {
+	q.span.SetAttributes(attribute.Int64("db.response.rows", rows))
+	RecordError(q.span, err)
+	q.span.End()
+}
+
+// RecordError отмечает спан как завершившийся ошибкой
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	// отсутствие записи - ожидаемый результат, а не сбой
+	var notFound common.NotFoundError
+	if errors.As(err, &notFound) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
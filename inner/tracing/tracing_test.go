@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"idm/inner/common"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useRecorder подменяет глобальный TracerProvider на записывающий спаны в память
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	a := assert.New(t)
+	recorder := useRecorder(t)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	var handlerSpan trace.SpanContext
+	app.Get("/api/v1/employees/:id", func(c *fiber.Ctx) error {
+		handlerSpan = trace.SpanContextFromContext(c.UserContext())
+		if c.Params("id") == "0" {
+			return errors.New("fail")
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	t.Run("continues incoming trace", func(t *testing.T) {
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		resp, err := app.Test(req, -1)
+		a.NoError(err)
+		a.Equal(fiber.StatusOK, resp.StatusCode)
+
+		spans := recorder.Ended()
+		require.NotEmpty(t, spans)
+		span := spans[len(spans)-1]
+		a.Equal("GET /api/v1/employees/:id", span.Name())
+		a.Equal(trace.SpanKindServer, span.SpanKind())
+		a.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		a.Equal("00f067aa0ba902b7", span.Parent().SpanID().String())
+		a.Equal(int64(200), attr(span, "http.response.status_code").AsInt64())
+		// обработчик получает контекст со спаном запроса
+		a.Equal(span.SpanContext().SpanID(), handlerSpan.SpanID())
+	})
+
+	t.Run("marks server errors", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/0", nil), -1)
+		a.NoError(err)
+		a.Equal(fiber.StatusInternalServerError, resp.StatusCode)
+
+		spans := recorder.Ended()
+		span := spans[len(spans)-1]
+		a.False(span.Parent().IsValid())
+		a.Equal(codes.Error, span.Status().Code)
+		a.Equal(int64(500), attr(span, "http.response.status_code").AsInt64())
+	})
+}
+
+func TestQuerySpan(t *testing.T) {
+	a := assert.New(t)
+	recorder := useRecorder(t)
+
+	ctx, parent := Start(context.Background(), "employee.Service.FindAll")
+	_, query := StartQuery(ctx, "employee.FindAll")
+	query.Finish(3, nil)
+	_, query = StartQuery(ctx, "employee.FindById")
+	query.Finish(0, common.NotFoundError{Message: "employee with id 1 not found"})
+	_, query = StartQuery(ctx, "employee.DeleteById")
+	query.Finish(0, errors.New("connection reset"))
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	for _, span := range spans[:3] {
+		a.Equal(parent.SpanContext().SpanID(), span.Parent().SpanID())
+		a.Equal(span.Name(), attr(span, "db.operation.name").AsString())
+	}
+	a.Equal(int64(3), attr(spans[0], "db.response.rows").AsInt64())
+	a.Equal(codes.Unset, spans[0].Status().Code)
+	a.Equal(codes.Unset, spans[1].Status().Code)
+	a.Equal(codes.Error, spans[2].Status().Code)
+	a.Len(spans[2].Events(), 1)
+}
+
+func TestSetup(t *testing.T) {
+	a := assert.New(t)
+
+	shutdown, err := Setup(context.Background(), common.Config{})
+	a.NoError(err)
+	a.NoError(shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), common.Config{TracingExporter: "jaeger"})
+	a.Error(err)
+}
@@ -2,25 +2,123 @@ package validator
 
 import (
 	"errors"
+	"fmt"
+	"idm/inner/common"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	ruTranslations "github.com/go-playground/validator/v10/translations/ru"
 )
 
+// loginPattern - допустимые символы логина: латиница, цифры, точка, дефис и подчёркивание
+var loginPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// customTranslations - сообщения для собственных правил по языкам
+var customTranslations = map[string]map[string]string{
+	"login": {
+		common.LanguageEn: "{0} may contain only latin letters, digits, '.', '-' and '_'",
+		common.LanguageRu: "{0} может содержать только латинские буквы, цифры, '.', '-' и '_'",
+	},
+}
+
 type Validator struct {
-	validate *validator.Validate
+	validate    *validator.Validate
+	translators map[string]ut.Translator
 }
 
 func New() *Validator {
 	validate := validator.New()
-	return &Validator{validate: validate}
+	// в ошибках используем имена полей из json-тегов, чтобы фронтенд мог сопоставить их с полями формы
+	validate.RegisterTagNameFunc(jsonFieldName)
+	if err := validate.RegisterValidation("login", func(fl validator.FieldLevel) bool {
+		return loginPattern.MatchString(fl.Field().String())
+	}); err != nil {
+		panic(fmt.Sprintf("register login validation: %v", err))
+	}
+
+	enLocale := en.New()
+	uni := ut.New(enLocale, enLocale, ru.New())
+	translators := make(map[string]ut.Translator, len(common.SupportedLanguages))
+	registrations := map[string]func(*validator.Validate, ut.Translator) error{
+		common.LanguageEn: enTranslations.RegisterDefaultTranslations,
+		common.LanguageRu: ruTranslations.RegisterDefaultTranslations,
+	}
+	for lang, register := range registrations {
+		trans, _ := uni.GetTranslator(lang)
+		if err := register(validate, trans); err != nil {
+			panic(fmt.Sprintf("register %s validation translations: %v", lang, err))
+		}
+		for tag, messages := range customTranslations {
+			if err := registerTranslation(validate, trans, tag, messages[lang]); err != nil {
+				panic(fmt.Sprintf("register %s %s validation translation: %v", lang, tag, err))
+			}
+		}
+		translators[lang] = trans
+	}
+	return &Validator{validate: validate, translators: translators}
 }
 
+// Validate проверяет запрос и при ошибках валидации возвращает common.RequestValidationError
+// со списком ошибок по полям, переведённых на все поддерживаемые языки
 func (v Validator) Validate(request any) (err error) {
 	err = v.validate.Struct(request)
 	if err != nil {
 		var validateErrs validator.ValidationErrors
 		if errors.As(err, &validateErrs) {
-			return validateErrs
+			return common.RequestValidationError{
+				Message: validateErrs.Error(),
+				Fields:  v.fieldErrors(validateErrs),
+				Err:     validateErrs,
+			}
 		}
 	}
 	return err
 }
+
+func (v Validator) fieldErrors(validateErrs validator.ValidationErrors) []common.FieldError {
+	fields := make([]common.FieldError, 0, len(validateErrs))
+	for _, fe := range validateErrs {
+		messages := make(map[string]string, len(v.translators))
+		for lang, trans := range v.translators {
+			messages[lang] = fe.Translate(trans)
+		}
+		fields = append(fields, common.FieldError{
+			Field:    fe.Field(),
+			Tag:      fe.Tag(),
+			Param:    fe.Param(),
+			Message:  messages[common.DefaultLanguage],
+			Messages: messages,
+		})
+	}
+	return fields
+}
+
+// registerTranslation регистрирует сообщение собственного правила; {0} заменяется именем поля
+func registerTranslation(validate *validator.Validate, trans ut.Translator, tag, message string) error {
+	return validate.RegisterTranslation(tag, trans,
+		func(ut ut.Translator) error {
+			return ut.Add(tag, message, true)
+		},
+		func(ut ut.Translator, fe validator.FieldError) string {
+			msg, _ := ut.T(tag, fe.Field())
+			return msg
+		})
+}
+
+// jsonFieldName возвращает имя поля из json-тега, а при его отсутствии - имя поля структуры
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
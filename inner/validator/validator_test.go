@@ -0,0 +1,57 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+
+	"idm/inner/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRequest struct {
+	Name    string  `json:"name" validate:"required,min=2"`
+	RoleIds []int64 `json:"role_ids" validate:"dive,gt=0"`
+	Size    int     `validate:"max=10"`
+	Login   string  `json:"login" validate:"omitempty,login"`
+}
+
+func TestValidator_Validate(t *testing.T) {
+	a := assert.New(t)
+	v := New()
+
+	a.NoError(v.Validate(testRequest{Name: "John", RoleIds: []int64{1}, Size: 1}))
+
+	err := v.Validate(testRequest{Name: "J", RoleIds: []int64{0}, Size: 11})
+	var validationErr common.RequestValidationError
+	a.True(errors.As(err, &validationErr))
+	a.Len(validationErr.Fields, 3)
+
+	name := validationErr.Fields[0]
+	a.Equal("name", name.Field)
+	a.Equal("min", name.Tag)
+	a.Equal("2", name.Param)
+	a.Equal("name must be at least 2 characters in length", name.Message)
+	a.Equal(name.Message, name.Messages[common.LanguageEn])
+	a.Contains(name.Messages[common.LanguageRu], "name должен содержать минимум 2 символа")
+
+	a.Equal("role_ids[0]", validationErr.Fields[1].Field)
+	a.Equal("gt", validationErr.Fields[1].Tag)
+	// поле без json-тега называется по имени поля структуры
+	a.Equal("Size", validationErr.Fields[2].Field)
+}
+
+func TestValidator_Login(t *testing.T) {
+	a := assert.New(t)
+	v := New()
+
+	a.NoError(v.Validate(testRequest{Name: "John", Login: "john.doe_1-a"}))
+
+	err := v.Validate(testRequest{Name: "John", Login: "john doe"})
+	var validationErr common.RequestValidationError
+	a.True(errors.As(err, &validationErr))
+	a.Len(validationErr.Fields, 1)
+	a.Equal("login", validationErr.Fields[0].Tag)
+	a.Equal("login may contain only latin letters, digits, '.', '-' and '_'", validationErr.Fields[0].Message)
+	a.Contains(validationErr.Fields[0].Messages[common.LanguageRu], "login может содержать")
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"idm/inner/common"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AccessLog пишет строку access log на каждый запрос: метод, маршрут, статус, длительность,
+// размер ответа, IP клиента, а также id запроса и пользователя из контекста.
+// Успешные запросы попадают в лог с вероятностью sampleRate, ответы 4xx и 5xx логируются всегда
+func AccessLog(logger *common.Logger, sampleRate float64) fiber.Handler {
+	return newAccessLog(logger, sampleRate, rand.Float64)
+}
+
+func newAccessLog(logger *common.Logger, sampleRate float64, random func() float64) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		err := ctx.Next()
+
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+		level := zapcore.InfoLevel
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = zapcore.ErrorLevel
+		case status >= fiber.StatusBadRequest:
+			level = zapcore.WarnLevel
+		case random() >= sampleRate:
+			return err
+		}
+
+		fields := []zap.Field{
+			zap.String("method", ctx.Method()),
+			zap.String("route", ctx.Route().Path),
+			zap.String("path", ctx.Path()),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", len(ctx.Response().Body())),
+			zap.String("client_ip", ctx.IP()),
+		}
+		// id запроса и пользователь добавляются из контекста, если мидлвары уже их положили
+		requestLogger := logger.Ctx(ctx.UserContext())
+		if common.RequestIdFromContext(ctx.UserContext()) == "" {
+			fields = append(fields, zap.String("request_id", common.RequestId(ctx)))
+		}
+		if ce := requestLogger.Check(level, "access"); ce != nil {
+			ce.Write(fields...)
+		}
+		return err
+	}
+}
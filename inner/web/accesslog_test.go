@@ -0,0 +1,87 @@
+package web
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"idm/inner/common"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newAccessLogTestServer(sampleRate float64, random float64) (*Server, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := &common.Logger{Logger: zap.New(core)}
+
+	server := NewServer()
+	server.App.Use(requestid.New())
+	server.App.Use(newAccessLog(logger, sampleRate, func() float64 { return random }))
+	server.App.Use(server.RequestContext(time.Second))
+	// stub-аутентификация, как у AuthMiddleware: токен в Locals и пользователь в контексте
+	server.GroupApi.Use(func(c *fiber.Ctx) error {
+		claims := &IdmClaims{PreferredUsername: "ivanov", RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}}
+		c.Locals(JwtKey, &jwt.Token{Claims: claims})
+		c.SetUserContext(common.WithUser(c.UserContext(), common.User{Subject: claims.Subject, Username: claims.PreferredUsername}))
+		return c.Next()
+	})
+	server.GroupApiV1.Get("/employees/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "0" {
+			return common.ErrResponse(c, fiber.StatusNotFound, "not found")
+		}
+		return c.SendString("employee")
+	})
+	return server, logs
+}
+
+func TestAccessLog(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("logs request with route, user and request id", func(t *testing.T) {
+		server, logs := newAccessLogTestServer(1, 0.5)
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7", nil)
+		req.Header.Set(fiber.HeaderXForwardedFor, "10.0.0.1")
+		resp, err := server.App.Test(req, -1)
+		a.NoError(err)
+
+		entries := logs.FilterMessage("access").AllUntimed()
+		if a.Len(entries, 1) {
+			fields := entries[0].ContextMap()
+			a.Equal(zapcore.InfoLevel, entries[0].Level)
+			a.Equal(fiber.MethodGet, fields["method"])
+			a.Equal("/api/v1/employees/:id", fields["route"])
+			a.Equal(int64(fiber.StatusOK), fields["status"])
+			a.Equal(int64(len("employee")), fields["bytes"])
+			a.Equal("user-1", fields["sub"])
+			a.Equal("ivanov", fields["preferred_username"])
+			a.Equal(resp.Header.Get(fiber.HeaderXRequestID), fields["request_id"])
+			a.Contains(fields, "latency")
+			a.Contains(fields, "client_ip")
+		}
+	})
+
+	t.Run("skips successful requests outside of sample", func(t *testing.T) {
+		server, logs := newAccessLogTestServer(0.1, 0.5)
+		_, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7", nil), -1)
+		a.NoError(err)
+		a.Zero(logs.FilterMessage("access").Len())
+	})
+
+	t.Run("always logs errors", func(t *testing.T) {
+		server, logs := newAccessLogTestServer(0, 0.5)
+		_, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/0", nil), -1)
+		a.NoError(err)
+
+		entries := logs.FilterMessage("access").AllUntimed()
+		if a.Len(entries, 1) {
+			a.Equal(zapcore.WarnLevel, entries[0].Level)
+			a.Equal(int64(fiber.StatusNotFound), entries[0].ContextMap()["status"])
+		}
+	})
+}
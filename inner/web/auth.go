@@ -1,9 +1,15 @@
 package web
 
 import (
+	"errors"
+	"fmt"
 	"idm/inner/common"
+	"idm/inner/metrics"
+	"slices"
+	"strings"
+	"time"
 
-	jwtMiddleware "github.com/gofiber/contrib/jwt"
+	"github.com/MicahParks/keyfunc/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
@@ -15,8 +21,24 @@ const (
 	IdmUser  = "IDM_USER"
 )
 
+var (
+	ErrJwtMissingOrMalformed = errors.New("missing or malformed JWT")
+	ErrJwtInvalidAzp         = errors.New("token has invalid authorized party")
+)
+
+// алгоритмы подписи, которые принимаем от Keycloak
+var jwtValidMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
 type IdmClaims struct {
 	RealmAccess RealmAccessClaims `json:"realm_access"`
+	// роли клиентов Keycloak: id клиента -> роли
+	ResourceAccess map[string]RealmAccessClaims `json:"resource_access"`
+	// OAuth scopes через пробел
+	Scope string `json:"scope"`
+	// клиент, которому выдан токен
+	Azp string `json:"azp"`
+	// логин пользователя
+	PreferredUsername string `json:"preferred_username"`
 	jwt.RegisteredClaims
 }
 
@@ -24,19 +46,120 @@ type RealmAccessClaims struct {
 	Roles []string `json:"roles"`
 }
 
-var AuthMiddleware = func(logger *common.Logger) fiber.Handler {
-	config := jwtMiddleware.Config{
-		ContextKey:   JwtKey,
-		ErrorHandler: createJwtErrorHandler(logger),
-		JWKSetURLs:   []string{"http://localhost:9990/realms/idm/protocol/openid-connect/certs"},
-		Claims:       &IdmClaims{},
+// ClientRoles возвращает роли клиента clientId из resource_access
+func (c *IdmClaims) ClientRoles(clientId string) []string {
+	return c.ResourceAccess[clientId].Roles
+}
+
+// Scopes возвращает список OAuth scopes токена
+func (c *IdmClaims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// AuthMiddleware проверяет JWT из заголовка Authorization: подпись по ключам из JWKS,
+// срок действия (с допуском cfg.JwtLeeway), издателя, аудиторию и клиента (azp).
+// Проверенный токен кладётся в Locals по ключу JwtKey
+func AuthMiddleware(cfg common.Config, logger *common.Logger) fiber.Handler {
+	keyFunc, err := newJwksKeyfunc(cfg, logger)
+	if err != nil {
+		logger.Panic("failed JWKS loading", zap.Error(err))
+	}
+	return newAuthHandler(cfg, keyFunc, createJwtErrorHandler(logger))
+}
+
+// newJwksKeyfunc загружает ключи по всем адресам JWKS из конфига и запускает их фоновое обновление
+func newJwksKeyfunc(cfg common.Config, logger *common.Logger) (jwt.Keyfunc, error) {
+	urls := common.SplitList(cfg.KeycloakJwkUrl)
+	if len(urls) == 0 {
+		return nil, errors.New("JWKS url is not configured")
+	}
+	options := keyfunc.Options{
+		RefreshErrorHandler: func(err error) {
+			logger.Error("failed JWKS refresh", zap.Error(err))
+		},
+		RefreshInterval:  cfg.JwksRefreshInterval,
+		RefreshRateLimit: 5 * time.Minute,
+		RefreshTimeout:   10 * time.Second,
+	}
+	if len(urls) == 1 {
+		// при появлении неизвестного kid (ротация ключей) обновляем JWKS, не дожидаясь интервала
+		options.RefreshUnknownKID = true
+		jwks, err := keyfunc.Get(urls[0], options)
+		if err != nil {
+			return nil, fmt.Errorf("loading JWKS from %s: %w", urls[0], err)
+		}
+		return jwks.Keyfunc, nil
+	}
+	multiple := make(map[string]keyfunc.Options, len(urls))
+	for _, url := range urls {
+		multiple[url] = options
+	}
+	jwks, err := keyfunc.GetMultiple(multiple, keyfunc.MultipleOptions{KeySelector: keyfunc.KeySelectorFirst})
+	if err != nil {
+		return nil, fmt.Errorf("loading JWKS from %v: %w", urls, err)
+	}
+	return jwks.Keyfunc, nil
+}
+
+func newAuthHandler(cfg common.Config, keyFunc jwt.Keyfunc, errorHandler fiber.ErrorHandler) fiber.Handler {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(jwtValidMethods),
+		jwt.WithLeeway(cfg.JwtLeeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	return func(ctx *fiber.Ctx) error {
+		raw, ok := bearerToken(ctx.Get(fiber.HeaderAuthorization))
+		if !ok {
+			return errorHandler(ctx, ErrJwtMissingOrMalformed)
+		}
+		claims := &IdmClaims{}
+		token, err := parser.ParseWithClaims(raw, claims, keyFunc)
+		if err != nil {
+			return errorHandler(ctx, err)
+		}
+		if err = validateClaims(cfg, claims); err != nil {
+			return errorHandler(ctx, err)
+		}
+		ctx.Locals(JwtKey, token)
+		ctx.SetUserContext(common.WithUser(ctx.UserContext(), common.User{
+			Subject:  claims.Subject,
+			Username: claims.PreferredUsername,
+		}))
+		return ctx.Next()
+	}
+}
+
+// validateClaims проверяет издателя, аудиторию и клиента; пустой список в конфиге отключает проверку
+func validateClaims(cfg common.Config, claims *IdmClaims) error {
+	if len(cfg.JwtIssuers) > 0 && !slices.Contains(cfg.JwtIssuers, claims.Issuer) {
+		return jwt.ErrTokenInvalidIssuer
+	}
+	if len(cfg.JwtAudiences) > 0 && !slices.ContainsFunc(claims.Audience, func(aud string) bool {
+		return slices.Contains(cfg.JwtAudiences, aud)
+	}) {
+		return jwt.ErrTokenInvalidAudience
+	}
+	if len(cfg.JwtAuthorizedParties) > 0 && !slices.Contains(cfg.JwtAuthorizedParties, claims.Azp) {
+		return ErrJwtInvalidAzp
+	}
+	return nil
+}
+
+// bearerToken достаёт токен из значения заголовка Authorization вида "Bearer <token>"
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
 	}
-	return jwtMiddleware.New(config)
+	token = strings.TrimSpace(token)
+	return token, token != ""
 }
 
 func createJwtErrorHandler(logger *common.Logger) fiber.ErrorHandler {
 	return func(ctx *fiber.Ctx, err error) error {
-		logger.Error("failed autentication", zap.Error(err))
+		logger.Ctx(ctx.UserContext()).Error("failed autentication", zap.Error(err))
+		metrics.AuthFailures.WithLabelValues(authFailureReason(err)).Inc()
 		// Если токен не может быть прочитан, то возвращаем 401
 		return common.ErrResponse(
 			ctx,
@@ -45,3 +168,27 @@ func createJwtErrorHandler(logger *common.Logger) fiber.ErrorHandler {
 		)
 	}
 }
+
+// authFailureReason сводит ошибку проверки токена к небольшому набору причин для метрик
+func authFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrJwtMissingOrMalformed):
+		return "missing_token"
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "not_valid_yet"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return "invalid_signature"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "invalid_issuer"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "invalid_audience"
+	case errors.Is(err, ErrJwtInvalidAzp):
+		return "invalid_azp"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "malformed"
+	default:
+		return "invalid"
+	}
+}
@@ -0,0 +1,299 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"idm/inner/common"
+	"idm/inner/metrics"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer      = "http://localhost:9990/realms/idm"
+	testOtherIssuer = "http://localhost:9990/realms/partners"
+	testAudience    = "idm"
+	testAzp         = "idm-frontend"
+)
+
+// jwksStub - локальный JWKS-сервер, отдающий публичный ключ тестовой пары
+type jwksStub struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	kid string
+}
+
+func newJwksStub(t *testing.T, kid string) *jwksStub {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	stub := &jwksStub{key: key, kid: kid}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (s *jwksStub) sign(t *testing.T, claims IdmClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.kid
+	signed, err := token.SignedString(s.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func validClaims() IdmClaims {
+	now := time.Now()
+	return IdmClaims{
+		RealmAccess: RealmAccessClaims{Roles: []string{IdmUser}},
+		Azp:         testAzp,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{"account", testAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+func newAuthTestServer(cfg common.Config) *Server {
+	server := NewServer()
+	server.GroupApi.Use(AuthMiddleware(cfg, common.NewLogger(cfg)))
+	server.GroupApiV1.Get("/whoami", func(c *fiber.Ctx) error {
+		claims, _ := claimsFromCtx(c)
+		return common.OkResponse(c, claims.Azp)
+	})
+	return server
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	a := assert.New(t)
+	stub := newJwksStub(t, "key-1")
+	otherStub := newJwksStub(t, "key-2")
+
+	cfg := common.Config{
+		KeycloakJwkUrl:       stub.URL + "," + otherStub.URL,
+		JwtIssuers:           []string{testIssuer, testOtherIssuer},
+		JwtAudiences:         []string{testAudience},
+		JwtAuthorizedParties: []string{testAzp},
+		JwtLeeway:            30 * time.Second,
+		JwksRefreshInterval:  time.Hour,
+	}
+	server := newAuthTestServer(cfg)
+
+	untrusted, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		header     func() string
+		wantStatus int
+	}{
+		{
+			name:       "valid token",
+			header:     func() string { return "Bearer " + stub.sign(t, validClaims()) },
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name: "token signed by key from second JWKS and second issuer",
+			header: func() string {
+				claims := validClaims()
+				claims.Issuer = testOtherIssuer
+				return "Bearer " + otherStub.sign(t, claims)
+			},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name: "expired within leeway",
+			header: func() string {
+				claims := validClaims()
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+				return "Bearer " + stub.sign(t, claims)
+			},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name: "expired beyond leeway",
+			header: func() string {
+				claims := validClaims()
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return "Bearer " + stub.sign(t, claims)
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name: "untrusted issuer",
+			header: func() string {
+				claims := validClaims()
+				claims.Issuer = "http://evil/realms/idm"
+				return "Bearer " + stub.sign(t, claims)
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name: "missing audience",
+			header: func() string {
+				claims := validClaims()
+				claims.Audience = jwt.ClaimStrings{"account"}
+				return "Bearer " + stub.sign(t, claims)
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name: "unknown client",
+			header: func() string {
+				claims := validClaims()
+				claims.Azp = "other-client"
+				return "Bearer " + stub.sign(t, claims)
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name: "signed by untrusted key",
+			header: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims())
+				token.Header["kid"] = "key-1"
+				signed, _ := token.SignedString(untrusted)
+				return "Bearer " + signed
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name: "symmetric algorithm is rejected",
+			header: func() string {
+				signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+				return "Bearer " + signed
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "missing header",
+			header:     func() string { return "" },
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "wrong scheme",
+			header:     func() string { return "Basic " + stub.sign(t, validClaims()) },
+			wantStatus: fiber.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/api/v1/whoami", nil)
+			if header := tt.header(); header != "" {
+				req.Header.Set(fiber.HeaderAuthorization, header)
+			}
+			resp, err := server.App.Test(req, -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestAuthMiddleware_ChecksDisabledWhenNotConfigured(t *testing.T) {
+	a := assert.New(t)
+	stub := newJwksStub(t, "key-1")
+	server := newAuthTestServer(common.Config{KeycloakJwkUrl: stub.URL})
+
+	claims := validClaims()
+	claims.Issuer = "http://any/realms/idm"
+	claims.Audience = nil
+	claims.Azp = ""
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/whoami", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+stub.sign(t, claims))
+
+	resp, err := server.App.Test(req, -1)
+	a.NoError(err)
+	a.Equal(fiber.StatusOK, resp.StatusCode)
+}
+
+func TestAuthMiddleware_CountsFailures(t *testing.T) {
+	a := assert.New(t)
+	stub := newJwksStub(t, "key-1")
+	server := newAuthTestServer(common.Config{KeycloakJwkUrl: stub.URL, JwtIssuers: []string{testIssuer}})
+
+	claims := validClaims()
+	claims.Issuer = "http://evil/realms/idm"
+	invalidIssuer := metrics.AuthFailures.WithLabelValues("invalid_issuer")
+	missingToken := metrics.AuthFailures.WithLabelValues("missing_token")
+	invalidIssuerBefore, missingTokenBefore := testutil.ToFloat64(invalidIssuer), testutil.ToFloat64(missingToken)
+
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/whoami", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+stub.sign(t, claims))
+	_, err := server.App.Test(req, -1)
+	a.NoError(err)
+	_, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/whoami", nil), -1)
+	a.NoError(err)
+
+	a.Equal(invalidIssuerBefore+1, testutil.ToFloat64(invalidIssuer))
+	a.Equal(missingTokenBefore+1, testutil.ToFloat64(missingToken))
+}
+
+func TestAuthMiddleware_PutsUserIntoContext(t *testing.T) {
+	a := assert.New(t)
+	stub := newJwksStub(t, "key-1")
+	server := NewServer()
+	server.GroupApi.Use(AuthMiddleware(common.Config{KeycloakJwkUrl: stub.URL}, common.NewLogger(common.Config{})))
+	server.GroupApiV1.Get("/me", func(c *fiber.Ctx) error {
+		user, ok := common.UserFromContext(c.UserContext())
+		a.True(ok)
+		return c.SendString(user.Subject + ":" + user.Username)
+	})
+
+	claims := validClaims()
+	claims.Subject = "user-1"
+	claims.PreferredUsername = "ivanov"
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/me", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+stub.sign(t, claims))
+	resp, err := server.App.Test(req, -1)
+	a.NoError(err)
+	body, _ := io.ReadAll(resp.Body)
+	a.Equal("user-1:ivanov", string(body))
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"Bearer abc", "abc", true},
+		{"bearer  abc ", "abc", true},
+		{"Bearer", "", false},
+		{"Bearer ", "", false},
+		{"Token abc", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(strings.ReplaceAll(tt.header, " ", "_"), func(t *testing.T) {
+			got, ok := bearerToken(tt.header)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package web
+
+import (
+	"idm/inner/common"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Requirement - условие, которому должны удовлетворять claims пользователя
+type Requirement func(claims *IdmClaims) bool
+
+// Require пропускает запрос дальше, только если claims пользователя удовлетворяют ВСЕМ условиям.
+// Без токена возвращает 401, при невыполненном условии - 403
+func Require(requirements ...Requirement) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		claims, ok := claimsFromCtx(ctx)
+		if !ok {
+			return common.ErrResponse(ctx, fiber.StatusUnauthorized, "unauthorized")
+		}
+		for _, requirement := range requirements {
+			if !requirement(claims) {
+				return common.ErrResponse(ctx, fiber.StatusForbidden, "forbidden")
+			}
+		}
+		return ctx.Next()
+	}
+}
+
+// Satisfies сообщает, удовлетворяют ли claims пользователя ВСЕМ условиям; без токена - false.
+// Нужна, когда права зависят не только от маршрута, но и от параметров запроса
+func Satisfies(ctx *fiber.Ctx, requirements ...Requirement) bool {
+	claims, ok := claimsFromCtx(ctx)
+	if !ok {
+		return false
+	}
+	for _, requirement := range requirements {
+		if !requirement(claims) {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireRoles пропускает запрос дальше, только если у пользователя есть ВСЕ перечисленные роли
+func RequireRoles(roles ...string) fiber.Handler {
+	return Require(HasAllRoles(roles...))
+}
+
+// RequireAnyRole пропускает запрос дальше, если у пользователя есть ХОТЯ БЫ ОДНА из перечисленных ролей
+func RequireAnyRole(roles ...string) fiber.Handler {
+	return Require(HasAnyRole(roles...))
+}
+
+// RequireClientRoles пропускает запрос дальше, только если у пользователя есть ВСЕ перечисленные роли клиента clientId
+func RequireClientRoles(clientId string, roles ...string) fiber.Handler {
+	return Require(HasAllClientRoles(clientId, roles...))
+}
+
+// RequireAnyClientRole пропускает запрос дальше, если у пользователя есть ХОТЯ БЫ ОДНА из ролей клиента clientId
+func RequireAnyClientRole(clientId string, roles ...string) fiber.Handler {
+	return Require(HasAnyClientRole(clientId, roles...))
+}
+
+// RequireScopes пропускает запрос дальше, только если токен выдан со ВСЕМИ перечисленными scopes
+func RequireScopes(scopes ...string) fiber.Handler {
+	return Require(HasAllScopes(scopes...))
+}
+
+// RequireAnyScope пропускает запрос дальше, если токен выдан ХОТЯ БЫ С ОДНИМ из перечисленных scopes
+func RequireAnyScope(scopes ...string) fiber.Handler {
+	return Require(HasAnyScope(scopes...))
+}
+
+// HasAllRoles - у пользователя есть все перечисленные роли realm
+func HasAllRoles(roles ...string) Requirement {
+	return hasAll(realmRoles, normalizeRole, roles)
+}
+
+// HasAnyRole - у пользователя есть хотя бы одна из перечисленных ролей realm
+func HasAnyRole(roles ...string) Requirement {
+	return hasAny(realmRoles, normalizeRole, roles)
+}
+
+// HasAllClientRoles - у пользователя есть все перечисленные роли клиента clientId
+func HasAllClientRoles(clientId string, roles ...string) Requirement {
+	return hasAll(clientRoles(clientId), normalizeRole, roles)
+}
+
+// HasAnyClientRole - у пользователя есть хотя бы одна из перечисленных ролей клиента clientId
+func HasAnyClientRole(clientId string, roles ...string) Requirement {
+	return hasAny(clientRoles(clientId), normalizeRole, roles)
+}
+
+// HasAllScopes - токен выдан со всеми перечисленными scopes.
+// В отличие от ролей, scopes сравниваются с учётом регистра
+func HasAllScopes(scopes ...string) Requirement {
+	return hasAll((*IdmClaims).Scopes, strings.TrimSpace, scopes)
+}
+
+// HasAnyScope - токен выдан хотя бы с одним из перечисленных scopes
+func HasAnyScope(scopes ...string) Requirement {
+	return hasAny((*IdmClaims).Scopes, strings.TrimSpace, scopes)
+}
+
+// grantsFunc достаёт из claims проверяемые значения: роли или scopes
+type grantsFunc func(claims *IdmClaims) []string
+
+func realmRoles(claims *IdmClaims) []string {
+	return claims.RealmAccess.Roles
+}
+
+func clientRoles(clientId string) grantsFunc {
+	return func(claims *IdmClaims) []string {
+		return claims.ClientRoles(clientId)
+	}
+}
+
+// normalizeRole приводит роль к виду для сравнения: роли сравниваются без учёта регистра
+func normalizeRole(role string) string {
+	return strings.ToUpper(strings.TrimSpace(role))
+}
+
+func hasAll(grants grantsFunc, normalize func(string) string, required []string) Requirement {
+	// нормализуем и фиксируем список обязательных значений
+	req := toSet(normalize, required)
+	return func(claims *IdmClaims) bool {
+		user := toSet(normalize, grants(claims))
+		for k := range req {
+			if _, ok := user[k]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func hasAny(grants grantsFunc, normalize func(string) string, required []string) Requirement {
+	req := toSet(normalize, required)
+	return func(claims *IdmClaims) bool {
+		for _, v := range grants(claims) {
+			if _, ok := req[normalize(v)]; ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// claimsFromCtx достаёт claims из токена, положенного в Locals мидлваром аутентификации
+func claimsFromCtx(ctx *fiber.Ctx) (*IdmClaims, bool) {
+	token, ok := ctx.Locals(JwtKey).(*jwt.Token)
+	if !ok || token == nil {
+		return nil, false
+	}
+	claims, ok := token.Claims.(*IdmClaims)
+	if !ok || claims == nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+func toSet(normalize func(string) string, values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if k := normalize(v); k != "" {
+			set[k] = struct{}{}
+		}
+	}
+	return set
+}
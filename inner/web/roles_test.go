@@ -0,0 +1,75 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// claims в том виде, в каком их выдаёт Keycloak
+const keycloakClaimsJson = `{
+	"realm_access": {"roles": ["idm_user", "offline_access"]},
+	"resource_access": {
+		"idm": {"roles": ["employee-reader", "role-reader"]},
+		"account": {"roles": ["manage-account"]}
+	},
+	"scope": "openid profile idm.read"
+}`
+
+func TestIdmClaims_Decoding(t *testing.T) {
+	a := assert.New(t)
+	var claims IdmClaims
+	a.NoError(json.Unmarshal([]byte(keycloakClaimsJson), &claims))
+
+	a.Equal([]string{"idm_user", "offline_access"}, claims.RealmAccess.Roles)
+	a.Equal([]string{"employee-reader", "role-reader"}, claims.ClientRoles("idm"))
+	a.Empty(claims.ClientRoles("unknown"))
+	a.Equal([]string{"openid", "profile", "idm.read"}, claims.Scopes())
+}
+
+func TestRequireHelpers(t *testing.T) {
+	a := assert.New(t)
+	var claims IdmClaims
+	a.NoError(json.Unmarshal([]byte(keycloakClaimsJson), &claims))
+
+	tests := []struct {
+		name       string
+		handler    fiber.Handler
+		noToken    bool
+		wantStatus int
+	}{
+		{"realm role ignores case", RequireRoles(IdmUser), false, fiber.StatusOK},
+		{"missing realm role", RequireRoles(IdmAdmin, IdmUser), false, fiber.StatusForbidden},
+		{"any realm role", RequireAnyRole(IdmAdmin, IdmUser), false, fiber.StatusOK},
+		{"all client roles", RequireClientRoles("idm", "EMPLOYEE-READER", "role-reader"), false, fiber.StatusOK},
+		{"missing client role", RequireClientRoles("idm", "employee-reader", "employee-writer"), false, fiber.StatusForbidden},
+		{"role of other client", RequireAnyClientRole("idm", "manage-account"), false, fiber.StatusForbidden},
+		{"any client role", RequireAnyClientRole("account", "manage-account", "view-profile"), false, fiber.StatusOK},
+		{"all scopes", RequireScopes("openid", "idm.read"), false, fiber.StatusOK},
+		{"scopes are case sensitive", RequireScopes("IDM.READ"), false, fiber.StatusForbidden},
+		{"any scope", RequireAnyScope("idm.write", "idm.read"), false, fiber.StatusOK},
+		{"missing scope", RequireAnyScope("idm.write"), false, fiber.StatusForbidden},
+		{"no token", RequireAnyScope("idm.read"), true, fiber.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				if !tt.noToken {
+					c.Locals(JwtKey, &jwt.Token{Claims: &claims})
+				}
+				return c.Next()
+			})
+			app.Get("/", tt.handler, func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil), -1)
+			a.NoError(err)
+			a.Equal(tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package web
 
 import (
+	"context"
+	"errors"
 	_ "idm/docs"
+	"idm/inner/common"
+	"sync/atomic"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -15,6 +20,17 @@ type Server struct {
 	GroupApiV1 fiber.Router
 	// группа непубличного API
 	GroupInternal fiber.Router
+	// приложение служебного порта: info, метрики, pprof и swagger
+	Management *fiber.App
+	// приложение порта проб livez и readyz; в отличие от служебного, доступен kubelet
+	Probes *fiber.App
+	// базовый контекст запросов, отменяется при остановке сервера
+	baseCtx        context.Context
+	cancelRequests context.CancelFunc
+	// признак начавшейся остановки сервера
+	shuttingDown atomic.Bool
+	// функции, вызываемые после остановки портов, например сброс трейсов
+	shutdownHooks []func(context.Context) error
 }
 
 type AuthMiddlewareInterface interface {
@@ -35,10 +51,70 @@ func NewServer() *Server {
 
 	groupInternal := groupApi.Group("/internal")
 
+	// служебные маршруты обслуживаются отдельным приложением на своём порту
+	management := fiber.New()
+	probes := fiber.New()
+
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+
 	return &Server{
-		App:           app,
-		GroupApi:      groupApi,
-		GroupApiV1:    groupApiV1,
-		GroupInternal: groupInternal,
+		App:            app,
+		GroupApi:       groupApi,
+		GroupApiV1:     groupApiV1,
+		GroupInternal:  groupInternal,
+		Management:     management,
+		Probes:         probes,
+		baseCtx:        baseCtx,
+		cancelRequests: cancelRequests,
+	}
+}
+
+// RequestContext кладёт в UserContext запроса контекст, который отменяется по истечении timeout
+// или при вызове CancelRequests. Этот контекст передаётся в сервисы и дальше в запросы к БД,
+// в нём же передаются id запроса для логов и IP клиента для журнала аудита
+func (s *Server) RequestContext(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(s.baseCtx, timeout)
+		defer cancel()
+		ctx = common.WithRequestId(ctx, common.RequestId(c))
+		c.SetUserContext(common.WithClientIp(ctx, c.IP()))
+		return c.Next()
+	}
+}
+
+// BeginShutdown отмечает начало остановки сервера: после этого он перестаёт считаться готовым
+func (s *Server) BeginShutdown() {
+	s.shuttingDown.Store(true)
+}
+
+// ShuttingDown сообщает, началась ли остановка сервера
+func (s *Server) ShuttingDown() bool {
+	return s.shuttingDown.Load()
+}
+
+// OnShutdown добавляет функцию, которая будет вызвана в Shutdown после остановки портов
+func (s *Server) OnShutdown(hook func(context.Context) error) {
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// Shutdown останавливает публичный, служебный порты и порт проб, дожидаясь завершения запросов не дольше, чем позволяет ctx,
+// затем вызывает функции, добавленные через OnShutdown
+func (s *Server) Shutdown(ctx context.Context) error {
+	errs := make(chan error, 2)
+	go func() {
+		errs <- s.Management.ShutdownWithContext(ctx)
+	}()
+	go func() {
+		errs <- s.Probes.ShutdownWithContext(ctx)
+	}()
+	err := errors.Join(s.App.ShutdownWithContext(ctx), <-errs, <-errs)
+	for _, hook := range s.shutdownHooks {
+		err = errors.Join(err, hook(ctx))
 	}
+	return err
+}
+
+// CancelRequests отменяет контексты всех выполняющихся запросов
+func (s *Server) CancelRequests() {
+	s.cancelRequests()
 }
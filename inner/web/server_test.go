@@ -0,0 +1,87 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_RequestContext(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("context expires after timeout", func(t *testing.T) {
+		server := NewServer()
+		server.App.Use(server.RequestContext(10 * time.Millisecond))
+		server.App.Get("/slow", func(c *fiber.Ctx) error {
+			<-c.UserContext().Done()
+			a.True(errors.Is(c.UserContext().Err(), context.DeadlineExceeded))
+			return c.SendStatus(fiber.StatusGatewayTimeout)
+		})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/slow", nil), -1)
+		a.NoError(err)
+		a.Equal(fiber.StatusGatewayTimeout, resp.StatusCode)
+	})
+
+	t.Run("context is cancelled on shutdown", func(t *testing.T) {
+		server := NewServer()
+		server.App.Use(server.RequestContext(time.Minute))
+		server.App.Get("/slow", func(c *fiber.Ctx) error {
+			server.CancelRequests()
+			<-c.UserContext().Done()
+			a.True(errors.Is(c.UserContext().Err(), context.Canceled))
+			return c.SendStatus(fiber.StatusServiceUnavailable)
+		})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/slow", nil), -1)
+		a.NoError(err)
+		a.Equal(fiber.StatusServiceUnavailable, resp.StatusCode)
+	})
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	a := assert.New(t)
+	server := NewServer()
+
+	publicLn, err := net.Listen("tcp", "127.0.0.1:0")
+	a.NoError(err)
+	managementLn, err := net.Listen("tcp", "127.0.0.1:0")
+	a.NoError(err)
+	probeLn, err := net.Listen("tcp", "127.0.0.1:0")
+	a.NoError(err)
+
+	stopped := make(chan error, 3)
+	go func() { stopped <- server.App.Listener(publicLn) }()
+	go func() { stopped <- server.Management.Listener(managementLn) }()
+	go func() { stopped <- server.Probes.Listener(probeLn) }()
+
+	// дожидаемся, пока все порты начнут принимать соединения
+	for _, ln := range []net.Listener{publicLn, managementLn, probeLn} {
+		a.Eventually(func() bool {
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err == nil {
+				_ = conn.Close()
+			}
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	a.NoError(server.Shutdown(ctx))
+
+	for range 3 {
+		select {
+		case err := <-stopped:
+			a.NoError(err)
+		case <-time.After(time.Second):
+			t.Fatal("listener was not stopped")
+		}
+	}
+}
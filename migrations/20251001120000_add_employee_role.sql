@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE employee_role
+(
+    employee_id BIGINT      NOT NULL REFERENCES employee (id) ON DELETE CASCADE,
+    role_id     BIGINT      NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (employee_id, role_id)
+);
+
+CREATE INDEX employee_role_role_id_idx ON employee_role (role_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists employee_role;
+-- +goose StatementEnd
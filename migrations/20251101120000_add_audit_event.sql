@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE audit_event
+(
+    id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    actor_sub   TEXT        NOT NULL,
+    actor_name  TEXT        NOT NULL,
+    action      TEXT        NOT NULL,
+    entity_type TEXT        NOT NULL,
+    entity_id   BIGINT      NOT NULL,
+    before      JSONB,
+    after       JSONB,
+    request_id  TEXT        NOT NULL,
+    ip          TEXT        NOT NULL,
+    -- хеш предыдущего события цепочки и хеш самого события (sha256, hex)
+    prev_hash   TEXT        NOT NULL,
+    hash        TEXT        NOT NULL UNIQUE
+);
+
+CREATE INDEX audit_event_entity_idx ON audit_event (entity_type, entity_id);
+CREATE INDEX audit_event_occurred_at_idx ON audit_event (occurred_at);
+
+-- журнал только дополняется: изменение и удаление событий запрещены
+CREATE FUNCTION audit_event_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_event is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_event_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_event
+    FOR EACH ROW EXECUTE FUNCTION audit_event_append_only();
+
+CREATE TRIGGER audit_event_no_truncate
+    BEFORE TRUNCATE ON audit_event
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_event_append_only();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists audit_event;
+drop function if exists audit_event_append_only();
+-- +goose StatementEnd
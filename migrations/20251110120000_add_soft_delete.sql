@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE employee
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD COLUMN deleted_by TEXT;
+
+ALTER TABLE role
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD COLUMN deleted_by TEXT;
+
+-- по этим индексам фоновая очистка ищет записи, срок хранения которых истёк
+CREATE INDEX employee_deleted_at_idx ON employee (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX role_deleted_at_idx ON role (deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists role_deleted_at_idx;
+drop index if exists employee_deleted_at_idx;
+alter table role drop column if exists deleted_at, drop column if exists deleted_by;
+alter table employee drop column if exists deleted_at, drop column if exists deleted_by;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- существующие сотрудники считаются работающими
+ALTER TABLE employee
+    ADD COLUMN status     TEXT NOT NULL DEFAULT 'active'
+        CONSTRAINT employee_status_check CHECK (status IN ('pre_hire', 'active', 'suspended', 'on_leave', 'terminated')),
+    ADD COLUMN start_date DATE,
+    ADD COLUMN end_date   DATE;
+
+CREATE TABLE employee_status_history
+(
+    id             BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    employee_id    BIGINT      NOT NULL REFERENCES employee (id) ON DELETE CASCADE,
+    from_status    TEXT        NOT NULL,
+    to_status      TEXT        NOT NULL,
+    -- дата, с которой действует новый статус (например, последний рабочий день для terminated)
+    effective_date DATE        NOT NULL,
+    reason         TEXT        NOT NULL DEFAULT '',
+    changed_by     TEXT        NOT NULL DEFAULT '',
+    changed_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX employee_status_history_employee_idx ON employee_status_history (employee_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists employee_status_history;
+alter table employee
+    drop column if exists end_date,
+    drop column if exists start_date,
+    drop column if exists status;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- профильные поля необязательны: у существующих сотрудников они остаются пустыми (NULL)
+ALTER TABLE employee
+    ADD COLUMN email           TEXT,
+    ADD COLUMN login           TEXT,
+    ADD COLUMN first_name      TEXT,
+    ADD COLUMN last_name       TEXT,
+    ADD COLUMN phone           TEXT,
+    ADD COLUMN title           TEXT,
+    ADD COLUMN employee_number TEXT,
+    -- дополнительные атрибуты, типы и правила проверки которых задаются в конфигурации
+    ADD COLUMN attributes      JSONB NOT NULL DEFAULT '{}'
+        CONSTRAINT employee_attributes_check CHECK (jsonb_typeof(attributes) = 'object');
+
+-- уникальность проверяется только среди неудалённых сотрудников, чтобы мягко удалённые не занимали значения
+CREATE UNIQUE INDEX employee_email_key ON employee (lower(email)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX employee_login_key ON employee (lower(login)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX employee_number_key ON employee (employee_number) WHERE deleted_at IS NULL;
+CREATE INDEX employee_attributes_idx ON employee USING GIN (attributes jsonb_path_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists employee_attributes_idx;
+drop index if exists employee_number_key;
+drop index if exists employee_login_key;
+drop index if exists employee_email_key;
+alter table employee
+    drop column if exists attributes,
+    drop column if exists employee_number,
+    drop column if exists title,
+    drop column if exists phone,
+    drop column if exists last_name,
+    drop column if exists first_name,
+    drop column if exists login,
+    drop column if exists email;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE org_unit
+(
+    id         BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name       TEXT        NOT NULL CHECK (char_length(trim(name)) > 0),
+    parent_id  BIGINT REFERENCES org_unit (id),
+    -- материализованный путь из id от корня до самого подразделения: /1/4/9/
+    path       TEXT        NOT NULL,
+    manager_id BIGINT REFERENCES employee (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX org_unit_parent_idx ON org_unit (parent_id);
+-- поиск поддерева: path LIKE '/1/4/%'
+CREATE INDEX org_unit_path_idx ON org_unit (path text_pattern_ops);
+-- имена подразделений уникальны среди подразделений одного родителя
+CREATE UNIQUE INDEX org_unit_name_key ON org_unit (coalesce(parent_id, 0), lower(name));
+
+ALTER TABLE employee
+    ADD COLUMN org_unit_id BIGINT REFERENCES org_unit (id) ON DELETE SET NULL;
+CREATE INDEX employee_org_unit_idx ON employee (org_unit_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists employee_org_unit_idx;
+alter table employee
+    drop column if exists org_unit_id;
+drop table if exists org_unit;
+-- +goose StatementEnd
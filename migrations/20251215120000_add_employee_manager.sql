@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE employee
+    ADD COLUMN manager_id BIGINT REFERENCES employee (id) ON DELETE SET NULL,
+    ADD CONSTRAINT employee_manager_not_self CHECK (manager_id <> id);
+-- прямые подчинённые и обход иерархии вниз
+CREATE INDEX employee_manager_idx ON employee (manager_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists employee_manager_idx;
+alter table employee
+    drop constraint if exists employee_manager_not_self,
+    drop column if exists manager_id;
+-- +goose StatementEnd
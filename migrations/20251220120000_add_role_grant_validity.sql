@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE employee_role
+    ADD COLUMN valid_from TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN valid_to   TIMESTAMPTZ,
+    ADD CONSTRAINT employee_role_validity CHECK (valid_to IS NULL OR valid_to > valid_from);
+-- поиск истёкших назначений фоновым отзывом
+CREATE INDEX employee_role_valid_to_idx ON employee_role (valid_to) WHERE valid_to IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists employee_role_valid_to_idx;
+alter table employee_role
+    drop constraint if exists employee_role_validity,
+    drop column if exists valid_to,
+    drop column if exists valid_from;
+-- +goose StatementEnd
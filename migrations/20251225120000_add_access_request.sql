@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE role
+    ADD COLUMN owner_id BIGINT REFERENCES employee (id) ON DELETE SET NULL;
+
+CREATE TABLE access_request
+(
+    id            BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    employee_id   BIGINT      NOT NULL REFERENCES employee (id) ON DELETE CASCADE,
+    role_id       BIGINT      NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    requested_by  TEXT        NOT NULL,
+    justification TEXT        NOT NULL CHECK (char_length(trim(justification)) > 0),
+    -- срок действия назначения, которое будет выдано после согласования; NULL - бессрочно
+    valid_to      TIMESTAMPTZ,
+    status        TEXT        NOT NULL DEFAULT 'pending',
+    -- этапы согласования по порядку (manager, role_owner, admin) и индекс текущего этапа
+    stages        TEXT[]      NOT NULL,
+    stage         INT         NOT NULL DEFAULT 0,
+    -- срок согласования текущего этапа (SLA)
+    due_at        TIMESTAMPTZ NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at   TIMESTAMPTZ,
+    resolved_by   TEXT
+);
+
+-- на одну роль у сотрудника может быть только одна незакрытая заявка
+CREATE UNIQUE INDEX access_request_pending_key ON access_request (employee_id, role_id) WHERE status = 'pending';
+-- поиск заявок с истёкшим сроком согласования
+CREATE INDEX access_request_due_idx ON access_request (due_at) WHERE status = 'pending';
+
+CREATE TABLE access_request_decision
+(
+    id         BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    request_id BIGINT      NOT NULL REFERENCES access_request (id) ON DELETE CASCADE,
+    stage      TEXT        NOT NULL,
+    approver   TEXT        NOT NULL,
+    decision   TEXT        NOT NULL,
+    comment    TEXT,
+    decided_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX access_request_decision_request_idx ON access_request_decision (request_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists access_request_decision;
+drop table if exists access_request;
+alter table role
+    drop column if exists owner_id;
+-- +goose StatementEnd
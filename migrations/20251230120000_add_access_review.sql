@@ -0,0 +1,61 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE access_review_campaign
+(
+    id               BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name             TEXT        NOT NULL CHECK (char_length(trim(name)) > 0),
+    -- охват: role - назначения одной роли, org_unit - сотрудники подразделения и его поддерева, all - все назначения
+    scope_type       TEXT        NOT NULL,
+    scope_id         BIGINT,
+    -- кто проверяет назначения: manager - руководитель сотрудника, role_owner - владелец роли
+    reviewer_type    TEXT        NOT NULL,
+    -- отзывать ли при закрытии назначения, по которым решение не принято
+    revoke_undecided BOOLEAN     NOT NULL DEFAULT false,
+    status           TEXT        NOT NULL DEFAULT 'open',
+    due_at           TIMESTAMPTZ NOT NULL,
+    created_by       TEXT        NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_at        TIMESTAMPTZ,
+    closed_by        TEXT,
+    -- итоговый отчёт (канонический JSON) и его sha256; заполняются при закрытии и больше не меняются
+    report           TEXT,
+    report_digest    TEXT,
+    CONSTRAINT access_review_campaign_scope CHECK ((scope_type = 'all') = (scope_id IS NULL))
+);
+
+-- закрытие кампаний по сроку
+CREATE INDEX access_review_campaign_due_idx ON access_review_campaign (due_at) WHERE status = 'open';
+
+CREATE TABLE access_review_item
+(
+    id               BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    campaign_id      BIGINT      NOT NULL REFERENCES access_review_campaign (id) ON DELETE CASCADE,
+    employee_id      BIGINT      NOT NULL REFERENCES employee (id) ON DELETE CASCADE,
+    role_id          BIGINT      NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    -- срок действия назначения на момент запуска кампании
+    granted_valid_to TIMESTAMPTZ,
+    -- проверяющий; NULL - назначение проверяет администратор IDM
+    reviewer_id      BIGINT REFERENCES employee (id) ON DELETE SET NULL,
+    -- keep или revoke; NULL - решение ещё не принято
+    decision         TEXT,
+    comment          TEXT,
+    decided_by       TEXT,
+    decided_at       TIMESTAMPTZ,
+    -- кто передал проверку текущему проверяющему
+    delegated_by     TEXT,
+    reminded_at      TIMESTAMPTZ,
+    -- результат закрытия: kept, revoked или missing (назначение снято до закрытия)
+    outcome          TEXT,
+    CONSTRAINT access_review_item_key UNIQUE (campaign_id, employee_id, role_id)
+);
+
+-- очередь проверяющего и рассылка напоминаний
+CREATE INDEX access_review_item_pending_idx ON access_review_item (reviewer_id) WHERE decision IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists access_review_item;
+drop table if exists access_review_campaign;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- HMAC-SHA256 итогового отчёта; вычисляется при закрытии кампании, правка отчёта в БД без ключа её не подделает
+ALTER TABLE access_review_campaign
+    ADD COLUMN report_signature TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table access_review_campaign
+    drop column if exists report_signature;
+-- +goose StatementEnd
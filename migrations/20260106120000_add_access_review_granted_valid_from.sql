@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- начало действия назначения на момент запуска кампании: вместе с granted_valid_to отличает проверенное назначение
+-- от выданного заново во время кампании
+ALTER TABLE access_review_item
+    ADD COLUMN granted_valid_from TIMESTAMPTZ;
+-- назначения, которые ещё не сняты и не менялись с запуска кампании; для остальных при закрытии будет missing
+UPDATE access_review_item i
+SET granted_valid_from = er.valid_from
+FROM employee_role er
+WHERE er.employee_id = i.employee_id
+  AND er.role_id = i.role_id
+  AND er.valid_to IS NOT DISTINCT FROM i.granted_valid_to;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table access_review_item
+    drop column if exists granted_valid_from;
+-- +goose StatementEnd
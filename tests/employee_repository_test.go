@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -27,11 +28,11 @@ func TestEmployeeRepository_AddAndFindById(t *testing.T) {
 		UpdatedAt: now,
 	}
 
-	id, err := repo.Save(e) // Save возвращает (id, error)
+	id, err := repo.Save(context.Background(), e) // Save возвращает (id, error)
 	if err != nil {
 		t.Fatalf("Save() error = %v", err)
 	}
-	got, err := repo.FindById(id)
+	got, err := repo.FindById(context.Background(), id)
 	if err != nil {
 		t.Fatalf("FindById(%d) error = %v", id, err)
 	}
@@ -46,10 +47,10 @@ func TestEmployeeRepository_FindAll(t *testing.T) {
 
 	repo := employee.NewEmployeeRepository(testDB)
 	now := time.Now()
-	_, _ = repo.Save(&employee.Entity{Name: "Bob", CreatedAt: now, UpdatedAt: now})
-	_, _ = repo.Save(&employee.Entity{Name: "Carol", CreatedAt: now, UpdatedAt: now})
+	_, _ = repo.Save(context.Background(), &employee.Entity{Name: "Bob", CreatedAt: now, UpdatedAt: now})
+	_, _ = repo.Save(context.Background(), &employee.Entity{Name: "Carol", CreatedAt: now, UpdatedAt: now})
 
-	all, err := repo.FindAll()
+	all, err := repo.FindAll(context.Background())
 	if err != nil {
 		t.Fatalf("FindAll() error = %v", err)
 	}
@@ -63,10 +64,10 @@ func TestEmployeeRepository_FindByIds(t *testing.T) {
 
 	repo := employee.NewEmployeeRepository(testDB)
 	now := time.Now()
-	id1, _ := repo.Save(&employee.Entity{Name: "Dave", CreatedAt: now, UpdatedAt: now})
-	id2, _ := repo.Save(&employee.Entity{Name: "Eve", CreatedAt: now, UpdatedAt: now})
+	id1, _ := repo.Save(context.Background(), &employee.Entity{Name: "Dave", CreatedAt: now, UpdatedAt: now})
+	id2, _ := repo.Save(context.Background(), &employee.Entity{Name: "Eve", CreatedAt: now, UpdatedAt: now})
 
-	subset, err := repo.FindByIds([]int64{id1, id2})
+	subset, err := repo.FindByIds(context.Background(), []int64{id1, id2})
 	if err != nil {
 		t.Fatalf("FindByIds() error = %v", err)
 	}
@@ -80,13 +81,13 @@ func TestEmployeeRepository_DeleteById(t *testing.T) {
 
 	repo := employee.NewEmployeeRepository(testDB)
 	now := time.Now()
-	id, _ := repo.Save(&employee.Entity{Name: "Frank", CreatedAt: now, UpdatedAt: now})
+	id, _ := repo.Save(context.Background(), &employee.Entity{Name: "Frank", CreatedAt: now, UpdatedAt: now})
 
-	if err := repo.DeleteById(id); err != nil {
+	if err := repo.DeleteById(context.Background(), id); err != nil {
 		t.Fatalf("DeleteById(%d) error = %v", id, err)
 	}
 
-	all, _ := repo.FindAll()
+	all, _ := repo.FindAll(context.Background())
 	if len(all) != 0 {
 		t.Errorf("After DeleteById, FindAll() len = %d; want 0", len(all))
 	}
@@ -97,13 +98,13 @@ func TestEmployeeRepository_DeleteByIds(t *testing.T) {
 
 	repo := employee.NewEmployeeRepository(testDB)
 	now := time.Now()
-	id1, _ := repo.Save(&employee.Entity{Name: "George", CreatedAt: now, UpdatedAt: now})
-	id2, _ := repo.Save(&employee.Entity{Name: "Hannah", CreatedAt: now, UpdatedAt: now})
+	id1, _ := repo.Save(context.Background(), &employee.Entity{Name: "George", CreatedAt: now, UpdatedAt: now})
+	id2, _ := repo.Save(context.Background(), &employee.Entity{Name: "Hannah", CreatedAt: now, UpdatedAt: now})
 
-	if err := repo.DeleteByIds([]int64{id1, id2}); err != nil {
+	if err := repo.DeleteByIds(context.Background(), []int64{id1, id2}); err != nil {
 		t.Fatalf("DeleteByIds(%v) error = %v", []int64{id1, id2}, err)
 	}
-	all, _ := repo.FindAll()
+	all, _ := repo.FindAll(context.Background())
 	if len(all) != 0 {
 		t.Errorf("After DeleteByIds, FindAll() len = %d; want 0", len(all))
 	}
@@ -114,7 +115,7 @@ func TestRepository_BeginTransaction_Rollback(t *testing.T) {
 	TruncateTable(testDB)
 	repo := employee.NewEmployeeRepository(testDB)
 	// Begin a transaction
-	tx, err := repo.BeginTransaction()
+	tx, err := repo.BeginTransaction(context.Background())
 	if err != nil {
 		t.Fatalf("BeginTransaction() error = %v", err)
 	}
@@ -141,7 +142,7 @@ func TestRepository_BeginTransaction_Rollback(t *testing.T) {
 func TestRepository_FindByNameTx(t *testing.T) {
 	TruncateTable(testDB)
 	repo := employee.NewEmployeeRepository(testDB)
-	tx, err := repo.BeginTransaction()
+	tx, err := repo.BeginTransaction(context.Background())
 	if err != nil {
 		t.Fatalf("BeginTransaction() error = %v", err)
 	}
@@ -149,7 +150,7 @@ func TestRepository_FindByNameTx(t *testing.T) {
 		_ = tx.Rollback()
 	}()
 	// Initially, no such name
-	exists, err := repo.FindByNameTx(tx, "Nobody")
+	exists, err := repo.FindByNameTx(context.Background(), tx, "Nobody")
 	if err != nil {
 		t.Fatalf("FindByNameTx() error = %v", err)
 	}
@@ -162,7 +163,7 @@ func TestRepository_FindByNameTx(t *testing.T) {
 		t.Fatalf("tx.Exec insert error = %v", err)
 	}
 	// Now FindByNameTx should see it
-	exists, err = repo.FindByNameTx(tx, "Alice")
+	exists, err = repo.FindByNameTx(context.Background(), tx, "Alice")
 	if err != nil {
 		t.Fatalf("FindByNameTx() error after insert = %v", err)
 	}
@@ -175,12 +176,12 @@ func TestRepository_FindByNameTx(t *testing.T) {
 func TestRepository_SaveTx(t *testing.T) {
 	TruncateTable(testDB)
 	repo := employee.NewEmployeeRepository(testDB)
-	tx, err := repo.BeginTransaction()
+	tx, err := repo.BeginTransaction(context.Background())
 	if err != nil {
 		t.Fatalf("BeginTransaction() error = %v", err)
 	}
 	// Use SaveTx to insert a new entity
-	id, err := repo.SaveTx(tx, &employee.Entity{Name: "Bob"})
+	id, err := repo.SaveTx(context.Background(), tx, &employee.Entity{Name: "Bob"})
 	if err != nil {
 		t.Fatalf("SaveTx() error = %v", err)
 	}
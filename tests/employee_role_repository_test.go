@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"testing"
+	"time"
+)
+
+func TestEmployeeRoleRepository_AssignAndRevoke(t *testing.T) {
+	TruncateTable(testDB)
+	TruncateRoleTable()
+
+	employees := employee.NewEmployeeRepository(testDB)
+	roles := role.NewRoleRepository(testDB)
+	now := time.Now()
+	employeeId, err := employees.Save(context.Background(), &employee.Entity{Name: "Alice", CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	_ = roles.Add(context.Background(), &role.Entity{Name: "Admin", CreatedAt: now, UpdatedAt: now})
+	_ = roles.Add(context.Background(), &role.Entity{Name: "User", CreatedAt: now, UpdatedAt: now})
+	all, _ := roles.FindAll(context.Background())
+	roleIds := []int64{all[0].Id, all[1].Id}
+
+	tx, err := employees.BeginTransaction(context.Background())
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	if err := employees.AssignRolesTx(context.Background(), tx, employeeId, roleIds, nil, nil); err != nil {
+		t.Fatalf("AssignRolesTx() error = %v", err)
+	}
+	// повторное назначение не должно приводить к ошибке
+	if err := employees.AssignRolesTx(context.Background(), tx, employeeId, roleIds[:1], nil, nil); err != nil {
+		t.Fatalf("AssignRolesTx() repeated error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit() error = %v", err)
+	}
+
+	assigned, err := roles.FindByEmployeeId(context.Background(), employeeId)
+	if err != nil {
+		t.Fatalf("FindByEmployeeId() error = %v", err)
+	}
+	if len(assigned) != 2 {
+		t.Errorf("FindByEmployeeId() len = %d; want 2", len(assigned))
+	}
+	holders, err := employees.FindByRoleId(context.Background(), roleIds[0])
+	if err != nil {
+		t.Fatalf("FindByRoleId() error = %v", err)
+	}
+	if len(holders) != 1 || holders[0].Id != employeeId {
+		t.Errorf("FindByRoleId() = %+v; want employee %d", holders, employeeId)
+	}
+
+	tx, _ = employees.BeginTransaction(context.Background())
+	if err := employees.RevokeRolesTx(context.Background(), tx, employeeId, roleIds[:1]); err != nil {
+		t.Fatalf("RevokeRolesTx() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit() error = %v", err)
+	}
+	assigned, _ = roles.FindByEmployeeId(context.Background(), employeeId)
+	if len(assigned) != 1 || assigned[0].Id != roleIds[1] {
+		t.Errorf("after revoke FindByEmployeeId() = %+v; want only role %d", assigned, roleIds[1])
+	}
+}
+
+func TestEmployeeRoleRepository_Validity(t *testing.T) {
+	TruncateTable(testDB)
+	TruncateRoleTable()
+
+	employees := employee.NewEmployeeRepository(testDB)
+	roles := role.NewRoleRepository(testDB)
+	now := time.Now()
+	employeeId, err := employees.Save(context.Background(), &employee.Entity{Name: "Alice", CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	_ = roles.Add(context.Background(), &role.Entity{Name: "Expired", CreatedAt: now, UpdatedAt: now})
+	_ = roles.Add(context.Background(), &role.Entity{Name: "Future", CreatedAt: now, UpdatedAt: now})
+	_ = roles.Add(context.Background(), &role.Entity{Name: "Temporary", CreatedAt: now, UpdatedAt: now})
+	all, _ := roles.FindAll(context.Background())
+
+	past, yesterday, tomorrow := now.Add(-48*time.Hour), now.Add(-24*time.Hour), now.Add(24*time.Hour)
+	tx, _ := employees.BeginTransaction(context.Background())
+	for _, grant := range []struct {
+		roleId   int64
+		from, to *time.Time
+	}{
+		{all[0].Id, &past, &yesterday},
+		{all[1].Id, &tomorrow, nil},
+		{all[2].Id, nil, &tomorrow},
+	} {
+		if err := employees.AssignRolesTx(context.Background(), tx, employeeId, []int64{grant.roleId}, grant.from, grant.to); err != nil {
+			t.Fatalf("AssignRolesTx() error = %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit() error = %v", err)
+	}
+
+	// действующим считается только назначение, срок которого уже начался и ещё не истёк
+	assigned, _ := roles.FindByEmployeeId(context.Background(), employeeId)
+	if len(assigned) != 1 || assigned[0].Id != all[2].Id {
+		t.Errorf("FindByEmployeeId() = %+v; want only role %d", assigned, all[2].Id)
+	}
+	grants, _ := employees.FindGrants(context.Background(), employeeId)
+	if len(grants) != 3 {
+		t.Errorf("FindGrants() len = %d; want 3", len(grants))
+	}
+
+	tx, _ = employees.BeginTransaction(context.Background())
+	locked, err := employees.TryLockGrantExpiryTx(context.Background(), tx)
+	if err != nil || !locked {
+		t.Fatalf("TryLockGrantExpiryTx() = %v, %v; want true", locked, err)
+	}
+	expired, err := employees.DeleteExpiredGrantsTx(context.Background(), tx, now)
+	if err != nil {
+		t.Fatalf("DeleteExpiredGrantsTx() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit() error = %v", err)
+	}
+	if len(expired) != 1 || expired[0].RoleId != all[0].Id {
+		t.Errorf("DeleteExpiredGrantsTx() = %+v; want only role %d", expired, all[0].Id)
+	}
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"github.com/jmoiron/sqlx"
 	"idm/inner/employee"
 	"os"
@@ -20,7 +21,7 @@ func (f *Fixture) Employee(name string) int64 {
 	var entity = employee.Entity{
 		Name: name,
 	}
-	var newId, err = f.employees.Save(&entity)
+	var newId, err = f.employees.Save(context.Background(), &entity)
 	if err != nil {
 		panic(err)
 	}
@@ -70,7 +71,22 @@ CREATE TABLE IF NOT EXISTS employee (
   id         BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
   name       TEXT        NOT NULL CHECK (char_length(trim(name)) > 0),
   created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
-  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  deleted_at TIMESTAMPTZ,
+  deleted_by TEXT,
+  status     TEXT        NOT NULL DEFAULT 'active',
+  start_date DATE,
+  end_date   DATE,
+  email           TEXT,
+  login           TEXT,
+  first_name      TEXT,
+  last_name       TEXT,
+  phone           TEXT,
+  title           TEXT,
+  employee_number TEXT,
+  attributes      JSONB NOT NULL DEFAULT '{}',
+  org_unit_id     BIGINT,
+  manager_id      BIGINT
 );`
 	if _, err := db.Exec(schema); err != nil {
 		return err
@@ -100,7 +116,10 @@ CREATE TABLE IF NOT EXISTS role
 (	id         BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
     name       TEXT        NOT NULL,
     created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
-    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ,
+    deleted_by TEXT,
+    owner_id   BIGINT REFERENCES employee (id) ON DELETE SET NULL
 );`
 	if _, err := db.Exec(schema); err != nil {
 		return err
@@ -113,3 +132,32 @@ CREATE TABLE IF NOT EXISTS role
 	}
 	return nil
 }
+
+func CreateEmployeeRoleTestTable() error {
+	dsn := os.Getenv("DB_DSN")
+	if dsn == "" {
+		dsn = "host=localhost port=5432 user=postgres password=postgres dbname=idm_tests sslmode=disable"
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		return err
+	}
+
+	// Создаём связующую таблицу employee_role, если она отсутствует:
+	schema := `
+CREATE TABLE IF NOT EXISTS employee_role
+(
+    employee_id BIGINT      NOT NULL REFERENCES employee (id) ON DELETE CASCADE,
+    role_id     BIGINT      NOT NULL REFERENCES role (id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    valid_from  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    valid_to    TIMESTAMPTZ CHECK (valid_to IS NULL OR valid_to > valid_from),
+    PRIMARY KEY (employee_id, role_id)
+);`
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	return db.Close()
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"idm/inner/common"
@@ -27,6 +28,10 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		panic(fmt.Errorf("failed to connect to test database: %v", err))
 	}
+	err = CreateEmployeeRoleTestTable()
+	if err != nil {
+		panic(fmt.Errorf("failed to connect to test database: %v", err))
+	}
 	// Запускаем все тесты в этом пакете:
 	code := m.Run()
 	removeDotEnv()
@@ -50,7 +55,7 @@ func TestRepository(t *testing.T) {
 	t.Run("find an employee by id", func(t *testing.T) {
 		var newEmployeeId = fixture.Employee("Test Name")
 
-		got, err := employeeRepository.FindById(newEmployeeId)
+		got, err := employeeRepository.FindById(context.Background(), newEmployeeId)
 
 		a.Nil(err)
 		a.NotEmpty(got)
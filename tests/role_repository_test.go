@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"idm/inner/role"
 	"testing"
@@ -25,17 +26,17 @@ func TestRoleRepository_AddAndFindById(t *testing.T) {
 		UpdatedAt: now,
 	}
 
-	err := repo.Add(r)
+	err := repo.Add(context.Background(), r)
 	if err != nil {
 		t.Fatalf("Add() error = %v", err)
 	}
 
-	roles, _ := repo.FindAll()
+	roles, _ := repo.FindAll(context.Background())
 	if len(roles) != 1 {
 		t.Fatalf("Expected 1 role, got %d", len(roles))
 	}
 
-	got, err := repo.FindById(roles[0].Id)
+	got, err := repo.FindById(context.Background(), roles[0].Id)
 	if err != nil {
 		t.Fatalf("FindById() error = %v", err)
 	}
@@ -48,16 +49,16 @@ func TestRoleRepository_FindAll(t *testing.T) {
 	TruncateRoleTable()
 	repo := role.NewRoleRepository(testDB)
 	now := time.Now()
-	err := repo.Add(&role.Entity{Name: "Dev", CreatedAt: now, UpdatedAt: now})
+	err := repo.Add(context.Background(), &role.Entity{Name: "Dev", CreatedAt: now, UpdatedAt: now})
 	if err != nil {
 		t.Fatalf("Add() error = %v", err)
 	}
-	err = repo.Add(&role.Entity{Name: "QA", CreatedAt: now, UpdatedAt: now})
+	err = repo.Add(context.Background(), &role.Entity{Name: "QA", CreatedAt: now, UpdatedAt: now})
 	if err != nil {
 		t.Fatalf("Add() error = %v", err)
 	}
 
-	all, err := repo.FindAll()
+	all, err := repo.FindAll(context.Background())
 	if err != nil {
 		t.Fatalf("FindAll() error = %v", err)
 	}
@@ -70,18 +71,18 @@ func TestRoleRepository_FindByIds(t *testing.T) {
 	TruncateRoleTable()
 	repo := role.NewRoleRepository(testDB)
 	now := time.Now()
-	err := repo.Add(&role.Entity{Name: "PM", CreatedAt: now, UpdatedAt: now})
+	err := repo.Add(context.Background(), &role.Entity{Name: "PM", CreatedAt: now, UpdatedAt: now})
 	if err != nil {
 		t.Fatalf("Add() error = %v", err)
 	}
-	err = repo.Add(&role.Entity{Name: "Support", CreatedAt: now, UpdatedAt: now})
+	err = repo.Add(context.Background(), &role.Entity{Name: "Support", CreatedAt: now, UpdatedAt: now})
 	if err != nil {
 		t.Fatalf("Add() error = %v", err)
 	}
-	all, _ := repo.FindAll()
+	all, _ := repo.FindAll(context.Background())
 	ids := []int64{all[0].Id, all[1].Id}
 
-	result, err := repo.FindByIds(ids)
+	result, err := repo.FindByIds(context.Background(), ids)
 	if err != nil {
 		t.Fatalf("FindByIds() error = %v", err)
 	}
@@ -94,18 +95,18 @@ func TestRoleRepository_DeleteById(t *testing.T) {
 	TruncateRoleTable()
 	repo := role.NewRoleRepository(testDB)
 	now := time.Now()
-	err := repo.Add(&role.Entity{Name: "Temp", CreatedAt: now, UpdatedAt: now})
+	err := repo.Add(context.Background(), &role.Entity{Name: "Temp", CreatedAt: now, UpdatedAt: now})
 	if err != nil {
 		t.Fatalf("Add() error = %v", err)
 	}
-	all, _ := repo.FindAll()
+	all, _ := repo.FindAll(context.Background())
 	id := all[0].Id
 
-	err = repo.DeleteById(id)
+	err = repo.DeleteById(context.Background(), id)
 	if err != nil {
 		t.Fatalf("DeleteById() error = %v", err)
 	}
-	remaining, _ := repo.FindAll()
+	remaining, _ := repo.FindAll(context.Background())
 	if len(remaining) != 0 {
 		t.Errorf("After DeleteById, FindAll() len = %d; want 0", len(remaining))
 	}
@@ -115,23 +116,71 @@ func TestRoleRepository_DeleteByIds(t *testing.T) {
 	TruncateRoleTable()
 	repo := role.NewRoleRepository(testDB)
 	now := time.Now()
-	err := repo.Add(&role.Entity{Name: "Intern", CreatedAt: now, UpdatedAt: now})
+	err := repo.Add(context.Background(), &role.Entity{Name: "Intern", CreatedAt: now, UpdatedAt: now})
 	if err != nil {
 		t.Fatalf("Add() error = %v", err)
 	}
-	err = repo.Add(&role.Entity{Name: "Contractor", CreatedAt: now, UpdatedAt: now})
+	err = repo.Add(context.Background(), &role.Entity{Name: "Contractor", CreatedAt: now, UpdatedAt: now})
 	if err != nil {
 		t.Fatalf("Add() error = %v", err)
 	}
-	all, _ := repo.FindAll()
+	all, _ := repo.FindAll(context.Background())
 	ids := []int64{all[0].Id, all[1].Id}
 
-	err = repo.DeleteByIds(ids)
+	err = repo.DeleteByIds(context.Background(), ids)
 	if err != nil {
 		t.Fatalf("DeleteByIds() error = %v", err)
 	}
-	remaining, _ := repo.FindAll()
+	remaining, _ := repo.FindAll(context.Background())
 	if len(remaining) != 0 {
 		t.Errorf("After DeleteByIds, FindAll() len = %d; want 0", len(remaining))
 	}
 }
+
+func TestRoleRepository_SaveTxAndFindByNameTx(t *testing.T) {
+	TruncateRoleTable()
+	repo := role.NewRoleRepository(testDB)
+	tx, err := repo.BeginTransaction(context.Background())
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	id, err := repo.SaveTx(context.Background(), tx, &role.Entity{Name: "Auditor"})
+	if err != nil {
+		t.Fatalf("SaveTx() error = %v", err)
+	}
+	exists, err := repo.FindByNameTx(context.Background(), tx, "Auditor")
+	if err != nil {
+		t.Fatalf("FindByNameTx() error = %v", err)
+	}
+	if !exists {
+		t.Errorf("expected exists=true for name 'Auditor', got false")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit() error = %v", err)
+	}
+	got, err := repo.FindById(context.Background(), id)
+	if err != nil {
+		t.Fatalf("FindById(%d) error = %v", id, err)
+	}
+	if got.Name != "Auditor" {
+		t.Errorf("FindById(%d).Name = %q; want %q", id, got.Name, "Auditor")
+	}
+}
+
+func TestRoleRepository_FindRolesPage(t *testing.T) {
+	TruncateRoleTable()
+	repo := role.NewRoleRepository(testDB)
+	now := time.Now()
+	for _, name := range []string{"Admin", "Administrator", "User"} {
+		if err := repo.Add(context.Background(), &role.Entity{Name: name, CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	page, total, err := repo.FindRolesPage(context.Background(), role.PageRequest{PageSize: 10, PageNumber: 0, TextFilter: "adm"})
+	if err != nil {
+		t.Fatalf("FindRolesPage() error = %v", err)
+	}
+	if len(page) != 2 || total != 2 {
+		t.Errorf("FindRolesPage() len = %d, total = %d; want 2, 2", len(page), total)
+	}
+}
@@ -33,7 +33,7 @@ func Test_EmployeePaginationIntegration(t *testing.T) {
 		{"Missing PageSize", "/api/v1/employees/page?pageNumber=0", 0, 400}, // считаем, что pageSize обязателен
 	}
 
-	srv, _ := server.Build()
+	srv, _, _ := server.Build()
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -105,7 +105,7 @@ func Test_EmployeeSearchByNameIntegration(t *testing.T) {
 		assert.NoError(t, err)
 	}
 
-	srv, _ := server.Build()
+	srv, _, _ := server.Build()
 
 	type respDTO struct {
 		Success bool   `json:"success"`